@@ -0,0 +1,321 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cryptellation/go-clients/client"
+	ticksapi "github.com/cryptellation/ticks/api"
+	ticksclient "github.com/cryptellation/ticks/pkg/clients"
+	"github.com/cryptellation/ticks/pkg/tick"
+	"github.com/google/uuid"
+	temporalLog "go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+	"golang.org/x/time/rate"
+)
+
+var (
+	// ErrInvalidRule is returned when a rule has no name or the same name
+	// as another one.
+	ErrInvalidRule = errors.New("invalid alert rule")
+)
+
+const (
+	// callbackPrefix is the prefix of the callbacks receiving the ticks.
+	callbackPrefix = "AlertEngine"
+	// stopTimeout is the maximum duration to stop listening to ticks once
+	// the engine stops.
+	stopTimeout = 10 * time.Second
+	// ticksBufferSize is the number of ticks received by the callbacks and
+	// waiting to be processed. Ticks are dropped when it is full.
+	ticksBufferSize = 1024
+)
+
+// Alert is raised when the condition of a rule becomes met.
+type Alert struct {
+	Rule     string    `json:"rule"`
+	Time     time.Time `json:"time"`
+	Exchange string    `json:"exchange"`
+	Pair     string    `json:"pair"`
+	Price    float64   `json:"price"`
+	Message  string    `json:"message"`
+}
+
+// TicksListener is a source of live ticks for the engine. The Cryptellation
+// client implements this interface.
+type TicksListener interface {
+	// ListenToTicks listens to ticks from a specific exchange and trading pair.
+	ListenToTicks(ctx context.Context, listener ticksclient.ListenerParams, exchange, pair string) error
+	// StopListeningToTicks unregisters a callback workflow from ticks for a given exchange and pair.
+	StopListeningToTicks(ctx context.Context, listener uuid.UUID, exchange string, pair string) error
+}
+
+// Engine evaluates rules on the ticks and delivers the alerts to sinks.
+// Alerts are deduplicated: a rule only raises an alert when its condition
+// becomes met, and not again before the cooldown. They can also be rate
+// limited across all the rules.
+type Engine struct {
+	rules    []Rule
+	sinks    []Sink
+	cooldown time.Duration
+	limiter  *rate.Limiter
+	logger   temporalLog.Logger
+
+	mu           sync.Mutex
+	market       *Market
+	met          map[string]bool
+	last         map[string]time.Time
+	dropped      atomic.Uint64
+	droppedTicks atomic.Uint64
+}
+
+// Options is a function that modifies the engine configuration.
+type Options func(*Engine)
+
+// WithSinks adds sinks receiving the alerts.
+func WithSinks(sinks ...Sink) Options {
+	return func(e *Engine) {
+		e.sinks = append(e.sinks, sinks...)
+	}
+}
+
+// WithLogger sets the logger of the errors of the sinks when the ticks are
+// consumed or listened to by the engine.
+func WithLogger(logger temporalLog.Logger) Options {
+	return func(e *Engine) {
+		e.logger = logger
+	}
+}
+
+// WithCooldown sets the minimum duration between two alerts of the same
+// rule, in ticks time. Default is no cooldown.
+func WithCooldown(cooldown time.Duration) Options {
+	return func(e *Engine) {
+		e.cooldown = cooldown
+	}
+}
+
+// WithRateLimit limits the number of alerts delivered per second, across all
+// the rules, with the given burst. Alerts above the limit are dropped.
+func WithRateLimit(perSecond float64, burst int) Options {
+	return func(e *Engine) {
+		e.limiter = rate.NewLimiter(rate.Limit(perSecond), burst)
+	}
+}
+
+// New creates an engine evaluating the given rules. The pairs of the rules
+// of this package are written with a separator in one of the common notations
+// (see market.NormalizePair), and kept in the notation of the services, as the
+// ticks are. The other rules must already use the notation of the services.
+func New(rules []Rule, opts ...Options) (*Engine, error) {
+	var window time.Duration
+	names := make(map[string]bool, len(rules))
+	normalized := make([]Rule, len(rules))
+	for i, r := range rules {
+		switch {
+		case r.Name() == "":
+			return nil, fmt.Errorf("%w: empty name", ErrInvalidRule)
+		case names[r.Name()]:
+			return nil, fmt.Errorf("%w: duplicate name %q", ErrInvalidRule, r.Name())
+		}
+
+		var err error
+		if normalized[i], err = normalizeRule(r); err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", ErrInvalidRule, r.Name(), err)
+		}
+		names[r.Name()] = true
+		window = max(window, r.Window())
+	}
+
+	e := &Engine{
+		rules:  normalized,
+		market: newMarket(window),
+		met:    make(map[string]bool),
+		last:   make(map[string]time.Time),
+		logger: &client.DummyLogger{},
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e, nil
+}
+
+// Subscriptions returns the exchanges and pairs watched by the rules.
+func (e *Engine) Subscriptions() []tick.Subscription {
+	seen := make(map[tick.Subscription]bool)
+	var subs []tick.Subscription
+	for _, r := range e.rules {
+		for _, s := range r.Subscriptions() {
+			if !seen[s] {
+				seen[s] = true
+				subs = append(subs, s)
+			}
+		}
+	}
+	return subs
+}
+
+// Dropped returns the number of alerts dropped by the rate limit.
+func (e *Engine) Dropped() uint64 {
+	return e.dropped.Load()
+}
+
+// DroppedTicks returns the number of ticks dropped by Listen because they
+// were received faster than they were processed.
+func (e *Engine) DroppedTicks() uint64 {
+	return e.droppedTicks.Load()
+}
+
+// Process evaluates the rules watching the tick and delivers the raised
+// alerts to the sinks. It returns the errors of the sinks.
+func (e *Engine) Process(ctx context.Context, t tick.Tick) error {
+	alerts := e.evaluate(t)
+
+	var errs []error
+	for _, a := range alerts {
+		if e.limiter != nil && !e.limiter.Allow() {
+			e.dropped.Add(1)
+			continue
+		}
+
+		for _, s := range e.sinks {
+			if err := s.Send(ctx, a); err != nil {
+				errs = append(errs, fmt.Errorf("sending alert of rule %q: %w", a.Rule, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// evaluate adds the tick to the market and returns the alerts raised by the rules.
+func (e *Engine) evaluate(t tick.Tick) []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.market.add(t)
+
+	var alerts []Alert
+	sub := tick.Subscription{Exchange: t.Exchange, Pair: t.Pair}
+	for _, r := range e.rules {
+		if !watches(r, sub) {
+			continue
+		}
+
+		// Only alert when the condition becomes met
+		eval := r.Evaluate(e.market, t)
+		wasMet := e.met[r.Name()]
+		e.met[r.Name()] = eval.Triggered
+		if !eval.Triggered || wasMet {
+			continue
+		}
+
+		// Respect the cooldown
+		if last, ok := e.last[r.Name()]; ok && t.Time.Sub(last) < e.cooldown {
+			continue
+		}
+		e.last[r.Name()] = t.Time
+
+		alerts = append(alerts, Alert{
+			Rule:     r.Name(),
+			Time:     t.Time,
+			Exchange: t.Exchange,
+			Pair:     t.Pair,
+			Price:    t.Price,
+			Message:  eval.Message,
+		})
+	}
+
+	return alerts
+}
+
+func watches(r Rule, sub tick.Subscription) bool {
+	for _, s := range r.Subscriptions() {
+		if s == sub {
+			return true
+		}
+	}
+	return false
+}
+
+// Consume processes the ticks of the channel until it is closed or the
+// context is done. Sinks errors are logged and do not stop the consumption.
+func (e *Engine) Consume(ctx context.Context, ticks <-chan tick.Tick) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case t, ok := <-ticks:
+			if !ok {
+				return nil
+			}
+			if err := e.Process(ctx, t); err != nil {
+				e.logger.Error("Delivering alerts", "error", err)
+			}
+		}
+	}
+}
+
+// Listen processes the ticks watched by the rules, received through the
+// listener, until the context is done. The callbacks receiving the ticks are
+// registered on the worker, that must listen on the task queue. They only hand
+// the ticks to a goroutine processing them, so the alerts are never delivered
+// from the workflows, and never delivered twice if a callback is retried.
+// Ticks are dropped when they are received faster than they are processed
+// (see DroppedTicks).
+func (e *Engine) Listen(ctx context.Context, listener TicksListener, w worker.Worker, taskQueue string) error {
+	ticks := make(chan tick.Tick, ticksBufferSize)
+	callback := func(_ workflow.Context, params ticksapi.ListenToTicksCallbackWorkflowParams) error {
+		select {
+		case ticks <- params.Tick:
+		default:
+			e.droppedTicks.Add(1)
+		}
+		return nil
+	}
+
+	// Process the ticks until the end of the listening
+	consumeCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = e.Consume(consumeCtx, ticks)
+	}()
+
+	// Listen to ticks, with one requester per subscription as each one has its callback
+	subs := e.Subscriptions()
+	requesters := make(map[uuid.UUID]tick.Subscription, len(subs))
+	defer func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+		defer cancel()
+		for id, sub := range requesters {
+			_ = listener.StopListeningToTicks(stopCtx, id, sub.Exchange, sub.Pair)
+		}
+	}()
+
+	for _, sub := range subs {
+		id := uuid.New()
+		err := listener.ListenToTicks(ctx, ticksclient.ListenerParams{
+			RequesterID:        id,
+			CallbackNamePrefix: callbackPrefix,
+			Callback:           callback,
+			Worker:             w,
+			TaskQueue:          taskQueue,
+		}, sub.Exchange, sub.Pair)
+		if err != nil {
+			return fmt.Errorf("listening to %s on %s: %w", sub.Pair, sub.Exchange, err)
+		}
+		requesters[id] = sub
+	}
+
+	<-ctx.Done()
+	return nil
+}
@@ -0,0 +1,195 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cryptellation/go-clients/internal/fixture"
+	"github.com/cryptellation/go-clients/market"
+	"github.com/cryptellation/ticks/pkg/tick"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	threshold := Threshold{ID: "a", Exchange: "binance", Pair: "BTC-USDT", Level: 100}
+
+	tests := []struct {
+		name  string
+		rules []Rule
+		err   error
+	}{
+		{name: "no rule", rules: nil},
+		{name: "valid rules", rules: []Rule{threshold, Spread{ID: "b", Pair: "BTC-USDT"}}},
+		{name: "empty name", rules: []Rule{Threshold{}}, err: ErrInvalidRule},
+		{name: "duplicate name", rules: []Rule{threshold, Spread{ID: "a"}}, err: ErrInvalidRule},
+		{name: "pair in another notation", rules: []Rule{Spread{ID: "b", Pair: "btc/usdt"}}},
+		{name: "compact pair", rules: []Rule{Spread{ID: "b", Pair: "BTCUSDT"}}, err: market.ErrAmbiguousPair},
+		{name: "other rule", rules: []Rule{customRule{pair: "BTC-USDT"}}},
+		{name: "other rule in another notation", rules: []Rule{customRule{pair: "btc/usdt"}}, err: ErrInvalidRule},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.rules)
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+// customRule is a rule of another package, that is never met.
+type customRule struct {
+	pair string
+}
+
+func (r customRule) Name() string {
+	return "custom"
+}
+
+func (r customRule) Subscriptions() []tick.Subscription {
+	return []tick.Subscription{{Exchange: "binance", Pair: r.pair}}
+}
+
+func (r customRule) Window() time.Duration {
+	return 0
+}
+
+func (r customRule) Evaluate(*Market, tick.Tick) Evaluation {
+	return Evaluation{}
+}
+
+func TestEngineSubscriptions(t *testing.T) {
+	e, err := New([]Rule{
+		Threshold{ID: "a", Exchange: "binance", Pair: "BTC-USDT"},
+		Spread{ID: "b", Pair: "BTC-USDT", ExchangeA: "binance", ExchangeB: "kraken"},
+		PercentChange{ID: "c", Exchange: "kraken", Pair: "eth/usdt"},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []tick.Subscription{
+		{Exchange: "binance", Pair: "BTC-USDT"},
+		{Exchange: "kraken", Pair: "BTC-USDT"},
+		{Exchange: "kraken", Pair: "ETH-USDT"},
+	}, e.Subscriptions())
+}
+
+func TestEngineCooldown(t *testing.T) {
+	rule := Threshold{ID: "r", Exchange: "binance", Pair: "BTC-USDT", Level: 100, Direction: DirectionAny}
+	ticks := []tick.Tick{
+		fixture.TickOn(0, "binance", 90),
+		fixture.TickOn(1, "binance", 110),
+		fixture.TickOn(2, "binance", 105),
+		fixture.TickOn(3, "binance", 90),
+		fixture.TickOn(4, "binance", 95),
+		fixture.TickOn(7, "binance", 110),
+	}
+
+	tests := []struct {
+		name     string
+		cooldown time.Duration
+		expected []int
+	}{
+		{name: "without cooldown", expected: []int{1, 3, 5}},
+		{name: "with cooldown", cooldown: 5 * time.Minute, expected: []int{1, 5}},
+		{name: "with cooldown longer than the ticks", cooldown: time.Hour, expected: []int{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := New([]Rule{rule}, WithCooldown(tt.cooldown))
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, alerted(t, e, ticks))
+		})
+	}
+}
+
+func TestEngineProcess(t *testing.T) {
+	rules := []Rule{
+		Threshold{ID: "a", Exchange: "binance", Pair: "BTC-USDT", Level: 100, Direction: DirectionUp},
+		Threshold{ID: "b", Exchange: "binance", Pair: "BTC-USDT", Level: 105, Direction: DirectionUp},
+	}
+	errSink := errors.New("sink error")
+
+	tests := []struct {
+		name      string
+		opts      []Options
+		sinkErr   error
+		delivered []string
+		dropped   uint64
+	}{
+		{
+			name:      "every alert delivered",
+			delivered: []string{"a", "b"},
+		},
+		{
+			name:      "rate limited",
+			opts:      []Options{WithRateLimit(0.001, 1)},
+			delivered: []string{"a"},
+			dropped:   1,
+		},
+		{
+			name:      "sink error",
+			sinkErr:   errSink,
+			delivered: []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var delivered []string
+			sink := SinkFunc(func(_ context.Context, a Alert) error {
+				delivered = append(delivered, a.Rule)
+				return tt.sinkErr
+			})
+
+			e, err := New(rules, append(tt.opts, WithSinks(sink))...)
+			require.NoError(t, err)
+			require.NoError(t, e.Process(context.Background(), fixture.TickOn(0, "binance", 90)))
+
+			err = e.Process(context.Background(), fixture.TickOn(1, "binance", 110))
+			require.ErrorIs(t, err, tt.sinkErr)
+			require.Equal(t, tt.delivered, delivered)
+			require.Equal(t, tt.dropped, e.Dropped())
+		})
+	}
+}
+
+func TestEngineAlert(t *testing.T) {
+	var alerts []Alert
+	e, err := New([]Rule{
+		Threshold{ID: "r", Exchange: "binance", Pair: "BTC-USDT", Level: 100, Direction: DirectionUp},
+	}, WithSinks(SinkFunc(func(_ context.Context, a Alert) error {
+		alerts = append(alerts, a)
+		return nil
+	})))
+	require.NoError(t, err)
+
+	require.NoError(t, e.Process(context.Background(), fixture.TickOn(0, "binance", 90)))
+	require.NoError(t, e.Process(context.Background(), fixture.TickOn(1, "binance", 110)))
+	require.Equal(t, []Alert{{
+		Rule:     "r",
+		Time:     fixture.TickOn(1, "binance", 110).Time,
+		Exchange: "binance",
+		Pair:     "BTC-USDT",
+		Price:    110,
+		Message:  "BTC-USDT on binance crossed 100 (90 -> 110)",
+	}}, alerts)
+}
+
+func TestEngineConsume(t *testing.T) {
+	alerts := make(chan Alert, 10)
+	e, err := New([]Rule{
+		Threshold{ID: "r", Exchange: "binance", Pair: "BTC-USDT", Level: 100, Direction: DirectionAny},
+	}, WithSinks(ChannelSink(alerts)))
+	require.NoError(t, err)
+
+	ticks := make(chan tick.Tick, 4)
+	for _, tk := range fixture.Ticks(90, 110, 105, 90) {
+		ticks <- tk
+	}
+	close(ticks)
+
+	require.NoError(t, e.Consume(context.Background(), ticks))
+	require.Len(t, alerts, 2)
+}
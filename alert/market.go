@@ -0,0 +1,66 @@
+package alert
+
+import (
+	"time"
+
+	"github.com/cryptellation/ticks/pkg/tick"
+)
+
+// Market is the recent history of the ticks received by an engine, used by
+// the rules to evaluate their conditions.
+type Market struct {
+	window time.Duration
+	ticks  map[tick.Subscription][]tick.Tick
+}
+
+func newMarket(window time.Duration) *Market {
+	return &Market{
+		window: window,
+		ticks:  make(map[tick.Subscription][]tick.Tick),
+	}
+}
+
+// add adds a tick to the history and removes the ticks out of the window,
+// keeping at least the two last ones.
+func (m *Market) add(t tick.Tick) {
+	sub := tick.Subscription{Exchange: t.Exchange, Pair: t.Pair}
+	history := append(m.ticks[sub], t)
+
+	from := t.Time.Add(-m.window)
+	first := 0
+	for first < len(history)-2 && history[first].Time.Before(from) {
+		first++
+	}
+	m.ticks[sub] = history[first:]
+}
+
+// Last returns the last tick of the pair on the exchange.
+func (m *Market) Last(exchange, pair string) (tick.Tick, bool) {
+	history := m.ticks[tick.Subscription{Exchange: exchange, Pair: pair}]
+	if len(history) == 0 {
+		return tick.Tick{}, false
+	}
+	return history[len(history)-1], true
+}
+
+// Previous returns the tick before the last one of the pair on the exchange.
+func (m *Market) Previous(exchange, pair string) (tick.Tick, bool) {
+	history := m.ticks[tick.Subscription{Exchange: exchange, Pair: pair}]
+	if len(history) < 2 {
+		return tick.Tick{}, false
+	}
+	return history[len(history)-2], true
+}
+
+// Since returns the ticks of the pair on the exchange received since the
+// given time, in chronological order. Only the ticks of the longest window of
+// the rules are kept.
+func (m *Market) Since(exchange, pair string, from time.Time) []tick.Tick {
+	history := m.ticks[tick.Subscription{Exchange: exchange, Pair: pair}]
+	for i, t := range history {
+		if !t.Time.Before(from) {
+			return history[i:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+package alert
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/cryptellation/go-clients/market"
+	"github.com/cryptellation/ticks/pkg/tick"
+)
+
+// Direction is the direction of the price moves watched by a rule.
+type Direction string
+
+const (
+	// DirectionUp watches the rises.
+	DirectionUp Direction = "up"
+	// DirectionDown watches the drops.
+	DirectionDown Direction = "down"
+	// DirectionAny watches the rises and the drops.
+	DirectionAny Direction = "any"
+)
+
+// String returns the string representation of the direction.
+func (d Direction) String() string {
+	return string(d)
+}
+
+// match returns true if the move from a to b is in the direction.
+func (d Direction) match(a, b float64) bool {
+	switch d {
+	case DirectionUp:
+		return b > a
+	case DirectionDown:
+		return b < a
+	default:
+		return b != a
+	}
+}
+
+// Evaluation is the result of the evaluation of a rule on a tick.
+type Evaluation struct {
+	// Triggered is true when the condition of the rule is met.
+	Triggered bool
+	// Message describes the condition, for the alert.
+	Message string
+}
+
+// Rule is a condition on the ticks that raises an alert when it is met.
+// Conditions that stay met (e.g. a spread above a level) only raise an alert
+// when they become met.
+type Rule interface {
+	// Name identifies the rule. It must be unique in an engine.
+	Name() string
+	// Subscriptions returns the exchanges and pairs whose ticks the rule watches.
+	Subscriptions() []tick.Subscription
+	// Window returns the duration of the ticks history the rule needs.
+	Window() time.Duration
+	// Evaluate evaluates the rule after a tick of one of its subscriptions.
+	Evaluate(m *Market, t tick.Tick) Evaluation
+}
+
+// normalizer is implemented by the rules of this package, to write their
+// pairs in the notation of the services.
+type normalizer interface {
+	normalize() (Rule, error)
+}
+
+// normalizeRule returns the rule with its pairs in the notation of the
+// services. The rules that can not be normalized must already use it.
+func normalizeRule(r Rule) (Rule, error) {
+	if n, ok := r.(normalizer); ok {
+		return n.normalize()
+	}
+
+	for _, s := range r.Subscriptions() {
+		pair, err := market.NormalizePair(s.Pair)
+		if err != nil {
+			return nil, err
+		}
+		if pair != s.Pair {
+			return nil, fmt.Errorf("pair %q is not in the notation of the ticks (%s)", s.Pair, pair)
+		}
+	}
+	return r, nil
+}
+
+// Threshold is a rule met when the price of a pair crosses a level.
+type Threshold struct {
+	ID        string
+	Exchange  string
+	Pair      string
+	Level     float64
+	Direction Direction
+}
+
+// Name returns the ID of the rule.
+func (r Threshold) Name() string {
+	return r.ID
+}
+
+// Subscriptions returns the pair of the rule.
+func (r Threshold) Subscriptions() []tick.Subscription {
+	return []tick.Subscription{{Exchange: r.Exchange, Pair: r.Pair}}
+}
+
+func (r Threshold) normalize() (Rule, error) {
+	var err error
+	r.Pair, err = market.NormalizePair(r.Pair)
+	return r, err
+}
+
+// Window returns zero, as only the previous tick is needed.
+func (r Threshold) Window() time.Duration {
+	return 0
+}
+
+// Evaluate checks if the price crossed the level since the previous tick.
+func (r Threshold) Evaluate(m *Market, t tick.Tick) Evaluation {
+	prev, ok := m.Previous(r.Exchange, r.Pair)
+	if !ok || !crossed(prev.Price, t.Price, r.Level, r.Level, r.Direction) {
+		return Evaluation{}
+	}
+
+	return Evaluation{
+		Triggered: true,
+		Message:   fmt.Sprintf("%s on %s crossed %g (%g -> %g)", r.Pair, r.Exchange, r.Level, prev.Price, t.Price),
+	}
+}
+
+// crossed returns true if a price crossed a level in the direction, the
+// level moving from prevLevel to level.
+func crossed(prev, price, prevLevel, level float64, d Direction) bool {
+	up := prev < prevLevel && price >= level
+	down := prev > prevLevel && price <= level
+	switch d {
+	case DirectionUp:
+		return up
+	case DirectionDown:
+		return down
+	default:
+		return up || down
+	}
+}
+
+// PercentChange is a rule met when the price of a pair moves by a percentage
+// over a sliding window (e.g. 5% in 10 minutes).
+type PercentChange struct {
+	ID       string
+	Exchange string
+	Pair     string
+	// Percent is the minimum move, where 5 is 5%.
+	Percent   float64
+	Period    time.Duration
+	Direction Direction
+}
+
+// Name returns the ID of the rule.
+func (r PercentChange) Name() string {
+	return r.ID
+}
+
+// Subscriptions returns the pair of the rule.
+func (r PercentChange) Subscriptions() []tick.Subscription {
+	return []tick.Subscription{{Exchange: r.Exchange, Pair: r.Pair}}
+}
+
+func (r PercentChange) normalize() (Rule, error) {
+	var err error
+	r.Pair, err = market.NormalizePair(r.Pair)
+	return r, err
+}
+
+// Window returns the period of the rule.
+func (r PercentChange) Window() time.Duration {
+	return r.Period
+}
+
+// Evaluate compares the price to the oldest price of the period.
+func (r PercentChange) Evaluate(m *Market, t tick.Tick) Evaluation {
+	history := m.Since(r.Exchange, r.Pair, t.Time.Add(-r.Period))
+	if len(history) < 2 || history[0].Price == 0 {
+		return Evaluation{}
+	}
+
+	ref := history[0].Price
+	change := (t.Price/ref - 1) * 100
+	if math.Abs(change) < r.Percent || !r.Direction.match(ref, t.Price) {
+		return Evaluation{}
+	}
+
+	return Evaluation{
+		Triggered: true,
+		Message:   fmt.Sprintf("%s on %s moved %+.2f%% in %s (%g -> %g)", r.Pair, r.Exchange, change, r.Period, ref, t.Price),
+	}
+}
+
+// Spread is a rule met when the prices of a pair on two exchanges differ by
+// a percentage of the lowest one.
+type Spread struct {
+	ID        string
+	Pair      string
+	ExchangeA string
+	ExchangeB string
+	// Percent is the minimum spread, where 1 is 1%.
+	Percent float64
+}
+
+// Name returns the ID of the rule.
+func (r Spread) Name() string {
+	return r.ID
+}
+
+// Subscriptions returns the pair on both exchanges.
+func (r Spread) Subscriptions() []tick.Subscription {
+	return []tick.Subscription{
+		{Exchange: r.ExchangeA, Pair: r.Pair},
+		{Exchange: r.ExchangeB, Pair: r.Pair},
+	}
+}
+
+func (r Spread) normalize() (Rule, error) {
+	var err error
+	r.Pair, err = market.NormalizePair(r.Pair)
+	return r, err
+}
+
+// Window returns zero, as only the last ticks are needed.
+func (r Spread) Window() time.Duration {
+	return 0
+}
+
+// Evaluate compares the last prices on both exchanges.
+func (r Spread) Evaluate(m *Market, _ tick.Tick) Evaluation {
+	a, okA := m.Last(r.ExchangeA, r.Pair)
+	b, okB := m.Last(r.ExchangeB, r.Pair)
+	lowest := math.Min(a.Price, b.Price)
+	if !okA || !okB || lowest <= 0 {
+		return Evaluation{}
+	}
+
+	spread := math.Abs(a.Price-b.Price) / lowest * 100
+	if spread < r.Percent {
+		return Evaluation{}
+	}
+
+	return Evaluation{
+		Triggered: true,
+		Message: fmt.Sprintf("%s spread is %.2f%% between %s (%g) and %s (%g)",
+			r.Pair, spread, r.ExchangeA, a.Price, r.ExchangeB, b.Price),
+	}
+}
+
+// Indicator computes an indicator from prices, in chronological order.
+type Indicator func(prices []float64) float64
+
+// SMA is the simple moving average of the prices.
+func SMA(prices []float64) float64 {
+	if len(prices) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, p := range prices {
+		sum += p
+	}
+	return sum / float64(len(prices))
+}
+
+// IndicatorCross is a rule met when the price of a pair crosses an indicator
+// computed on the ticks of a sliding window (e.g. the SMA of the last hour).
+type IndicatorCross struct {
+	ID        string
+	Exchange  string
+	Pair      string
+	Indicator Indicator
+	Period    time.Duration
+	Direction Direction
+}
+
+// Name returns the ID of the rule.
+func (r IndicatorCross) Name() string {
+	return r.ID
+}
+
+// Subscriptions returns the pair of the rule.
+func (r IndicatorCross) Subscriptions() []tick.Subscription {
+	return []tick.Subscription{{Exchange: r.Exchange, Pair: r.Pair}}
+}
+
+func (r IndicatorCross) normalize() (Rule, error) {
+	var err error
+	r.Pair, err = market.NormalizePair(r.Pair)
+	return r, err
+}
+
+// Window returns the period of the indicator.
+func (r IndicatorCross) Window() time.Duration {
+	return r.Period
+}
+
+// Evaluate checks if the price crossed the indicator since the previous tick.
+func (r IndicatorCross) Evaluate(m *Market, t tick.Tick) Evaluation {
+	history := m.Since(r.Exchange, r.Pair, t.Time.Add(-r.Period))
+	if len(history) < 2 {
+		return Evaluation{}
+	}
+
+	prices := make([]float64, len(history))
+	for i, h := range history {
+		prices[i] = h.Price
+	}
+	prevValue := r.Indicator(prices[:len(prices)-1])
+	value := r.Indicator(prices)
+
+	prev := prices[len(prices)-2]
+	if !crossed(prev, t.Price, prevValue, value, r.Direction) {
+		return Evaluation{}
+	}
+
+	return Evaluation{
+		Triggered: true,
+		Message:   fmt.Sprintf("%s on %s crossed its indicator at %g (%g -> %g)", r.Pair, r.Exchange, value, prev, t.Price),
+	}
+}
@@ -0,0 +1,200 @@
+package alert
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cryptellation/go-clients/internal/fixture"
+	"github.com/cryptellation/ticks/pkg/tick"
+	"github.com/stretchr/testify/require"
+)
+
+// alerted processes the ticks with the engine and returns the indexes of the
+// ticks that raised an alert.
+func alerted(t *testing.T, e *Engine, ticks []tick.Tick) []int {
+	t.Helper()
+
+	indexes := make([]int, 0)
+	for i, tk := range ticks {
+		var raised bool
+		e.sinks = []Sink{SinkFunc(func(context.Context, Alert) error {
+			raised = true
+			return nil
+		})}
+		require.NoError(t, e.Process(context.Background(), tk))
+		if raised {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+func TestCrossed(t *testing.T) {
+	tests := []struct {
+		name      string
+		prev      float64
+		price     float64
+		prevLevel float64
+		level     float64
+		up        bool
+		down      bool
+	}{
+		{name: "below to above", prev: 90, price: 110, prevLevel: 100, level: 100, up: true},
+		{name: "below to level", prev: 90, price: 100, prevLevel: 100, level: 100, up: true},
+		{name: "above to below", prev: 110, price: 90, prevLevel: 100, level: 100, down: true},
+		{name: "above to level", prev: 110, price: 100, prevLevel: 100, level: 100, down: true},
+		{name: "level to above", prev: 100, price: 110, prevLevel: 100, level: 100},
+		{name: "level to below", prev: 100, price: 90, prevLevel: 100, level: 100},
+		{name: "stays below", prev: 90, price: 95, prevLevel: 100, level: 100},
+		{name: "stays above", prev: 110, price: 105, prevLevel: 100, level: 100},
+		{name: "level moving below the price", prev: 95, price: 95, prevLevel: 100, level: 90, up: true},
+		{name: "level moving above the price", prev: 95, price: 95, prevLevel: 90, level: 100, down: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.up, crossed(tt.prev, tt.price, tt.prevLevel, tt.level, DirectionUp))
+			require.Equal(t, tt.down, crossed(tt.prev, tt.price, tt.prevLevel, tt.level, DirectionDown))
+			require.Equal(t, tt.up || tt.down, crossed(tt.prev, tt.price, tt.prevLevel, tt.level, DirectionAny))
+		})
+	}
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     Rule
+		ticks    []tick.Tick
+		expected []int
+	}{
+		{
+			name:     "threshold crossed up",
+			rule:     Threshold{ID: "r", Exchange: "binance", Pair: "BTC-USDT", Level: 100, Direction: DirectionUp},
+			ticks:    fixture.Ticks(90, 95, 100, 105, 95, 101),
+			expected: []int{2, 5},
+		},
+		{
+			name:     "threshold crossed down",
+			rule:     Threshold{ID: "r", Exchange: "binance", Pair: "BTC-USDT", Level: 100, Direction: DirectionDown},
+			ticks:    fixture.Ticks(110, 100, 99, 105, 95),
+			expected: []int{1, 4},
+		},
+		{
+			name:     "threshold crossed in any direction",
+			rule:     Threshold{ID: "r", Exchange: "binance", Pair: "BTC-USDT", Level: 100, Direction: DirectionAny},
+			ticks:    fixture.Ticks(90, 110, 105, 90),
+			expected: []int{1, 3},
+		},
+		{
+			name:     "threshold crossed on consecutive ticks",
+			rule:     Threshold{ID: "r", Exchange: "binance", Pair: "BTC-USDT", Level: 100, Direction: DirectionAny},
+			ticks:    fixture.Ticks(90, 110, 90, 95),
+			expected: []int{1},
+		},
+		{
+			name:     "threshold touched",
+			rule:     Threshold{ID: "r", Exchange: "binance", Pair: "BTC-USDT", Level: 100, Direction: DirectionAny},
+			ticks:    fixture.Ticks(100, 100, 100),
+			expected: []int{},
+		},
+		{
+			name:     "threshold on another pair",
+			rule:     Threshold{ID: "r", Exchange: "binance", Pair: "ETH-USDT", Level: 100, Direction: DirectionAny},
+			ticks:    fixture.Ticks(90, 110, 90),
+			expected: []int{},
+		},
+		{
+			name: "percent change up",
+			rule: PercentChange{
+				ID: "r", Exchange: "binance", Pair: "BTC-USDT",
+				Percent: 5, Period: 10 * time.Minute, Direction: DirectionUp,
+			},
+			ticks:    fixture.Ticks(100, 102, 106, 107, 101, 106),
+			expected: []int{2, 5},
+		},
+		{
+			name: "percent change down",
+			rule: PercentChange{
+				ID: "r", Exchange: "binance", Pair: "BTC-USDT",
+				Percent: 5, Period: 10 * time.Minute, Direction: DirectionDown,
+			},
+			ticks:    fixture.Ticks(100, 106, 94),
+			expected: []int{2},
+		},
+		{
+			name: "percent change in the other direction",
+			rule: PercentChange{
+				ID: "r", Exchange: "binance", Pair: "BTC-USDT",
+				Percent: 5, Period: 10 * time.Minute, Direction: DirectionUp,
+			},
+			ticks:    fixture.Ticks(100, 94),
+			expected: []int{},
+		},
+		{
+			name: "percent change out of the period",
+			rule: PercentChange{
+				ID: "r", Exchange: "binance", Pair: "BTC-USDT",
+				Percent: 5, Period: 10 * time.Minute, Direction: DirectionAny,
+			},
+			ticks:    []tick.Tick{fixture.TickOn(0, "binance", 100), fixture.TickOn(11, "binance", 104), fixture.TickOn(12, "binance", 106)},
+			expected: []int{},
+		},
+		{
+			name: "spread",
+			rule: Spread{ID: "r", Pair: "BTC-USDT", ExchangeA: "binance", ExchangeB: "kraken", Percent: 1},
+			ticks: []tick.Tick{
+				fixture.TickOn(0, "binance", 100),
+				fixture.TickOn(0, "kraken", 100.5),
+				fixture.TickOn(1, "kraken", 101.5),
+				fixture.TickOn(1, "binance", 101),
+				fixture.TickOn(2, "binance", 99),
+			},
+			expected: []int{2, 4},
+		},
+		{
+			name: "indicator crossed up",
+			rule: IndicatorCross{
+				ID: "r", Exchange: "binance", Pair: "BTC-USDT",
+				Indicator: SMA, Period: 10 * time.Minute, Direction: DirectionUp,
+			},
+			ticks:    fixture.Ticks(100, 100, 90, 95, 110),
+			expected: []int{4},
+		},
+		{
+			name: "indicator crossed down",
+			rule: IndicatorCross{
+				ID: "r", Exchange: "binance", Pair: "BTC-USDT",
+				Indicator: SMA, Period: 10 * time.Minute, Direction: DirectionDown,
+			},
+			ticks:    fixture.Ticks(100, 110, 90),
+			expected: []int{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := New([]Rule{tt.rule})
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, alerted(t, e, tt.ticks))
+		})
+	}
+}
+
+func TestSMA(t *testing.T) {
+	tests := []struct {
+		name     string
+		prices   []float64
+		expected float64
+	}{
+		{name: "empty", prices: nil, expected: 0},
+		{name: "single price", prices: []float64{100}, expected: 100},
+		{name: "several prices", prices: []float64{90, 100, 110, 120}, expected: 105},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.InDelta(t, tt.expected, SMA(tt.prices), 1e-9)
+		})
+	}
+}
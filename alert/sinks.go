@@ -0,0 +1,92 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	temporalLog "go.temporal.io/sdk/log"
+)
+
+const (
+	// webhookTimeout is the maximum duration of a webhook call.
+	webhookTimeout = 10 * time.Second
+)
+
+// Sink delivers the alerts.
+type Sink interface {
+	// Send delivers an alert.
+	Send(ctx context.Context, a Alert) error
+}
+
+// SinkFunc is a function delivering the alerts.
+type SinkFunc func(ctx context.Context, a Alert) error
+
+// Send calls the function.
+func (f SinkFunc) Send(ctx context.Context, a Alert) error {
+	return f(ctx, a)
+}
+
+// LogSink returns a sink logging the alerts with the logger.
+func LogSink(logger temporalLog.Logger) Sink {
+	return SinkFunc(func(_ context.Context, a Alert) error {
+		logger.Info("Alert",
+			"rule", a.Rule,
+			"time", a.Time,
+			"exchange", a.Exchange,
+			"pair", a.Pair,
+			"price", a.Price,
+			"message", a.Message)
+		return nil
+	})
+}
+
+// WebhookSink returns a sink posting the alerts as JSON to the URL. A nil
+// HTTP client uses one with a 10 seconds timeout.
+func WebhookSink(url string, httpClient *http.Client) Sink {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: webhookTimeout}
+	}
+
+	return SinkFunc(func(ctx context.Context, a Alert) error {
+		body, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("webhook responded %s", resp.Status)
+		}
+		return nil
+	})
+}
+
+// ChannelSink returns a sink sending the alerts on the channel. It blocks
+// until the alert is received or the context is done.
+func ChannelSink(ch chan<- Alert) Sink {
+	return SinkFunc(func(ctx context.Context, a Alert) error {
+		select {
+		case ch <- a:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
@@ -0,0 +1,392 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	exchangesapi "github.com/cryptellation/exchanges/api"
+	"github.com/cryptellation/exchanges/pkg/exchange"
+	"github.com/cryptellation/go-clients/market"
+	ticksapi "github.com/cryptellation/ticks/api"
+	ticksclient "github.com/cryptellation/ticks/pkg/clients"
+	"github.com/cryptellation/ticks/pkg/tick"
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+var (
+	// ErrNoExchange is returned when less than two exchanges list the pair.
+	ErrNoExchange = errors.New("not enough exchanges to scan")
+)
+
+const (
+	// callbackPrefix is the prefix of the callbacks receiving the ticks.
+	callbackPrefix = "ArbitrageScanner"
+	// stopTimeout is the maximum duration to stop listening to ticks once
+	// the scan is over.
+	stopTimeout = 10 * time.Second
+	// ticksBufferSize is the number of ticks received by the callbacks and
+	// waiting to be scanned. Ticks are dropped when it is full.
+	ticksBufferSize = 1024
+
+	defaultMaxAge  = 5 * time.Second
+	defaultMaxSkew = time.Second
+)
+
+// Source is the source of the exchanges and of the ticks scanned. The
+// Cryptellation client implements this interface.
+type Source interface {
+	// GetExchange retrieves an exchange by name.
+	GetExchange(
+		ctx context.Context,
+		params exchangesapi.GetExchangeWorkflowParams,
+	) (exchangesapi.GetExchangeWorkflowResults, error)
+	// ListExchanges retrieves a list of exchanges.
+	ListExchanges(
+		ctx context.Context,
+		params exchangesapi.ListExchangesWorkflowParams,
+	) (exchangesapi.ListExchangesWorkflowResults, error)
+	// ListenToTicks listens to ticks from a specific exchange and trading pair.
+	ListenToTicks(ctx context.Context, listener ticksclient.ListenerParams, exchange, pair string) error
+	// StopListeningToTicks unregisters a callback workflow from ticks for a given exchange and pair.
+	StopListeningToTicks(ctx context.Context, listener uuid.UUID, exchange string, pair string) error
+}
+
+// Quote is the last price of the pair on an exchange.
+type Quote struct {
+	Exchange string  `json:"exchange"`
+	Price    float64 `json:"price"`
+	// Time is the time of the tick, from the clock of the exchange.
+	Time time.Time `json:"time"`
+	// ReceivedAt is the time the tick was received by the scanner, from its
+	// clock. The age and skew of the quotes are measured from it.
+	ReceivedAt time.Time `json:"received_at"`
+	// Fees are the fees of the exchange, in percent.
+	Fees float64 `json:"fees"`
+}
+
+// Opportunity is a price difference between two exchanges: buying on one
+// and selling on the other is profitable after the fees.
+type Opportunity struct {
+	Pair string    `json:"pair"`
+	Time time.Time `json:"time"`
+	Buy  Quote     `json:"buy"`
+	Sell Quote     `json:"sell"`
+	// GrossSpread is the relative difference of the prices, in percent.
+	GrossSpread float64 `json:"gross_spread"`
+	// NetSpread is the gross spread minus the fees of both exchanges, in percent.
+	NetSpread float64 `json:"net_spread"`
+	// Age is the age of the oldest quote when the opportunity is detected,
+	// from its reception.
+	Age time.Duration `json:"age"`
+}
+
+// Scanner scans the price of a pair across the exchanges and emits the
+// arbitrage opportunities above a threshold.
+type Scanner struct {
+	source    Source
+	pair      string
+	threshold float64
+	maxAge    time.Duration
+	maxSkew   time.Duration
+	handlers  []func(Opportunity)
+	filter    []string
+
+	mu      sync.Mutex
+	fees    map[string]float64
+	quotes  map[string]Quote
+	dropped atomic.Uint64
+}
+
+// Options is a function that modifies the scanner configuration.
+type Options func(*Scanner)
+
+// WithThreshold sets the minimum net spread of the emitted opportunities,
+// in percent. Default is 0, which emits every profitable opportunity.
+func WithThreshold(percent float64) Options {
+	return func(s *Scanner) {
+		s.threshold = percent
+	}
+}
+
+// WithMaxAge sets the maximum age of a quote, from its reception by the
+// scanner, to be used in an opportunity. Quotes of an exchange that stopped
+// sending ticks are ignored. Default is 5 seconds.
+func WithMaxAge(age time.Duration) Options {
+	return func(s *Scanner) {
+		s.maxAge = age
+	}
+}
+
+// WithMaxSkew sets the maximum time difference between the receptions of the
+// two quotes of an opportunity. Default is 1 second.
+func WithMaxSkew(skew time.Duration) Options {
+	return func(s *Scanner) {
+		s.maxSkew = skew
+	}
+}
+
+// WithExchanges restricts the scan to the given exchanges, instead of all
+// the exchanges listing the pair.
+func WithExchanges(names ...string) Options {
+	return func(s *Scanner) {
+		s.filter = append(s.filter, names...)
+	}
+}
+
+// OnOpportunity adds a handler called with the best opportunity after each
+// tick, when it is above the threshold. Handlers are called by Update, from
+// the goroutine scanning the ticks in Run: they must not block, or the ticks
+// received meanwhile are dropped (see Dropped).
+func OnOpportunity(handler func(Opportunity)) Options {
+	return func(s *Scanner) {
+		s.handlers = append(s.handlers, handler)
+	}
+}
+
+// New creates a scanner of the pair, written with a separator in one of the
+// common notations (see market.NormalizePair). It is kept in the notation of
+// the services, as the ticks are.
+func New(source Source, pair string, opts ...Options) (*Scanner, error) {
+	normalized, err := market.NormalizePair(pair)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Scanner{
+		source:  source,
+		pair:    normalized,
+		maxAge:  defaultMaxAge,
+		maxSkew: defaultMaxSkew,
+		fees:    make(map[string]float64),
+		quotes:  make(map[string]Quote),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+// Load gets the exchanges listing the pair with their fees. It is called by
+// Run, and only needs to be called to feed the scanner with Update.
+func (s *Scanner) Load(ctx context.Context) ([]exchange.Exchange, error) {
+	names := s.filter
+	if len(names) == 0 {
+		res, err := s.source.ListExchanges(ctx, exchangesapi.ListExchangesWorkflowParams{})
+		if err != nil {
+			return nil, fmt.Errorf("listing exchanges: %w", err)
+		}
+		names = res.List
+	}
+
+	exchanges := make([]exchange.Exchange, 0, len(names))
+	for _, name := range names {
+		res, err := s.source.GetExchange(ctx, exchangesapi.GetExchangeWorkflowParams{Name: name})
+		if err != nil {
+			return nil, fmt.Errorf("getting exchange %q: %w", name, err)
+		}
+
+		// Keep the exchanges that list the pair, or that list no pairs at all
+		if len(res.Exchange.Pairs) > 0 && !slices.Contains(res.Exchange.Pairs, s.pair) {
+			continue
+		}
+		exchanges = append(exchanges, res.Exchange)
+	}
+	if len(exchanges) < 2 {
+		return nil, fmt.Errorf("%w: %d exchanges with %s", ErrNoExchange, len(exchanges), s.pair)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range exchanges {
+		s.fees[e.Name] = e.Fees
+	}
+	return exchanges, nil
+}
+
+// Dropped returns the number of ticks dropped by Run because they were
+// received faster than they were scanned.
+func (s *Scanner) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Run loads the exchanges and scans the ticks of the pair on each of them
+// until the context is done. The callbacks receiving the ticks are
+// registered on the worker, that must listen on the task queue. They only
+// hand the ticks to a goroutine scanning them, so the handlers are never
+// called from the workflows.
+func (s *Scanner) Run(ctx context.Context, w worker.Worker, taskQueue string) error {
+	exchanges, err := s.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	ticks := make(chan tick.Tick, ticksBufferSize)
+	callback := func(_ workflow.Context, params ticksapi.ListenToTicksCallbackWorkflowParams) error {
+		select {
+		case ticks <- params.Tick:
+		default:
+			s.dropped.Add(1)
+		}
+		return nil
+	}
+
+	// Scan the ticks until the end of the run
+	scanCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-scanCtx.Done():
+				return
+			case t := <-ticks:
+				s.Update(t)
+			}
+		}
+	}()
+
+	// Listen to ticks, with one requester per exchange as each one has its callback
+	requesters := make(map[uuid.UUID]string, len(exchanges))
+	defer func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+		defer cancel()
+		for id, name := range requesters {
+			_ = s.source.StopListeningToTicks(stopCtx, id, name, s.pair)
+		}
+	}()
+
+	for _, e := range exchanges {
+		id := uuid.New()
+		err := s.source.ListenToTicks(ctx, ticksclient.ListenerParams{
+			RequesterID:        id,
+			CallbackNamePrefix: callbackPrefix,
+			Callback:           callback,
+			Worker:             w,
+			TaskQueue:          taskQueue,
+		}, e.Name, s.pair)
+		if err != nil {
+			return fmt.Errorf("listening to %s on %s: %w", s.pair, e.Name, err)
+		}
+		requesters[id] = e.Name
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+// Update records the tick as the quote of its exchange, received now, and
+// returns the best opportunity, if it is above the threshold. Ticks of other
+// pairs are ignored.
+func (s *Scanner) Update(t tick.Tick) (Opportunity, bool) {
+	return s.update(t, time.Now())
+}
+
+// update records the tick as the quote of its exchange, received at the
+// given time, and returns the best opportunity at this time.
+func (s *Scanner) update(t tick.Tick, now time.Time) (Opportunity, bool) {
+	if t.Pair != s.pair {
+		return Opportunity{}, false
+	}
+
+	s.mu.Lock()
+	if prev, ok := s.quotes[t.Exchange]; !ok || !t.Time.Before(prev.Time) {
+		s.quotes[t.Exchange] = Quote{
+			Exchange:   t.Exchange,
+			Price:      t.Price,
+			Time:       t.Time,
+			ReceivedAt: now,
+			Fees:       s.fees[t.Exchange],
+		}
+	}
+	opp, ok := s.best(now)
+	s.mu.Unlock()
+
+	if !ok {
+		return Opportunity{}, false
+	}
+	for _, h := range s.handlers {
+		h(opp)
+	}
+	return opp, true
+}
+
+// best returns the opportunity with the highest net spread between the
+// fresh quotes, if it is above the threshold.
+func (s *Scanner) best(now time.Time) (Opportunity, bool) {
+	// Get the fresh quotes
+	fresh := make([]Quote, 0, len(s.quotes))
+	for _, q := range s.quotes {
+		if now.Sub(q.ReceivedAt) <= s.maxAge && q.Price > 0 {
+			fresh = append(fresh, q)
+		}
+	}
+
+	// Compare each pair of quotes
+	var best Opportunity
+	found := false
+	for _, buy := range fresh {
+		for _, sell := range fresh {
+			if buy.Exchange == sell.Exchange || absDuration(buy.ReceivedAt.Sub(sell.ReceivedAt)) > s.maxSkew {
+				continue
+			}
+
+			gross := (sell.Price/buy.Price - 1) * 100
+			net := (sell.Price*(1-sell.Fees/100)/(buy.Price*(1+buy.Fees/100)) - 1) * 100
+			if net < s.threshold || net <= 0 || (found && net <= best.NetSpread) {
+				continue
+			}
+
+			oldest := buy.ReceivedAt
+			if sell.ReceivedAt.Before(oldest) {
+				oldest = sell.ReceivedAt
+			}
+			best = Opportunity{
+				Pair:        s.pair,
+				Time:        now,
+				Buy:         buy,
+				Sell:        sell,
+				GrossSpread: gross,
+				NetSpread:   net,
+				Age:         now.Sub(oldest),
+			}
+			found = true
+		}
+	}
+
+	return best, found
+}
+
+// Quotes returns the last quote of each exchange, sorted by price.
+func (s *Scanner) Quotes() []Quote {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quotes := make([]Quote, 0, len(s.quotes))
+	for _, q := range s.quotes {
+		quotes = append(quotes, q)
+	}
+	sort.Slice(quotes, func(i, j int) bool {
+		return quotes[i].Price < quotes[j].Price
+	})
+	return quotes
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,319 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	exchangesapi "github.com/cryptellation/exchanges/api"
+	"github.com/cryptellation/exchanges/pkg/exchange"
+	"github.com/cryptellation/go-clients/internal/fixture"
+	"github.com/cryptellation/go-clients/market"
+	ticksclient "github.com/cryptellation/ticks/pkg/clients"
+	"github.com/cryptellation/ticks/pkg/tick"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// source is a source of exchanges that does not send ticks.
+type source struct {
+	exchanges map[string]exchange.Exchange
+}
+
+func (s source) GetExchange(
+	_ context.Context,
+	params exchangesapi.GetExchangeWorkflowParams,
+) (exchangesapi.GetExchangeWorkflowResults, error) {
+	e, ok := s.exchanges[params.Name]
+	if !ok {
+		return exchangesapi.GetExchangeWorkflowResults{}, errors.New("exchange not found")
+	}
+	return exchangesapi.GetExchangeWorkflowResults{Exchange: e}, nil
+}
+
+func (s source) ListExchanges(
+	_ context.Context,
+	_ exchangesapi.ListExchangesWorkflowParams,
+) (exchangesapi.ListExchangesWorkflowResults, error) {
+	list := make([]string, 0, len(s.exchanges))
+	for name := range s.exchanges {
+		list = append(list, name)
+	}
+	return exchangesapi.ListExchangesWorkflowResults{List: list}, nil
+}
+
+func (s source) ListenToTicks(context.Context, ticksclient.ListenerParams, string, string) error {
+	return nil
+}
+
+func (s source) StopListeningToTicks(context.Context, uuid.UUID, string, string) error {
+	return nil
+}
+
+// update is a tick received by the scanner.
+type update struct {
+	exchange string
+	pair     string
+	price    float64
+	// tickTime is the time of the tick, in seconds after the fixture.Start time.
+	tickTime int
+	// received is the time of the reception, in milliseconds after the fixture.Start time.
+	received int
+}
+
+func TestScannerUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Options
+		fees     map[string]float64
+		updates  []update
+		found    bool
+		buy      string
+		sell     string
+		gross    float64
+		net      float64
+		age      time.Duration
+		buyPrice float64
+	}{
+		{
+			name:    "single quote",
+			updates: []update{{exchange: "a", price: 100}},
+		},
+		{
+			name: "spread between two exchanges",
+			updates: []update{
+				{exchange: "a", price: 100},
+				{exchange: "b", price: 101},
+			},
+			found: true, buy: "a", sell: "b", gross: 1, net: 1, buyPrice: 100,
+		},
+		{
+			name: "spread below the fees",
+			fees: map[string]float64{"a": 0.1, "b": 0.1},
+			updates: []update{
+				{exchange: "a", price: 100},
+				{exchange: "b", price: 100.1},
+			},
+		},
+		{
+			name: "spread above the fees",
+			fees: map[string]float64{"a": 0.1, "b": 0.1},
+			updates: []update{
+				{exchange: "a", price: 100},
+				{exchange: "b", price: 101},
+			},
+			found: true, buy: "a", sell: "b", gross: 1, net: (101*0.999/(100*1.001) - 1) * 100, buyPrice: 100,
+		},
+		{
+			name: "spread below the threshold",
+			opts: []Options{WithThreshold(2)},
+			updates: []update{
+				{exchange: "a", price: 100},
+				{exchange: "b", price: 101},
+			},
+		},
+		{
+			name: "best of several exchanges",
+			updates: []update{
+				{exchange: "a", price: 101},
+				{exchange: "b", price: 100},
+				{exchange: "c", price: 102},
+			},
+			found: true, buy: "b", sell: "c", gross: 2, net: 2, buyPrice: 100,
+		},
+		{
+			name: "stale quote",
+			updates: []update{
+				{exchange: "a", price: 100, received: 0},
+				{exchange: "b", price: 101, received: 6000},
+			},
+		},
+		{
+			name: "quotes received too far apart",
+			opts: []Options{WithMaxAge(10 * time.Second)},
+			updates: []update{
+				{exchange: "a", price: 100, received: 0},
+				{exchange: "b", price: 101, received: 2000},
+			},
+		},
+		{
+			name: "age from the reception of the oldest quote",
+			updates: []update{
+				{exchange: "a", price: 100, tickTime: -60, received: 0},
+				{exchange: "b", price: 101, tickTime: 0, received: 500},
+			},
+			found: true, buy: "a", sell: "b", gross: 1, net: 1, age: 500 * time.Millisecond, buyPrice: 100,
+		},
+		{
+			name: "older tick of an exchange",
+			updates: []update{
+				{exchange: "a", price: 100, tickTime: 10},
+				{exchange: "a", price: 90, tickTime: 5},
+				{exchange: "b", price: 101, tickTime: 10},
+			},
+			found: true, buy: "a", sell: "b", gross: 1, net: 1, buyPrice: 100,
+		},
+		{
+			name: "tick of another pair",
+			updates: []update{
+				{exchange: "a", price: 100},
+				{exchange: "b", price: 101, pair: "ETH-USDT"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(source{}, "BTC-USDT", tt.opts...)
+			require.NoError(t, err)
+			for name, fees := range tt.fees {
+				s.fees[name] = fees
+			}
+
+			var opp Opportunity
+			var found bool
+			for _, u := range tt.updates {
+				pair := u.pair
+				if pair == "" {
+					pair = "BTC-USDT"
+				}
+				opp, found = s.update(tick.Tick{
+					Time:     fixture.Start.Add(time.Duration(u.tickTime) * time.Second),
+					Exchange: u.exchange,
+					Pair:     pair,
+					Price:    u.price,
+				}, fixture.Start.Add(time.Duration(u.received)*time.Millisecond))
+			}
+
+			require.Equal(t, tt.found, found)
+			if !tt.found {
+				return
+			}
+			require.Equal(t, tt.buy, opp.Buy.Exchange)
+			require.Equal(t, tt.sell, opp.Sell.Exchange)
+			require.Equal(t, tt.buyPrice, opp.Buy.Price)
+			require.InDelta(t, tt.gross, opp.GrossSpread, 1e-9)
+			require.InDelta(t, tt.net, opp.NetSpread, 1e-9)
+			require.Equal(t, tt.age, opp.Age)
+		})
+	}
+}
+
+func TestScannerHandlers(t *testing.T) {
+	var opportunities []Opportunity
+	s, err := New(source{}, "BTC-USDT", OnOpportunity(func(o Opportunity) {
+		opportunities = append(opportunities, o)
+	}))
+	require.NoError(t, err)
+
+	s.update(tick.Tick{Time: fixture.Start, Exchange: "a", Pair: "BTC-USDT", Price: 100}, fixture.Start)
+	require.Empty(t, opportunities)
+
+	s.update(tick.Tick{Time: fixture.Start, Exchange: "b", Pair: "BTC-USDT", Price: 101}, fixture.Start)
+	require.Len(t, opportunities, 1)
+
+	quotes := s.Quotes()
+	require.Len(t, quotes, 2)
+	require.Equal(t, "a", quotes[0].Exchange)
+	require.Equal(t, "b", quotes[1].Exchange)
+}
+
+func TestScannerLoad(t *testing.T) {
+	src := source{exchanges: map[string]exchange.Exchange{
+		"binance":  {Name: "binance", Pairs: []string{"BTC-USDT", "ETH-USDT"}, Fees: 0.1},
+		"kraken":   {Name: "kraken", Pairs: []string{"BTC-USDT"}, Fees: 0.2},
+		"coinbase": {Name: "coinbase", Pairs: []string{"ETH-USDT"}, Fees: 0.3},
+		"unlisted": {Name: "unlisted", Fees: 0.4},
+	}}
+
+	tests := []struct {
+		name      string
+		pair      string
+		opts      []Options
+		exchanges []string
+		err       error
+	}{
+		{
+			name:      "exchanges listing the pair or no pairs",
+			pair:      "BTC-USDT",
+			exchanges: []string{"binance", "kraken", "unlisted"},
+		},
+		{
+			name:      "pair in another notation",
+			pair:      "btc/usdt",
+			exchanges: []string{"binance", "kraken", "unlisted"},
+		},
+		{
+			name:      "filtered exchanges",
+			pair:      "BTC-USDT",
+			opts:      []Options{WithExchanges("binance", "kraken")},
+			exchanges: []string{"binance", "kraken"},
+		},
+		{
+			name: "not enough exchanges",
+			pair: "BTC-USDT",
+			opts: []Options{WithExchanges("binance", "coinbase")},
+			err:  ErrNoExchange,
+		},
+		{
+			name: "unknown exchange",
+			pair: "BTC-USDT",
+			opts: []Options{WithExchanges("binance", "unknown")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(src, tt.pair, tt.opts...)
+			require.NoError(t, err)
+			exchanges, err := s.Load(context.Background())
+			switch {
+			case tt.err != nil:
+				require.ErrorIs(t, err, tt.err)
+				return
+			case tt.exchanges == nil:
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			names := make([]string, len(exchanges))
+			for i, e := range exchanges {
+				names[i] = e.Name
+				require.Equal(t, e.Fees, s.fees[e.Name])
+			}
+			require.ElementsMatch(t, tt.exchanges, names)
+		})
+	}
+}
+
+func TestNewPair(t *testing.T) {
+	tests := []struct {
+		name     string
+		pair     string
+		expected string
+		err      error
+	}{
+		{name: "services notation", pair: "BTC-USDT", expected: "BTC-USDT"},
+		{name: "other notation", pair: "btc/usdt", expected: "BTC-USDT"},
+		{name: "compact", pair: "BTCUSDT", err: market.ErrAmbiguousPair},
+		{name: "invalid", pair: "BTC", err: market.ErrInvalidPair},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(source{}, tt.pair)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+
+			// The ticks, in the notation of the services, are scanned
+			s.update(tick.Tick{Time: fixture.Start, Exchange: "a", Pair: tt.expected, Price: 100}, fixture.Start)
+			_, found := s.update(tick.Tick{Time: fixture.Start, Exchange: "b", Pair: tt.expected, Price: 101}, fixture.Start)
+			require.True(t, found)
+		})
+	}
+}
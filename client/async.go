@@ -0,0 +1,282 @@
+package client
+
+import (
+	"context"
+
+	backtestsapi "github.com/cryptellation/backtests/api"
+	backtestsclient "github.com/cryptellation/backtests/pkg/clients"
+	candlesticksapi "github.com/cryptellation/candlesticks/api"
+	forwardtestsapi "github.com/cryptellation/forwardtests/api"
+	forwardtestsclient "github.com/cryptellation/forwardtests/pkg/clients"
+	smaapi "github.com/cryptellation/sma/api"
+	enums "go.temporal.io/api/enums/v1"
+	temporalclient "go.temporal.io/sdk/client"
+)
+
+// WorkflowStatus is the status of a workflow started asynchronously.
+type WorkflowStatus string
+
+const (
+	// WorkflowStatusRunning is the status of a running workflow.
+	WorkflowStatusRunning WorkflowStatus = "running"
+	// WorkflowStatusCompleted is the status of a successfully completed workflow.
+	WorkflowStatusCompleted WorkflowStatus = "completed"
+	// WorkflowStatusFailed is the status of a failed workflow.
+	WorkflowStatusFailed WorkflowStatus = "failed"
+	// WorkflowStatusCanceled is the status of a canceled workflow.
+	WorkflowStatusCanceled WorkflowStatus = "canceled"
+	// WorkflowStatusTerminated is the status of a terminated workflow.
+	WorkflowStatusTerminated WorkflowStatus = "terminated"
+	// WorkflowStatusContinuedAsNew is the status of a workflow continued as a new run.
+	WorkflowStatusContinuedAsNew WorkflowStatus = "continued_as_new"
+	// WorkflowStatusTimedOut is the status of a workflow that reached its execution timeout.
+	WorkflowStatusTimedOut WorkflowStatus = "timed_out"
+	// WorkflowStatusUnknown is the status of a workflow whose status is unknown.
+	WorkflowStatusUnknown WorkflowStatus = "unknown"
+)
+
+// String returns the string representation of the workflow status.
+func (s WorkflowStatus) String() string {
+	return string(s)
+}
+
+// Done returns true if the workflow is not running anymore.
+func (s WorkflowStatus) Done() bool {
+	return s != WorkflowStatusRunning && s != WorkflowStatusUnknown
+}
+
+func workflowStatusFromTemporal(s enums.WorkflowExecutionStatus) WorkflowStatus {
+	switch s {
+	case enums.WORKFLOW_EXECUTION_STATUS_RUNNING:
+		return WorkflowStatusRunning
+	case enums.WORKFLOW_EXECUTION_STATUS_COMPLETED:
+		return WorkflowStatusCompleted
+	case enums.WORKFLOW_EXECUTION_STATUS_FAILED:
+		return WorkflowStatusFailed
+	case enums.WORKFLOW_EXECUTION_STATUS_CANCELED:
+		return WorkflowStatusCanceled
+	case enums.WORKFLOW_EXECUTION_STATUS_TERMINATED:
+		return WorkflowStatusTerminated
+	case enums.WORKFLOW_EXECUTION_STATUS_CONTINUED_AS_NEW:
+		return WorkflowStatusContinuedAsNew
+	case enums.WORKFLOW_EXECUTION_STATUS_TIMED_OUT:
+		return WorkflowStatusTimedOut
+	default:
+		return WorkflowStatusUnknown
+	}
+}
+
+// WorkflowHandle is a handle on a workflow started asynchronously, returning
+// results of type R. Its IDs can be persisted to attach to the workflow later,
+// possibly from another process, with the client Attach* methods.
+type WorkflowHandle[R any] struct {
+	// WorkflowID is the ID of the workflow.
+	WorkflowID string
+	// RunID is the ID of the workflow run.
+	RunID string
+
+	temporal temporalclient.Client
+	results  func(ctx context.Context, run temporalclient.WorkflowRun) (R, error)
+}
+
+// newWorkflowHandle creates a handle whose results are the workflow results
+// of type W converted to R.
+func newWorkflowHandle[W, R any](
+	cl temporalclient.Client,
+	workflowID, runID string,
+	convert func(ctx context.Context, res W) (R, error),
+) WorkflowHandle[R] {
+	return WorkflowHandle[R]{
+		WorkflowID: workflowID,
+		RunID:      runID,
+		temporal:   cl,
+		results: func(ctx context.Context, run temporalclient.WorkflowRun) (R, error) {
+			var res W
+			if err := run.Get(ctx, &res); err != nil {
+				var r R
+				return r, MapError(err)
+			}
+			return convert(ctx, res)
+		},
+	}
+}
+
+func identity[R any](_ context.Context, res R) (R, error) {
+	return res, nil
+}
+
+// Get waits for the workflow to complete and returns its results.
+func (h WorkflowHandle[R]) Get(ctx context.Context) (R, error) {
+	return h.results(ctx, h.temporal.GetWorkflow(ctx, h.WorkflowID, h.RunID))
+}
+
+// Cancel requests the cancellation of the workflow.
+func (h WorkflowHandle[R]) Cancel(ctx context.Context) error {
+	return MapError(h.temporal.CancelWorkflow(ctx, h.WorkflowID, h.RunID))
+}
+
+// Status returns the current status of the workflow.
+func (h WorkflowHandle[R]) Status(ctx context.Context) (WorkflowStatus, error) {
+	desc, err := h.temporal.DescribeWorkflowExecution(ctx, h.WorkflowID, h.RunID)
+	if err != nil {
+		return WorkflowStatusUnknown, MapError(err)
+	}
+	return workflowStatusFromTemporal(desc.GetWorkflowExecutionInfo().GetStatus()), nil
+}
+
+// startWorkflow starts a workflow without waiting for its results, with a
+// deterministic ID if the context has an idempotency key.
+func (c client) startWorkflow(
+	ctx context.Context,
+	taskQueue, workflowName string,
+	args ...any,
+) (temporalclient.WorkflowRun, error) {
+	workflowOptions := temporalclient.StartWorkflowOptions{
+		TaskQueue: taskQueue,
+	}
+
+	if key, ok := IdempotencyKeyFromContext(ctx); ok {
+		return c.startIdempotentWorkflow(ctx, workflowOptions, key, workflowName, args...)
+	}
+	return c.temporal.services.ExecuteWorkflow(ctx, workflowOptions, workflowName, args...)
+}
+
+// ListCandlesticksAsync starts the candlesticks list workflow without waiting for its results.
+func (c client) ListCandlesticksAsync(
+	ctx context.Context,
+	params candlesticksapi.ListCandlesticksWorkflowParams,
+) (WorkflowHandle[candlesticksapi.ListCandlesticksWorkflowResults], error) {
+	pair, err := NormalizePair(params.Pair)
+	if err != nil {
+		return WorkflowHandle[candlesticksapi.ListCandlesticksWorkflowResults]{}, err
+	}
+	params.Pair = pair
+	return call(ctx, c, ServiceCandlesticks, MethodListCandlesticksAsync, params,
+		func(ctx context.Context, p candlesticksapi.ListCandlesticksWorkflowParams) (
+			WorkflowHandle[candlesticksapi.ListCandlesticksWorkflowResults], error,
+		) {
+			run, err := c.startWorkflow(ctx,
+				candlesticksapi.WorkerTaskQueueName, candlesticksapi.ListCandlesticksWorkflowName, p)
+			if err != nil {
+				return WorkflowHandle[candlesticksapi.ListCandlesticksWorkflowResults]{}, err
+			}
+			return c.AttachListCandlesticks(run.GetID(), run.GetRunID()), nil
+		})
+}
+
+// AttachListCandlesticks returns the handle of a candlesticks list workflow started asynchronously.
+func (c client) AttachListCandlesticks(
+	workflowID, runID string,
+) WorkflowHandle[candlesticksapi.ListCandlesticksWorkflowResults] {
+	return newWorkflowHandle(c.temporal.services, workflowID, runID,
+		identity[candlesticksapi.ListCandlesticksWorkflowResults])
+}
+
+// ListSMAAsync starts the SMA list workflow without waiting for its results.
+func (c client) ListSMAAsync(
+	ctx context.Context,
+	params smaapi.ListWorkflowParams,
+) (WorkflowHandle[smaapi.ListWorkflowResults], error) {
+	pair, err := NormalizePair(params.Pair)
+	if err != nil {
+		return WorkflowHandle[smaapi.ListWorkflowResults]{}, err
+	}
+	params.Pair = pair
+	return call(ctx, c, ServiceSMA, MethodListSMAAsync, params,
+		func(ctx context.Context, p smaapi.ListWorkflowParams) (WorkflowHandle[smaapi.ListWorkflowResults], error) {
+			run, err := c.startWorkflow(ctx, smaapi.WorkerTaskQueueName, smaapi.ListWorkflowName, p)
+			if err != nil {
+				return WorkflowHandle[smaapi.ListWorkflowResults]{}, err
+			}
+			return c.AttachListSMA(run.GetID(), run.GetRunID()), nil
+		})
+}
+
+// AttachListSMA returns the handle of a SMA list workflow started asynchronously.
+func (c client) AttachListSMA(workflowID, runID string) WorkflowHandle[smaapi.ListWorkflowResults] {
+	return newWorkflowHandle(c.temporal.services, workflowID, runID, identity[smaapi.ListWorkflowResults])
+}
+
+// NewBacktestAsync starts the backtest creation workflow without waiting for its results.
+func (c client) NewBacktestAsync(
+	ctx context.Context,
+	params NewBacktestParams,
+) (WorkflowHandle[backtestsclient.Backtest], error) {
+	return call(ctx, c, ServiceBacktests, MethodNewBacktestAsync, params,
+		func(ctx context.Context, p NewBacktestParams) (WorkflowHandle[backtestsclient.Backtest], error) {
+			run, err := c.startWorkflow(ctx,
+				backtestsapi.WorkerTaskQueueName, backtestsapi.CreateBacktestWorkflowName,
+				backtestsapi.CreateBacktestWorkflowParams{
+					BacktestParameters: p.Parameters,
+					Callbacks:          p.Callbacks,
+				})
+			if err != nil {
+				return WorkflowHandle[backtestsclient.Backtest]{}, err
+			}
+			return c.AttachNewBacktest(run.GetID(), run.GetRunID()), nil
+		})
+}
+
+// AttachNewBacktest returns the handle of a backtest creation workflow started asynchronously.
+func (c client) AttachNewBacktest(workflowID, runID string) WorkflowHandle[backtestsclient.Backtest] {
+	return newWorkflowHandle(c.temporal.services, workflowID, runID,
+		func(ctx context.Context, res backtestsapi.CreateBacktestWorkflowResults) (backtestsclient.Backtest, error) {
+			return c.GetBacktest(ctx, backtestsapi.GetBacktestWorkflowParams{
+				BacktestID: res.ID,
+			})
+		})
+}
+
+// RunBacktestAsync starts the run of a backtest without waiting for its end.
+func (c client) RunBacktestAsync(
+	ctx context.Context,
+	params backtestsapi.RunBacktestWorkflowParams,
+) (WorkflowHandle[backtestsapi.RunBacktestWorkflowResults], error) {
+	return call(ctx, c, ServiceBacktests, MethodRunBacktestAsync, params,
+		func(ctx context.Context, p backtestsapi.RunBacktestWorkflowParams) (
+			WorkflowHandle[backtestsapi.RunBacktestWorkflowResults], error,
+		) {
+			run, err := c.startWorkflow(ctx,
+				backtestsapi.WorkerTaskQueueName, backtestsapi.RunBacktestWorkflowName, p)
+			if err != nil {
+				return WorkflowHandle[backtestsapi.RunBacktestWorkflowResults]{}, err
+			}
+			return c.AttachRunBacktest(run.GetID(), run.GetRunID()), nil
+		})
+}
+
+// AttachRunBacktest returns the handle of a backtest run started asynchronously.
+func (c client) AttachRunBacktest(workflowID, runID string) WorkflowHandle[backtestsapi.RunBacktestWorkflowResults] {
+	return newWorkflowHandle(c.temporal.services, workflowID, runID, identity[backtestsapi.RunBacktestWorkflowResults])
+}
+
+// NewForwardtestAsync starts the forwardtest creation workflow without waiting for its results.
+func (c client) NewForwardtestAsync(
+	ctx context.Context,
+	params forwardtestsapi.CreateForwardtestWorkflowParams,
+) (WorkflowHandle[forwardtestsclient.Forwardtest], error) {
+	return call(ctx, c, ServiceForwardtests, MethodNewForwardtestAsync, params,
+		func(ctx context.Context, p forwardtestsapi.CreateForwardtestWorkflowParams) (
+			WorkflowHandle[forwardtestsclient.Forwardtest], error,
+		) {
+			run, err := c.startWorkflow(ctx,
+				forwardtestsapi.WorkerTaskQueueName, forwardtestsapi.CreateForwardtestWorkflowName, p)
+			if err != nil {
+				return WorkflowHandle[forwardtestsclient.Forwardtest]{}, err
+			}
+			return c.AttachNewForwardtest(run.GetID(), run.GetRunID()), nil
+		})
+}
+
+// AttachNewForwardtest returns the handle of a forwardtest creation workflow started asynchronously.
+func (c client) AttachNewForwardtest(workflowID, runID string) WorkflowHandle[forwardtestsclient.Forwardtest] {
+	return WorkflowHandle[forwardtestsclient.Forwardtest]{
+		WorkflowID: workflowID,
+		RunID:      runID,
+		temporal:   c.temporal.services,
+		results: func(ctx context.Context, run temporalclient.WorkflowRun) (forwardtestsclient.Forwardtest, error) {
+			ft, err := c.forwardtestHandle(ctx, run)
+			return ft, MapError(err)
+		},
+	}
+}
@@ -9,13 +9,46 @@ import (
 	"github.com/cryptellation/runtime"
 )
 
+// Backtest is the handle of a backtest, with the metadata attached on its
+// creation (see WithMetadata).
+type Backtest struct {
+	clients.Backtest
+	Metadata Metadata
+}
+
 // NewBacktest creates a new backtest.
+// If the context has an idempotency key (see WithIdempotencyKey), the backtest
+// created by a previous call with the same key is returned. If it has
+// metadata (see WithMetadata), they are saved in the metadata store once the
+// backtest is created: if this fails, the backtest is returned with a
+// *MetadataError.
 func (c client) NewBacktest(
 	ctx context.Context,
 	params backtest.Parameters,
 	callbacks runtime.Callbacks,
 ) (clients.Backtest, error) {
-	return c.backtests.NewBacktest(ctx, params, callbacks)
+	p := NewBacktestParams{Parameters: params, Callbacks: callbacks}
+	bt, err := call(ctx, c, ServiceBacktests, MethodNewBacktest, p,
+		func(ctx context.Context, p NewBacktestParams) (clients.Backtest, error) {
+			if _, ok := IdempotencyKeyFromContext(ctx); !ok {
+				return c.backtests.NewBacktest(ctx, p.Parameters, p.Callbacks)
+			}
+
+			run, err := c.startWorkflow(ctx, api.WorkerTaskQueueName, api.CreateBacktestWorkflowName,
+				api.CreateBacktestWorkflowParams{
+					BacktestParameters: p.Parameters,
+					Callbacks:          p.Callbacks,
+				})
+			if err != nil {
+				return clients.Backtest{}, err
+			}
+			return c.AttachNewBacktest(run.GetID(), run.GetRunID()).Get(ctx)
+		})
+	if err != nil {
+		return bt, err
+	}
+
+	return bt, c.saveMetadata(ctx, RunKindBacktest, bt.ID)
 }
 
 // GetBacktest gets a backtest.
@@ -23,7 +56,37 @@ func (c client) GetBacktest(
 	ctx context.Context,
 	params api.GetBacktestWorkflowParams,
 ) (clients.Backtest, error) {
-	return c.backtests.GetBacktest(ctx, params)
+	return call(ctx, c, ServiceBacktests, MethodGetBacktest, params, c.backtests.GetBacktest)
+}
+
+// GetBacktestWithMetadata gets a backtest, with its metadata.
+func (c client) GetBacktestWithMetadata(
+	ctx context.Context,
+	params api.GetBacktestWorkflowParams,
+) (Backtest, error) {
+	bt, err := c.GetBacktest(ctx, params)
+	if err != nil {
+		return Backtest{}, err
+	}
+
+	md, err := c.GetMetadata(ctx, RunKindBacktest, bt.ID)
+	if err != nil {
+		return Backtest{}, err
+	}
+	return Backtest{Backtest: bt, Metadata: md}, nil
+}
+
+// GetBacktestDetails gets the whole state of a backtest (parameters,
+// accounts, orders, etc.), instead of a handle.
+func (c client) GetBacktestDetails(
+	ctx context.Context,
+	params api.GetBacktestWorkflowParams,
+) (backtest.Backtest, error) {
+	return call(ctx, c, ServiceBacktests, MethodGetBacktestDetails, params,
+		func(ctx context.Context, p api.GetBacktestWorkflowParams) (backtest.Backtest, error) {
+			res, err := clients.NewRaw(c.temporal.services).GetBacktest(ctx, p)
+			return res.Backtest, err
+		})
 }
 
 // ListBacktests lists backtests.
@@ -31,5 +94,27 @@ func (c client) ListBacktests(
 	ctx context.Context,
 	params api.ListBacktestsWorkflowParams,
 ) ([]clients.Backtest, error) {
-	return c.backtests.ListBacktests(ctx, params)
+	return call(ctx, c, ServiceBacktests, MethodListBacktests, params, c.backtests.ListBacktests)
+}
+
+// ListBacktestsWithMetadata lists backtests, with their metadata.
+func (c client) ListBacktestsWithMetadata(
+	ctx context.Context,
+	params api.ListBacktestsWorkflowParams,
+) ([]Backtest, error) {
+	list, err := c.ListBacktests(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := c.ListMetadata(ctx, RunKindBacktest, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	backtests := make([]Backtest, len(list))
+	for i, bt := range list {
+		backtests[i] = Backtest{Backtest: bt, Metadata: metadata[bt.ID]}
+	}
+	return backtests, nil
 }
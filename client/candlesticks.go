@@ -2,8 +2,11 @@ package client
 
 import (
 	"context"
+	"time"
 
 	candlesticksapi "github.com/cryptellation/candlesticks/api"
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
+	"github.com/cryptellation/go-clients/resample"
 )
 
 // ListCandlesticks calls the candlesticks list workflow.
@@ -11,5 +14,24 @@ func (c client) ListCandlesticks(
 	ctx context.Context,
 	params candlesticksapi.ListCandlesticksWorkflowParams,
 ) (res candlesticksapi.ListCandlesticksWorkflowResults, err error) {
-	return c.candlesticks.ListCandlesticks(ctx, params)
+	if params.Pair, err = NormalizePair(params.Pair); err != nil {
+		return res, err
+	}
+	return call(ctx, c, ServiceCandlesticks, MethodListCandlesticks, params, c.candlesticks.ListCandlesticks)
+}
+
+// ListResampledCandlesticks lists candlesticks with the period of the params
+// and resamples them into candlesticks of the target duration.
+func (c client) ListResampledCandlesticks(
+	ctx context.Context,
+	params candlesticksapi.ListCandlesticksWorkflowParams,
+	to time.Duration,
+	opts ...resample.Options,
+) ([]candlestick.Candlestick, error) {
+	res, err := c.ListCandlesticks(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return resample.Resample(res.List, params.Period, to, opts...)
 }
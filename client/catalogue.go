@@ -0,0 +1,406 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cryptellation/candlesticks/pkg/period"
+	exchangesapi "github.com/cryptellation/exchanges/api"
+	"github.com/cryptellation/exchanges/pkg/exchange"
+	"github.com/cryptellation/go-clients/market"
+	temporalLog "go.temporal.io/sdk/log"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// defaultCatalogueTTL is the default duration during which the exchanges
+	// metadata are served from the catalogue without calling the service.
+	defaultCatalogueTTL = 5 * time.Minute
+	// catalogueFetchTimeout is the maximum duration of a refresh from the
+	// service, shared by all the callers waiting for it.
+	catalogueFetchTimeout = 30 * time.Second
+	// listKey is the singleflight key of the exchanges list.
+	listKey = "\x00list"
+)
+
+// ChangeKind is the kind of change of an exchange in the catalogue.
+type ChangeKind string
+
+const (
+	// ExchangeAdded is the change of an exchange seen for the first time.
+	ExchangeAdded ChangeKind = "added"
+	// ExchangeUpdated is the change of an exchange whose metadata changed.
+	ExchangeUpdated ChangeKind = "updated"
+	// ExchangeRemoved is the change of an exchange that no longer exists.
+	ExchangeRemoved ChangeKind = "removed"
+)
+
+// ExchangeChange is a change of the metadata of an exchange, detected when
+// the catalogue refreshes it.
+type ExchangeChange struct {
+	Kind ChangeKind
+	Name string
+	// Previous is the exchange before the change, empty when added.
+	Previous exchange.Exchange
+	// Current is the exchange after the change, empty when removed.
+	Current exchange.Exchange
+}
+
+// Fees are the fees of an exchange, in percent.
+type Fees struct {
+	Maker float64
+	Taker float64
+}
+
+// exchangesSource is the source of the exchanges metadata of the catalogue.
+type exchangesSource interface {
+	GetExchange(
+		ctx context.Context,
+		params exchangesapi.GetExchangeWorkflowParams,
+	) (exchangesapi.GetExchangeWorkflowResults, error)
+	ListExchanges(
+		ctx context.Context,
+		params exchangesapi.ListExchangesWorkflowParams,
+	) (exchangesapi.ListExchangesWorkflowResults, error)
+}
+
+type catalogueEntry struct {
+	exchange  exchange.Exchange
+	fetchedAt time.Time
+}
+
+// Catalogue is a cache of the exchanges metadata (pairs, periods and fees).
+// Entries are refreshed from the exchanges service when they are older than
+// the TTL (see WithCatalogueTTL), and the changes detected on refresh are
+// notified to the handlers registered with OnChange.
+type Catalogue struct {
+	source exchangesSource
+	logger temporalLog.Logger
+	ttl    time.Duration
+	group  singleflight.Group
+
+	mu        sync.RWMutex
+	entries   map[string]catalogueEntry
+	names     []string
+	listedAt  time.Time
+	handlers  map[int]func(ExchangeChange)
+	handlerID int
+}
+
+func newCatalogue() *Catalogue {
+	return &Catalogue{
+		ttl:      defaultCatalogueTTL,
+		entries:  make(map[string]catalogueEntry),
+		handlers: make(map[int]func(ExchangeChange)),
+	}
+}
+
+// WithCatalogueTTL sets the duration during which the exchanges metadata are
+// served from the catalogue before being refreshed. It must be positive.
+// Default is 5 minutes.
+func WithCatalogueTTL(ttl time.Duration) func(*client) {
+	return func(c *client) {
+		c.catalogue.ttl = ttl
+	}
+}
+
+// Catalogue returns the cached catalogue of the exchanges metadata.
+func (c client) Catalogue() *Catalogue {
+	return c.catalogue
+}
+
+// Exchange returns the metadata of an exchange, refreshed if older than the TTL.
+func (cat *Catalogue) Exchange(ctx context.Context, name string) (exchange.Exchange, error) {
+	cat.mu.RLock()
+	entry, ok := cat.entries[name]
+	cat.mu.RUnlock()
+	if ok && time.Since(entry.fetchedAt) < cat.ttl {
+		return entry.exchange, nil
+	}
+
+	return cat.fetch(ctx, name)
+}
+
+// Exchanges returns the metadata of all the exchanges, sorted by name.
+func (cat *Catalogue) Exchanges(ctx context.Context) ([]exchange.Exchange, error) {
+	names, err := cat.listNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	exchanges := make([]exchange.Exchange, 0, len(names))
+	for _, name := range names {
+		exch, err := cat.Exchange(ctx, name)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			continue
+		case err != nil:
+			return nil, err
+		}
+		exchanges = append(exchanges, exch)
+	}
+	return exchanges, nil
+}
+
+// SupportsPair returns true if the pair, in one of the common notations (see
+// market.ParsePair), is listed on the exchange.
+func (cat *Catalogue) SupportsPair(ctx context.Context, exchangeName, pair string) (bool, error) {
+	exch, err := cat.Exchange(ctx, exchangeName)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = market.ParsePairOn(exch, pair)
+	switch {
+	case errors.Is(err, market.ErrUnknownPair):
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("%w: %w", ErrInvalidParams, err)
+	}
+	return true, nil
+}
+
+// Periods returns the periods available on the exchange.
+func (cat *Catalogue) Periods(ctx context.Context, exchangeName string) ([]period.Symbol, error) {
+	exch, err := cat.Exchange(ctx, exchangeName)
+	if err != nil {
+		return nil, err
+	}
+
+	periods := make([]period.Symbol, 0, len(exch.Periods))
+	for _, p := range exch.Periods {
+		if symbol, err := period.FromString(p); err == nil {
+			periods = append(periods, symbol)
+		}
+	}
+	return periods, nil
+}
+
+// SupportsPeriod returns true if the period is available on the exchange.
+func (cat *Catalogue) SupportsPeriod(ctx context.Context, exchangeName string, p period.Symbol) (bool, error) {
+	periods, err := cat.Periods(ctx, exchangeName)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(periods, p), nil
+}
+
+// Fees returns the fees of the exchange. The exchanges service has a single
+// fee rate per exchange, used for both the maker and taker fees.
+func (cat *Catalogue) Fees(ctx context.Context, exchangeName string) (Fees, error) {
+	exch, err := cat.Exchange(ctx, exchangeName)
+	if err != nil {
+		return Fees{}, err
+	}
+	return Fees{Maker: exch.Fees, Taker: exch.Fees}, nil
+}
+
+// TakerFee returns the taker fee of the exchange, in percent.
+func (cat *Catalogue) TakerFee(ctx context.Context, exchangeName string) (float64, error) {
+	fees, err := cat.Fees(ctx, exchangeName)
+	return fees.Taker, err
+}
+
+// MakerFee returns the maker fee of the exchange, in percent.
+func (cat *Catalogue) MakerFee(ctx context.Context, exchangeName string) (float64, error) {
+	fees, err := cat.Fees(ctx, exchangeName)
+	return fees.Maker, err
+}
+
+// Invalidate expires the given exchanges (all if none), so they are refreshed
+// on their next lookup.
+func (cat *Catalogue) Invalidate(names ...string) {
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+
+	if len(names) == 0 {
+		cat.listedAt = time.Time{}
+		names = make([]string, 0, len(cat.entries))
+		for name := range cat.entries {
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		if entry, ok := cat.entries[name]; ok {
+			entry.fetchedAt = time.Time{}
+			cat.entries[name] = entry
+		}
+	}
+}
+
+// Refresh refreshes all the exchanges from the service, notifying the changes.
+func (cat *Catalogue) Refresh(ctx context.Context) error {
+	cat.Invalidate()
+	_, err := cat.Exchanges(ctx)
+	return err
+}
+
+// Run refreshes the catalogue every TTL until the context is done, so the
+// changes are notified even without lookups. Refresh errors are logged.
+func (cat *Catalogue) Run(ctx context.Context) error {
+	ticker := time.NewTicker(cat.ttl)
+	defer ticker.Stop()
+
+	for {
+		if err := cat.Refresh(ctx); err != nil && ctx.Err() == nil {
+			cat.logger.Error("Refreshing exchanges catalogue", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// OnChange registers a handler called with each change detected on refresh.
+// Handlers are called synchronously and must not block. The returned
+// function unregisters the handler.
+func (cat *Catalogue) OnChange(handler func(ExchangeChange)) (unregister func()) {
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+
+	id := cat.handlerID
+	cat.handlerID++
+	cat.handlers[id] = handler
+
+	return func() {
+		cat.mu.Lock()
+		defer cat.mu.Unlock()
+		delete(cat.handlers, id)
+	}
+}
+
+// listNames returns the names of the exchanges, refreshed if older than the TTL.
+func (cat *Catalogue) listNames(ctx context.Context) ([]string, error) {
+	cat.mu.RLock()
+	names, listedAt := cat.names, cat.listedAt
+	cat.mu.RUnlock()
+	if !listedAt.IsZero() && time.Since(listedAt) < cat.ttl {
+		return names, nil
+	}
+
+	res, err := cat.do(ctx, listKey, func(ctx context.Context) (any, error) {
+		res, err := cat.source.ListExchanges(ctx, exchangesapi.ListExchangesWorkflowParams{})
+		if err != nil {
+			return nil, err
+		}
+		names := slices.Clone(res.List)
+		slices.Sort(names)
+
+		// Remove the exchanges no longer listed
+		var changes []ExchangeChange
+		cat.mu.Lock()
+		for name, entry := range cat.entries {
+			if !slices.Contains(names, name) {
+				delete(cat.entries, name)
+				changes = append(changes, ExchangeChange{Kind: ExchangeRemoved, Name: name, Previous: entry.exchange})
+			}
+		}
+		cat.names, cat.listedAt = names, time.Now()
+		cat.mu.Unlock()
+
+		cat.notify(changes...)
+		return names, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]string), nil
+}
+
+// fetch gets the exchange from the service, updates the catalogue and
+// notifies the change, if any.
+func (cat *Catalogue) fetch(ctx context.Context, name string) (exchange.Exchange, error) {
+	res, err := cat.do(ctx, name, func(ctx context.Context) (any, error) {
+		res, err := cat.source.GetExchange(ctx, exchangesapi.GetExchangeWorkflowParams{Name: name})
+		if errors.Is(err, ErrNotFound) {
+			cat.remove(name)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Update the entry
+		cat.mu.Lock()
+		prev, existed := cat.entries[name]
+		cat.entries[name] = catalogueEntry{exchange: res.Exchange, fetchedAt: time.Now()}
+		cat.mu.Unlock()
+
+		// Notify the change
+		switch {
+		case !existed:
+			cat.notify(ExchangeChange{Kind: ExchangeAdded, Name: name, Current: res.Exchange})
+		case !sameMetadata(prev.exchange, res.Exchange):
+			cat.notify(ExchangeChange{Kind: ExchangeUpdated, Name: name, Previous: prev.exchange, Current: res.Exchange})
+		}
+		return res.Exchange, nil
+	})
+	if err != nil {
+		return exchange.Exchange{}, err
+	}
+	return res.(exchange.Exchange), nil
+}
+
+// do calls the function once for all the concurrent callers with the same
+// key. The function is not bound to the context of the first caller, so it is
+// not canceled with it, but each caller stops waiting when its context is done.
+func (cat *Catalogue) do(ctx context.Context, key string, fn func(ctx context.Context) (any, error)) (any, error) {
+	ch := cat.group.DoChan(key, func() (any, error) {
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), catalogueFetchTimeout)
+		defer cancel()
+		return fn(fetchCtx)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		return res.Val, res.Err
+	}
+}
+
+// remove removes an exchange that no longer exists from the catalogue.
+func (cat *Catalogue) remove(name string) {
+	cat.mu.Lock()
+	entry, ok := cat.entries[name]
+	delete(cat.entries, name)
+	cat.mu.Unlock()
+
+	if ok {
+		cat.notify(ExchangeChange{Kind: ExchangeRemoved, Name: name, Previous: entry.exchange})
+	}
+}
+
+// notify calls the handlers with the changes.
+func (cat *Catalogue) notify(changes ...ExchangeChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	cat.mu.RLock()
+	handlers := make([]func(ExchangeChange), 0, len(cat.handlers))
+	for _, h := range cat.handlers {
+		handlers = append(handlers, h)
+	}
+	cat.mu.RUnlock()
+
+	for _, change := range changes {
+		for _, h := range handlers {
+			h(change)
+		}
+	}
+}
+
+// sameMetadata checks that two exchanges have the same metadata, regardless
+// of their last synchronization time.
+func sameMetadata(a, b exchange.Exchange) bool {
+	a.LastSyncTime, b.LastSyncTime = time.Time{}, time.Time{}
+	return a.Equals(b)
+}
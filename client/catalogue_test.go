@@ -0,0 +1,352 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cryptellation/candlesticks/pkg/period"
+	exchangesapi "github.com/cryptellation/exchanges/api"
+	"github.com/cryptellation/exchanges/pkg/exchange"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeExchanges is an exchanges source counting the calls it receives.
+type fakeExchanges struct {
+	mu        sync.Mutex
+	exchanges map[string]exchange.Exchange
+	gets      map[string]int
+	lists     int
+	err       error
+	// release, if set, blocks the calls until it is closed.
+	release chan struct{}
+}
+
+func newFakeExchanges(exchanges ...exchange.Exchange) *fakeExchanges {
+	src := &fakeExchanges{
+		exchanges: make(map[string]exchange.Exchange),
+		gets:      make(map[string]int),
+	}
+	for _, e := range exchanges {
+		src.exchanges[e.Name] = e
+	}
+	return src
+}
+
+func (s *fakeExchanges) set(e exchange.Exchange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exchanges[e.Name] = e
+}
+
+func (s *fakeExchanges) delete(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.exchanges, name)
+}
+
+func (s *fakeExchanges) calls(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gets[name]
+}
+
+func (s *fakeExchanges) GetExchange(
+	_ context.Context,
+	params exchangesapi.GetExchangeWorkflowParams,
+) (exchangesapi.GetExchangeWorkflowResults, error) {
+	if s.release != nil {
+		<-s.release
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gets[params.Name]++
+	if s.err != nil {
+		return exchangesapi.GetExchangeWorkflowResults{}, s.err
+	}
+
+	e, ok := s.exchanges[params.Name]
+	if !ok {
+		return exchangesapi.GetExchangeWorkflowResults{}, ErrNotFound
+	}
+	return exchangesapi.GetExchangeWorkflowResults{Exchange: e}, nil
+}
+
+func (s *fakeExchanges) ListExchanges(
+	_ context.Context,
+	_ exchangesapi.ListExchangesWorkflowParams,
+) (exchangesapi.ListExchangesWorkflowResults, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lists++
+	if s.err != nil {
+		return exchangesapi.ListExchangesWorkflowResults{}, s.err
+	}
+
+	names := make([]string, 0, len(s.exchanges))
+	for name := range s.exchanges {
+		names = append(names, name)
+	}
+	return exchangesapi.ListExchangesWorkflowResults{List: names}, nil
+}
+
+func newTestCatalogue(src *fakeExchanges) *Catalogue {
+	cat := newCatalogue()
+	cat.source = src
+	cat.logger = &DummyLogger{}
+	return cat
+}
+
+var (
+	binance = exchange.Exchange{
+		Name:    "binance",
+		Pairs:   []string{"BTC-USDT", "ETH-BTC"},
+		Periods: []string{"M1", "H1", "unknown"},
+		Fees:    0.1,
+	}
+	kraken = exchange.Exchange{Name: "kraken", Pairs: []string{"BTC-EUR"}, Fees: 0.2}
+)
+
+func TestCatalogueExchange(t *testing.T) {
+	tests := []struct {
+		name     string
+		ttl      time.Duration
+		between  func(cat *Catalogue)
+		expected int
+	}{
+		{name: "cached", ttl: time.Hour, expected: 1},
+		{name: "expired", ttl: time.Nanosecond, between: func(*Catalogue) { time.Sleep(time.Millisecond) }, expected: 2},
+		{name: "invalidated", ttl: time.Hour, between: func(cat *Catalogue) { cat.Invalidate("binance") }, expected: 2},
+		{name: "other invalidated", ttl: time.Hour, between: func(cat *Catalogue) { cat.Invalidate("kraken") }, expected: 1},
+		{name: "all invalidated", ttl: time.Hour, between: func(cat *Catalogue) { cat.Invalidate() }, expected: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := newFakeExchanges(binance, kraken)
+			cat := newTestCatalogue(src)
+			cat.ttl = tt.ttl
+
+			e, err := cat.Exchange(context.Background(), "binance")
+			require.NoError(t, err)
+			require.Equal(t, binance, e)
+			if tt.between != nil {
+				tt.between(cat)
+			}
+			e, err = cat.Exchange(context.Background(), "binance")
+			require.NoError(t, err)
+			require.Equal(t, binance, e)
+			require.Equal(t, tt.expected, src.calls("binance"))
+		})
+	}
+}
+
+func TestCatalogueExchangeErrors(t *testing.T) {
+	errService := errors.New("service error")
+	src := newFakeExchanges(binance)
+	cat := newTestCatalogue(src)
+
+	// Unknown exchanges are not found
+	_, err := cat.Exchange(context.Background(), "unknown")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// Errors are not cached
+	src.err = errService
+	_, err = cat.Exchange(context.Background(), "binance")
+	require.ErrorIs(t, err, errService)
+	src.err = nil
+	_, err = cat.Exchange(context.Background(), "binance")
+	require.NoError(t, err)
+	require.Equal(t, 2, src.calls("binance"))
+}
+
+func TestCatalogueExchangeConcurrent(t *testing.T) {
+	src := newFakeExchanges(binance)
+	src.release = make(chan struct{})
+	cat := newTestCatalogue(src)
+
+	// Concurrent lookups share the same call
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e, err := cat.Exchange(context.Background(), "binance")
+			require.NoError(t, err)
+			require.Equal(t, binance, e)
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(src.release)
+	wg.Wait()
+	require.Equal(t, 1, src.calls("binance"))
+
+	// A canceled caller stops waiting
+	cat.Invalidate()
+	src.release = make(chan struct{})
+	defer close(src.release)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := cat.Exchange(ctx, "binance")
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestCatalogueExchanges(t *testing.T) {
+	src := newFakeExchanges(kraken, binance)
+	cat := newTestCatalogue(src)
+
+	list, err := cat.Exchanges(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []exchange.Exchange{binance, kraken}, list)
+
+	// The list is cached
+	_, err = cat.Exchanges(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, src.lists)
+	require.Equal(t, 1, src.calls("binance"))
+}
+
+func TestCatalogueChanges(t *testing.T) {
+	src := newFakeExchanges(binance, kraken)
+	cat := newTestCatalogue(src)
+
+	var changes []ExchangeChange
+	unregister := cat.OnChange(func(c ExchangeChange) {
+		changes = append(changes, c)
+	})
+
+	// The exchanges are added on their first lookup
+	require.NoError(t, cat.Refresh(context.Background()))
+	require.Equal(t, []ExchangeChange{
+		{Kind: ExchangeAdded, Name: "binance", Current: binance},
+		{Kind: ExchangeAdded, Name: "kraken", Current: kraken},
+	}, changes)
+
+	// A new synchronization is not a change
+	changes = nil
+	synced := binance
+	synced.LastSyncTime = time.Now()
+	src.set(synced)
+	require.NoError(t, cat.Refresh(context.Background()))
+	require.Empty(t, changes)
+
+	// Updated and removed exchanges
+	updated := synced
+	updated.Fees = 0.2
+	src.set(updated)
+	src.delete("kraken")
+	require.NoError(t, cat.Refresh(context.Background()))
+	require.Equal(t, []ExchangeChange{
+		{Kind: ExchangeRemoved, Name: "kraken", Previous: kraken},
+		{Kind: ExchangeUpdated, Name: "binance", Previous: synced, Current: updated},
+	}, changes)
+
+	// Exchanges not found on lookup are removed
+	changes = nil
+	src.delete("binance")
+	cat.Invalidate("binance")
+	_, err := cat.Exchange(context.Background(), "binance")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.Equal(t, []ExchangeChange{{Kind: ExchangeRemoved, Name: "binance", Previous: updated}}, changes)
+
+	// Unregistered handlers are not called
+	changes = nil
+	unregister()
+	src.set(binance)
+	require.NoError(t, cat.Refresh(context.Background()))
+	require.Empty(t, changes)
+}
+
+func TestCatalogueSupportsPair(t *testing.T) {
+	tests := []struct {
+		name     string
+		exchange string
+		pair     string
+		expected bool
+		err      error
+	}{
+		{name: "listed", exchange: "binance", pair: "BTC-USDT", expected: true},
+		{name: "other notation", exchange: "binance", pair: "eth/btc", expected: true},
+		{name: "compact listed", exchange: "binance", pair: "ETHBTC", expected: true},
+		{name: "not listed", exchange: "binance", pair: "BTC-EUR", expected: false},
+		{name: "invalid pair", exchange: "binance", pair: "BTC-", err: ErrInvalidParams},
+		{name: "unknown exchange", exchange: "unknown", pair: "BTC-USDT", err: ErrNotFound},
+	}
+
+	cat := newTestCatalogue(newFakeExchanges(binance))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := cat.SupportsPair(context.Background(), tt.exchange, tt.pair)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, ok)
+		})
+	}
+}
+
+func TestCataloguePeriods(t *testing.T) {
+	cat := newTestCatalogue(newFakeExchanges(binance))
+
+	// Unknown periods are skipped
+	periods, err := cat.Periods(context.Background(), "binance")
+	require.NoError(t, err)
+	require.Equal(t, []period.Symbol{period.M1, period.H1}, periods)
+
+	tests := []struct {
+		name     string
+		period   period.Symbol
+		expected bool
+	}{
+		{name: "available", period: period.H1, expected: true},
+		{name: "not available", period: period.D1, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := cat.SupportsPeriod(context.Background(), "binance", tt.period)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, ok)
+		})
+	}
+}
+
+func TestCatalogueFees(t *testing.T) {
+	cat := newTestCatalogue(newFakeExchanges(binance))
+
+	fees, err := cat.Fees(context.Background(), "binance")
+	require.NoError(t, err)
+	require.Equal(t, Fees{Maker: 0.1, Taker: 0.1}, fees)
+
+	maker, err := cat.MakerFee(context.Background(), "binance")
+	require.NoError(t, err)
+	require.Equal(t, 0.1, maker)
+	taker, err := cat.TakerFee(context.Background(), "binance")
+	require.NoError(t, err)
+	require.Equal(t, 0.1, taker)
+
+	_, err = cat.Fees(context.Background(), "unknown")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestCatalogueRun(t *testing.T) {
+	src := newFakeExchanges(binance)
+	cat := newTestCatalogue(src)
+	cat.ttl = 10 * time.Millisecond
+
+	// The catalogue is refreshed every TTL, without lookups
+	ctx, cancel := context.WithTimeout(context.Background(), 55*time.Millisecond)
+	defer cancel()
+	require.NoError(t, cat.Run(ctx))
+	require.GreaterOrEqual(t, src.calls("binance"), 3)
+}
+
+func TestNewCatalogueTTL(t *testing.T) {
+	_, err := New(WithCatalogueTTL(0))
+	require.ErrorIs(t, err, ErrInvalidParams)
+}
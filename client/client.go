@@ -3,23 +3,33 @@ package client
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
+	"time"
 
 	backtestsapi "github.com/cryptellation/backtests/api"
 	"github.com/cryptellation/backtests/pkg/backtest"
 	backtestsclient "github.com/cryptellation/backtests/pkg/clients"
 	candlesticksapi "github.com/cryptellation/candlesticks/api"
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
 	candlesticksclient "github.com/cryptellation/candlesticks/pkg/clients"
 	exchangesapi "github.com/cryptellation/exchanges/api"
 	exchangesclient "github.com/cryptellation/exchanges/pkg/clients"
 	forwardtestsapi "github.com/cryptellation/forwardtests/api"
 	forwardtestsclient "github.com/cryptellation/forwardtests/pkg/clients"
+	"github.com/cryptellation/forwardtests/pkg/forwardtest"
+	"github.com/cryptellation/go-clients/market"
+	"github.com/cryptellation/go-clients/resample"
 	"github.com/cryptellation/runtime"
 	smaapi "github.com/cryptellation/sma/api"
 	smaclient "github.com/cryptellation/sma/pkg/clients"
 	ticksclient "github.com/cryptellation/ticks/pkg/clients"
 	"github.com/google/uuid"
 	temporalclient "go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/interceptor"
 	temporalLog "go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/worker"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -31,22 +41,71 @@ type Client interface {
 		params backtest.Parameters,
 		callbacks runtime.Callbacks,
 	) (backtestsclient.Backtest, error)
+	// NewBacktestAsync starts the creation of a new backtest without waiting for it.
+	NewBacktestAsync(
+		ctx context.Context,
+		params NewBacktestParams,
+	) (WorkflowHandle[backtestsclient.Backtest], error)
+	// AttachNewBacktest returns the handle of a backtest creation started asynchronously.
+	AttachNewBacktest(workflowID, runID string) WorkflowHandle[backtestsclient.Backtest]
+	// RunBacktestAsync starts the run of a backtest without waiting for its end.
+	RunBacktestAsync(
+		ctx context.Context,
+		params backtestsapi.RunBacktestWorkflowParams,
+	) (WorkflowHandle[backtestsapi.RunBacktestWorkflowResults], error)
+	// AttachRunBacktest returns the handle of a backtest run started asynchronously.
+	AttachRunBacktest(workflowID, runID string) WorkflowHandle[backtestsapi.RunBacktestWorkflowResults]
 	// GetBacktest gets a backtest.
 	GetBacktest(
 		ctx context.Context,
 		params backtestsapi.GetBacktestWorkflowParams,
 	) (backtestsclient.Backtest, error)
+	// GetBacktestWithMetadata gets a backtest, with its metadata.
+	GetBacktestWithMetadata(
+		ctx context.Context,
+		params backtestsapi.GetBacktestWorkflowParams,
+	) (Backtest, error)
+	// GetBacktestDetails gets the whole state of a backtest (parameters,
+	// accounts, orders, etc.), instead of a handle.
+	GetBacktestDetails(
+		ctx context.Context,
+		params backtestsapi.GetBacktestWorkflowParams,
+	) (backtest.Backtest, error)
 	// ListBacktests lists backtests.
 	ListBacktests(
 		ctx context.Context,
 		params backtestsapi.ListBacktestsWorkflowParams,
 	) ([]backtestsclient.Backtest, error)
+	// ListBacktestsWithMetadata lists backtests, with their metadata.
+	ListBacktestsWithMetadata(
+		ctx context.Context,
+		params backtestsapi.ListBacktestsWorkflowParams,
+	) ([]Backtest, error)
+	// QueryBacktests lists the backtests matching the query.
+	QueryBacktests(ctx context.Context, q Query) (Page[backtest.Backtest], error)
 
 	// ListCandlesticks calls the candlesticks list workflow.
 	ListCandlesticks(
 		ctx context.Context,
 		params candlesticksapi.ListCandlesticksWorkflowParams,
 	) (res candlesticksapi.ListCandlesticksWorkflowResults, err error)
+	// ListCandlesticksAsync starts the candlesticks list workflow without waiting for it.
+	ListCandlesticksAsync(
+		ctx context.Context,
+		params candlesticksapi.ListCandlesticksWorkflowParams,
+	) (WorkflowHandle[candlesticksapi.ListCandlesticksWorkflowResults], error)
+	// AttachListCandlesticks returns the handle of a candlesticks list started asynchronously.
+	AttachListCandlesticks(
+		workflowID, runID string,
+	) WorkflowHandle[candlesticksapi.ListCandlesticksWorkflowResults]
+	// ListResampledCandlesticks lists candlesticks with the period of the params
+	// and resamples them into candlesticks of the target duration.
+	ListResampledCandlesticks(
+		ctx context.Context,
+		params candlesticksapi.ListCandlesticksWorkflowParams,
+		to time.Duration,
+		opts ...resample.Options,
+	) ([]candlestick.Candlestick, error)
 
 	// GetExchange retrieves an exchange by name.
 	GetExchange(
@@ -58,23 +117,60 @@ type Client interface {
 		ctx context.Context,
 		params exchangesapi.ListExchangesWorkflowParams,
 	) (exchangesapi.ListExchangesWorkflowResults, error)
+	// ValidatePair parses the pair from one of the common notations and checks
+	// that it is listed on the exchange.
+	ValidatePair(ctx context.Context, exchange, pair string) (market.Pair, error)
+	// Catalogue returns the cached catalogue of the exchanges metadata, with
+	// the pairs, periods and fees lookups.
+	Catalogue() *Catalogue
 
 	// ListSMA retrieves a list of simple moving averages (SMA) for a specific exchange and trading pair.
 	ListSMA(
 		ctx context.Context,
 		params smaapi.ListWorkflowParams,
 	) (res smaapi.ListWorkflowResults, err error)
+	// ListSMAAsync starts the SMA list workflow without waiting for it.
+	ListSMAAsync(
+		ctx context.Context,
+		params smaapi.ListWorkflowParams,
+	) (WorkflowHandle[smaapi.ListWorkflowResults], error)
+	// AttachListSMA returns the handle of a SMA list started asynchronously.
+	AttachListSMA(workflowID, runID string) WorkflowHandle[smaapi.ListWorkflowResults]
 
 	// NewForwardtest creates a new forwardtest.
 	NewForwardtest(
 		ctx context.Context,
 		params forwardtestsapi.CreateForwardtestWorkflowParams,
 	) (forwardtestsclient.Forwardtest, error)
+	// NewForwardtestAsync starts the creation of a new forwardtest without waiting for it.
+	NewForwardtestAsync(
+		ctx context.Context,
+		params forwardtestsapi.CreateForwardtestWorkflowParams,
+	) (WorkflowHandle[forwardtestsclient.Forwardtest], error)
+	// AttachNewForwardtest returns the handle of a forwardtest creation started asynchronously.
+	AttachNewForwardtest(workflowID, runID string) WorkflowHandle[forwardtestsclient.Forwardtest]
+	// GetForwardtest gets a forwardtest.
+	GetForwardtest(
+		ctx context.Context,
+		params forwardtestsapi.GetForwardtestWorkflowParams,
+	) (forwardtestsclient.Forwardtest, error)
+	// GetForwardtestWithMetadata gets a forwardtest, with its metadata.
+	GetForwardtestWithMetadata(
+		ctx context.Context,
+		params forwardtestsapi.GetForwardtestWorkflowParams,
+	) (Forwardtest, error)
 	// ListForwardtests lists the forwardtests.
 	ListForwardtests(
 		ctx context.Context,
 		params forwardtestsapi.ListForwardtestsWorkflowParams,
 	) ([]forwardtestsclient.Forwardtest, error)
+	// ListForwardtestsWithMetadata lists the forwardtests, with their metadata.
+	ListForwardtestsWithMetadata(
+		ctx context.Context,
+		params forwardtestsapi.ListForwardtestsWorkflowParams,
+	) ([]Forwardtest, error)
+	// QueryForwardtests lists the forwardtests matching the query.
+	QueryForwardtests(ctx context.Context, q Query) (Page[forwardtest.Forwardtest], error)
 
 	// ListenToTicks listens to ticks from a specific exchange and trading pair.
 	ListenToTicks(
@@ -93,6 +189,40 @@ type Client interface {
 
 	// ServicesInfo retrieves information about the services.
 	ServicesInfo(ctx context.Context) (map[string]any, error)
+	// WaitReady blocks until the given services (all if none) respond to their
+	// info workflow. If the context ends before, the returned error is a
+	// *NotReadyError listing the services that are not ready.
+	WaitReady(ctx context.Context, services ...Service) error
+	// ServicesVersions retrieves the versions of the given services (all if none).
+	ServicesVersions(ctx context.Context, services ...Service) (map[Service]Version, error)
+	// CheckCompatibility checks that the versions of the given services (all if
+	// none) are in the compatibility table. It returns the compatibility of each
+	// service, and an *IncompatibleError if some are not compatible.
+	CheckCompatibility(ctx context.Context, services ...Service) ([]ServiceCompatibility, error)
+
+	// GetMetadata gets the metadata attached to a backtest or a forwardtest on
+	// its creation (see WithMetadata), from the metadata store.
+	GetMetadata(ctx context.Context, kind RunKind, id uuid.UUID) (Metadata, error)
+	// ListMetadata lists the metadata of the runs of the given kind that have
+	// all the values of the filters.
+	ListMetadata(ctx context.Context, kind RunKind, filters Metadata) (map[uuid.UUID]Metadata, error)
+
+	// CreateBacktestSchedule creates a schedule that regularly runs backtests
+	// from a template over a rolling date range.
+	CreateBacktestSchedule(ctx context.Context, s BacktestSchedule) error
+	// ListBacktestSchedules lists the backtests schedules.
+	ListBacktestSchedules(ctx context.Context) ([]ScheduleInfo, error)
+	// PauseBacktestSchedule pauses a backtests schedule.
+	PauseBacktestSchedule(ctx context.Context, id, note string) error
+	// UnpauseBacktestSchedule unpauses a backtests schedule.
+	UnpauseBacktestSchedule(ctx context.Context, id, note string) error
+	// DeleteBacktestSchedule deletes a backtests schedule.
+	DeleteBacktestSchedule(ctx context.Context, id string) error
+	// ListScheduledRuns lists the runs of a backtests schedule with their summary.
+	ListScheduledRuns(ctx context.Context, id string) ([]ScheduledRun, error)
+	// RegisterSchedulesWorker registers the workflow and activity running the
+	// scheduled backtests on the worker.
+	RegisterSchedulesWorker(w worker.Worker)
 
 	GetTemporalClient() temporalclient.Client
 	Close()
@@ -103,6 +233,12 @@ type client struct {
 		client temporalclient.Client
 		addr   string
 		logger temporalLog.Logger
+
+		// services is the client used by the services, wrapping the
+		// provided client if interceptors or a data converter are needed
+		services      temporalclient.Client
+		wrapped       bool
+		dataConverter converter.DataConverter
 	}
 
 	backtests    backtestsclient.Client
@@ -111,6 +247,23 @@ type client struct {
 	forwardtests forwardtestsclient.Client
 	sma          smaclient.Client
 	ticks        ticksclient.Client
+
+	timeouts     *timeouts
+	resilience   *resilience
+	rateLimiter  *rateLimiter
+	interceptors []Interceptor
+	interceptor  Interceptor
+	catalogue    *Catalogue
+
+	compatibility struct {
+		check    CompatibilityCheck
+		services []Service
+	}
+
+	// metadata is the store of the runs metadata, and the search attribute
+	// indexing them in the default store
+	metadata                MetadataStore
+	metadataSearchAttribute string
 }
 
 // Options is a function that modifies the client configuration.
@@ -131,6 +284,15 @@ func WithTemporalClient(cl temporalclient.Client) func(*client) {
 	}
 }
 
+// WithDataConverter sets the data converter used to encode the payloads of the
+// workflows called by the client (see the codec package for compression and
+// encryption). The services workers must use the same data converter.
+func WithDataConverter(dc converter.DataConverter) func(*client) {
+	return func(c *client) {
+		c.temporal.dataConverter = dc
+	}
+}
+
 // WithTemporalLogger sets the logger for the temporal client.
 func WithTemporalLogger(logger temporalLog.Logger) func(*client) {
 	return func(c *client) {
@@ -144,61 +306,121 @@ func New(opts ...Options) (Client, error) {
 
 	// Apply default options
 	c.temporal.logger = &DummyLogger{}
+	c.timeouts = newTimeouts()
+	c.resilience = newResilience()
+	c.rateLimiter = newRateLimiter()
+	c.catalogue = newCatalogue()
 
 	// Apply options
 	for _, opt := range opts {
 		opt(&c)
 	}
+	if c.catalogue.ttl <= 0 {
+		return nil, fmt.Errorf("%w: catalogue TTL must be positive, got %s", ErrInvalidParams, c.catalogue.ttl)
+	}
+
+	// Set the temporal client
+	if err := c.initTemporal(); err != nil {
+		return nil, err
+	}
+
+	// Build the interceptors chain, with the rate limits applied on each attempt
+	c.interceptor = ChainInterceptors(append(c.interceptors,
+		c.timeouts.intercept,
+		c.resilience.intercept,
+		c.rateLimiter.intercept)...)
+
+	// Initialize services
+	c.backtests = backtestsclient.New(c.temporal.services)
+	c.candlesticks = candlesticksclient.New(c.temporal.services)
+	c.exchanges = exchangesclient.New(c.temporal.services)
+	c.forwardtests = forwardtestsclient.New(c.temporal.services)
+	c.sma = smaclient.New(c.temporal.services)
+	c.ticks = ticksclient.New(c.temporal.services)
+
+	// Keep the runs metadata in Temporal if there is no other store
+	if c.metadata == nil {
+		c.metadata = NewTemporalMetadataStore(c.temporal.services, c.temporal.dataConverter, c.metadataSearchAttribute)
+	}
+
+	// Set the exchanges catalogue source
+	c.catalogue.source = &c
+	c.catalogue.logger = c.temporal.logger
+
+	// Check the services compatibility
+	if err := c.checkCompatibilityOnCreation(); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// initTemporal sets the temporal client, and the one used by the services
+// with the client interceptors.
+func (c *client) initTemporal() error {
+	// Set the interceptors
+	var interceptors []interceptor.ClientInterceptor
+	if c.timeouts.enabled() {
+		interceptors = append(interceptors, c.timeouts)
+	}
 
 	// Check if either temporal client or address is provided
 	switch {
 	case c.temporal.client == nil && c.temporal.addr == "":
-		return nil, errors.New("temporal client or address must be provided")
+		return errors.New("temporal client or address must be provided")
 	case c.temporal.client != nil && c.temporal.addr != "":
-		return nil, errors.New("only one of temporal client or address must be provided")
+		return errors.New("only one of temporal client or address must be provided")
 	case c.temporal.client == nil:
 		cl, err := temporalclient.Dial(temporalclient.Options{
-			Logger:   c.temporal.logger,
-			HostPort: c.temporal.addr,
+			Logger:        c.temporal.logger,
+			HostPort:      c.temporal.addr,
+			Interceptors:  interceptors,
+			DataConverter: c.temporal.dataConverter,
 		})
 		if err != nil {
-			return nil, err
+			return err
 		}
 		c.temporal.client = cl
+		c.temporal.services = cl
+	case len(interceptors) > 0 || c.temporal.dataConverter != nil:
+		cl, err := temporalclient.NewClientFromExisting(c.temporal.client, temporalclient.Options{
+			Logger:        c.temporal.logger,
+			Interceptors:  interceptors,
+			DataConverter: c.temporal.dataConverter,
+		})
+		if err != nil {
+			return err
+		}
+		c.temporal.services = cl
+		c.temporal.wrapped = true
+	default:
+		c.temporal.services = c.temporal.client
 	}
 
-	// Initialize services
-	c.backtests = backtestsclient.New(c.temporal.client)
-	c.candlesticks = candlesticksclient.New(c.temporal.client)
-	c.exchanges = exchangesclient.New(c.temporal.client)
-	c.forwardtests = forwardtestsclient.New(c.temporal.client)
-	c.sma = smaclient.New(c.temporal.client)
-	c.ticks = ticksclient.New(c.temporal.client)
+	// Set the default data converter if none is provided
+	if c.temporal.dataConverter == nil {
+		c.temporal.dataConverter = converter.GetDefaultDataConverter()
+	}
 
-	return &c, nil
+	return nil
 }
 
 // ServicesInfo retrieves information about the services.
 func (c *client) ServicesInfo(ctx context.Context) (map[string]any, error) {
 	eg, egCtx := errgroup.WithContext(ctx)
 	res := make(map[string]any)
-	callbacks := map[string]func(ctx context.Context) (any, error){
-		"backtests":    func(ctx context.Context) (any, error) { return c.backtests.Info(ctx) },
-		"candlesticks": func(ctx context.Context) (any, error) { return c.candlesticks.Info(ctx) },
-		"exchanges":    func(ctx context.Context) (any, error) { return c.exchanges.Info(ctx) },
-		"forwardtests": func(ctx context.Context) (any, error) { return c.forwardtests.Info(ctx) },
-		"sma":          func(ctx context.Context) (any, error) { return c.sma.Info(ctx) },
-		"ticks":        func(ctx context.Context) (any, error) { return c.ticks.Info(ctx) },
-	}
-
-	for name, callback := range callbacks {
+	var mu sync.Mutex
+	for _, service := range Services {
 		eg.Go(func() error {
-			r, err := callback(egCtx)
+			r, err := c.info(egCtx, service)
 			if err != nil {
 				return err
 			}
 
-			res[name] = r
+			mu.Lock()
+			res[service.String()] = r
+			mu.Unlock()
 			return nil
 		})
 	}
@@ -206,6 +428,31 @@ func (c *client) ServicesInfo(ctx context.Context) (map[string]any, error) {
 	return res, eg.Wait()
 }
 
+// info calls the info of a service.
+func (c *client) info(ctx context.Context, service Service) (any, error) {
+	callback, ok := c.infoCallback(service)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown service %q", ErrInvalidParams, service)
+	}
+	return call(ctx, *c, service, MethodInfo, nil, callback)
+}
+
+// infoCallback returns the function calling the info workflow of a service,
+// without the interceptors.
+func (c *client) infoCallback(service Service) (func(ctx context.Context, _ any) (any, error), bool) {
+	callbacks := map[Service]func(ctx context.Context, _ any) (any, error){
+		ServiceBacktests:    func(ctx context.Context, _ any) (any, error) { return c.backtests.Info(ctx) },
+		ServiceCandlesticks: func(ctx context.Context, _ any) (any, error) { return c.candlesticks.Info(ctx) },
+		ServiceExchanges:    func(ctx context.Context, _ any) (any, error) { return c.exchanges.Info(ctx) },
+		ServiceForwardtests: func(ctx context.Context, _ any) (any, error) { return c.forwardtests.Info(ctx) },
+		ServiceSMA:          func(ctx context.Context, _ any) (any, error) { return c.sma.Info(ctx) },
+		ServiceTicks:        func(ctx context.Context, _ any) (any, error) { return c.ticks.Info(ctx) },
+	}
+
+	callback, ok := callbacks[service]
+	return callback, ok
+}
+
 // GetTemporalClient returns the internal temporal client.
 func (c *client) GetTemporalClient() temporalclient.Client {
 	return c.temporal.client
@@ -214,6 +461,11 @@ func (c *client) GetTemporalClient() temporalclient.Client {
 // Close closes the temporal client if it was created in this package.
 // If the client was provided externally, it is the caller's responsibility to close it.
 func (c *client) Close() {
+	// Close the wrapper of the provided temporal client
+	if c.temporal.wrapped {
+		c.temporal.services.Close()
+	}
+
 	// Close the temporal client if it was created in this package
 	if c.temporal.client != nil && c.temporal.addr != "" {
 		c.temporal.client.Close()
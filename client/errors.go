@@ -0,0 +1,277 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cryptellation/backtests/pkg/backtest"
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
+	"github.com/cryptellation/candlesticks/pkg/pair"
+	"github.com/cryptellation/candlesticks/pkg/period"
+	"github.com/cryptellation/forwardtests/pkg/forwardtest"
+	"github.com/cryptellation/runtime"
+	"github.com/cryptellation/runtime/account"
+	"github.com/cryptellation/runtime/order"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/temporal"
+)
+
+var (
+	// ErrNotFound is returned when the requested resource does not exist
+	// (backtest, forwardtest, exchange, etc.).
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidParams is returned when the params of a call are rejected by the service.
+	ErrInvalidParams = errors.New("invalid params")
+	// ErrAlreadyExists is returned when the resource to create already exists.
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrUnavailable is returned when the service can not be reached.
+	ErrUnavailable = errors.New("service unavailable")
+	// ErrTimeout is returned when the call did not complete in time.
+	ErrTimeout = errors.New("timeout")
+	// ErrCanceled is returned when the call has been canceled.
+	ErrCanceled = errors.New("canceled")
+	// ErrPermissionDenied is returned when the call is not allowed.
+	ErrPermissionDenied = errors.New("permission denied")
+	// ErrNotImplemented is returned when the feature is not implemented by the service.
+	ErrNotImplemented = errors.New("not implemented")
+)
+
+// Types of the Temporal application errors returned by the services, used
+// to classify them. Error codes (gRPC-like names such as "NOT_FOUND" or
+// "INVALID_ARGUMENT") are accepted as well, in any case.
+const (
+	ErrorTypeNotFound         = "NotFound"
+	ErrorTypeInvalidParams    = "InvalidParams"
+	ErrorTypeAlreadyExists    = "AlreadyExists"
+	ErrorTypeUnavailable      = "Unavailable"
+	ErrorTypeTimeout          = "Timeout"
+	ErrorTypeCanceled         = "Canceled"
+	ErrorTypePermissionDenied = "PermissionDenied"
+	ErrorTypeNotImplemented   = "NotImplemented"
+)
+
+// applicationErrorKinds are the kinds of the application errors, by their
+// normalized type (see normalizeErrorType).
+var applicationErrorKinds = map[string]error{
+	"notfound":           ErrNotFound,
+	"invalidparams":      ErrInvalidParams,
+	"invalidargument":    ErrInvalidParams,
+	"failedprecondition": ErrInvalidParams,
+	"outofrange":         ErrInvalidParams,
+	"alreadyexists":      ErrAlreadyExists,
+	"unavailable":        ErrUnavailable,
+	"resourceexhausted":  ErrUnavailable,
+	"timeout":            ErrTimeout,
+	"deadlineexceeded":   ErrTimeout,
+	"canceled":           ErrCanceled,
+	"cancelled":          ErrCanceled,
+	"permissiondenied":   ErrPermissionDenied,
+	"unauthenticated":    ErrPermissionDenied,
+	"notimplemented":     ErrNotImplemented,
+	"unimplemented":      ErrNotImplemented,
+}
+
+// notFoundMessages are the messages of the services errors meaning that a
+// resource does not exist.
+var notFoundMessages = []string{
+	"not found",
+	"not-found",
+	"inexistant exchange",
+}
+
+// invalidParamsMessages are the messages of the services errors meaning that
+// the params are invalid.
+var invalidParamsMessages = []string{
+	period.ErrInvalidPeriod.Error(),
+	pair.ErrInvalidPair.Error(),
+	candlestick.ErrPeriodMismatch.Error(),
+	candlestick.ErrExchangeMismatch.Error(),
+	candlestick.ErrPairMismatch.Error(),
+	candlestick.ErrInvalidPriceType.Error(),
+	backtest.ErrTickSubscriptionAlreadyExists.Error(),
+	backtest.ErrInvalidExchange.Error(),
+	backtest.ErrNoDataForOrderValidation.Error(),
+	backtest.ErrStartAfterEnd.Error(),
+	backtest.ErrInvalidPricePeriod.Error(),
+	forwardtest.ErrEmptyAccounts.Error(),
+	forwardtest.ErrInvalidStatus.Error(),
+	runtime.ErrInvalidMode.Error(),
+	runtime.ErrEmptyWorkflowName.Error(),
+	runtime.ErrEmptyTaskQueueName.Error(),
+	account.ErrInvalidBalanceAmount.Error(),
+	account.ErrInvalidBalanceAsset.Error(),
+	account.ErrNotEnoughAsset.Error(),
+	order.ErrInvalidSide.Error(),
+	order.ErrInvalidType.Error(),
+	order.ErrInvalidOrderQty.Error(),
+	" is required",
+	" must be provided",
+	" must be greater than ",
+	" must be after ",
+}
+
+// Error is an error returned by the Cryptellation stack, classified by kind.
+// It can be checked with errors.Is against its kind (ErrNotFound, ErrTimeout, etc.)
+// and with errors.As against the original Temporal error.
+type Error struct {
+	// Kind is the kind of the error, as one of the Err* sentinel errors.
+	Kind error
+	// Message is the message of the original error, without the Temporal context.
+	Message string
+	// Err is the original error.
+	Err error
+}
+
+// Error returns the error message.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Kind, e.Message)
+}
+
+// Unwrap returns the kind and the original error.
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// MapError maps an error returned by Temporal (workflow execution error,
+// application error, service error, etc.) to an *Error of the corresponding
+// kind. Errors that can not be classified are returned unchanged.
+func MapError(err error) error {
+	var (
+		e  *Error
+		te *TimeoutError
+	)
+	if err == nil || errors.As(err, &e) || errors.As(err, &te) {
+		return err
+	}
+
+	kind := errorKind(err)
+	if kind == nil {
+		return err
+	}
+
+	return &Error{
+		Kind:    kind,
+		Message: rootMessage(err),
+		Err:     err,
+	}
+}
+
+func errorKind(err error) error {
+	if kind := serviceErrorKind(err); kind != nil {
+		return kind
+	}
+
+	var (
+		timeout  *temporal.TimeoutError
+		canceled *temporal.CanceledError
+	)
+	switch {
+	case errors.As(err, &timeout), errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout
+	case errors.As(err, &canceled), errors.Is(err, context.Canceled):
+		return ErrCanceled
+	}
+
+	if kind := applicationErrorKind(err); kind != nil {
+		return kind
+	}
+
+	// Last resort: classify the untyped errors returned by the services from
+	// their messages, as Temporal only keeps the Go type name of the errors
+	// that are not application errors. This is only a best effort, as any
+	// wording change in the services breaks it.
+	msg := err.Error()
+	switch {
+	case containsAny(msg, notFoundMessages):
+		return ErrNotFound
+	case containsAny(msg, invalidParamsMessages):
+		return ErrInvalidParams
+	case strings.Contains(msg, ErrNotImplemented.Error()):
+		return ErrNotImplemented
+	default:
+		return nil
+	}
+}
+
+func serviceErrorKind(err error) error {
+	var (
+		notFound      *serviceerror.NotFound
+		invalid       *serviceerror.InvalidArgument
+		started       *serviceerror.WorkflowExecutionAlreadyStarted
+		exists        *serviceerror.AlreadyExists
+		unavailable   *serviceerror.Unavailable
+		exhausted     *serviceerror.ResourceExhausted
+		deadline      *serviceerror.DeadlineExceeded
+		canceled      *serviceerror.Canceled
+		permission    *serviceerror.PermissionDenied
+		unimplemented *serviceerror.Unimplemented
+	)
+
+	switch {
+	case errors.As(err, &notFound):
+		return ErrNotFound
+	case errors.As(err, &invalid):
+		return ErrInvalidParams
+	case errors.As(err, &started), errors.As(err, &exists):
+		return ErrAlreadyExists
+	case errors.As(err, &unavailable), errors.As(err, &exhausted):
+		return ErrUnavailable
+	case errors.As(err, &deadline):
+		return ErrTimeout
+	case errors.As(err, &canceled):
+		return ErrCanceled
+	case errors.As(err, &permission):
+		return ErrPermissionDenied
+	case errors.As(err, &unimplemented):
+		return ErrNotImplemented
+	default:
+		return nil
+	}
+}
+
+// applicationErrorKind returns the kind of the first application error of the
+// chain with a known type, if any.
+func applicationErrorKind(err error) error {
+	var app *temporal.ApplicationError
+	for errors.As(err, &app) {
+		if kind, ok := applicationErrorKinds[normalizeErrorType(app.Type())]; ok {
+			return kind
+		}
+		err = app.Unwrap()
+	}
+	return nil
+}
+
+// normalizeErrorType returns the error type in lower case, without
+// separators, so "NOT_FOUND", "not-found" and "NotFound" are the same.
+func normalizeErrorType(t string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "", " ", "").Replace(t))
+}
+
+// rootMessage returns the message of the deepest error of the chain, which is
+// the one created by the service.
+func rootMessage(err error) string {
+	msg := err.Error()
+	var app *temporal.ApplicationError
+	if errors.As(err, &app) {
+		msg = app.Message()
+	}
+
+	for err = errors.Unwrap(err); err != nil; err = errors.Unwrap(err) {
+		if errors.As(err, &app) {
+			msg = app.Message()
+		}
+	}
+	return msg
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cryptellation/backtests/pkg/backtest"
+	"github.com/stretchr/testify/require"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestMapError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		kind    error
+		message string
+	}{
+		{name: "nil", err: nil},
+		{name: "unknown", err: errors.New("unknown")},
+		{name: "service not found", err: serviceerror.NewNotFound("no workflow"), kind: ErrNotFound},
+		{name: "service invalid", err: serviceerror.NewInvalidArgument("bad"), kind: ErrInvalidParams},
+		{
+			name: "service already started",
+			err:  serviceerror.NewWorkflowExecutionAlreadyStarted("started", "", ""),
+			kind: ErrAlreadyExists,
+		},
+		{name: "service unavailable", err: serviceerror.NewUnavailable("down"), kind: ErrUnavailable},
+		{name: "service exhausted", err: serviceerror.NewResourceExhausted(0, "busy"), kind: ErrUnavailable},
+		{name: "service permission", err: serviceerror.NewPermissionDenied("no", ""), kind: ErrPermissionDenied},
+		{name: "service unimplemented", err: serviceerror.NewUnimplemented("no"), kind: ErrNotImplemented},
+		{
+			name: "workflow timeout",
+			err:  temporal.NewTimeoutError(enumspb.TIMEOUT_TYPE_START_TO_CLOSE, nil),
+			kind: ErrTimeout,
+		},
+		{name: "context deadline", err: context.DeadlineExceeded, kind: ErrTimeout},
+		{name: "workflow canceled", err: temporal.NewCanceledError(), kind: ErrCanceled},
+		{name: "context canceled", err: fmt.Errorf("calling: %w", context.Canceled), kind: ErrCanceled},
+		{
+			name:    "application type",
+			err:     temporal.NewApplicationError("backtest 42", ErrorTypeNotFound),
+			kind:    ErrNotFound,
+			message: "backtest 42",
+		},
+		{
+			name:    "application code",
+			err:     temporal.NewApplicationError("bad period", "INVALID_ARGUMENT"),
+			kind:    ErrInvalidParams,
+			message: "bad period",
+		},
+		{
+			name:    "wrapped application type",
+			err:     fmt.Errorf("executing: %w", temporal.NewApplicationError("exists", ErrorTypeAlreadyExists)),
+			kind:    ErrAlreadyExists,
+			message: "exists",
+		},
+		{
+			name: "type over message",
+			err:  temporal.NewApplicationError("exchange not found", ErrorTypePermissionDenied),
+			kind: ErrPermissionDenied,
+		},
+		{
+			name: "nested application type",
+			err: temporal.NewApplicationErrorWithCause("calling service", "*errors.errorString",
+				temporal.NewApplicationError("no exchange", ErrorTypeNotFound)),
+			kind:    ErrNotFound,
+			message: "no exchange",
+		},
+		{
+			name:    "untyped not found message",
+			err:     temporal.NewApplicationError("backtest not found", "*errors.errorString"),
+			kind:    ErrNotFound,
+			message: "backtest not found",
+		},
+		{
+			name: "untyped invalid params message",
+			err:  temporal.NewApplicationError(backtest.ErrStartAfterEnd.Error(), "*fmt.wrapError"),
+			kind: ErrInvalidParams,
+		},
+		{
+			name: "untyped unknown message",
+			err:  temporal.NewApplicationError("boom", "*errors.errorString"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := MapError(tt.err)
+			if tt.kind == nil {
+				require.Equal(t, tt.err, err)
+				return
+			}
+
+			var e *Error
+			require.ErrorAs(t, err, &e)
+			require.Equal(t, tt.kind, e.Kind)
+			require.ErrorIs(t, err, tt.kind)
+			require.ErrorIs(t, err, tt.err)
+			if tt.message != "" {
+				require.Equal(t, tt.message, e.Message)
+			}
+
+			// Mapping twice does not change the error
+			require.Equal(t, err, MapError(err))
+		})
+	}
+}
@@ -11,7 +11,7 @@ func (c client) GetExchange(
 	ctx context.Context,
 	params exchangesapi.GetExchangeWorkflowParams,
 ) (exchangesapi.GetExchangeWorkflowResults, error) {
-	return c.exchanges.GetExchange(ctx, params)
+	return call(ctx, c, ServiceExchanges, MethodGetExchange, params, c.exchanges.GetExchange)
 }
 
 // ListExchanges retrieves a list of exchanges.
@@ -19,5 +19,5 @@ func (c client) ListExchanges(
 	ctx context.Context,
 	params exchangesapi.ListExchangesWorkflowParams,
 ) (exchangesapi.ListExchangesWorkflowResults, error) {
-	return c.exchanges.ListExchanges(ctx, params)
+	return call(ctx, c, ServiceExchanges, MethodListExchanges, params, c.exchanges.ListExchanges)
 }
@@ -2,17 +2,86 @@ package client
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/cryptellation/forwardtests/api"
 	"github.com/cryptellation/forwardtests/pkg/clients"
+	temporalclient "go.temporal.io/sdk/client"
 )
 
+// Forwardtest is the handle of a forwardtest, with the metadata attached on
+// its creation (see WithMetadata).
+type Forwardtest struct {
+	clients.Forwardtest
+	Metadata Metadata
+}
+
 // NewForwardtest creates a new forwardtest.
+// If the context has an idempotency key (see WithIdempotencyKey), the forwardtest
+// created by a previous call with the same key is returned. If it has
+// metadata (see WithMetadata), they are saved in the metadata store once the
+// forwardtest is created: if this fails, the forwardtest is returned with a
+// *MetadataError.
 func (c client) NewForwardtest(
 	ctx context.Context,
 	params api.CreateForwardtestWorkflowParams,
 ) (clients.Forwardtest, error) {
-	return c.forwardtests.NewForwardtest(ctx, params)
+	ft, err := call(ctx, c, ServiceForwardtests, MethodNewForwardtest, params,
+		func(ctx context.Context, p api.CreateForwardtestWorkflowParams) (clients.Forwardtest, error) {
+			if _, ok := IdempotencyKeyFromContext(ctx); !ok {
+				return c.forwardtests.NewForwardtest(ctx, p)
+			}
+
+			run, err := c.startWorkflow(ctx, api.WorkerTaskQueueName, api.CreateForwardtestWorkflowName, p)
+			if err != nil {
+				return clients.Forwardtest{}, err
+			}
+			return c.forwardtestHandle(ctx, run)
+		})
+	if err != nil {
+		return ft, err
+	}
+
+	return ft, c.saveMetadata(ctx, RunKindForwardtest, ft.ID)
+}
+
+// GetForwardtest gets a forwardtest.
+func (c client) GetForwardtest(
+	ctx context.Context,
+	params api.GetForwardtestWorkflowParams,
+) (clients.Forwardtest, error) {
+	return call(ctx, c, ServiceForwardtests, MethodGetForwardtest, params,
+		func(ctx context.Context, p api.GetForwardtestWorkflowParams) (clients.Forwardtest, error) {
+			// The forwardtests client only builds handles from the list or
+			// creation workflows, so look for the forwardtest in the list
+			list, err := c.forwardtests.ListForwardtests(ctx, api.ListForwardtestsWorkflowParams{})
+			if err != nil {
+				return clients.Forwardtest{}, err
+			}
+			for _, ft := range list {
+				if ft.ID == p.ForwardtestID {
+					return ft, nil
+				}
+			}
+			return clients.Forwardtest{}, fmt.Errorf("%w: forwardtest %s", ErrNotFound, p.ForwardtestID)
+		})
+}
+
+// GetForwardtestWithMetadata gets a forwardtest, with its metadata.
+func (c client) GetForwardtestWithMetadata(
+	ctx context.Context,
+	params api.GetForwardtestWorkflowParams,
+) (Forwardtest, error) {
+	ft, err := c.GetForwardtest(ctx, params)
+	if err != nil {
+		return Forwardtest{}, err
+	}
+
+	md, err := c.GetMetadata(ctx, RunKindForwardtest, ft.ID)
+	if err != nil {
+		return Forwardtest{}, err
+	}
+	return Forwardtest{Forwardtest: ft, Metadata: md}, nil
 }
 
 // ListForwardtests lists the forwardtests.
@@ -20,5 +89,57 @@ func (c client) ListForwardtests(
 	ctx context.Context,
 	params api.ListForwardtestsWorkflowParams,
 ) ([]clients.Forwardtest, error) {
-	return c.forwardtests.ListForwardtests(ctx, params)
+	return call(ctx, c, ServiceForwardtests, MethodListForwardtests, params, c.forwardtests.ListForwardtests)
+}
+
+// ListForwardtestsWithMetadata lists the forwardtests, with their metadata.
+func (c client) ListForwardtestsWithMetadata(
+	ctx context.Context,
+	params api.ListForwardtestsWorkflowParams,
+) ([]Forwardtest, error) {
+	list, err := c.ListForwardtests(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := c.ListMetadata(ctx, RunKindForwardtest, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	forwardtests := make([]Forwardtest, len(list))
+	for i, ft := range list {
+		forwardtests[i] = Forwardtest{Forwardtest: ft, Metadata: metadata[ft.ID]}
+	}
+	return forwardtests, nil
+}
+
+// forwardtestHandle returns the handle of the forwardtest created by the
+// creation workflow run. The forwardtests client only builds handles from the
+// creation workflow results, so it is given this run instead of starting a
+// new creation workflow.
+func (c client) forwardtestHandle(ctx context.Context, run temporalclient.WorkflowRun) (clients.Forwardtest, error) {
+	return clients.New(createdForwardtestClient{Client: c.temporal.services, run: run}).
+		NewForwardtest(ctx, api.CreateForwardtestWorkflowParams{})
+}
+
+// createdForwardtestClient is a temporal client returning an already started
+// run for the forwardtest creation workflow, and executing the others.
+type createdForwardtestClient struct {
+	temporalclient.Client
+	run temporalclient.WorkflowRun
+}
+
+// ExecuteWorkflow returns the run for the creation workflow, and executes
+// the other workflows with the wrapped client.
+func (c createdForwardtestClient) ExecuteWorkflow(
+	ctx context.Context,
+	options temporalclient.StartWorkflowOptions,
+	workflow any,
+	args ...any,
+) (temporalclient.WorkflowRun, error) {
+	if workflow != api.CreateForwardtestWorkflowName {
+		return c.Client.ExecuteWorkflow(ctx, options, workflow, args...)
+	}
+	return c.run, nil
 }
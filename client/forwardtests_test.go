@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cryptellation/forwardtests/api"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	temporalclient "go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/mocks"
+)
+
+func TestForwardtestHandle(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name        string
+		err         error
+		expectedErr error
+	}{
+		{name: "created"},
+		{name: "creation error", err: ErrInvalidParams, expectedErr: ErrInvalidParams},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := &mocks.WorkflowRun{}
+			run.On("Get", mock.Anything, mock.Anything).Return(tt.err).Run(func(args mock.Arguments) {
+				args.Get(1).(*api.CreateForwardtestWorkflowResults).ID = id
+			})
+
+			c := client{}
+			c.temporal.services = &mocks.Client{}
+			ft, err := c.forwardtestHandle(context.Background(), run)
+			require.ErrorIs(t, err, tt.expectedErr)
+			require.Equal(t, id, ft.ID)
+			run.AssertExpectations(t)
+		})
+	}
+}
+
+func TestForwardtestHandleCalls(t *testing.T) {
+	id := uuid.New()
+	run := &mocks.WorkflowRun{}
+	run.On("Get", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*api.CreateForwardtestWorkflowResults).ID = id
+	})
+
+	// The handle calls the service with the real client
+	stopRun := &mocks.WorkflowRun{}
+	stopRun.On("Get", mock.Anything, mock.Anything).Return(nil)
+	temporal := &mocks.Client{}
+	temporal.On("ExecuteWorkflow", mock.Anything, mock.Anything, api.StopForwardtestWorkflowName,
+		api.StopForwardtestWorkflowParams{ForwardtestID: id}).
+		Return(temporalclient.WorkflowRun(stopRun), nil)
+
+	c := client{}
+	c.temporal.services = temporal
+	ft, err := c.forwardtestHandle(context.Background(), run)
+	require.NoError(t, err)
+	require.NoError(t, ft.Stop(context.Background()))
+	temporal.AssertExpectations(t)
+	stopRun.AssertExpectations(t)
+}
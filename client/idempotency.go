@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	enums "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	temporalclient "go.temporal.io/sdk/client"
+)
+
+var (
+	// ErrIdempotencyConflict is returned when an idempotency key is reused
+	// with different params.
+	ErrIdempotencyConflict = fmt.Errorf("%w: idempotency key used with different params", ErrAlreadyExists)
+)
+
+// idempotencyHashMemo is the memo field storing the hash of the params of a
+// workflow started with an idempotency key.
+const idempotencyHashMemo = "IdempotencyParamsHash"
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey returns a context whose creation calls (NewBacktest,
+// NewForwardtest and their async variants) are idempotent: calls with the same
+// key start the same workflow, so a retried call returns the backtest or
+// forwardtest created by the first one instead of a duplicate.
+//
+// A key can be used again if the previous creation failed. Keys are kept as
+// long as the workflows are retained by the Temporal namespace.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// IdempotencyKeyFromContext returns the idempotency key of the context, if any.
+func IdempotencyKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKey{}).(string)
+	return key, ok && key != ""
+}
+
+// IdempotentWorkflowID returns the ID of the workflow started with an idempotency key.
+func IdempotentWorkflowID(workflowName, key string) string {
+	return fmt.Sprintf("%s-idempotency-%s", workflowName, key)
+}
+
+func paramsHash(args ...any) (string, error) {
+	b, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// startIdempotentWorkflow starts a workflow with a deterministic ID computed
+// from the idempotency key, or returns the existing one if it was already
+// started with the same params.
+func (c client) startIdempotentWorkflow(
+	ctx context.Context,
+	opts temporalclient.StartWorkflowOptions,
+	key, workflowName string,
+	args ...any,
+) (temporalclient.WorkflowRun, error) {
+	hash, err := paramsHash(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start the workflow, unless it already exists and did not fail
+	opts.ID = IdempotentWorkflowID(workflowName, key)
+	opts.WorkflowIDReusePolicy = enums.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE_FAILED_ONLY
+	opts.WorkflowExecutionErrorWhenAlreadyStarted = true
+	opts.Memo = map[string]any{idempotencyHashMemo: hash}
+	run, err := c.temporal.services.ExecuteWorkflow(ctx, opts, workflowName, args...)
+	var started *serviceerror.WorkflowExecutionAlreadyStarted
+	if !errors.As(err, &started) {
+		return run, err
+	}
+
+	// Check that the existing workflow has been started with the same params
+	desc, err := c.temporal.services.DescribeWorkflowExecution(ctx, opts.ID, started.RunId)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing string
+	payload := desc.GetWorkflowExecutionInfo().GetMemo().GetFields()[idempotencyHashMemo]
+	if err := c.temporal.dataConverter.FromPayload(payload, &existing); err != nil || existing != hash {
+		return nil, fmt.Errorf("%w: key %q", ErrIdempotencyConflict, key)
+	}
+
+	return c.temporal.services.GetWorkflow(ctx, opts.ID, started.RunId), nil
+}
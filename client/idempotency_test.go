@@ -0,0 +1,141 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	enums "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	temporalclient "go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/mocks"
+)
+
+func TestIdempotencyKeyFromContext(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		expected string
+		ok       bool
+	}{
+		{name: "none", ctx: context.Background()},
+		{name: "empty", ctx: WithIdempotencyKey(context.Background(), "")},
+		{name: "key", ctx: WithIdempotencyKey(context.Background(), "key"), expected: "key", ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, ok := IdempotencyKeyFromContext(tt.ctx)
+			require.Equal(t, tt.expected, key)
+			require.Equal(t, tt.ok, ok)
+		})
+	}
+}
+
+func TestParamsHash(t *testing.T) {
+	h1, err := paramsHash("a", 1)
+	require.NoError(t, err)
+	h2, err := paramsHash("a", 1)
+	require.NoError(t, err)
+	h3, err := paramsHash("a", 2)
+	require.NoError(t, err)
+
+	require.Equal(t, h1, h2)
+	require.NotEqual(t, h1, h3)
+
+	_, err = paramsHash(func() {})
+	require.Error(t, err)
+}
+
+// describeWithHash returns the description of a workflow started with the
+// hash of the params in its memo.
+func describeWithHash(t *testing.T, hash string) *workflowservice.DescribeWorkflowExecutionResponse {
+	payload, err := converter.GetDefaultDataConverter().ToPayload(hash)
+	require.NoError(t, err)
+	return &workflowservice.DescribeWorkflowExecutionResponse{
+		WorkflowExecutionInfo: &workflowpb.WorkflowExecutionInfo{
+			Memo: &commonpb.Memo{Fields: map[string]*commonpb.Payload{idempotencyHashMemo: payload}},
+		},
+	}
+}
+
+func TestStartIdempotentWorkflow(t *testing.T) {
+	const (
+		workflow = "Workflow"
+		params   = "params"
+	)
+	hash, err := paramsHash(params)
+	require.NoError(t, err)
+	id := IdempotentWorkflowID(workflow, "key")
+	started := serviceerror.NewWorkflowExecutionAlreadyStarted("started", "", "run-id")
+	errDescribe := errors.New("describe error")
+
+	tests := []struct {
+		name        string
+		executeErr  error
+		describe    *workflowservice.DescribeWorkflowExecutionResponse
+		describeErr error
+		existing    bool
+		expectedErr error
+	}{
+		{name: "new workflow"},
+		{name: "start error", executeErr: errUnavailable, expectedErr: errUnavailable},
+		{name: "existing workflow", executeErr: started, describe: describeWithHash(t, hash), existing: true},
+		{
+			name:        "different params",
+			executeErr:  started,
+			describe:    describeWithHash(t, "other"),
+			expectedErr: ErrIdempotencyConflict,
+		},
+		{
+			name:        "no hash",
+			executeErr:  started,
+			describe:    &workflowservice.DescribeWorkflowExecutionResponse{},
+			expectedErr: ErrIdempotencyConflict,
+		},
+		{name: "describe error", executeErr: started, describeErr: errDescribe, expectedErr: errDescribe},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newRun, existingRun := &mocks.WorkflowRun{}, &mocks.WorkflowRun{}
+			temporal := &mocks.Client{}
+			temporal.On("ExecuteWorkflow", mock.Anything, mock.MatchedBy(func(o temporalclient.StartWorkflowOptions) bool {
+				return o.ID == id &&
+					o.WorkflowIDReusePolicy == enums.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE_FAILED_ONLY &&
+					o.WorkflowExecutionErrorWhenAlreadyStarted &&
+					o.Memo[idempotencyHashMemo] == hash
+			}), workflow, params).Return(temporalclient.WorkflowRun(newRun), tt.executeErr)
+			if tt.describe != nil || tt.describeErr != nil {
+				temporal.On("DescribeWorkflowExecution", mock.Anything, id, "run-id").Return(tt.describe, tt.describeErr)
+			}
+			if tt.existing {
+				temporal.On("GetWorkflow", mock.Anything, id, "run-id").Return(temporalclient.WorkflowRun(existingRun))
+			}
+
+			c := client{}
+			c.temporal.services = temporal
+			c.temporal.dataConverter = converter.GetDefaultDataConverter()
+			run, err := c.startIdempotentWorkflow(context.Background(),
+				temporalclient.StartWorkflowOptions{TaskQueue: "queue"}, "key", workflow, params)
+			temporal.AssertExpectations(t)
+
+			switch {
+			case tt.expectedErr != nil:
+				require.ErrorIs(t, err, tt.expectedErr)
+			case tt.existing:
+				require.NoError(t, err)
+				require.Same(t, existingRun, run)
+			default:
+				require.NoError(t, err)
+				require.Same(t, newRun, run)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cryptellation/backtests/pkg/backtest"
+	"github.com/cryptellation/runtime"
+	ticksclient "github.com/cryptellation/ticks/pkg/clients"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidCallType is returned when an interceptor passes params or
+	// returns results that do not have the type expected by the method.
+	ErrInvalidCallType = errors.New("invalid call params or results type")
+)
+
+// CallInfo describes a call made through the client.
+type CallInfo struct {
+	// Service is the service called.
+	Service Service
+	// Method is the name of the client method called (see Method* constants).
+	Method string
+}
+
+// Invoker executes a call with the given params and returns its results.
+type Invoker func(ctx context.Context, params any) (any, error)
+
+// Interceptor intercepts every call made through the client. It can inspect
+// or rewrite the params, call the invoker (or not, e.g. for caching) and
+// inspect or rewrite the results.
+//
+// Params and results have the types of the corresponding client method. The
+// methods with several arguments use a dedicated params type (NewBacktestParams,
+// ListenToTicksParams and StopListeningToTicksParams), the methods without
+// results have nil results and the Info calls have nil params.
+type Interceptor func(ctx context.Context, info CallInfo, params any, invoker Invoker) (any, error)
+
+// NewBacktestParams are the params seen by the interceptors on NewBacktest calls.
+type NewBacktestParams struct {
+	Parameters backtest.Parameters
+	Callbacks  runtime.Callbacks
+}
+
+// ListenToTicksParams are the params seen by the interceptors on ListenToTicks calls.
+type ListenToTicksParams struct {
+	Listener ticksclient.ListenerParams
+	Exchange string
+	Pair     string
+}
+
+// StopListeningToTicksParams are the params seen by the interceptors on StopListeningToTicks calls.
+type StopListeningToTicksParams struct {
+	Listener uuid.UUID
+	Exchange string
+	Pair     string
+}
+
+// ChainInterceptors chains the interceptors into one: the first one is the
+// outermost, the last one is the closest to the actual call.
+func ChainInterceptors(interceptors ...Interceptor) Interceptor {
+	return func(ctx context.Context, info CallInfo, params any, invoker Invoker) (any, error) {
+		return chainFrom(interceptors, info, invoker)(ctx, params)
+	}
+}
+
+func chainFrom(interceptors []Interceptor, info CallInfo, invoker Invoker) Invoker {
+	if len(interceptors) == 0 {
+		return invoker
+	}
+
+	return func(ctx context.Context, params any) (any, error) {
+		return interceptors[0](ctx, info, params, chainFrom(interceptors[1:], info, invoker))
+	}
+}
+
+// WithInterceptors adds interceptors on every call of the client. They are
+// executed in the given order, before the retry policies, circuit breakers
+// and rate limits.
+func WithInterceptors(interceptors ...Interceptor) func(*client) {
+	return func(c *client) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+// call executes a call of the client through the interceptors.
+func call[P, R any](
+	ctx context.Context,
+	c client,
+	service Service,
+	method string,
+	params P,
+	fn func(context.Context, P) (R, error),
+) (R, error) {
+	invoker := func(ctx context.Context, params any) (any, error) {
+		p, ok := params.(P)
+		if !ok && params != nil {
+			return nil, fmt.Errorf("%w: %s params are %T", ErrInvalidCallType, method, params)
+		}
+
+		res, err := fn(ctx, p)
+		return res, MapError(err)
+	}
+
+	var res R
+	results, err := c.interceptor(ctx, CallInfo{Service: service, Method: method}, params, invoker)
+	if results == nil {
+		return res, err
+	}
+
+	res, ok := results.(R)
+	if !ok && err == nil {
+		err = fmt.Errorf("%w: %s results are %T", ErrInvalidCallType, method, results)
+	}
+	return res, err
+}
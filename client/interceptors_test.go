@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordingInterceptor returns an interceptor recording its calls in the events.
+func recordingInterceptor(name string, events *[]string) Interceptor {
+	return func(ctx context.Context, info CallInfo, params any, invoker Invoker) (any, error) {
+		*events = append(*events, name+" before "+info.Method)
+		res, err := invoker(ctx, params)
+		*events = append(*events, name+" after "+info.Method)
+		return res, err
+	}
+}
+
+func TestChainInterceptors(t *testing.T) {
+	tests := []struct {
+		name     string
+		chain    []string
+		expected []string
+	}{
+		{
+			name:     "empty",
+			expected: []string{"invoke"},
+		},
+		{
+			name:     "one",
+			chain:    []string{"a"},
+			expected: []string{"a before m", "invoke", "a after m"},
+		},
+		{
+			name:     "first is outermost",
+			chain:    []string{"a", "b", "c"},
+			expected: []string{"a before m", "b before m", "c before m", "invoke", "c after m", "b after m", "a after m"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []string
+			interceptors := make([]Interceptor, len(tt.chain))
+			for i, name := range tt.chain {
+				interceptors[i] = recordingInterceptor(name, &events)
+			}
+
+			res, err := ChainInterceptors(interceptors...)(context.Background(), CallInfo{Method: "m"}, 1,
+				func(_ context.Context, params any) (any, error) {
+					events = append(events, "invoke")
+					return params.(int) + 1, nil
+				})
+			require.NoError(t, err)
+			require.Equal(t, 2, res)
+			require.Equal(t, tt.expected, events)
+		})
+	}
+}
+
+func TestCall(t *testing.T) {
+	errCall := errors.New("call error")
+
+	tests := []struct {
+		name        string
+		interceptor Interceptor
+		expected    int
+		expectedErr error
+		called      bool
+	}{
+		{
+			name: "pass through",
+			interceptor: func(ctx context.Context, _ CallInfo, params any, invoker Invoker) (any, error) {
+				return invoker(ctx, params)
+			},
+			expected: 2,
+			called:   true,
+		},
+		{
+			name: "rewritten params",
+			interceptor: func(ctx context.Context, _ CallInfo, params any, invoker Invoker) (any, error) {
+				return invoker(ctx, params.(int)*10)
+			},
+			expected: 11,
+			called:   true,
+		},
+		{
+			name: "rewritten results",
+			interceptor: func(ctx context.Context, _ CallInfo, params any, invoker Invoker) (any, error) {
+				res, err := invoker(ctx, params)
+				return res.(int) * 10, err
+			},
+			expected: 20,
+			called:   true,
+		},
+		{
+			name: "short-circuited",
+			interceptor: func(context.Context, CallInfo, any, Invoker) (any, error) {
+				return 42, nil
+			},
+			expected: 42,
+		},
+		{
+			name: "short-circuited error",
+			interceptor: func(context.Context, CallInfo, any, Invoker) (any, error) {
+				return nil, errCall
+			},
+			expectedErr: errCall,
+		},
+		{
+			name: "invalid params",
+			interceptor: func(ctx context.Context, _ CallInfo, _ any, invoker Invoker) (any, error) {
+				return invoker(ctx, "1")
+			},
+			expectedErr: ErrInvalidCallType,
+		},
+		{
+			name: "invalid results",
+			interceptor: func(context.Context, CallInfo, any, Invoker) (any, error) {
+				return "42", nil
+			},
+			expectedErr: ErrInvalidCallType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var called bool
+			c := client{interceptor: tt.interceptor}
+			res, err := call(context.Background(), c, ServiceBacktests, MethodGetBacktest, 1,
+				func(_ context.Context, p int) (int, error) {
+					called = true
+					return p + 1, nil
+				})
+			require.ErrorIs(t, err, tt.expectedErr)
+			require.Equal(t, tt.expected, res)
+			require.Equal(t, tt.called, called)
+		})
+	}
+}
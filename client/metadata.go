@@ -0,0 +1,473 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	commonpb "go.temporal.io/api/common/v1"
+	enums "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/api/workflowservice/v1"
+	temporalclient "go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
+)
+
+// Metadata are labels attached to a backtest or a forwardtest on its creation,
+// like the strategy name, the git commit, the author or the experiment ID.
+type Metadata map[string]string
+
+// Common metadata keys.
+const (
+	MetadataStrategy   = "strategy"
+	MetadataCommit     = "commit"
+	MetadataAuthor     = "author"
+	MetadataExperiment = "experiment"
+)
+
+const (
+	// metadataWorkflowPrefix is the prefix of the names and IDs of the record
+	// workflows of the TemporalMetadataStore.
+	metadataWorkflowPrefix = "CryptellationMetadata"
+	// recordTaskQueue is the task queue of the record workflows, keeping data
+	// in their memo. No worker listens on it, so they are never executed.
+	recordTaskQueue = "CryptellationRecordsTaskQueue"
+	// metadataMemo is the memo field holding the metadata of a run.
+	metadataMemo = "metadata"
+)
+
+// RunKind is the kind of run the metadata are attached to.
+type RunKind string
+
+const (
+	// RunKindBacktest is the kind of the backtests.
+	RunKindBacktest RunKind = "backtest"
+	// RunKindForwardtest is the kind of the forwardtests.
+	RunKindForwardtest RunKind = "forwardtest"
+)
+
+// MetadataStore stores the metadata of the runs. The services have no place
+// for them, so they are kept by the client: by default in Temporal (see
+// TemporalMetadataStore), or in the store set with WithMetadataStore.
+type MetadataStore interface {
+	// SaveMetadata saves the metadata of a run, replacing the previous ones.
+	SaveMetadata(ctx context.Context, kind RunKind, id uuid.UUID, md Metadata) error
+	// LoadMetadata loads the metadata of a run, empty if it has none.
+	LoadMetadata(ctx context.Context, kind RunKind, id uuid.UUID) (Metadata, error)
+	// ListMetadata lists the metadata of the runs of the given kind that have
+	// all the values of the filters.
+	ListMetadata(ctx context.Context, kind RunKind, filters Metadata) (map[uuid.UUID]Metadata, error)
+}
+
+// WithMetadataStore sets the store of the runs metadata, instead of the
+// default TemporalMetadataStore.
+func WithMetadataStore(store MetadataStore) func(*client) {
+	return func(c *client) {
+		c.metadata = store
+	}
+}
+
+// WithMetadataSearchAttribute sets the KeywordList search attribute indexing
+// the metadata in the default TemporalMetadataStore. It must be registered on
+// the namespace (e.g. temporal operator search-attribute create --name
+// CryptellationMetadata --type KeywordList).
+func WithMetadataSearchAttribute(name string) func(*client) {
+	return func(c *client) {
+		c.metadataSearchAttribute = name
+	}
+}
+
+// MetadataError is returned by the creation calls (NewBacktest and
+// NewForwardtest) when the run has been created but its metadata could not be
+// saved. The run is returned with the error, so the metadata can be saved
+// again without creating another run: directly in the store, or by retrying
+// the creation with the same idempotency key (see WithIdempotencyKey).
+type MetadataError struct {
+	Kind RunKind
+	ID   uuid.UUID
+	Err  error
+}
+
+// Error returns the error message.
+func (e *MetadataError) Error() string {
+	return fmt.Sprintf("saving metadata of %s %s: %s", e.Kind, e.ID, e.Err)
+}
+
+// Unwrap returns the error of the store.
+func (e *MetadataError) Unwrap() error {
+	return e.Err
+}
+
+type metadataKey struct{}
+
+// WithMetadata returns a context whose creation calls (NewBacktest and
+// NewForwardtest) attach the metadata to the created run.
+func WithMetadata(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+// MetadataFromContext returns the metadata of the context, if any.
+func MetadataFromContext(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(metadataKey{}).(Metadata)
+	return md, ok && len(md) > 0
+}
+
+// saveMetadata saves the metadata of the context, if any, for the created run.
+func (c client) saveMetadata(ctx context.Context, kind RunKind, id uuid.UUID) error {
+	md, ok := MetadataFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	if err := c.metadata.SaveMetadata(ctx, kind, id, md); err != nil {
+		return &MetadataError{Kind: kind, ID: id, Err: err}
+	}
+	return nil
+}
+
+// GetMetadata gets the metadata of a backtest or a forwardtest. Runs created
+// without metadata have none.
+func (c client) GetMetadata(ctx context.Context, kind RunKind, id uuid.UUID) (Metadata, error) {
+	return c.metadata.LoadMetadata(ctx, kind, id)
+}
+
+// ListMetadata lists the metadata of the runs of the given kind that have all
+// the values of the filters.
+func (c client) ListMetadata(ctx context.Context, kind RunKind, filters Metadata) (map[uuid.UUID]Metadata, error) {
+	return c.metadata.ListMetadata(ctx, kind, filters)
+}
+
+// matchMetadata returns true if the metadata have all the filtered values.
+func matchMetadata(md, filters Metadata) bool {
+	for key, value := range filters {
+		if md[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// TemporalMetadataStore is a metadata store keeping the metadata of each run
+// in the memo of a record workflow, whose ID is derived from the run ID. The
+// record workflows are started on a task queue without worker: they are never
+// executed and stay open, so they are kept whatever the namespace retention.
+//
+// With a search attribute, the metadata are also indexed as "key=value"
+// keywords, and the lists are filtered with a visibility query. Without, they
+// are filtered on the memo of the listed record workflows.
+type TemporalMetadataStore struct {
+	temporal        temporalclient.Client
+	dataConverter   converter.DataConverter
+	searchAttribute string
+}
+
+// NewTemporalMetadataStore creates a metadata store on the Temporal client,
+// decoding the memos with the data converter of the client (the default one
+// if nil). The search attribute, if not empty, must be a KeywordList search
+// attribute registered on the namespace.
+func NewTemporalMetadataStore(
+	cl temporalclient.Client,
+	dc converter.DataConverter,
+	searchAttribute string,
+) *TemporalMetadataStore {
+	if dc == nil {
+		dc = converter.GetDefaultDataConverter()
+	}
+
+	return &TemporalMetadataStore{
+		temporal:        cl,
+		dataConverter:   dc,
+		searchAttribute: searchAttribute,
+	}
+}
+
+// metadataWorkflowName returns the name of the record workflows of the kind.
+func metadataWorkflowName(kind RunKind) string {
+	return fmt.Sprintf("%s-%s", metadataWorkflowPrefix, kind)
+}
+
+// metadataWorkflowID returns the ID of the record workflow of the run.
+func metadataWorkflowID(kind RunKind, id uuid.UUID) string {
+	return fmt.Sprintf("%s-%s-%s", metadataWorkflowPrefix, kind, id)
+}
+
+// metadataKeywords returns the metadata as sorted "key=value" keywords.
+func metadataKeywords(md Metadata) []string {
+	keywords := make([]string, 0, len(md))
+	for key, value := range md {
+		keywords = append(keywords, key+"="+value)
+	}
+	sort.Strings(keywords)
+	return keywords
+}
+
+// startRecordWorkflow starts a record workflow holding the memo, replacing
+// the previous one with the same ID. It is never executed and stays open, so
+// it is kept whatever the namespace retention.
+func startRecordWorkflow(
+	ctx context.Context,
+	cl temporalclient.Client,
+	name, id string,
+	memo map[string]any,
+	sa temporal.SearchAttributes,
+) error {
+	_, err := cl.ExecuteWorkflow(ctx, temporalclient.StartWorkflowOptions{
+		ID:                       id,
+		TaskQueue:                recordTaskQueue,
+		WorkflowIDReusePolicy:    enums.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE,
+		WorkflowIDConflictPolicy: enums.WORKFLOW_ID_CONFLICT_POLICY_TERMINATE_EXISTING,
+		Memo:                     memo,
+		TypedSearchAttributes:    sa,
+	}, name)
+	return err
+}
+
+// SaveMetadata saves the metadata of a run, replacing the record workflow of
+// the previous ones.
+func (s *TemporalMetadataStore) SaveMetadata(ctx context.Context, kind RunKind, id uuid.UUID, md Metadata) error {
+	var sa temporal.SearchAttributes
+	if s.searchAttribute != "" {
+		key := temporal.NewSearchAttributeKeyKeywordList(s.searchAttribute)
+		sa = temporal.NewSearchAttributes(key.ValueSet(metadataKeywords(md)))
+	}
+
+	return startRecordWorkflow(ctx, s.temporal, metadataWorkflowName(kind), metadataWorkflowID(kind, id),
+		map[string]any{metadataMemo: md}, sa)
+}
+
+// LoadMetadata loads the metadata of a run, empty if it has none.
+func (s *TemporalMetadataStore) LoadMetadata(ctx context.Context, kind RunKind, id uuid.UUID) (Metadata, error) {
+	desc, err := s.temporal.DescribeWorkflowExecution(ctx, metadataWorkflowID(kind, id), "")
+	var notFound *serviceerror.NotFound
+	switch {
+	case errors.As(err, &notFound):
+		return Metadata{}, nil
+	case err != nil:
+		return nil, err
+	}
+
+	return s.decode(desc.GetWorkflowExecutionInfo().GetMemo())
+}
+
+// ListMetadata lists the metadata of the runs of the given kind that have all
+// the values of the filters.
+func (s *TemporalMetadataStore) ListMetadata(
+	ctx context.Context,
+	kind RunKind,
+	filters Metadata,
+) (map[uuid.UUID]Metadata, error) {
+	query := fmt.Sprintf("WorkflowType = '%s' AND ExecutionStatus = 'Running'",
+		escapeQueryValue(metadataWorkflowName(kind)))
+	if s.searchAttribute != "" {
+		for _, keyword := range metadataKeywords(filters) {
+			query += fmt.Sprintf(" AND %s = '%s'", s.searchAttribute, escapeQueryValue(keyword))
+		}
+	}
+
+	list := make(map[uuid.UUID]Metadata)
+	prefix := metadataWorkflowName(kind) + "-"
+	var token []byte
+	for {
+		res, err := s.temporal.ListWorkflow(ctx, &workflowservice.ListWorkflowExecutionsRequest{
+			Query:         query,
+			NextPageToken: token,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, exec := range res.GetExecutions() {
+			name, ok := strings.CutPrefix(exec.GetExecution().GetWorkflowId(), prefix)
+			if !ok {
+				continue
+			}
+			id, err := uuid.Parse(name)
+			if err != nil {
+				continue
+			}
+
+			md, err := s.decode(exec.GetMemo())
+			if err != nil {
+				return nil, err
+			}
+			if matchMetadata(md, filters) {
+				list[id] = md
+			}
+		}
+
+		if token = res.GetNextPageToken(); len(token) == 0 {
+			return list, nil
+		}
+	}
+}
+
+// decode decodes the metadata in the memo of a record workflow.
+func (s *TemporalMetadataStore) decode(memo *commonpb.Memo) (Metadata, error) {
+	md := make(Metadata)
+	payload, ok := memo.GetFields()[metadataMemo]
+	if !ok {
+		return md, nil
+	}
+
+	if err := s.dataConverter.FromPayload(payload, &md); err != nil {
+		return nil, fmt.Errorf("decoding metadata memo: %w", err)
+	}
+	return md, nil
+}
+
+// FileMetadataStore is a metadata store backed by a local directory, with a
+// JSON file per run, readable only by its owner. The files of a kind are read
+// on its first lookup and then kept in memory, so the store must be the only
+// writer of the directory.
+type FileMetadataStore struct {
+	dir string
+
+	mu     sync.RWMutex
+	loaded map[RunKind]map[uuid.UUID]Metadata
+}
+
+// NewFileMetadataStore creates a new metadata store in the given directory.
+func NewFileMetadataStore(dir string) *FileMetadataStore {
+	return &FileMetadataStore{
+		dir:    dir,
+		loaded: make(map[RunKind]map[uuid.UUID]Metadata),
+	}
+}
+
+// SaveMetadata saves the metadata of a run, replacing the previous ones.
+// The file is replaced atomically, so it is never partially written.
+func (s *FileMetadataStore) SaveMetadata(_ context.Context, kind RunKind, id uuid.UUID, md Metadata) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list, err := s.load(kind)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(md)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file, then move it in place
+	path := s.path(kind, id)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	list[id] = copyMetadata(md)
+	return nil
+}
+
+// LoadMetadata loads the metadata of a run, empty if it has none.
+func (s *FileMetadataStore) LoadMetadata(_ context.Context, kind RunKind, id uuid.UUID) (Metadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list, err := s.load(kind)
+	if err != nil {
+		return nil, err
+	}
+	return copyMetadata(list[id]), nil
+}
+
+// ListMetadata lists the metadata of the runs of the given kind that have all
+// the values of the filters.
+func (s *FileMetadataStore) ListMetadata(
+	_ context.Context,
+	kind RunKind,
+	filters Metadata,
+) (map[uuid.UUID]Metadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list, err := s.load(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[uuid.UUID]Metadata)
+	for id, md := range list {
+		if matchMetadata(md, filters) {
+			res[id] = copyMetadata(md)
+		}
+	}
+	return res, nil
+}
+
+// load returns the metadata of the runs of the kind, reading their files on
+// the first call. The caller must hold the lock.
+func (s *FileMetadataStore) load(kind RunKind) (map[uuid.UUID]Metadata, error) {
+	if list, ok := s.loaded[kind]; ok {
+		return list, nil
+	}
+
+	list := make(map[uuid.UUID]Metadata)
+	entries, err := os.ReadDir(filepath.Join(s.dir, string(kind)))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		id, err := uuid.Parse(name)
+		if err != nil {
+			continue
+		}
+
+		md, err := s.read(filepath.Join(s.dir, string(kind), e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		list[id] = md
+	}
+
+	s.loaded[kind] = list
+	return list, nil
+}
+
+func (s *FileMetadataStore) path(kind RunKind, id uuid.UUID) string {
+	return filepath.Join(s.dir, string(kind), id.String()+".json")
+}
+
+func (s *FileMetadataStore) read(path string) (Metadata, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	md := make(Metadata)
+	if err := json.Unmarshal(content, &md); err != nil {
+		return nil, fmt.Errorf("decoding metadata %s: %w", path, err)
+	}
+	return md, nil
+}
+
+// copyMetadata returns a copy of the metadata, empty if nil, so the stored
+// ones are not modified by the callers.
+func copyMetadata(md Metadata) Metadata {
+	c := make(Metadata, len(md))
+	for k, v := range md {
+		c[k] = v
+	}
+	return c
+}
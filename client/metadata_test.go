@@ -0,0 +1,302 @@
+package client
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	enums "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	temporalclient "go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/mocks"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestMatchMetadata(t *testing.T) {
+	md := Metadata{MetadataAuthor: "me", MetadataExperiment: "a"}
+
+	tests := []struct {
+		name     string
+		filters  Metadata
+		expected bool
+	}{
+		{name: "no filter", expected: true},
+		{name: "matching", filters: Metadata{MetadataAuthor: "me"}, expected: true},
+		{name: "all matching", filters: md, expected: true},
+		{name: "other value", filters: Metadata{MetadataAuthor: "you"}, expected: false},
+		{name: "missing key", filters: Metadata{MetadataCommit: "abc"}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, matchMetadata(md, tt.filters))
+		})
+	}
+}
+
+func TestFileMetadataStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	id1, id2 := uuid.New(), uuid.New()
+
+	s := NewFileMetadataStore(dir)
+	require.NoError(t, s.SaveMetadata(ctx, RunKindBacktest, id1, Metadata{MetadataAuthor: "me"}))
+	require.NoError(t, s.SaveMetadata(ctx, RunKindBacktest, id2, Metadata{MetadataAuthor: "you"}))
+	require.NoError(t, s.SaveMetadata(ctx, RunKindForwardtest, id1, Metadata{MetadataAuthor: "me"}))
+
+	// Files are only readable by their owner
+	info, err := os.Stat(filepath.Join(dir, string(RunKindBacktest)))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o750), info.Mode().Perm())
+	info, err = os.Stat(s.path(RunKindBacktest, id1))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// Load
+	md, err := s.LoadMetadata(ctx, RunKindBacktest, id1)
+	require.NoError(t, err)
+	require.Equal(t, Metadata{MetadataAuthor: "me"}, md)
+	md, err = s.LoadMetadata(ctx, RunKindBacktest, uuid.New())
+	require.NoError(t, err)
+	require.Equal(t, Metadata{}, md)
+
+	// The loaded metadata are copies
+	md[MetadataAuthor] = "changed"
+	md, err = s.LoadMetadata(ctx, RunKindBacktest, id1)
+	require.NoError(t, err)
+	require.Equal(t, Metadata{MetadataAuthor: "me"}, md)
+
+	// List
+	list, err := s.ListMetadata(ctx, RunKindBacktest, Metadata{MetadataAuthor: "you"})
+	require.NoError(t, err)
+	require.Equal(t, map[uuid.UUID]Metadata{id2: {MetadataAuthor: "you"}}, list)
+	list, err = s.ListMetadata(ctx, RunKindForwardtest, nil)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+
+	// Another store reads the files
+	list, err = NewFileMetadataStore(dir).ListMetadata(ctx, RunKindBacktest, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[uuid.UUID]Metadata{id1: {MetadataAuthor: "me"}, id2: {MetadataAuthor: "you"}}, list)
+}
+
+func TestFileMetadataStoreReadOnce(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s := NewFileMetadataStore(dir)
+
+	list, err := s.ListMetadata(ctx, RunKindBacktest, nil)
+	require.NoError(t, err)
+	require.Empty(t, list)
+
+	// Files written by others after the first lookup are not read
+	other := NewFileMetadataStore(dir)
+	require.NoError(t, other.SaveMetadata(ctx, RunKindBacktest, uuid.New(), Metadata{MetadataAuthor: "me"}))
+	list, err = s.ListMetadata(ctx, RunKindBacktest, nil)
+	require.NoError(t, err)
+	require.Empty(t, list)
+}
+
+func TestFileMetadataStoreInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, string(RunKindBacktest)), 0o750))
+	path := filepath.Join(dir, string(RunKindBacktest), uuid.NewString()+".json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+
+	_, err := NewFileMetadataStore(dir).ListMetadata(context.Background(), RunKindBacktest, nil)
+	require.ErrorContains(t, err, "decoding metadata")
+}
+
+// memoWithMetadata returns the memo of a record workflow with the metadata.
+func memoWithMetadata(t *testing.T, md Metadata) *commonpb.Memo {
+	payload, err := converter.GetDefaultDataConverter().ToPayload(md)
+	require.NoError(t, err)
+	return &commonpb.Memo{Fields: map[string]*commonpb.Payload{metadataMemo: payload}}
+}
+
+func TestTemporalMetadataStoreSave(t *testing.T) {
+	id := uuid.New()
+	md := Metadata{MetadataExperiment: "a", MetadataAuthor: "me"}
+
+	tests := []struct {
+		name            string
+		searchAttribute string
+		keywords        []string
+	}{
+		{name: "memo only"},
+		{
+			name:            "search attribute",
+			searchAttribute: "CryptellationMetadata",
+			keywords:        []string{"author=me", "experiment=a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts temporalclient.StartWorkflowOptions
+			temporalClient := &mocks.Client{}
+			temporalClient.On("ExecuteWorkflow", mock.Anything, mock.Anything, metadataWorkflowName(RunKindBacktest)).
+				Run(func(args mock.Arguments) {
+					opts = args.Get(1).(temporalclient.StartWorkflowOptions)
+				}).
+				Return(&mocks.WorkflowRun{}, nil)
+
+			s := NewTemporalMetadataStore(temporalClient, nil, tt.searchAttribute)
+			require.NoError(t, s.SaveMetadata(context.Background(), RunKindBacktest, id, md))
+			temporalClient.AssertExpectations(t)
+
+			require.Equal(t, metadataWorkflowID(RunKindBacktest, id), opts.ID)
+			require.Equal(t, recordTaskQueue, opts.TaskQueue)
+			require.Equal(t, enums.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE, opts.WorkflowIDReusePolicy)
+			require.Equal(t, enums.WORKFLOW_ID_CONFLICT_POLICY_TERMINATE_EXISTING, opts.WorkflowIDConflictPolicy)
+			require.Equal(t, map[string]any{metadataMemo: md}, opts.Memo)
+			if tt.searchAttribute == "" {
+				require.Equal(t, 0, opts.TypedSearchAttributes.Size())
+				return
+			}
+			keywords, ok := opts.TypedSearchAttributes.GetKeywordList(
+				temporal.NewSearchAttributeKeyKeywordList(tt.searchAttribute))
+			require.True(t, ok)
+			require.Equal(t, tt.keywords, keywords)
+		})
+	}
+}
+
+func TestTemporalMetadataStoreLoad(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name     string
+		res      *workflowservice.DescribeWorkflowExecutionResponse
+		err      error
+		expected Metadata
+		fails    bool
+	}{
+		{
+			name: "found",
+			res: &workflowservice.DescribeWorkflowExecutionResponse{
+				WorkflowExecutionInfo: &workflowpb.WorkflowExecutionInfo{
+					Memo: memoWithMetadata(t, Metadata{MetadataAuthor: "me"}),
+				},
+			},
+			expected: Metadata{MetadataAuthor: "me"},
+		},
+		{
+			name:     "without memo",
+			res:      &workflowservice.DescribeWorkflowExecutionResponse{},
+			expected: Metadata{},
+		},
+		{
+			name:     "not found",
+			err:      serviceerror.NewNotFound("not found"),
+			expected: Metadata{},
+		},
+		{
+			name:  "error",
+			err:   serviceerror.NewUnavailable("unavailable"),
+			fails: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			temporalClient := &mocks.Client{}
+			temporalClient.On("DescribeWorkflowExecution", mock.Anything, metadataWorkflowID(RunKindBacktest, id), "").
+				Return(tt.res, tt.err)
+
+			md, err := NewTemporalMetadataStore(temporalClient, nil, "").
+				LoadMetadata(context.Background(), RunKindBacktest, id)
+			if tt.fails {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, md)
+		})
+	}
+}
+
+func TestTemporalMetadataStoreList(t *testing.T) {
+	id1, id2 := uuid.New(), uuid.New()
+	execution := func(wid string, md Metadata) *workflowpb.WorkflowExecutionInfo {
+		return &workflowpb.WorkflowExecutionInfo{
+			Execution: &commonpb.WorkflowExecution{WorkflowId: wid},
+			Memo:      memoWithMetadata(t, md),
+		}
+	}
+	pages := []*workflowservice.ListWorkflowExecutionsResponse{
+		{
+			Executions: []*workflowpb.WorkflowExecutionInfo{
+				execution(metadataWorkflowID(RunKindBacktest, id1), Metadata{MetadataAuthor: "me"}),
+				execution(metadataWorkflowName(RunKindBacktest)+"-invalid", Metadata{MetadataAuthor: "me"}),
+			},
+			NextPageToken: []byte("next"),
+		},
+		{
+			Executions: []*workflowpb.WorkflowExecutionInfo{
+				execution(metadataWorkflowID(RunKindBacktest, id2), Metadata{MetadataAuthor: "you"}),
+			},
+		},
+	}
+	typeQuery := "WorkflowType = 'CryptellationMetadata-backtest' AND ExecutionStatus = 'Running'"
+
+	tests := []struct {
+		name            string
+		searchAttribute string
+		filters         Metadata
+		query           string
+		expected        map[uuid.UUID]Metadata
+	}{
+		{
+			name:  "all",
+			query: typeQuery,
+			expected: map[uuid.UUID]Metadata{
+				id1: {MetadataAuthor: "me"},
+				id2: {MetadataAuthor: "you"},
+			},
+		},
+		{
+			name:     "filtered on memo",
+			filters:  Metadata{MetadataAuthor: "you"},
+			query:    typeQuery,
+			expected: map[uuid.UUID]Metadata{id2: {MetadataAuthor: "you"}},
+		},
+		{
+			name:            "filtered on search attribute",
+			searchAttribute: "CryptellationMetadata",
+			filters:         Metadata{MetadataAuthor: "it's me"},
+			query:           typeQuery + " AND CryptellationMetadata = 'author=it\\'s me'",
+			expected:        map[uuid.UUID]Metadata{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			temporalClient := &mocks.Client{}
+			for _, token := range [][]byte{nil, []byte("next")} {
+				page := pages[0]
+				if token != nil {
+					page = pages[1]
+				}
+				temporalClient.On("ListWorkflow", mock.Anything,
+					mock.MatchedBy(func(req *workflowservice.ListWorkflowExecutionsRequest) bool {
+						return req.Query == tt.query && string(req.NextPageToken) == string(token)
+					})).Return(page, nil).Once()
+			}
+
+			list, err := NewTemporalMetadataStore(temporalClient, nil, tt.searchAttribute).
+				ListMetadata(context.Background(), RunKindBacktest, tt.filters)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, list)
+			temporalClient.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cryptellation/go-clients/market"
+)
+
+// NormalizePair returns the pair in the notation of the services (e.g.
+// BTC-USDT) when it is written with a separator in one of the common
+// notations (see market.NormalizePair). Other pairs, including the pairs
+// without separator that can not be split without guessing (see
+// ValidatePair), return an error wrapping ErrInvalidParams.
+func NormalizePair(pair string) (string, error) {
+	normalized, err := market.NormalizePair(pair)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidParams, err)
+	}
+	return normalized, nil
+}
+
+// pairKey returns the pair in the notation of the services, or unchanged if
+// it can not be normalized, to compare pairs written in different notations.
+func pairKey(pair string) string {
+	if normalized, err := NormalizePair(pair); err == nil {
+		return normalized
+	}
+	return pair
+}
+
+// ValidatePair parses the pair and checks that it is listed on the exchange,
+// using the exchanges catalogue.
+func (c client) ValidatePair(ctx context.Context, exchange, pair string) (market.Pair, error) {
+	exch, err := c.catalogue.Exchange(ctx, exchange)
+	if err != nil {
+		return market.Pair{}, err
+	}
+
+	p, err := market.ParsePairOn(exch, pair)
+	if err != nil {
+		return market.Pair{}, fmt.Errorf("%w: %w", ErrInvalidParams, err)
+	}
+	return p, nil
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/cryptellation/go-clients/market"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizePair(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		err      error
+	}{
+		{name: "services notation", input: "BTC-USDT", expected: "BTC-USDT"},
+		{name: "other notation", input: "btc/usdt", expected: "BTC-USDT"},
+		{name: "compact", input: "ETHBTC", err: market.ErrAmbiguousPair},
+		{name: "unknown shape", input: "BTC-USDT-PERP", err: market.ErrInvalidPair},
+		{name: "empty", input: "", err: market.ErrInvalidPair},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pair, err := NormalizePair(tt.input)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				require.ErrorIs(t, err, ErrInvalidParams)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, pair)
+		})
+	}
+}
+
+func TestPairKey(t *testing.T) {
+	require.Equal(t, "BTC-USDT", pairKey("btc_usdt"))
+	require.Equal(t, "BTCUSDT", pairKey("BTCUSDT"))
+}
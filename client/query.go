@@ -0,0 +1,427 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	backtestsapi "github.com/cryptellation/backtests/api"
+	"github.com/cryptellation/backtests/pkg/backtest"
+	backtestsclient "github.com/cryptellation/backtests/pkg/clients"
+	forwardtestsapi "github.com/cryptellation/forwardtests/api"
+	forwardtestsclient "github.com/cryptellation/forwardtests/pkg/clients"
+	"github.com/cryptellation/forwardtests/pkg/forwardtest"
+	"github.com/cryptellation/runtime"
+	"github.com/cryptellation/runtime/order"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidCursor is returned when a query cursor is malformed or
+	// refers to a run that is not listed anymore.
+	ErrInvalidCursor = fmt.Errorf("%w: invalid cursor", ErrInvalidParams)
+)
+
+// RunStatus is the status of a backtest or a forwardtest.
+type RunStatus string
+
+const (
+	// RunStatusReady is the status of a run that has not started yet.
+	RunStatusReady RunStatus = "ready"
+	// RunStatusRunning is the status of a started run.
+	RunStatusRunning RunStatus = "running"
+	// RunStatusFinished is the status of a finished run.
+	RunStatusFinished RunStatus = "finished"
+)
+
+// SortField is the field used to sort the runs.
+type SortField string
+
+const (
+	// SortByStartTime sorts the runs by start time. For forwardtests, it is
+	// the time of their first order, or their last update without orders.
+	SortByStartTime SortField = "start_time"
+	// SortByEndTime sorts the runs by end time. For forwardtests, it is their
+	// last update.
+	SortByEndTime SortField = "end_time"
+	// SortByStrategy sorts the runs by strategy name.
+	SortByStrategy SortField = "strategy"
+)
+
+// Query filters, sorts and paginates the backtests and forwardtests.
+// The services do not support filtering yet, so the filters are applied on
+// the client side, after listing the runs. A zero Query returns all the runs,
+// the most recent first.
+type Query struct {
+	statuses  []RunStatus
+	from, to  time.Time
+	exchange  string
+	pair      string
+	strategy  string
+	tags      Metadata
+	metadata  bool
+	sortField SortField
+	ascending bool
+	limit     int
+	cursor    string
+}
+
+// NewQuery creates a new query returning all the runs, the most recent first.
+func NewQuery() Query {
+	return Query{}
+}
+
+// WithStatus keeps the runs with one of the given statuses.
+func (q Query) WithStatus(statuses ...RunStatus) Query {
+	q.statuses = append(append([]RunStatus(nil), q.statuses...), statuses...)
+	return q
+}
+
+// StartedBetween keeps the runs that started between from and to, inclusive.
+// A zero time leaves the range open on its side.
+func (q Query) StartedBetween(from, to time.Time) Query {
+	q.from, q.to = from, to
+	return q
+}
+
+// OnExchange keeps the runs with an account or an order on the exchange.
+func (q Query) OnExchange(exchange string) Query {
+	q.exchange = exchange
+	return q
+}
+
+// OnPair keeps the runs trading or watching the pair, in any of the common
+// notations with a separator (see NormalizePair), as the pairs of the runs
+// are. Other pairs are matched as they are written.
+func (q Query) OnPair(pair string) Query {
+	q.pair = pairKey(pair)
+	return q
+}
+
+// WithStrategy keeps the runs of the strategy with the given name, as
+// returned by the Name method of its runtime.Runnable or set in the
+// MetadataStrategy metadata.
+func (q Query) WithStrategy(name string) Query {
+	q.strategy = name
+	return q
+}
+
+// WithTag keeps the runs whose metadata have the value for the key (see WithMetadata).
+func (q Query) WithTag(key, value string) Query {
+	tags := make(Metadata, len(q.tags)+1)
+	for k, v := range q.tags {
+		tags[k] = v
+	}
+	tags[key] = value
+
+	q.tags = tags
+	return q
+}
+
+// IncludeMetadata returns the metadata of the runs in the pages (see
+// Page.Metadata). They are listed from the metadata store (see
+// MetadataStore), so only when requested or needed by the filters.
+func (q Query) IncludeMetadata() Query {
+	q.metadata = true
+	return q
+}
+
+// needsMetadata returns true if the metadata of the runs must be listed, to
+// be returned or filtered on.
+func (q Query) needsMetadata() bool {
+	return q.metadata || len(q.tags) > 0 || q.strategy != ""
+}
+
+// SortBy sorts the runs by the field, in ascending or descending order.
+func (q Query) SortBy(field SortField, ascending bool) Query {
+	q.sortField, q.ascending = field, ascending
+	return q
+}
+
+// Limit sets the maximum number of runs per page. Zero means no limit.
+func (q Query) Limit(limit int) Query {
+	q.limit = limit
+	return q
+}
+
+// After returns the page following the given cursor, as returned in the
+// previous page. The other parameters of the query must not change.
+func (q Query) After(cursor string) Query {
+	q.cursor = cursor
+	return q
+}
+
+// Page is a page of runs returned by a query.
+type Page[T any] struct {
+	Items []T
+	// Metadata are the metadata of the items that have some (see WithMetadata),
+	// when requested with Query.IncludeMetadata.
+	Metadata map[uuid.UUID]Metadata
+	// NextCursor is the cursor of the next page, empty on the last page.
+	NextCursor string
+}
+
+// runInfo is the information used to filter and sort the runs.
+type runInfo struct {
+	ID        uuid.UUID
+	Status    RunStatus
+	StartTime time.Time
+	EndTime   time.Time
+	Exchanges map[string]bool
+	Pairs     map[string]bool
+	Strategy  string
+	Metadata  Metadata
+}
+
+// strategyName returns the name of the runnable that registered the callbacks.
+func strategyName(cbs runtime.Callbacks) string {
+	name, _ := strings.CutSuffix(cbs.OnInitCallback.Name, "-OnInit")
+	return name
+}
+
+func addOrders(info *runInfo, orders []order.Order) {
+	for _, o := range orders {
+		info.Exchanges[o.Exchange] = true
+		info.Pairs[pairKey(o.Pair)] = true
+	}
+}
+
+func backtestInfo(bt backtest.Backtest) runInfo {
+	info := runInfo{
+		ID:        bt.ID,
+		StartTime: bt.StartTime,
+		EndTime:   bt.EndTime,
+		Exchanges: make(map[string]bool),
+		Pairs:     make(map[string]bool),
+		Strategy:  strategyName(bt.Callbacks),
+	}
+
+	switch {
+	case bt.Done():
+		info.Status = RunStatusFinished
+	case bt.CurrentCandlestick.Time.After(bt.StartTime):
+		info.Status = RunStatusRunning
+	default:
+		info.Status = RunStatusReady
+	}
+
+	for exchange := range bt.Accounts {
+		info.Exchanges[exchange] = true
+	}
+	for _, s := range bt.PricesSubscriptions {
+		info.Exchanges[s.Exchange] = true
+		info.Pairs[pairKey(s.Pair)] = true
+	}
+	addOrders(&info, bt.Orders)
+
+	return info
+}
+
+func forwardtestInfo(ft forwardtest.Forwardtest) runInfo {
+	info := runInfo{
+		ID:        ft.ID,
+		Status:    RunStatus(ft.Status),
+		StartTime: ft.UpdatedAt,
+		EndTime:   ft.UpdatedAt,
+		Exchanges: make(map[string]bool),
+		Pairs:     make(map[string]bool),
+		Strategy:  strategyName(ft.Callbacks),
+	}
+
+	for exchange := range ft.Accounts {
+		info.Exchanges[exchange] = true
+	}
+	for _, o := range ft.Orders {
+		if o.ExecutionTime != nil && o.ExecutionTime.Before(info.StartTime) {
+			info.StartTime = *o.ExecutionTime
+		}
+	}
+	addOrders(&info, ft.Orders)
+
+	return info
+}
+
+// match returns true if the run satisfies the filters of the query.
+func (q Query) match(info runInfo) bool {
+	if len(q.statuses) > 0 && !containsStatus(q.statuses, info.Status) {
+		return false
+	}
+
+	switch {
+	case !q.from.IsZero() && info.StartTime.Before(q.from),
+		!q.to.IsZero() && info.StartTime.After(q.to),
+		q.exchange != "" && !info.Exchanges[q.exchange],
+		q.pair != "" && !info.Pairs[q.pair],
+		q.strategy != "" && info.Strategy != q.strategy && info.Metadata[MetadataStrategy] != q.strategy,
+		!matchMetadata(info.Metadata, q.tags):
+		return false
+	default:
+		return true
+	}
+}
+
+func containsStatus(statuses []RunStatus, status RunStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// less returns true if a is before b in the order of the query. Runs are
+// ordered by ID when the field is equal, to keep the pagination stable.
+func (q Query) less(a, b runInfo) bool {
+	var cmp int
+	switch q.sortField {
+	case SortByEndTime:
+		cmp = a.EndTime.Compare(b.EndTime)
+	case SortByStrategy:
+		cmp = strings.Compare(a.Strategy, b.Strategy)
+	default:
+		cmp = a.StartTime.Compare(b.StartTime)
+	}
+	if cmp == 0 {
+		cmp = strings.Compare(a.ID.String(), b.ID.String())
+	}
+
+	if q.ascending {
+		return cmp < 0
+	}
+	return cmp > 0
+}
+
+func encodeCursor(id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString(id[:])
+}
+
+func decodeCursor(cursor string) (uuid.UUID, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	id, err := uuid.FromBytes(b)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+	return id, nil
+}
+
+// applyQuery filters, sorts and paginates the runs.
+func applyQuery[T any](
+	q Query,
+	runs []T,
+	info func(T) runInfo,
+	metadata map[uuid.UUID]Metadata,
+) (Page[T], error) {
+	if q.limit < 0 {
+		return Page[T]{}, fmt.Errorf("%w: negative limit %d", ErrInvalidParams, q.limit)
+	}
+
+	// Filter
+	type entry struct {
+		run  T
+		info runInfo
+	}
+	entries := make([]entry, 0, len(runs))
+	for _, r := range runs {
+		i := info(r)
+		i.Metadata = metadata[i.ID]
+		if q.match(i) {
+			entries = append(entries, entry{run: r, info: i})
+		}
+	}
+
+	// Sort
+	sort.Slice(entries, func(i, j int) bool {
+		return q.less(entries[i].info, entries[j].info)
+	})
+
+	// Start after the cursor
+	if q.cursor != "" {
+		id, err := decodeCursor(q.cursor)
+		if err != nil {
+			return Page[T]{}, err
+		}
+
+		found := false
+		for i, e := range entries {
+			if e.info.ID == id {
+				entries, found = entries[i+1:], true
+				break
+			}
+		}
+		if !found {
+			return Page[T]{}, fmt.Errorf("%w: run %s not found", ErrInvalidCursor, id)
+		}
+	}
+
+	// Paginate
+	var page Page[T]
+	if q.limit > 0 && len(entries) > q.limit {
+		entries = entries[:q.limit]
+		page.NextCursor = encodeCursor(entries[len(entries)-1].info.ID)
+	}
+
+	page.Items = make([]T, len(entries))
+	for i, e := range entries {
+		page.Items[i] = e.run
+	}
+
+	// Add the metadata, if requested
+	if q.metadata {
+		page.Metadata = make(map[uuid.UUID]Metadata)
+		for _, e := range entries {
+			if e.info.Metadata != nil {
+				page.Metadata[e.info.ID] = e.info.Metadata
+			}
+		}
+	}
+	return page, nil
+}
+
+// QueryBacktests lists the backtests matching the query.
+func (c client) QueryBacktests(ctx context.Context, q Query) (Page[backtest.Backtest], error) {
+	return call(ctx, c, ServiceBacktests, MethodQueryBacktests, q,
+		func(ctx context.Context, q Query) (Page[backtest.Backtest], error) {
+			res, err := backtestsclient.NewRaw(c.temporal.services).
+				ListBacktests(ctx, backtestsapi.ListBacktestsWorkflowParams{})
+			if err != nil {
+				return Page[backtest.Backtest]{}, err
+			}
+
+			var metadata map[uuid.UUID]Metadata
+			if q.needsMetadata() {
+				metadata, err = c.ListMetadata(ctx, RunKindBacktest, q.tags)
+				if err != nil {
+					return Page[backtest.Backtest]{}, err
+				}
+			}
+			return applyQuery(q, res.Backtests, backtestInfo, metadata)
+		})
+}
+
+// QueryForwardtests lists the forwardtests matching the query.
+func (c client) QueryForwardtests(ctx context.Context, q Query) (Page[forwardtest.Forwardtest], error) {
+	return call(ctx, c, ServiceForwardtests, MethodQueryForwardtests, q,
+		func(ctx context.Context, q Query) (Page[forwardtest.Forwardtest], error) {
+			res, err := forwardtestsclient.NewRaw(c.temporal.services).
+				ListForwardtests(ctx, forwardtestsapi.ListForwardtestsWorkflowParams{})
+			if err != nil {
+				return Page[forwardtest.Forwardtest]{}, err
+			}
+
+			var metadata map[uuid.UUID]Metadata
+			if q.needsMetadata() {
+				metadata, err = c.ListMetadata(ctx, RunKindForwardtest, q.tags)
+				if err != nil {
+					return Page[forwardtest.Forwardtest]{}, err
+				}
+			}
+			return applyQuery(q, res.Forwardtests, forwardtestInfo, metadata)
+		})
+}
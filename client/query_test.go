@@ -0,0 +1,284 @@
+package client
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cryptellation/backtests/pkg/backtest"
+	"github.com/cryptellation/forwardtests/pkg/forwardtest"
+	"github.com/cryptellation/go-clients/internal/fixture"
+	"github.com/cryptellation/runtime/order"
+	"github.com/cryptellation/ticks/pkg/tick"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// runID returns the ID of the nth test run.
+func runID(n int) uuid.UUID {
+	return uuid.MustParse(fmt.Sprintf("00000000-0000-0000-0000-%012d", n))
+}
+
+// testRun returns the information of the nth test run, started on the nth
+// day and ended the day after.
+func testRun(n int, status RunStatus, exchange, pair, strategy string) runInfo {
+	return runInfo{
+		ID:        runID(n),
+		Status:    status,
+		StartTime: fixture.Start.AddDate(0, 0, n),
+		EndTime:   fixture.Start.AddDate(0, 0, n+1),
+		Exchanges: map[string]bool{exchange: true},
+		Pairs:     map[string]bool{pair: true},
+		Strategy:  strategy,
+	}
+}
+
+var testRuns = []runInfo{
+	testRun(3, RunStatusFinished, "binance", "BTC-USDT", "sma"),
+	testRun(1, RunStatusFinished, "binance", "ETH-USDT", "rsi"),
+	testRun(5, RunStatusRunning, "kraken", "BTC-USDT", "sma"),
+	testRun(2, RunStatusReady, "kraken", "ETH-USDT", "macd"),
+	testRun(4, RunStatusFinished, "binance", "BTC-USDT", "rsi"),
+}
+
+var testMetadata = map[uuid.UUID]Metadata{
+	runID(1): {MetadataExperiment: "a"},
+	runID(2): {MetadataExperiment: "b", MetadataStrategy: "sma"},
+	runID(4): {MetadataExperiment: "a"},
+}
+
+// ownInfo returns the test run, which is its own information.
+func ownInfo(i runInfo) runInfo {
+	return i
+}
+
+// pageIDs returns the numbers of the runs of the page.
+func pageIDs(page Page[runInfo]) []int {
+	ids := make([]int, len(page.Items))
+	for i, r := range page.Items {
+		ids[i] = int(r.ID[15])
+	}
+	return ids
+}
+
+func TestApplyQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    Query
+		expected []int
+		next     bool
+	}{
+		{
+			name:     "zero query",
+			query:    NewQuery(),
+			expected: []int{5, 4, 3, 2, 1},
+		},
+		{
+			name:     "status",
+			query:    NewQuery().WithStatus(RunStatusRunning, RunStatusReady),
+			expected: []int{5, 2},
+		},
+		{
+			name:     "start time range",
+			query:    NewQuery().StartedBetween(fixture.Start.AddDate(0, 0, 2), fixture.Start.AddDate(0, 0, 4)),
+			expected: []int{4, 3, 2},
+		},
+		{
+			name:     "open start time range",
+			query:    NewQuery().StartedBetween(fixture.Start.AddDate(0, 0, 4), time.Time{}),
+			expected: []int{5, 4},
+		},
+		{
+			name:     "exchange and pair",
+			query:    NewQuery().OnExchange("binance").OnPair("BTC-USDT"),
+			expected: []int{4, 3},
+		},
+		{
+			name:     "pair in another notation",
+			query:    NewQuery().OnExchange("binance").OnPair("btc/usdt"),
+			expected: []int{4, 3},
+		},
+		{
+			name:     "strategy from the callbacks or the metadata",
+			query:    NewQuery().WithStrategy("sma"),
+			expected: []int{5, 3, 2},
+		},
+		{
+			name:     "tag",
+			query:    NewQuery().WithTag(MetadataExperiment, "a"),
+			expected: []int{4, 1},
+		},
+		{
+			name:     "ascending start time",
+			query:    NewQuery().SortBy(SortByStartTime, true),
+			expected: []int{1, 2, 3, 4, 5},
+		},
+		{
+			name:     "descending end time",
+			query:    NewQuery().SortBy(SortByEndTime, false),
+			expected: []int{5, 4, 3, 2, 1},
+		},
+		{
+			name:     "strategy then ID",
+			query:    NewQuery().SortBy(SortByStrategy, true),
+			expected: []int{2, 1, 4, 3, 5},
+		},
+		{
+			name:     "limited",
+			query:    NewQuery().Limit(2),
+			expected: []int{5, 4},
+			next:     true,
+		},
+		{
+			name:     "limit equal to the runs",
+			query:    NewQuery().Limit(5),
+			expected: []int{5, 4, 3, 2, 1},
+		},
+		{
+			name:     "after cursor",
+			query:    NewQuery().After(encodeCursor(runID(4))).Limit(2),
+			expected: []int{3, 2},
+			next:     true,
+		},
+		{
+			name:     "after last run",
+			query:    NewQuery().After(encodeCursor(runID(1))),
+			expected: []int{},
+		},
+		{
+			name:     "no match",
+			query:    NewQuery().OnExchange("coinbase"),
+			expected: []int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := applyQuery(tt.query, testRuns, ownInfo, testMetadata)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, pageIDs(page))
+			require.Equal(t, tt.next, page.NextCursor != "")
+			require.Nil(t, page.Metadata)
+		})
+	}
+}
+
+func TestApplyQueryPaging(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    Query
+		expected []int
+	}{
+		{
+			name:     "most recent first",
+			query:    NewQuery(),
+			expected: []int{5, 4, 3, 2, 1},
+		},
+		{
+			name:     "by strategy",
+			query:    NewQuery().SortBy(SortByStrategy, false),
+			expected: []int{5, 3, 4, 1, 2},
+		},
+		{
+			name:     "filtered",
+			query:    NewQuery().WithStatus(RunStatusFinished).SortBy(SortByStartTime, true),
+			expected: []int{1, 3, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		for limit := 1; limit <= len(testRuns)+1; limit++ {
+			t.Run(fmt.Sprintf("%s by %d", tt.name, limit), func(t *testing.T) {
+				ids := make([]int, 0, len(testRuns))
+				q := tt.query.Limit(limit)
+				for pages := 0; ; pages++ {
+					require.Less(t, pages, len(testRuns)+1, "too many pages")
+
+					page, err := applyQuery(q, testRuns, ownInfo, testMetadata)
+					require.NoError(t, err)
+					require.LessOrEqual(t, len(page.Items), limit)
+					ids = append(ids, pageIDs(page)...)
+
+					if page.NextCursor == "" {
+						break
+					}
+					q = q.After(page.NextCursor)
+				}
+				require.Equal(t, tt.expected, ids)
+			})
+		}
+	}
+}
+
+func TestApplyQueryInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		query Query
+	}{
+		{name: "negative limit", query: NewQuery().Limit(-1)},
+		{name: "malformed cursor", query: NewQuery().After("not a cursor!")},
+		{name: "cursor of another size", query: NewQuery().After("AAAA")},
+		{name: "cursor of an unlisted run", query: NewQuery().After(encodeCursor(runID(42)))},
+		{name: "cursor of a filtered run", query: NewQuery().WithStatus(RunStatusReady).After(encodeCursor(runID(1)))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := applyQuery(tt.query, testRuns, ownInfo, testMetadata)
+			require.ErrorIs(t, err, ErrInvalidParams)
+		})
+	}
+}
+
+func TestApplyQueryMetadata(t *testing.T) {
+	page, err := applyQuery(NewQuery().IncludeMetadata().Limit(2), testRuns, ownInfo, testMetadata)
+	require.NoError(t, err)
+	require.Equal(t, []int{5, 4}, pageIDs(page))
+	require.Equal(t, map[uuid.UUID]Metadata{runID(4): testMetadata[runID(4)]}, page.Metadata)
+}
+
+func TestRunInfoPairs(t *testing.T) {
+	bt := backtest.Backtest{
+		PricesSubscriptions: []tick.Subscription{{Exchange: "binance", Pair: "btc/usdt"}},
+		Orders:              []order.Order{{Exchange: "binance", Pair: "ETH_USDT"}},
+	}
+	ft := forwardtest.Forwardtest{
+		Orders: []order.Order{{Exchange: "kraken", Pair: "eth-usdt"}},
+	}
+
+	expected := map[string]bool{"BTC-USDT": true, "ETH-USDT": true}
+	require.Equal(t, expected, backtestInfo(bt).Pairs)
+	require.Equal(t, map[string]bool{"ETH-USDT": true}, forwardtestInfo(ft).Pairs)
+
+	q := NewQuery().OnPair("BTC_USDT")
+	require.True(t, q.match(backtestInfo(bt)))
+	require.False(t, q.match(forwardtestInfo(ft)))
+}
+
+func TestQueryNeedsMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    Query
+		expected bool
+	}{
+		{name: "zero query", query: NewQuery(), expected: false},
+		{name: "other filters", query: NewQuery().OnExchange("binance").WithStatus(RunStatusReady), expected: false},
+		{name: "included", query: NewQuery().IncludeMetadata(), expected: true},
+		{name: "tag", query: NewQuery().WithTag(MetadataAuthor, "me"), expected: true},
+		{name: "strategy", query: NewQuery().WithStrategy("sma"), expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.query.needsMetadata())
+		})
+	}
+}
+
+func TestQueryImmutable(t *testing.T) {
+	base := NewQuery().WithTag("a", "1").WithStatus(RunStatusReady)
+	_ = base.WithTag("b", "2").WithStatus(RunStatusRunning)
+
+	require.Equal(t, Metadata{"a": "1"}, base.tags)
+	require.Equal(t, []RunStatus{RunStatusReady}, base.statuses)
+}
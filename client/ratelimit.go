@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Priority is the priority of a call regarding the rate limits.
+type Priority int
+
+const (
+	// PriorityBatch is the default priority, for bulk calls that can wait.
+	PriorityBatch Priority = iota
+	// PriorityInteractive is the priority of the calls that a user waits for.
+	// They can use the capacity reserved for them in the rate limits.
+	PriorityInteractive
+)
+
+// String returns the string representation of the priority.
+func (p Priority) String() string {
+	switch p {
+	case PriorityInteractive:
+		return "interactive"
+	default:
+		return "batch"
+	}
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context whose calls have the given priority.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the priority of the calls made with the context.
+func PriorityFromContext(ctx context.Context) Priority {
+	p, ok := ctx.Value(priorityKey{}).(Priority)
+	if !ok {
+		return PriorityBatch
+	}
+	return p
+}
+
+// RateLimit is a token bucket rate limit.
+type RateLimit struct {
+	// Rate is the number of calls allowed per second. Zero (or less) means
+	// no limit.
+	Rate float64
+	// Burst is the number of calls that can be made at once.
+	Burst int
+	// InteractiveRate is the number of calls per second reserved for the
+	// interactive calls, on top of Rate. Interactive calls use the shared
+	// capacity first, then the reserved one, so batch calls can not starve them.
+	InteractiveRate float64
+	// InteractiveBurst is the number of interactive calls that can be made at
+	// once with the reserved capacity.
+	InteractiveBurst int
+}
+
+// RateLimitObserver is called after each call has waited for the rate limits,
+// for example to record the waiting time in metrics.
+type RateLimitObserver func(info CallInfo, priority Priority, wait time.Duration)
+
+type bucket struct {
+	shared      *rate.Limiter
+	interactive *rate.Limiter
+}
+
+func newBucket(limit RateLimit) *bucket {
+	// A zero limit would allow the burst once and never refill
+	r := rate.Inf
+	if limit.Rate > 0 {
+		r = rate.Limit(limit.Rate)
+	}
+
+	b := &bucket{
+		shared: rate.NewLimiter(r, max(limit.Burst, 1)),
+	}
+	if limit.Rate > 0 && limit.InteractiveRate > 0 {
+		b.interactive = rate.NewLimiter(rate.Limit(limit.InteractiveRate), max(limit.InteractiveBurst, 1))
+	}
+	return b
+}
+
+func (b *bucket) wait(ctx context.Context, priority Priority) error {
+	if priority != PriorityInteractive || b.interactive == nil {
+		return b.shared.Wait(ctx)
+	}
+
+	if b.shared.Allow() {
+		return nil
+	}
+	return b.interactive.Wait(ctx)
+}
+
+// rateLimiter applies the rate limits to the calls.
+type rateLimiter struct {
+	global   *bucket
+	services map[Service]*bucket
+	methods  map[string]*bucket
+	observer RateLimitObserver
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		services: make(map[Service]*bucket),
+		methods:  make(map[string]*bucket),
+	}
+}
+
+// intercept is the interceptor waiting for the rate limits before each call.
+func (rl *rateLimiter) intercept(ctx context.Context, info CallInfo, params any, invoker Invoker) (any, error) {
+	priority := PriorityFromContext(ctx)
+	start := time.Now()
+
+	// Wait for every rate limit applying to the call
+	for _, b := range []*bucket{rl.global, rl.services[info.Service], rl.methods[info.Method]} {
+		if b == nil {
+			continue
+		}
+
+		if err := b.wait(ctx, priority); err != nil {
+			if ctx.Err() != nil {
+				return nil, MapError(ctx.Err())
+			}
+			return nil, fmt.Errorf("%w: rate limit: %w", ErrTimeout, err)
+		}
+	}
+
+	if rl.observer != nil {
+		rl.observer(info, priority, time.Since(start))
+	}
+
+	return invoker(ctx, params)
+}
+
+// WithRateLimit sets a rate limit shared by all the calls of the client.
+func WithRateLimit(limit RateLimit) func(*client) {
+	return func(c *client) {
+		c.rateLimiter.global = newBucket(limit)
+	}
+}
+
+// WithServiceRateLimit sets a rate limit on the calls to a service.
+func WithServiceRateLimit(service Service, limit RateLimit) func(*client) {
+	return func(c *client) {
+		c.rateLimiter.services[service] = newBucket(limit)
+	}
+}
+
+// WithMethodRateLimit sets a rate limit on the calls to a method (see Method* constants).
+func WithMethodRateLimit(method string, limit RateLimit) func(*client) {
+	return func(c *client) {
+		c.rateLimiter.methods[method] = newBucket(limit)
+	}
+}
+
+// WithRateLimitObserver sets a function called with the time waited by each
+// call for the rate limits.
+func WithRateLimitObserver(observer RateLimitObserver) func(*client) {
+	return func(c *client) {
+		c.rateLimiter.observer = observer
+	}
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// rateLimitCall is a call made through the rate limiter.
+type rateLimitCall struct {
+	service  Service
+	method   string
+	priority Priority
+	// limited is true when the call should wait for the rate limits.
+	limited bool
+}
+
+func TestRateLimiter(t *testing.T) {
+	slow := RateLimit{Rate: 0.001, Burst: 2}
+
+	tests := []struct {
+		name    string
+		options []func(*client)
+		calls   []rateLimitCall
+	}{
+		{
+			name:  "no limit",
+			calls: []rateLimitCall{{}, {}, {}},
+		},
+		{
+			name:    "zero rate",
+			options: []func(*client){WithRateLimit(RateLimit{})},
+			calls:   []rateLimitCall{{}, {}, {}},
+		},
+		{
+			name:    "burst",
+			options: []func(*client){WithRateLimit(slow)},
+			calls:   []rateLimitCall{{}, {}, {limited: true}, {method: MethodListBacktests, limited: true}},
+		},
+		{
+			name:    "service",
+			options: []func(*client){WithServiceRateLimit(ServiceBacktests, slow)},
+			calls:   []rateLimitCall{{}, {}, {limited: true}, {service: ServiceTicks}},
+		},
+		{
+			name:    "method",
+			options: []func(*client){WithMethodRateLimit(MethodGetBacktest, slow)},
+			calls:   []rateLimitCall{{}, {}, {limited: true}, {method: MethodListBacktests}},
+		},
+		{
+			name: "every limit",
+			options: []func(*client){
+				WithRateLimit(RateLimit{Rate: 0.001, Burst: 3}),
+				WithMethodRateLimit(MethodGetBacktest, RateLimit{Rate: 0.001, Burst: 1}),
+			},
+			calls: []rateLimitCall{
+				{},
+				{limited: true},
+				{method: MethodListBacktests},
+				{method: MethodListBacktests, limited: true},
+			},
+		},
+		{
+			name: "interactive reserved capacity",
+			options: []func(*client){WithRateLimit(RateLimit{
+				Rate: 0.001, Burst: 1, InteractiveRate: 0.001, InteractiveBurst: 1,
+			})},
+			calls: []rateLimitCall{
+				{},
+				{limited: true},
+				{priority: PriorityInteractive},
+				{priority: PriorityInteractive, limited: true},
+			},
+		},
+		{
+			name: "interactive uses shared capacity first",
+			options: []func(*client){WithRateLimit(RateLimit{
+				Rate: 0.001, Burst: 1, InteractiveRate: 0.001, InteractiveBurst: 1,
+			})},
+			calls: []rateLimitCall{
+				{priority: PriorityInteractive},
+				{priority: PriorityInteractive},
+				{limited: true},
+				{priority: PriorityInteractive, limited: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &client{rateLimiter: newRateLimiter()}
+			for _, opt := range tt.options {
+				opt(c)
+			}
+
+			for i, call := range tt.calls {
+				if call.service == "" {
+					call.service = ServiceBacktests
+				}
+				if call.method == "" {
+					call.method = MethodGetBacktest
+				}
+
+				// A waiting call would exceed the deadline, so it fails at once
+				ctx, cancel := context.WithTimeout(WithPriority(context.Background(), call.priority), time.Minute)
+				var called bool
+				_, err := c.rateLimiter.intercept(ctx, CallInfo{Service: call.service, Method: call.method}, nil,
+					func(context.Context, any) (any, error) {
+						called = true
+						return nil, nil
+					})
+				cancel()
+
+				if call.limited {
+					require.ErrorIs(t, err, ErrTimeout, "call %d", i)
+				} else {
+					require.NoError(t, err, "call %d", i)
+				}
+				require.Equal(t, !call.limited, called, "call %d", i)
+			}
+		})
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	var waits []time.Duration
+	c := &client{rateLimiter: newRateLimiter()}
+	WithRateLimit(RateLimit{Rate: 20, Burst: 1})(c)
+	WithRateLimitObserver(func(info CallInfo, priority Priority, wait time.Duration) {
+		require.Equal(t, MethodGetBacktest, info.Method)
+		require.Equal(t, PriorityBatch, priority)
+		waits = append(waits, wait)
+	})(c)
+
+	for range 2 {
+		_, err := c.rateLimiter.intercept(context.Background(),
+			CallInfo{Service: ServiceBacktests, Method: MethodGetBacktest}, nil,
+			func(context.Context, any) (any, error) { return nil, nil })
+		require.NoError(t, err)
+	}
+
+	require.Len(t, waits, 2)
+	require.Less(t, waits[0], 10*time.Millisecond)
+	require.Greater(t, waits[1], 30*time.Millisecond)
+}
+
+func TestRateLimiterCanceled(t *testing.T) {
+	c := &client{rateLimiter: newRateLimiter()}
+	WithRateLimit(RateLimit{Rate: 0.001, Burst: 1})(c)
+	invoker := func(context.Context, any) (any, error) { return nil, nil }
+	info := CallInfo{Service: ServiceBacktests, Method: MethodGetBacktest}
+
+	_, err := c.rateLimiter.intercept(context.Background(), info, nil, invoker)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.rateLimiter.intercept(ctx, info, nil, invoker)
+	require.ErrorIs(t, err, ErrCanceled)
+}
+
+func TestPriorityFromContext(t *testing.T) {
+	require.Equal(t, PriorityBatch, PriorityFromContext(context.Background()))
+	require.Equal(t, PriorityInteractive,
+		PriorityFromContext(WithPriority(context.Background(), PriorityInteractive)))
+	require.Equal(t, "batch", PriorityBatch.String())
+	require.Equal(t, "interactive", PriorityInteractive.String())
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// waitReadyAttemptTimeout is the maximum duration of each call to the info
+// workflow of a service: without worker, the call would wait indefinitely.
+const waitReadyAttemptTimeout = 2 * time.Second
+
+// waitReadyPolicy is the policy used to wait between the calls to the info
+// workflow of a service.
+var waitReadyPolicy = RetryPolicy{
+	InitialInterval: 100 * time.Millisecond,
+	MaxInterval:     5 * time.Second,
+	Multiplier:      2,
+	Jitter:          0.2,
+}
+
+// NotReadyError is returned when some services are not ready before the end
+// of the context. It matches ErrUnavailable with errors.Is.
+type NotReadyError struct {
+	// Missing are the services that did not respond.
+	Missing []Service
+	// Err is the last error of the context.
+	Err error
+}
+
+// Error returns the error message.
+func (e *NotReadyError) Error() string {
+	names := make([]string, len(e.Missing))
+	for i, s := range e.Missing {
+		names[i] = s.String()
+	}
+	return fmt.Sprintf("%s: services not ready: %s", ErrUnavailable, strings.Join(names, ", "))
+}
+
+// Unwrap returns ErrUnavailable and the error of the context.
+func (e *NotReadyError) Unwrap() []error {
+	return []error{ErrUnavailable, e.Err}
+}
+
+// WaitReady blocks until the given services (all if none) respond to their
+// info workflow. If the context ends before, the returned error is a
+// *NotReadyError listing the services that are not ready.
+// Each call has a short timeout and is retried with a backoff until the end of
+// the context. The calls do not go through the interceptors (retries, circuit
+// breakers, etc.), as they are already retried here.
+func (c *client) WaitReady(ctx context.Context, services ...Service) error {
+	if len(services) == 0 {
+		services = Services
+	}
+	for _, service := range services {
+		if !slices.Contains(Services, service) {
+			return fmt.Errorf("%w: unknown service %q", ErrInvalidParams, service)
+		}
+	}
+
+	// Poll every service concurrently
+	var wg sync.WaitGroup
+	ready := make([]bool, len(services))
+	for i, service := range services {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ready[i] = c.waitServiceReady(ctx, service)
+		}()
+	}
+	wg.Wait()
+
+	// Report the missing services
+	var missing []Service
+	for i, service := range services {
+		if !ready[i] {
+			missing = append(missing, service)
+		}
+	}
+	if len(missing) > 0 {
+		return &NotReadyError{Missing: missing, Err: ctx.Err()}
+	}
+
+	return nil
+}
+
+// waitServiceReady calls the info workflow of a service until it responds or
+// the context ends, and returns true if the service is ready.
+func (c *client) waitServiceReady(ctx context.Context, service Service) bool {
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, waitReadyAttemptTimeout)
+		ready := c.serviceReady(attemptCtx, service)
+		cancel()
+		if ready {
+			return true
+		}
+
+		timer := time.NewTimer(waitReadyPolicy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
+
+// serviceReady returns true if the service responds to its info workflow.
+// The timeout is also set as the execution timeout of the workflow when the
+// timeouts are enabled, so the workflows of failed calls do not stay pending.
+func (c *client) serviceReady(ctx context.Context, service Service) bool {
+	callback, ok := c.infoCallback(service)
+	if !ok {
+		return false
+	}
+
+	_, err := callback(context.WithValue(ctx, timeoutKey{}, waitReadyAttemptTimeout), nil)
+	return err == nil
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	backtestsapi "github.com/cryptellation/backtests/api"
+	ticksapi "github.com/cryptellation/ticks/api"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	temporalclient "go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/mocks"
+)
+
+// onTaskQueue matches the options of the workflows started on the task queue.
+func onTaskQueue(queue string) any {
+	return mock.MatchedBy(func(o temporalclient.StartWorkflowOptions) bool {
+		return o.TaskQueue == queue
+	})
+}
+
+func TestWaitReady(t *testing.T) {
+	// The backtests service responds once its worker is started, at the
+	// second call, and the ticks service never responds
+	down := &mocks.WorkflowRun{}
+	down.On("Get", mock.Anything, mock.Anything).Return(func(ctx context.Context, _ any) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	up := &mocks.WorkflowRun{}
+	up.On("Get", mock.Anything, mock.Anything).Return(nil)
+
+	temporal := &mocks.Client{}
+	temporal.On("ExecuteWorkflow", mock.Anything, onTaskQueue(backtestsapi.WorkerTaskQueueName),
+		backtestsapi.ServiceInfoWorkflowName).Return(temporalclient.WorkflowRun(down), nil).Once()
+	temporal.On("ExecuteWorkflow", mock.Anything, onTaskQueue(backtestsapi.WorkerTaskQueueName),
+		backtestsapi.ServiceInfoWorkflowName).Return(temporalclient.WorkflowRun(up), nil)
+	temporal.On("ExecuteWorkflow", mock.Anything, onTaskQueue(ticksapi.WorkerTaskQueueName),
+		ticksapi.ServiceInfoWorkflowName).Return(temporalclient.WorkflowRun(down), nil)
+
+	cl, err := New(WithTemporalClient(temporal))
+	require.NoError(t, err)
+
+	// Ready services
+	ctx, cancel := context.WithTimeout(context.Background(), 3*waitReadyAttemptTimeout)
+	defer cancel()
+	require.NoError(t, cl.WaitReady(ctx, ServiceBacktests))
+
+	// Missing services
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var notReady *NotReadyError
+	err = cl.WaitReady(ctx, ServiceBacktests, ServiceTicks)
+	require.ErrorAs(t, err, &notReady)
+	require.Equal(t, []Service{ServiceTicks}, notReady.Missing)
+	require.ErrorIs(t, err, ErrUnavailable)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Unknown service
+	require.ErrorIs(t, cl.WaitReady(context.Background(), Service("unknown")), ErrInvalidParams)
+}
@@ -0,0 +1,292 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/temporal"
+)
+
+var (
+	// ErrCircuitOpen is returned when a call is rejected because the circuit
+	// breaker of the service is open.
+	ErrCircuitOpen = fmt.Errorf("%w: circuit breaker is open", ErrUnavailable)
+)
+
+// RetryPolicy is the policy used to retry the failed calls.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	// A value of 0 or 1 disables the retries.
+	MaxAttempts int
+	// InitialInterval is the wait duration before the first retry.
+	InitialInterval time.Duration
+	// MaxInterval is the maximum wait duration between two attempts.
+	MaxInterval time.Duration
+	// Multiplier is the factor applied to the wait duration after each attempt.
+	Multiplier float64
+	// Jitter is the ratio of the wait duration randomly added or removed
+	// to avoid synchronized retries, between 0 and 1.
+	Jitter float64
+	// IsRetryable tells if an error can be retried. If nil, IsRetryableError is used.
+	IsRetryable func(error) bool
+}
+
+// DefaultRetryPolicy is a retry policy suitable for most of the calls.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:     5,
+	InitialInterval: 200 * time.Millisecond,
+	MaxInterval:     10 * time.Second,
+	Multiplier:      2,
+	Jitter:          0.2,
+}
+
+// backoff returns the wait duration after the given attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	wait := float64(p.InitialInterval) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxInterval > 0 && wait > float64(p.MaxInterval) {
+		wait = float64(p.MaxInterval)
+	}
+
+	if p.Jitter > 0 {
+		wait += wait * p.Jitter * (2*rand.Float64() - 1) //nolint:gosec // No need for crypto here
+	}
+
+	return time.Duration(wait)
+}
+
+func (p RetryPolicy) isRetryable(err error) bool {
+	if p.IsRetryable != nil {
+		return p.IsRetryable(err)
+	}
+	return IsRetryableError(err)
+}
+
+// IsRetryableError returns true if the error is a transient failure of the
+// Temporal stack (unavailable server, timeouts, etc.) and not an error
+// returned by the services themselves.
+func IsRetryableError(err error) bool {
+	var (
+		unavailable *serviceerror.Unavailable
+		exhausted   *serviceerror.ResourceExhausted
+		deadline    *serviceerror.DeadlineExceeded
+		timeout     *temporal.TimeoutError
+		application *temporal.ApplicationError
+	)
+
+	switch {
+	case err == nil,
+		errors.Is(err, context.Canceled),
+		errors.Is(err, ErrCircuitOpen),
+		errors.As(err, &application):
+		return false
+	case errors.As(err, &unavailable),
+		errors.As(err, &exhausted),
+		errors.As(err, &deadline),
+		errors.As(err, &timeout):
+		return true
+	default:
+		return false
+	}
+}
+
+// CircuitBreakerPolicy is the policy of the circuit breaker applied on each service.
+type CircuitBreakerPolicy struct {
+	// FailureThreshold is the number of consecutive failures that opens the circuit.
+	FailureThreshold int
+	// OpenDuration is the duration during which the calls are rejected before
+	// letting a call test the service again.
+	OpenDuration time.Duration
+}
+
+// DefaultCircuitBreakerPolicy is a circuit breaker policy suitable for most of the services.
+var DefaultCircuitBreakerPolicy = CircuitBreakerPolicy{
+	FailureThreshold: 5,
+	OpenDuration:     30 * time.Second,
+}
+
+type circuitBreaker struct {
+	policy   CircuitBreakerPolicy
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// allow returns an error if the call should be rejected.
+func (cb *circuitBreaker) allow() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch {
+	case cb.failures < cb.policy.FailureThreshold:
+		return nil
+	case cb.probing, time.Since(cb.openedAt) < cb.policy.OpenDuration:
+		return ErrCircuitOpen
+	default:
+		// Half-open: let one call test the service
+		cb.probing = true
+		return nil
+	}
+}
+
+// record records the result of an allowed call. Calls canceled by the caller
+// leave the failures count unchanged, as they tell nothing about the service.
+func (cb *circuitBreaker) record(ctx context.Context, err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.probing = false
+	switch {
+	case isCallerError(ctx, err):
+		return
+	case !IsRetryableError(err):
+		cb.failures = 0
+		return
+	}
+
+	cb.failures++
+	if cb.failures >= cb.policy.FailureThreshold {
+		cb.openedAt = time.Now()
+	}
+}
+
+// isCallerError returns true if the call failed because the caller canceled it.
+// Expired deadlines are not caller errors, as they include the client timeouts.
+func isCallerError(ctx context.Context, err error) bool {
+	return err != nil && (errors.Is(ctx.Err(), context.Canceled) ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, ErrCanceled))
+}
+
+// resilience applies the retry policies and circuit breakers to the calls.
+type resilience struct {
+	defaultRetry  *RetryPolicy
+	methodsRetry  map[string]RetryPolicy
+	breakerPolicy *CircuitBreakerPolicy
+	breakers      map[Service]*circuitBreaker
+	mu            sync.Mutex
+}
+
+func newResilience() *resilience {
+	return &resilience{
+		methodsRetry: make(map[string]RetryPolicy),
+		breakers:     make(map[Service]*circuitBreaker),
+	}
+}
+
+func (r *resilience) retryPolicy(method string, idempotent bool) RetryPolicy {
+	if p, ok := r.methodsRetry[method]; ok {
+		return p
+	}
+
+	if r.defaultRetry != nil && (idempotent || !nonIdempotentMethods[method]) {
+		return *r.defaultRetry
+	}
+
+	return RetryPolicy{}
+}
+
+func (r *resilience) breaker(service Service) *circuitBreaker {
+	if r.breakerPolicy == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cb, ok := r.breakers[service]
+	if !ok {
+		cb = &circuitBreaker{policy: *r.breakerPolicy}
+		r.breakers[service] = cb
+	}
+	return cb
+}
+
+// do executes the call with the retry policy of the method and the circuit
+// breaker of the service.
+func (r *resilience) do(ctx context.Context, service Service, method string, fn func(context.Context) error) error {
+	_, idempotent := IdempotencyKeyFromContext(ctx)
+	policy := r.retryPolicy(method, idempotent)
+	cb := r.breaker(service)
+
+	for attempt := 1; ; attempt++ {
+		err := r.attempt(ctx, cb, fn)
+		if err == nil || attempt >= policy.MaxAttempts || !policy.isRetryable(err) {
+			return err
+		}
+
+		// Wait before retrying, unless the context ends before
+		wait := policy.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func (r *resilience) attempt(ctx context.Context, cb *circuitBreaker, fn func(context.Context) error) error {
+	if cb == nil {
+		return fn(ctx)
+	}
+
+	if err := cb.allow(); err != nil {
+		return err
+	}
+
+	err := fn(ctx)
+	cb.record(ctx, err)
+	return err
+}
+
+// intercept is the interceptor applying the retry policies and circuit breakers.
+func (r *resilience) intercept(ctx context.Context, info CallInfo, params any, invoker Invoker) (any, error) {
+	var res any
+	err := r.do(ctx, info.Service, info.Method, func(ctx context.Context) error {
+		var err error
+		res, err = invoker(ctx, params)
+		return err
+	})
+	return res, err
+}
+
+// WithRetryPolicy sets the retry policy applied to every method, except the
+// ones creating resources (NewBacktest, NewForwardtest, ListenToTicks) that
+// need a method specific policy or an idempotency key to be retried.
+func WithRetryPolicy(policy RetryPolicy) func(*client) {
+	return func(c *client) {
+		c.resilience.defaultRetry = &policy
+	}
+}
+
+// WithMethodRetryPolicy sets the retry policy of a specific method (see Method* constants).
+func WithMethodRetryPolicy(method string, policy RetryPolicy) func(*client) {
+	return func(c *client) {
+		c.resilience.methodsRetry[method] = policy
+	}
+}
+
+// WithCircuitBreaker enables a circuit breaker per service with the given policy.
+// Only the transient failures (see IsRetryableError) are counted.
+func WithCircuitBreaker(policy CircuitBreakerPolicy) func(*client) {
+	return func(c *client) {
+		c.resilience.breakerPolicy = &policy
+	}
+}
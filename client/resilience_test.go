@@ -0,0 +1,269 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/temporal"
+)
+
+var errUnavailable = serviceerror.NewUnavailable("unavailable")
+
+// fastRetry is a retry policy without waits worth mentioning.
+var fastRetry = RetryPolicy{
+	MaxAttempts:     3,
+	InitialInterval: time.Millisecond,
+	Multiplier:      1,
+}
+
+// failingCall returns a call failing with the errors, in order, then
+// succeeding, and counting its attempts.
+func failingCall(attempts *int, errs ...error) func(context.Context) error {
+	return func(context.Context) error {
+		*attempts++
+		if *attempts <= len(errs) {
+			return errs[*attempts-1]
+		}
+		return nil
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		expected []time.Duration
+	}{
+		{
+			name:     "exponential",
+			policy:   RetryPolicy{InitialInterval: 100 * time.Millisecond, Multiplier: 2},
+			expected: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond},
+		},
+		{
+			name:     "capped",
+			policy:   RetryPolicy{InitialInterval: time.Second, MaxInterval: 3 * time.Second, Multiplier: 2},
+			expected: []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second},
+		},
+		{
+			name:     "constant below one",
+			policy:   RetryPolicy{InitialInterval: time.Second, Multiplier: 0.5},
+			expected: []time.Duration{time.Second, time.Second, time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, expected := range tt.expected {
+				require.Equal(t, expected, tt.policy.backoff(i+1), "attempt %d", i+1)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoffJitter(t *testing.T) {
+	p := RetryPolicy{InitialInterval: time.Second, Multiplier: 1, Jitter: 0.2}
+	for range 100 {
+		wait := p.backoff(1)
+		require.GreaterOrEqual(t, wait, 800*time.Millisecond)
+		require.LessOrEqual(t, wait, 1200*time.Millisecond)
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "nil", err: nil, expected: false},
+		{name: "unavailable", err: errUnavailable, expected: true},
+		{name: "wrapped unavailable", err: fmt.Errorf("calling: %w", errUnavailable), expected: true},
+		{name: "resource exhausted", err: serviceerror.NewResourceExhausted(0, "exhausted"), expected: true},
+		{name: "deadline exceeded", err: serviceerror.NewDeadlineExceeded("deadline"), expected: true},
+		{
+			name:     "workflow timeout",
+			err:      temporal.NewTimeoutError(enumspb.TIMEOUT_TYPE_START_TO_CLOSE, nil),
+			expected: true,
+		},
+		{name: "service error", err: temporal.NewApplicationError("invalid", "Error"), expected: false},
+		{name: "canceled", err: context.Canceled, expected: false},
+		{name: "circuit open", err: ErrCircuitOpen, expected: false},
+		{name: "other", err: errors.New("other"), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, IsRetryableError(tt.err))
+		})
+	}
+}
+
+func TestResilienceRetry(t *testing.T) {
+	notFound := temporal.NewApplicationError("not found", "Error")
+
+	tests := []struct {
+		name     string
+		options  []func(*client)
+		ctx      context.Context
+		method   string
+		errs     []error
+		expected error
+		attempts int
+	}{
+		{
+			name:     "no policy",
+			method:   MethodGetBacktest,
+			errs:     []error{errUnavailable},
+			expected: errUnavailable,
+			attempts: 1,
+		},
+		{
+			name:     "retried until success",
+			options:  []func(*client){WithRetryPolicy(fastRetry)},
+			method:   MethodGetBacktest,
+			errs:     []error{errUnavailable, errUnavailable},
+			attempts: 3,
+		},
+		{
+			name:     "retried until max attempts",
+			options:  []func(*client){WithRetryPolicy(fastRetry)},
+			method:   MethodGetBacktest,
+			errs:     []error{errUnavailable, errUnavailable, errUnavailable},
+			expected: errUnavailable,
+			attempts: 3,
+		},
+		{
+			name:     "not retryable",
+			options:  []func(*client){WithRetryPolicy(fastRetry)},
+			method:   MethodGetBacktest,
+			errs:     []error{notFound},
+			expected: notFound,
+			attempts: 1,
+		},
+		{
+			name:     "non idempotent method",
+			options:  []func(*client){WithRetryPolicy(fastRetry)},
+			method:   MethodNewBacktest,
+			errs:     []error{errUnavailable},
+			expected: errUnavailable,
+			attempts: 1,
+		},
+		{
+			name:     "non idempotent method with key",
+			options:  []func(*client){WithRetryPolicy(fastRetry)},
+			ctx:      WithIdempotencyKey(context.Background(), "key"),
+			method:   MethodNewBacktest,
+			errs:     []error{errUnavailable},
+			attempts: 2,
+		},
+		{
+			name:     "method policy",
+			options:  []func(*client){WithMethodRetryPolicy(MethodNewBacktest, fastRetry)},
+			method:   MethodNewBacktest,
+			errs:     []error{errUnavailable},
+			attempts: 2,
+		},
+		{
+			name: "custom retryable errors",
+			options: []func(*client){WithRetryPolicy(RetryPolicy{
+				MaxAttempts: 2,
+				IsRetryable: func(err error) bool { return errors.Is(err, notFound) },
+			})},
+			method:   MethodGetBacktest,
+			errs:     []error{notFound},
+			attempts: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &client{resilience: newResilience()}
+			for _, opt := range tt.options {
+				opt(c)
+			}
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			var attempts int
+			err := c.resilience.do(ctx, ServiceBacktests, tt.method, failingCall(&attempts, tt.errs...))
+			require.Equal(t, tt.expected, err)
+			require.Equal(t, tt.attempts, attempts)
+		})
+	}
+}
+
+func TestResilienceRetryCanceled(t *testing.T) {
+	r := newResilience()
+	r.defaultRetry = &RetryPolicy{MaxAttempts: 5, InitialInterval: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var attempts int
+	err := r.do(ctx, ServiceBacktests, MethodGetBacktest, failingCall(&attempts, errUnavailable))
+	require.Equal(t, errUnavailable, err)
+	require.Equal(t, 1, attempts, "no retry should be attempted past the deadline")
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	r := newResilience()
+	r.breakerPolicy = &CircuitBreakerPolicy{FailureThreshold: 2, OpenDuration: 50 * time.Millisecond}
+	serviceErr := temporal.NewApplicationError("not found", "Error")
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	steps := []struct {
+		name     string
+		ctx      context.Context
+		service  Service
+		err      error
+		wait     time.Duration
+		expected error
+		called   bool
+	}{
+		{name: "first failure", err: errUnavailable, expected: errUnavailable, called: true},
+		{name: "caller cancellation", ctx: canceled, err: context.Canceled, expected: context.Canceled, called: true},
+		{name: "opening failure", err: errUnavailable, expected: errUnavailable, called: true},
+		{name: "open", expected: ErrCircuitOpen},
+		{name: "other service", service: ServiceTicks, called: true},
+		{name: "failed probe", wait: 60 * time.Millisecond, err: errUnavailable, expected: errUnavailable, called: true},
+		{name: "open again", expected: ErrCircuitOpen},
+		{name: "successful probe", wait: 60 * time.Millisecond, called: true},
+		{name: "failure after success", err: errUnavailable, expected: errUnavailable, called: true},
+		{name: "service error", err: serviceErr, expected: serviceErr, called: true},
+		{name: "failure after service error", err: errUnavailable, expected: errUnavailable, called: true},
+		{name: "still closed", called: true},
+	}
+
+	for _, s := range steps {
+		time.Sleep(s.wait)
+
+		ctx, service := s.ctx, s.service
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		if service == "" {
+			service = ServiceBacktests
+		}
+
+		var called bool
+		err := r.do(ctx, service, MethodGetBacktest, func(context.Context) error {
+			called = true
+			return s.err
+		})
+		if s.expected == nil {
+			require.NoError(t, err, s.name)
+		} else {
+			require.ErrorIs(t, err, s.expected, s.name)
+		}
+		require.Equal(t, s.called, called, s.name)
+	}
+}
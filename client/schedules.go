@@ -0,0 +1,543 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	backtestsapi "github.com/cryptellation/backtests/api"
+	"github.com/cryptellation/backtests/pkg/backtest"
+	"github.com/cryptellation/candlesticks/pkg/period"
+	"github.com/cryptellation/go-clients/report"
+	"github.com/cryptellation/runtime"
+	"github.com/cryptellation/runtime/account"
+	"github.com/google/uuid"
+	enums "go.temporal.io/api/enums/v1"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/activity"
+	temporalclient "go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+var (
+	// ErrInvalidSchedule is returned when a schedule can not be created.
+	ErrInvalidSchedule = fmt.Errorf("%w: invalid schedule", ErrInvalidParams)
+)
+
+const (
+	// ScheduledBacktestWorkflowName is the name of the workflow started by
+	// the backtests schedules (see RegisterSchedulesWorker).
+	ScheduledBacktestWorkflowName = "CryptellationScheduledBacktestWorkflow"
+	// ScheduledBacktestSummaryActivityName is the name of the activity
+	// computing the summary of a scheduled backtest.
+	ScheduledBacktestSummaryActivityName = "CryptellationScheduledBacktestSummaryActivity"
+	// ScheduledBacktestRecordActivityName is the name of the activity
+	// recording a run of a backtests schedule.
+	ScheduledBacktestRecordActivityName = "CryptellationScheduledBacktestRecordActivity"
+
+	// MetadataSchedule is the metadata key set to the ID of the schedule that
+	// created a backtest.
+	MetadataSchedule = "schedule"
+
+	// scheduledBacktestSummaryTimeout is the maximum duration to compute the
+	// summary of a scheduled backtest.
+	scheduledBacktestSummaryTimeout = 10 * time.Minute
+	// scheduledBacktestRecordTimeout is the maximum duration to record a run
+	// of a backtests schedule.
+	scheduledBacktestRecordTimeout = time.Minute
+
+	// scheduledRunPrefix is the prefix of the names and IDs of the record
+	// workflows of the scheduled runs.
+	scheduledRunPrefix = "CryptellationScheduledRun"
+	// scheduledRunMemo is the memo field holding a scheduled run.
+	scheduledRunMemo = "run"
+)
+
+// BacktestTemplate is the template of the backtests created by a schedule.
+type BacktestTemplate struct {
+	// Accounts are the initial accounts of each backtest.
+	Accounts map[string]account.Account
+	// Window is the duration of the rolling date range of each backtest,
+	// ending when the backtest is created (e.g. 30 days for "last 30 days").
+	Window time.Duration
+	// Mode is the mode of the backtests. Optional.
+	Mode *backtest.Mode
+	// PricePeriod is the price period of the backtests. Optional.
+	PricePeriod *period.Symbol
+	// Callbacks are the callbacks of the strategy.
+	Callbacks runtime.Callbacks
+	// Metadata are attached to each backtest, with the schedule ID (see
+	// MetadataSchedule), in the metadata store of the client running the
+	// schedules worker, if any.
+	Metadata Metadata
+	// QuoteAsset is the asset used to value the accounts in the summaries. Default is USDT.
+	QuoteAsset string
+}
+
+// BacktestSchedule is a schedule creating backtests from a template.
+type BacktestSchedule struct {
+	// ID is the identifier of the schedule.
+	ID string
+	// Cron are the cron expressions of the schedule (e.g. "0 2 * * *" for nightly).
+	Cron []string
+	// Every is the interval of the schedule, in addition to the cron expressions.
+	Every time.Duration
+	// TimeZone is the time zone of the cron expressions. Default is UTC.
+	TimeZone string
+	// TaskQueue is the task queue of the worker running the scheduled
+	// backtests (see RegisterSchedulesWorker).
+	TaskQueue string
+	// Template is the template of the backtests.
+	Template BacktestTemplate
+	// Paused creates the schedule paused.
+	Paused bool
+	// Note is a human readable note on the schedule.
+	Note string
+}
+
+// ScheduleInfo is the information about a backtests schedule.
+type ScheduleInfo struct {
+	ID       string
+	Paused   bool
+	Note     string
+	NextRuns []time.Time
+	LastRuns []time.Time
+}
+
+// ScheduledBacktestParams are the parameters of the scheduled backtest workflow.
+type ScheduledBacktestParams struct {
+	ScheduleID string
+	Template   BacktestTemplate
+}
+
+// ScheduledBacktestResults are the results of the scheduled backtest workflow.
+type ScheduledBacktestResults struct {
+	BacktestID uuid.UUID
+	StartTime  time.Time
+	EndTime    time.Time
+	Summary    report.Summary
+}
+
+// ScheduledRun is a run of a backtests schedule, with the summary of its
+// backtest once completed.
+type ScheduledRun struct {
+	WorkflowID string
+	RunID      string
+	Status     WorkflowStatus
+	StartedAt  time.Time
+	// Results are only set if the run is completed.
+	Results *ScheduledBacktestResults
+	// Error is the error of a failed run.
+	Error error
+}
+
+// scheduledRunRecord is a run of a backtests schedule, as recorded in the
+// memo of its record workflow once done.
+type scheduledRunRecord struct {
+	WorkflowID string
+	RunID      string
+	Status     WorkflowStatus
+	StartedAt  time.Time
+	Results    *ScheduledBacktestResults
+	Error      string
+}
+
+// scheduledBacktestRecordParams are the parameters of the record activity.
+type scheduledBacktestRecordParams struct {
+	ScheduleID string
+	Run        scheduledRunRecord
+}
+
+// scheduledBacktestSummaryParams are the parameters of the summary activity.
+type scheduledBacktestSummaryParams struct {
+	BacktestID uuid.UUID
+	QuoteAsset string
+	Metadata   Metadata
+}
+
+// CreateBacktestSchedule creates a Temporal schedule that regularly creates
+// and runs backtests from the template, over a rolling date range. The
+// backtests are run by the workflows registered with RegisterSchedulesWorker.
+func (c client) CreateBacktestSchedule(ctx context.Context, s BacktestSchedule) error {
+	_, err := call(ctx, c, ServiceBacktests, MethodCreateBacktestSchedule, s,
+		func(ctx context.Context, s BacktestSchedule) (any, error) {
+			switch {
+			case s.ID == "":
+				return nil, fmt.Errorf("%w: empty ID", ErrInvalidSchedule)
+			case s.TaskQueue == "":
+				return nil, fmt.Errorf("%w: empty task queue", ErrInvalidSchedule)
+			case len(s.Cron) == 0 && s.Every <= 0:
+				return nil, fmt.Errorf("%w: no cron expression nor interval", ErrInvalidSchedule)
+			case s.Template.Window <= 0:
+				return nil, fmt.Errorf("%w: window must be positive", ErrInvalidSchedule)
+			}
+
+			spec := temporalclient.ScheduleSpec{
+				CronExpressions: s.Cron,
+				TimeZoneName:    s.TimeZone,
+			}
+			if s.Every > 0 {
+				spec.Intervals = []temporalclient.ScheduleIntervalSpec{{Every: s.Every}}
+			}
+
+			_, err := c.temporal.services.ScheduleClient().Create(ctx, temporalclient.ScheduleOptions{
+				ID:   s.ID,
+				Spec: spec,
+				Action: &temporalclient.ScheduleWorkflowAction{
+					ID:        fmt.Sprintf("%s-%s", ScheduledBacktestWorkflowName, s.ID),
+					Workflow:  ScheduledBacktestWorkflowName,
+					Args:      []any{ScheduledBacktestParams{ScheduleID: s.ID, Template: s.Template}},
+					TaskQueue: s.TaskQueue,
+				},
+				Overlap: enums.SCHEDULE_OVERLAP_POLICY_SKIP,
+				Paused:  s.Paused,
+				Note:    s.Note,
+			})
+			return nil, err
+		})
+	return err
+}
+
+// ListBacktestSchedules lists the backtests schedules.
+func (c client) ListBacktestSchedules(ctx context.Context) ([]ScheduleInfo, error) {
+	return call(ctx, c, ServiceBacktests, MethodListBacktestSchedules, nil,
+		func(ctx context.Context, _ any) ([]ScheduleInfo, error) {
+			it, err := c.temporal.services.ScheduleClient().List(ctx, temporalclient.ScheduleListOptions{})
+			if err != nil {
+				return nil, err
+			}
+
+			var list []ScheduleInfo
+			for it.HasNext() {
+				entry, err := it.Next()
+				if err != nil {
+					return nil, err
+				}
+				if entry.WorkflowType.Name != ScheduledBacktestWorkflowName {
+					continue
+				}
+
+				info := ScheduleInfo{
+					ID:       entry.ID,
+					Paused:   entry.Paused,
+					Note:     entry.Note,
+					NextRuns: entry.NextActionTimes,
+				}
+				for _, a := range entry.RecentActions {
+					info.LastRuns = append(info.LastRuns, a.ActualTime)
+				}
+				list = append(list, info)
+			}
+
+			return list, nil
+		})
+}
+
+// PauseBacktestSchedule pauses a backtests schedule, with an optional note.
+func (c client) PauseBacktestSchedule(ctx context.Context, id, note string) error {
+	_, err := call(ctx, c, ServiceBacktests, MethodPauseBacktestSchedule, id,
+		func(ctx context.Context, id string) (any, error) {
+			return nil, c.temporal.services.ScheduleClient().GetHandle(ctx, id).
+				Pause(ctx, temporalclient.SchedulePauseOptions{Note: note})
+		})
+	return err
+}
+
+// UnpauseBacktestSchedule unpauses a backtests schedule, with an optional note.
+func (c client) UnpauseBacktestSchedule(ctx context.Context, id, note string) error {
+	_, err := call(ctx, c, ServiceBacktests, MethodUnpauseBacktestSchedule, id,
+		func(ctx context.Context, id string) (any, error) {
+			return nil, c.temporal.services.ScheduleClient().GetHandle(ctx, id).
+				Unpause(ctx, temporalclient.ScheduleUnpauseOptions{Note: note})
+		})
+	return err
+}
+
+// DeleteBacktestSchedule deletes a backtests schedule. The backtests already
+// created and the runs history are kept.
+func (c client) DeleteBacktestSchedule(ctx context.Context, id string) error {
+	_, err := call(ctx, c, ServiceBacktests, MethodDeleteBacktestSchedule, id,
+		func(ctx context.Context, id string) (any, error) {
+			return nil, c.temporal.services.ScheduleClient().GetHandle(ctx, id).Delete(ctx)
+		})
+	return err
+}
+
+// ListScheduledRuns lists the runs of a backtests schedule, oldest first,
+// with the summary of the completed ones to track their trend. The done runs
+// are recorded when they end, so they are kept after the Temporal namespace
+// retention and after the deletion of the schedule. The other runs are listed
+// from their workflows, as long as the namespace retains them.
+func (c client) ListScheduledRuns(ctx context.Context, id string) ([]ScheduledRun, error) {
+	return call(ctx, c, ServiceBacktests, MethodListScheduledRuns, id,
+		func(ctx context.Context, id string) ([]ScheduledRun, error) {
+			// Get the recorded runs
+			query := fmt.Sprintf("WorkflowType = '%s' AND ExecutionStatus = 'Running'",
+				escapeQueryValue(scheduledRunWorkflowName(id)))
+			var runs []ScheduledRun
+			recorded := make(map[string]bool)
+			err := c.listWorkflows(ctx, query, func(exec *workflowpb.WorkflowExecutionInfo) error {
+				payload, ok := exec.GetMemo().GetFields()[scheduledRunMemo]
+				if !ok {
+					return nil
+				}
+
+				var record scheduledRunRecord
+				if err := c.temporal.dataConverter.FromPayload(payload, &record); err != nil {
+					return fmt.Errorf("decoding scheduled run memo: %w", err)
+				}
+
+				run := ScheduledRun{
+					WorkflowID: record.WorkflowID,
+					RunID:      record.RunID,
+					Status:     record.Status,
+					StartedAt:  record.StartedAt,
+					Results:    record.Results,
+				}
+				if record.Error != "" {
+					run.Error = errors.New(record.Error)
+				}
+				runs = append(runs, run)
+				recorded[record.RunID] = true
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			// Add the runs that are not recorded: still running, or ended
+			// without running their workflow code (e.g. terminated)
+			query = fmt.Sprintf("TemporalScheduledById = '%s'", escapeQueryValue(id))
+			err = c.listWorkflows(ctx, query, func(exec *workflowpb.WorkflowExecutionInfo) error {
+				if recorded[exec.GetExecution().GetRunId()] {
+					return nil
+				}
+
+				runs = append(runs, ScheduledRun{
+					WorkflowID: exec.GetExecution().GetWorkflowId(),
+					RunID:      exec.GetExecution().GetRunId(),
+					Status:     workflowStatusFromTemporal(exec.GetStatus()),
+					StartedAt:  exec.GetStartTime().AsTime(),
+				})
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			sort.Slice(runs, func(i, j int) bool {
+				return runs[i].StartedAt.Before(runs[j].StartedAt)
+			})
+			return runs, nil
+		})
+}
+
+// listWorkflows calls fn on each workflow execution matching the visibility
+// query, through all the pages.
+func (c client) listWorkflows(
+	ctx context.Context,
+	query string,
+	fn func(exec *workflowpb.WorkflowExecutionInfo) error,
+) error {
+	var token []byte
+	for {
+		res, err := c.temporal.services.ListWorkflow(ctx, &workflowservice.ListWorkflowExecutionsRequest{
+			Query:         query,
+			NextPageToken: token,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, exec := range res.GetExecutions() {
+			if err := fn(exec); err != nil {
+				return err
+			}
+		}
+
+		if token = res.GetNextPageToken(); len(token) == 0 {
+			return nil
+		}
+	}
+}
+
+// scheduledRunWorkflowName returns the name of the record workflows of the
+// runs of a backtests schedule.
+func scheduledRunWorkflowName(scheduleID string) string {
+	return fmt.Sprintf("%s-%s", scheduledRunPrefix, scheduleID)
+}
+
+// RegisterSchedulesWorker registers on the worker the workflow and activity
+// running the scheduled backtests. The worker must listen on the task queue
+// of the schedules and be started by the caller.
+func (c client) RegisterSchedulesWorker(w worker.Worker) {
+	w.RegisterWorkflowWithOptions(ScheduledBacktestWorkflow, workflow.RegisterOptions{
+		Name: ScheduledBacktestWorkflowName,
+	})
+	w.RegisterActivityWithOptions(c.scheduledBacktestSummary, activity.RegisterOptions{
+		Name: ScheduledBacktestSummaryActivityName,
+	})
+	w.RegisterActivityWithOptions(c.scheduledBacktestRecord, activity.RegisterOptions{
+		Name: ScheduledBacktestRecordActivityName,
+	})
+}
+
+// ScheduledBacktestWorkflow creates a backtest from the template over the
+// rolling date range ending now, runs it and returns its summary. The run is
+// then recorded, with its summary or its error (see ListScheduledRuns).
+func ScheduledBacktestWorkflow(
+	ctx workflow.Context,
+	params ScheduledBacktestParams,
+) (ScheduledBacktestResults, error) {
+	results, err := scheduledBacktest(ctx, params)
+
+	info := workflow.GetInfo(ctx)
+	record := scheduledRunRecord{
+		WorkflowID: info.WorkflowExecution.ID,
+		RunID:      info.WorkflowExecution.RunID,
+		Status:     WorkflowStatusCompleted,
+		StartedAt:  info.WorkflowStartTime,
+	}
+	switch {
+	case temporal.IsCanceledError(err):
+		record.Status = WorkflowStatusCanceled
+		record.Error = err.Error()
+	case err != nil:
+		record.Status = WorkflowStatusFailed
+		record.Error = err.Error()
+	default:
+		record.Results = &results
+	}
+
+	// Record the run even if it is canceled
+	recordCtx, _ := workflow.NewDisconnectedContext(ctx)
+	recordCtx = workflow.WithActivityOptions(recordCtx, workflow.ActivityOptions{
+		StartToCloseTimeout: scheduledBacktestRecordTimeout,
+	})
+	recordErr := workflow.ExecuteActivity(recordCtx, ScheduledBacktestRecordActivityName,
+		scheduledBacktestRecordParams{
+			ScheduleID: params.ScheduleID,
+			Run:        record,
+		}).Get(recordCtx, nil)
+
+	switch {
+	case err != nil:
+		return ScheduledBacktestResults{}, err
+	case recordErr != nil:
+		return ScheduledBacktestResults{}, fmt.Errorf("recording run: %w", recordErr)
+	default:
+		return results, nil
+	}
+}
+
+// scheduledBacktest creates and runs the backtest of a scheduled run, and
+// returns its summary.
+func scheduledBacktest(
+	ctx workflow.Context,
+	params ScheduledBacktestParams,
+) (ScheduledBacktestResults, error) {
+	tmpl := params.Template
+	end := workflow.Now(ctx).UTC()
+	start := end.Add(-tmpl.Window)
+
+	// Create the backtest
+	childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+		TaskQueue: backtestsapi.WorkerTaskQueueName,
+	})
+	var created backtestsapi.CreateBacktestWorkflowResults
+	err := workflow.ExecuteChildWorkflow(childCtx, backtestsapi.CreateBacktestWorkflowName,
+		backtestsapi.CreateBacktestWorkflowParams{
+			BacktestParameters: backtest.Parameters{
+				Accounts:    tmpl.Accounts,
+				StartTime:   start,
+				EndTime:     &end,
+				Mode:        tmpl.Mode,
+				PricePeriod: tmpl.PricePeriod,
+			},
+			Callbacks: tmpl.Callbacks,
+		}).Get(ctx, &created)
+	if err != nil {
+		return ScheduledBacktestResults{}, fmt.Errorf("creating backtest: %w", err)
+	}
+
+	// Run the backtest
+	err = workflow.ExecuteChildWorkflow(childCtx, backtestsapi.RunBacktestWorkflowName,
+		backtestsapi.RunBacktestWorkflowParams{
+			BacktestID: created.ID,
+		}).Get(ctx, nil)
+	if err != nil {
+		return ScheduledBacktestResults{}, fmt.Errorf("running backtest %s: %w", created.ID, err)
+	}
+
+	// Compute the summary and record the metadata
+	md := make(Metadata, len(tmpl.Metadata)+1)
+	for k, v := range tmpl.Metadata {
+		md[k] = v
+	}
+	md[MetadataSchedule] = params.ScheduleID
+
+	activityCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: scheduledBacktestSummaryTimeout,
+	})
+	var summary report.Summary
+	err = workflow.ExecuteActivity(activityCtx, ScheduledBacktestSummaryActivityName,
+		scheduledBacktestSummaryParams{
+			BacktestID: created.ID,
+			QuoteAsset: tmpl.QuoteAsset,
+			Metadata:   md,
+		}).Get(ctx, &summary)
+	if err != nil {
+		return ScheduledBacktestResults{}, fmt.Errorf("summarizing backtest %s: %w", created.ID, err)
+	}
+
+	return ScheduledBacktestResults{
+		BacktestID: created.ID,
+		StartTime:  start,
+		EndTime:    end,
+		Summary:    summary,
+	}, nil
+}
+
+// scheduledBacktestSummary records the metadata of a scheduled backtest and
+// computes its summary.
+func (c client) scheduledBacktestSummary(
+	ctx context.Context,
+	params scheduledBacktestSummaryParams,
+) (report.Summary, error) {
+	if err := c.saveMetadata(WithMetadata(ctx, params.Metadata), RunKindBacktest, params.BacktestID); err != nil {
+		return report.Summary{}, err
+	}
+
+	var opts []report.Options
+	if params.QuoteAsset != "" {
+		opts = append(opts, report.WithQuoteAsset(params.QuoteAsset))
+	}
+
+	r, err := report.New(ctx, c, params.BacktestID, opts...)
+	if errors.Is(err, report.ErrNoPrice) {
+		return report.Summary{}, temporal.NewNonRetryableApplicationError(err.Error(), "NoPrice", err)
+	} else if err != nil {
+		return report.Summary{}, err
+	}
+	return r.Summary, nil
+}
+
+// scheduledBacktestRecord records a run of a backtests schedule in the memo
+// of a record workflow, named after the schedule.
+func (c client) scheduledBacktestRecord(ctx context.Context, params scheduledBacktestRecordParams) error {
+	return startRecordWorkflow(ctx, c.temporal.services,
+		scheduledRunWorkflowName(params.ScheduleID),
+		fmt.Sprintf("%s-%s", scheduledRunWorkflowName(params.ScheduleID), params.Run.RunID),
+		map[string]any{scheduledRunMemo: params.Run}, temporal.SearchAttributes{})
+}
+
+// escapeQueryValue escapes a value of a visibility query.
+func escapeQueryValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+}
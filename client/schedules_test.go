@@ -0,0 +1,214 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	backtestsapi "github.com/cryptellation/backtests/api"
+	"github.com/cryptellation/go-clients/report"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	enums "go.temporal.io/api/enums/v1"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/mocks"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestScheduledBacktestWorkflow(t *testing.T) {
+	id := uuid.New()
+	now := time.Date(2024, 1, 31, 2, 0, 0, 0, time.UTC)
+	summary := report.Summary{InitialEquity: 100, FinalEquity: 110}
+	errService := errors.New("service error")
+
+	tests := []struct {
+		name      string
+		createErr error
+		runErr    error
+		recordErr error
+		status    WorkflowStatus
+		fails     bool
+	}{
+		{name: "completed", status: WorkflowStatusCompleted},
+		{name: "creation error", createErr: errService, status: WorkflowStatusFailed, fails: true},
+		{name: "run error", runErr: errService, status: WorkflowStatusFailed, fails: true},
+		{name: "record error", recordErr: errService, status: WorkflowStatusCompleted, fails: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var suite testsuite.WorkflowTestSuite
+			env := suite.NewTestWorkflowEnvironment()
+			env.SetStartTime(now)
+
+			var created backtestsapi.CreateBacktestWorkflowParams
+			env.RegisterWorkflowWithOptions(func(
+				_ workflow.Context,
+				params backtestsapi.CreateBacktestWorkflowParams,
+			) (backtestsapi.CreateBacktestWorkflowResults, error) {
+				created = params
+				return backtestsapi.CreateBacktestWorkflowResults{ID: id}, tt.createErr
+			}, workflow.RegisterOptions{Name: backtestsapi.CreateBacktestWorkflowName})
+			env.RegisterWorkflowWithOptions(func(
+				_ workflow.Context,
+				params backtestsapi.RunBacktestWorkflowParams,
+			) (backtestsapi.RunBacktestWorkflowResults, error) {
+				require.Equal(t, id, params.BacktestID)
+				return backtestsapi.RunBacktestWorkflowResults{}, tt.runErr
+			}, workflow.RegisterOptions{Name: backtestsapi.RunBacktestWorkflowName})
+
+			var summarized scheduledBacktestSummaryParams
+			env.RegisterActivityWithOptions(func(
+				_ context.Context,
+				params scheduledBacktestSummaryParams,
+			) (report.Summary, error) {
+				summarized = params
+				return summary, nil
+			}, activity.RegisterOptions{Name: ScheduledBacktestSummaryActivityName})
+			var recorded scheduledBacktestRecordParams
+			env.RegisterActivityWithOptions(func(_ context.Context, params scheduledBacktestRecordParams) error {
+				recorded = params
+				return tt.recordErr
+			}, activity.RegisterOptions{Name: ScheduledBacktestRecordActivityName})
+
+			env.ExecuteWorkflow(ScheduledBacktestWorkflow, ScheduledBacktestParams{
+				ScheduleID: "nightly",
+				Template: BacktestTemplate{
+					Window:     24 * time.Hour,
+					Metadata:   Metadata{MetadataAuthor: "me"},
+					QuoteAsset: "EUR",
+				},
+			})
+			require.True(t, env.IsWorkflowCompleted())
+
+			// The run is always recorded
+			require.Equal(t, "nightly", recorded.ScheduleID)
+			require.Equal(t, tt.status, recorded.Run.Status)
+			require.Equal(t, now, recorded.Run.StartedAt.UTC())
+			if tt.createErr != nil || tt.runErr != nil {
+				require.Error(t, env.GetWorkflowError())
+				require.Contains(t, recorded.Run.Error, errService.Error())
+				require.Nil(t, recorded.Run.Results)
+				return
+			}
+
+			expected := ScheduledBacktestResults{
+				BacktestID: id,
+				StartTime:  now.Add(-24 * time.Hour),
+				EndTime:    now,
+				Summary:    summary,
+			}
+			require.Equal(t, now.Add(-24*time.Hour), created.BacktestParameters.StartTime)
+			require.Equal(t, Metadata{MetadataAuthor: "me", MetadataSchedule: "nightly"}, summarized.Metadata)
+			require.Equal(t, "EUR", summarized.QuoteAsset)
+			require.Empty(t, recorded.Run.Error)
+			require.NotNil(t, recorded.Run.Results)
+			require.Equal(t, expected.BacktestID, recorded.Run.Results.BacktestID)
+
+			if tt.fails {
+				require.ErrorContains(t, env.GetWorkflowError(), "recording run")
+				return
+			}
+			require.NoError(t, env.GetWorkflowError())
+			var results ScheduledBacktestResults
+			require.NoError(t, env.GetWorkflowResult(&results))
+			require.Equal(t, expected.BacktestID, results.BacktestID)
+			require.Equal(t, expected.StartTime, results.StartTime.UTC())
+			require.Equal(t, expected.EndTime, results.EndTime.UTC())
+			require.Equal(t, expected.Summary, results.Summary)
+		})
+	}
+}
+
+func TestListScheduledRuns(t *testing.T) {
+	id := uuid.New()
+	start := time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)
+	record := func(runID string, started time.Time, r scheduledRunRecord) *workflowpb.WorkflowExecutionInfo {
+		r.RunID, r.StartedAt = runID, started
+		payload, err := converter.GetDefaultDataConverter().ToPayload(r)
+		require.NoError(t, err)
+		return &workflowpb.WorkflowExecutionInfo{
+			Memo: &commonpb.Memo{Fields: map[string]*commonpb.Payload{scheduledRunMemo: payload}},
+		}
+	}
+	execution := func(
+		runID string,
+		started time.Time,
+		status enums.WorkflowExecutionStatus,
+	) *workflowpb.WorkflowExecutionInfo {
+		return &workflowpb.WorkflowExecutionInfo{
+			Execution: &commonpb.WorkflowExecution{WorkflowId: "wf", RunId: runID},
+			Status:    status,
+			StartTime: timestamppb.New(started),
+		}
+	}
+	results := &ScheduledBacktestResults{BacktestID: id}
+
+	temporal := &mocks.Client{}
+	listing := func(match func(req *workflowservice.ListWorkflowExecutionsRequest) bool) *mock.Call {
+		return temporal.On("ListWorkflow", mock.Anything, mock.MatchedBy(match))
+	}
+	listing(func(req *workflowservice.ListWorkflowExecutionsRequest) bool {
+		return req.Query == "WorkflowType = 'CryptellationScheduledRun-it\\'s' AND ExecutionStatus = 'Running'" &&
+			len(req.NextPageToken) == 0
+	}).Return(&workflowservice.ListWorkflowExecutionsResponse{
+		Executions: []*workflowpb.WorkflowExecutionInfo{
+			record("completed", start.Add(time.Hour), scheduledRunRecord{
+				Status:  WorkflowStatusCompleted,
+				Results: results,
+			}),
+		},
+		NextPageToken: []byte("next"),
+	}, nil).Once()
+	listing(func(req *workflowservice.ListWorkflowExecutionsRequest) bool {
+		return string(req.NextPageToken) == "next"
+	}).Return(&workflowservice.ListWorkflowExecutionsResponse{
+		Executions: []*workflowpb.WorkflowExecutionInfo{
+			record("failed", start, scheduledRunRecord{
+				Status: WorkflowStatusFailed,
+				Error:  "service error",
+			}),
+		},
+	}, nil).Once()
+	listing(func(req *workflowservice.ListWorkflowExecutionsRequest) bool {
+		return req.Query == "TemporalScheduledById = 'it\\'s'"
+	}).Return(&workflowservice.ListWorkflowExecutionsResponse{
+		Executions: []*workflowpb.WorkflowExecutionInfo{
+			execution("completed", start.Add(time.Hour), enums.WORKFLOW_EXECUTION_STATUS_COMPLETED),
+			execution("running", start.Add(2*time.Hour), enums.WORKFLOW_EXECUTION_STATUS_RUNNING),
+			execution("terminated", start.Add(-time.Hour), enums.WORKFLOW_EXECUTION_STATUS_TERMINATED),
+		},
+	}, nil).Once()
+
+	cl, err := New(WithTemporalClient(temporal))
+	require.NoError(t, err)
+	runs, err := cl.ListScheduledRuns(context.Background(), "it's")
+	require.NoError(t, err)
+	temporal.AssertExpectations(t)
+
+	// The runs are listed oldest first, without a call per run
+	require.Len(t, runs, 4)
+	require.Equal(t, ScheduledRun{
+		WorkflowID: "wf",
+		RunID:      "terminated",
+		Status:     WorkflowStatusTerminated,
+		StartedAt:  start.Add(-time.Hour),
+	}, runs[0])
+	require.Equal(t, "failed", runs[1].RunID)
+	require.Equal(t, WorkflowStatusFailed, runs[1].Status)
+	require.EqualError(t, runs[1].Error, "service error")
+	require.Equal(t, "completed", runs[2].RunID)
+	require.Equal(t, WorkflowStatusCompleted, runs[2].Status)
+	require.Equal(t, results, runs[2].Results)
+	require.Equal(t, "running", runs[3].RunID)
+	require.Equal(t, WorkflowStatusRunning, runs[3].Status)
+	require.Nil(t, runs[3].Results)
+}
@@ -0,0 +1,79 @@
+package client
+
+// Service is a service of the Cryptellation stack.
+type Service string
+
+const (
+	// ServiceBacktests is the backtests service.
+	ServiceBacktests Service = "backtests"
+	// ServiceCandlesticks is the candlesticks service.
+	ServiceCandlesticks Service = "candlesticks"
+	// ServiceExchanges is the exchanges service.
+	ServiceExchanges Service = "exchanges"
+	// ServiceForwardtests is the forwardtests service.
+	ServiceForwardtests Service = "forwardtests"
+	// ServiceSMA is the sma service.
+	ServiceSMA Service = "sma"
+	// ServiceTicks is the ticks service.
+	ServiceTicks Service = "ticks"
+)
+
+// Services is the list of the services of the Cryptellation stack.
+var Services = []Service{
+	ServiceBacktests,
+	ServiceCandlesticks,
+	ServiceExchanges,
+	ServiceForwardtests,
+	ServiceSMA,
+	ServiceTicks,
+}
+
+// String returns the string representation of the service.
+func (s Service) String() string {
+	return string(s)
+}
+
+// Names of the client methods, used to configure behaviors per method.
+const (
+	MethodNewBacktest          = "NewBacktest"
+	MethodGetBacktest          = "GetBacktest"
+	MethodGetBacktestDetails   = "GetBacktestDetails"
+	MethodListBacktests        = "ListBacktests"
+	MethodQueryBacktests       = "QueryBacktests"
+	MethodListCandlesticks     = "ListCandlesticks"
+	MethodGetExchange          = "GetExchange"
+	MethodListExchanges        = "ListExchanges"
+	MethodListSMA              = "ListSMA"
+	MethodNewForwardtest       = "NewForwardtest"
+	MethodGetForwardtest       = "GetForwardtest"
+	MethodListForwardtests     = "ListForwardtests"
+	MethodQueryForwardtests    = "QueryForwardtests"
+	MethodListenToTicks        = "ListenToTicks"
+	MethodStopListeningToTicks = "StopListeningToTicks"
+	MethodInfo                 = "Info"
+
+	MethodCreateBacktestSchedule  = "CreateBacktestSchedule"
+	MethodListBacktestSchedules   = "ListBacktestSchedules"
+	MethodPauseBacktestSchedule   = "PauseBacktestSchedule"
+	MethodUnpauseBacktestSchedule = "UnpauseBacktestSchedule"
+	MethodDeleteBacktestSchedule  = "DeleteBacktestSchedule"
+	MethodListScheduledRuns       = "ListScheduledRuns"
+
+	MethodNewBacktestAsync      = "NewBacktestAsync"
+	MethodListCandlesticksAsync = "ListCandlesticksAsync"
+	MethodListSMAAsync          = "ListSMAAsync"
+	MethodNewForwardtestAsync   = "NewForwardtestAsync"
+	MethodRunBacktestAsync      = "RunBacktestAsync"
+)
+
+// nonIdempotentMethods are the methods that are not retried by the default
+// retry policy, as retrying them could have side effects (duplicates, etc.).
+// They can still be retried with a method specific policy.
+var nonIdempotentMethods = map[string]bool{
+	MethodNewBacktest:         true,
+	MethodNewBacktestAsync:    true,
+	MethodNewForwardtest:      true,
+	MethodNewForwardtestAsync: true,
+	MethodRunBacktestAsync:    true,
+	MethodListenToTicks:       true,
+}
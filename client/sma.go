@@ -10,5 +10,8 @@ func (c client) ListSMA(
 	ctx context.Context,
 	params api.ListWorkflowParams,
 ) (res api.ListWorkflowResults, err error) {
-	return c.sma.List(ctx, params)
+	if params.Pair, err = NormalizePair(params.Pair); err != nil {
+		return res, err
+	}
+	return call(ctx, c, ServiceSMA, MethodListSMA, params, c.sma.List)
 }
@@ -13,7 +13,17 @@ func (c client) ListenToTicks(
 	listener clients.ListenerParams,
 	exchange, pair string,
 ) error {
-	return c.ticks.ListenToTicks(ctx, listener, exchange, pair)
+	pair, err := NormalizePair(pair)
+	if err != nil {
+		return err
+	}
+
+	p := ListenToTicksParams{Listener: listener, Exchange: exchange, Pair: pair}
+	_, err = call(ctx, c, ServiceTicks, MethodListenToTicks, p,
+		func(ctx context.Context, p ListenToTicksParams) (any, error) {
+			return nil, c.ticks.ListenToTicks(ctx, p.Listener, p.Exchange, p.Pair)
+		})
+	return err
 }
 
 // StopListeningToTicks unregisters a callback workflow from ticks for a given exchange and pair.
@@ -23,5 +33,15 @@ func (c client) StopListeningToTicks(
 	exchange string,
 	pair string,
 ) error {
-	return c.ticks.StopListeningToTicks(ctx, listener, exchange, pair)
+	pair, err := NormalizePair(pair)
+	if err != nil {
+		return err
+	}
+
+	p := StopListeningToTicksParams{Listener: listener, Exchange: exchange, Pair: pair}
+	_, err = call(ctx, c, ServiceTicks, MethodStopListeningToTicks, p,
+		func(ctx context.Context, p StopListeningToTicksParams) (any, error) {
+			return nil, c.ticks.StopListeningToTicks(ctx, p.Listener, p.Exchange, p.Pair)
+		})
+	return err
 }
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	temporalclient "go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/interceptor"
+)
+
+// TimeoutError is returned when a call did not complete within its timeout.
+// It matches ErrTimeout with errors.Is.
+type TimeoutError struct {
+	// Method is the name of the method called.
+	Method string
+	// Timeout is the effective timeout of the call: the timeout of the method,
+	// or the remaining time of the caller context if it was shorter.
+	Timeout time.Duration
+	// Err is the original error.
+	Err error
+}
+
+// Error returns the error message.
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s: %s did not complete within %s", ErrTimeout, e.Method, e.Timeout)
+}
+
+// Unwrap returns ErrTimeout and the original error.
+func (e *TimeoutError) Unwrap() []error {
+	return []error{ErrTimeout, e.Err}
+}
+
+type timeoutKey struct{}
+
+// timeouts applies the default timeouts to the calls.
+type timeouts struct {
+	interceptor.ClientInterceptorBase
+
+	defaultTimeout time.Duration
+	methods        map[string]time.Duration
+}
+
+func newTimeouts() *timeouts {
+	return &timeouts{
+		methods: make(map[string]time.Duration),
+	}
+}
+
+func (t *timeouts) enabled() bool {
+	return t.defaultTimeout > 0 || len(t.methods) > 0
+}
+
+func (t *timeouts) timeout(method string) time.Duration {
+	if d, ok := t.methods[method]; ok {
+		return d
+	}
+	return t.defaultTimeout
+}
+
+// intercept is the interceptor setting the deadline of the calls. The timeout
+// is also passed in the context to set the execution timeout of the workflows.
+func (t *timeouts) intercept(ctx context.Context, info CallInfo, params any, invoker Invoker) (any, error) {
+	timeout := t.timeout(info.Method)
+	if timeout <= 0 {
+		return invoker(ctx, params)
+	}
+
+	// Keep the caller deadline if it is shorter
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, timeoutKey{}, timeout)
+
+	res, err := invoker(ctx, params)
+	if err != nil && (errors.Is(err, ErrTimeout) || errors.Is(ctx.Err(), context.DeadlineExceeded)) {
+		return res, &TimeoutError{
+			Method:  info.Method,
+			Timeout: timeout,
+			Err:     err,
+		}
+	}
+	return res, err
+}
+
+// InterceptClient sets the execution timeout of the workflows started by the
+// calls having a timeout.
+func (t *timeouts) InterceptClient(next interceptor.ClientOutboundInterceptor) interceptor.ClientOutboundInterceptor {
+	return &timeoutsOutbound{
+		ClientOutboundInterceptorBase: interceptor.ClientOutboundInterceptorBase{Next: next},
+	}
+}
+
+type timeoutsOutbound struct {
+	interceptor.ClientOutboundInterceptorBase
+}
+
+// ExecuteWorkflow sets the execution timeout of the workflow if it is not already set.
+func (o *timeoutsOutbound) ExecuteWorkflow(
+	ctx context.Context,
+	in *interceptor.ClientExecuteWorkflowInput,
+) (temporalclient.WorkflowRun, error) {
+	timeout, ok := ctx.Value(timeoutKey{}).(time.Duration)
+	if ok && in.Options != nil && in.Options.WorkflowExecutionTimeout == 0 {
+		in.Options.WorkflowExecutionTimeout = timeout
+	}
+	return o.Next.ExecuteWorkflow(ctx, in)
+}
+
+// WithTimeout sets the default timeout of every call. It is used as the client
+// side deadline and as the execution timeout of the workflows started.
+func WithTimeout(timeout time.Duration) func(*client) {
+	return func(c *client) {
+		c.timeouts.defaultTimeout = timeout
+	}
+}
+
+// WithMethodTimeout sets the timeout of a specific method (see Method* constants).
+// A zero timeout disables the default timeout for the method.
+func WithMethodTimeout(method string, timeout time.Duration) func(*client) {
+	return func(c *client) {
+		c.timeouts.methods[method] = timeout
+	}
+}
@@ -0,0 +1,198 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	temporalclient "go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/interceptor"
+)
+
+func TestTimeoutsIntercept(t *testing.T) {
+	errCall := errors.New("call error")
+
+	tests := []struct {
+		name     string
+		options  []func(*client)
+		deadline time.Duration
+		method   string
+		err      error
+		// expected is the timeout of the call, zero if none.
+		expected    time.Duration
+		expectedErr error
+	}{
+		{
+			name:   "no timeout",
+			method: MethodGetBacktest,
+		},
+		{
+			name:     "default timeout",
+			options:  []func(*client){WithTimeout(time.Minute)},
+			method:   MethodGetBacktest,
+			expected: time.Minute,
+		},
+		{
+			name:     "method timeout",
+			options:  []func(*client){WithTimeout(time.Minute), WithMethodTimeout(MethodGetBacktest, time.Hour)},
+			method:   MethodGetBacktest,
+			expected: time.Hour,
+		},
+		{
+			name:     "other method timeout",
+			options:  []func(*client){WithTimeout(time.Minute), WithMethodTimeout(MethodNewBacktest, time.Hour)},
+			method:   MethodGetBacktest,
+			expected: time.Minute,
+		},
+		{
+			name:    "method timeout disabled",
+			options: []func(*client){WithTimeout(time.Minute), WithMethodTimeout(MethodGetBacktest, 0)},
+			method:  MethodGetBacktest,
+		},
+		{
+			name:     "shorter caller deadline",
+			options:  []func(*client){WithTimeout(time.Hour)},
+			deadline: time.Minute,
+			method:   MethodGetBacktest,
+			expected: time.Minute,
+		},
+		{
+			name:     "longer caller deadline",
+			options:  []func(*client){WithTimeout(time.Minute)},
+			deadline: time.Hour,
+			method:   MethodGetBacktest,
+			expected: time.Minute,
+		},
+		{
+			name:        "other error",
+			options:     []func(*client){WithTimeout(time.Minute)},
+			method:      MethodGetBacktest,
+			err:         errCall,
+			expected:    time.Minute,
+			expectedErr: errCall,
+		},
+		{
+			name:        "timeout error",
+			options:     []func(*client){WithTimeout(time.Minute)},
+			method:      MethodGetBacktest,
+			err:         &Error{Kind: ErrTimeout, Message: "timeout", Err: errCall},
+			expected:    time.Minute,
+			expectedErr: &TimeoutError{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &client{timeouts: newTimeouts()}
+			for _, opt := range tt.options {
+				opt(c)
+			}
+
+			ctx := context.Background()
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+
+			_, err := c.timeouts.intercept(ctx, CallInfo{Service: ServiceBacktests, Method: tt.method}, nil,
+				func(ctx context.Context, _ any) (any, error) {
+					deadline, hasDeadline := ctx.Deadline()
+					timeout, hasTimeout := ctx.Value(timeoutKey{}).(time.Duration)
+					if tt.expected == 0 {
+						require.Equal(t, tt.deadline > 0, hasDeadline)
+						require.False(t, hasTimeout)
+						return nil, tt.err
+					}
+
+					require.True(t, hasDeadline)
+					require.InDelta(t, tt.expected, time.Until(deadline), float64(time.Second))
+					require.True(t, hasTimeout)
+					require.InDelta(t, tt.expected, timeout, float64(time.Second))
+					return nil, tt.err
+				})
+
+			var te *TimeoutError
+			switch {
+			case tt.expectedErr == nil:
+				require.NoError(t, err)
+			case errors.As(tt.expectedErr, &te):
+				require.ErrorAs(t, err, &te)
+				require.ErrorIs(t, err, ErrTimeout)
+				require.ErrorIs(t, err, tt.err)
+				require.Equal(t, tt.method, te.Method)
+				require.InDelta(t, tt.expected, te.Timeout, float64(time.Second))
+			default:
+				require.Equal(t, tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestTimeoutsDeadlineExceeded(t *testing.T) {
+	c := &client{timeouts: newTimeouts()}
+	WithTimeout(10 * time.Millisecond)(c)
+
+	_, err := c.timeouts.intercept(context.Background(), CallInfo{Method: MethodGetBacktest}, nil,
+		func(ctx context.Context, _ any) (any, error) {
+			<-ctx.Done()
+			return nil, MapError(ctx.Err())
+		})
+
+	var te *TimeoutError
+	require.ErrorAs(t, err, &te)
+	require.Equal(t, MethodGetBacktest, te.Method)
+	require.Equal(t, 10*time.Millisecond, te.Timeout)
+	require.ErrorIs(t, err, ErrTimeout)
+}
+
+// executeWorkflowRecorder is an outbound interceptor recording the options
+// of the workflows executed.
+type executeWorkflowRecorder struct {
+	interceptor.ClientOutboundInterceptorBase
+	options *temporalclient.StartWorkflowOptions
+}
+
+func (r *executeWorkflowRecorder) ExecuteWorkflow(
+	_ context.Context,
+	in *interceptor.ClientExecuteWorkflowInput,
+) (temporalclient.WorkflowRun, error) {
+	r.options = in.Options
+	return nil, nil
+}
+
+func TestTimeoutsExecuteWorkflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		options  temporalclient.StartWorkflowOptions
+		expected time.Duration
+	}{
+		{name: "no timeout"},
+		{name: "timeout", timeout: time.Minute, expected: time.Minute},
+		{
+			name:     "already set",
+			timeout:  time.Minute,
+			options:  temporalclient.StartWorkflowOptions{WorkflowExecutionTimeout: time.Hour},
+			expected: time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				ctx = context.WithValue(ctx, timeoutKey{}, tt.timeout)
+			}
+
+			recorder := &executeWorkflowRecorder{}
+			options := tt.options
+			_, err := newTimeouts().InterceptClient(recorder).ExecuteWorkflow(ctx,
+				&interceptor.ClientExecuteWorkflowInput{Options: &options})
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, recorder.options.WorkflowExecutionTimeout)
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	backtestsapi "github.com/cryptellation/backtests/api"
+	candlesticksapi "github.com/cryptellation/candlesticks/api"
+	exchangesapi "github.com/cryptellation/exchanges/api"
+	forwardtestsapi "github.com/cryptellation/forwardtests/api"
+	smaapi "github.com/cryptellation/sma/api"
+	ticksapi "github.com/cryptellation/ticks/api"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	// ErrInvalidVersion is returned when a version can not be parsed.
+	ErrInvalidVersion = errors.New("invalid version")
+	// ErrIncompatibleVersion is returned when a service version is not
+	// compatible with this client.
+	ErrIncompatibleVersion = errors.New("incompatible service version")
+)
+
+// developmentVersion is the version reported by the services built without version.
+const developmentVersion = "devel"
+
+var versionRegexp = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(.*)$`)
+
+// Version is the version of a service.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+	// Suffix is what follows the patch number (pre-release, build, commit, etc.).
+	Suffix string
+	// Development is true if the service has been built without version.
+	Development bool
+}
+
+// ParseVersion parses a version as reported by the services info
+// (e.g. "v1.2.3", "1.2.3-rc1" or "devel").
+func ParseVersion(s string) (Version, error) {
+	s = strings.TrimSpace(s)
+	if s == developmentVersion || strings.HasPrefix(s, developmentVersion+"-") {
+		return Version{Development: true, Suffix: strings.TrimPrefix(s, developmentVersion)}, nil
+	}
+
+	m := versionRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, fmt.Errorf("%w: %q", ErrInvalidVersion, s)
+	}
+
+	var v Version
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	v.Patch, _ = strconv.Atoi(m[3])
+	v.Suffix = m[4]
+	return v, nil
+}
+
+// MustParseVersion parses a version and panics if it is invalid.
+func MustParseVersion(s string) Version {
+	v, err := ParseVersion(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// String returns the string representation of the version.
+func (v Version) String() string {
+	if v.Development {
+		return developmentVersion + v.Suffix
+	}
+	return fmt.Sprintf("v%d.%d.%d%s", v.Major, v.Minor, v.Patch, v.Suffix)
+}
+
+// Compare returns -1, 0 or 1 if the version is lower, equal or greater than
+// the other version, ignoring the suffixes.
+func (v Version) Compare(other Version) int {
+	switch {
+	case v.Major != other.Major:
+		return sign(v.Major - other.Major)
+	case v.Minor != other.Minor:
+		return sign(v.Minor - other.Minor)
+	default:
+		return sign(v.Patch - other.Patch)
+	}
+}
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// VersionRange is a range of versions, from Min (included) to Max (excluded).
+type VersionRange struct {
+	Min Version
+	Max Version
+}
+
+// Contains returns true if the version is in the range.
+func (r VersionRange) Contains(v Version) bool {
+	return v.Compare(r.Min) >= 0 && v.Compare(r.Max) < 0
+}
+
+// String returns the string representation of the range.
+func (r VersionRange) String() string {
+	return fmt.Sprintf(">=%s, <%s", r.Min, r.Max)
+}
+
+// CompatibilityTable is the range of versions of each service known to be
+// compatible with this client: from the versions of the APIs it has been
+// built against (see go.mod) to the next major versions.
+var CompatibilityTable = map[Service]VersionRange{
+	ServiceBacktests:    {Min: MustParseVersion("v1.2.4"), Max: MustParseVersion("v2.0.0")},
+	ServiceCandlesticks: {Min: MustParseVersion("v1.1.0"), Max: MustParseVersion("v2.0.0")},
+	ServiceExchanges:    {Min: MustParseVersion("v1.2.0"), Max: MustParseVersion("v2.0.0")},
+	ServiceForwardtests: {Min: MustParseVersion("v1.2.0"), Max: MustParseVersion("v2.0.0")},
+	ServiceSMA:          {Min: MustParseVersion("v1.1.0"), Max: MustParseVersion("v2.0.0")},
+	ServiceTicks:        {Min: MustParseVersion("v1.3.1"), Max: MustParseVersion("v2.0.0")},
+}
+
+// ServiceCompatibility is the compatibility of a deployed service with this client.
+type ServiceCompatibility struct {
+	Service    Service
+	Version    Version
+	Required   VersionRange
+	Compatible bool
+	// Reason explains why the service is not compatible, or why its
+	// compatibility can not be guaranteed (development version).
+	Reason string
+}
+
+// IncompatibleError is returned when some services are not compatible with this client.
+// It matches ErrIncompatibleVersion with errors.Is.
+type IncompatibleError struct {
+	Services []ServiceCompatibility
+}
+
+// Error returns the error message.
+func (e *IncompatibleError) Error() string {
+	reasons := make([]string, len(e.Services))
+	for i, s := range e.Services {
+		reasons[i] = fmt.Sprintf("%s: %s", s.Service, s.Reason)
+	}
+	return fmt.Sprintf("%s: %s", ErrIncompatibleVersion, strings.Join(reasons, "; "))
+}
+
+// Unwrap returns ErrIncompatibleVersion.
+func (e *IncompatibleError) Unwrap() error {
+	return ErrIncompatibleVersion
+}
+
+// ServicesVersions retrieves the versions of the given services (all if none).
+func (c *client) ServicesVersions(ctx context.Context, services ...Service) (map[Service]Version, error) {
+	if len(services) == 0 {
+		services = Services
+	}
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	res := make(map[Service]Version, len(services))
+	var mu sync.Mutex
+	for _, service := range services {
+		eg.Go(func() error {
+			info, err := c.info(egCtx, service)
+			if err != nil {
+				return err
+			}
+
+			v, err := ParseVersion(infoVersion(info))
+			if err != nil {
+				return fmt.Errorf("%s: %w", service, err)
+			}
+
+			mu.Lock()
+			res[service] = v
+			mu.Unlock()
+			return nil
+		})
+	}
+
+	return res, eg.Wait()
+}
+
+func infoVersion(info any) string {
+	switch i := info.(type) {
+	case backtestsapi.ServiceInfoResults:
+		return i.Version
+	case candlesticksapi.ServiceInfoResults:
+		return i.Version
+	case exchangesapi.ServiceInfoResults:
+		return i.Version
+	case forwardtestsapi.ServiceInfoResults:
+		return i.Version
+	case smaapi.ServiceInfoResults:
+		return i.Version
+	case ticksapi.ServiceInfoResults:
+		return i.Version
+	default:
+		return ""
+	}
+}
+
+// CheckCompatibility checks that the versions of the given services (all if
+// none) are in the compatibility table. It returns the compatibility of each
+// service, and an *IncompatibleError if some are not compatible.
+func (c *client) CheckCompatibility(ctx context.Context, services ...Service) ([]ServiceCompatibility, error) {
+	versions, err := c.ServicesVersions(ctx, services...)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		res          = make([]ServiceCompatibility, 0, len(versions))
+		incompatible []ServiceCompatibility
+	)
+	for _, service := range Services {
+		v, ok := versions[service]
+		if !ok {
+			continue
+		}
+
+		sc := checkCompatibility(service, v)
+		res = append(res, sc)
+		if !sc.Compatible {
+			incompatible = append(incompatible, sc)
+		}
+	}
+
+	if len(incompatible) > 0 {
+		return res, &IncompatibleError{Services: incompatible}
+	}
+	return res, nil
+}
+
+func checkCompatibility(service Service, v Version) ServiceCompatibility {
+	sc := ServiceCompatibility{
+		Service:    service,
+		Version:    v,
+		Required:   CompatibilityTable[service],
+		Compatible: true,
+	}
+
+	switch {
+	case v.Development:
+		sc.Reason = "development version, compatibility can not be checked"
+	case v.Compare(sc.Required.Min) < 0:
+		sc.Compatible = false
+		sc.Reason = fmt.Sprintf("version %s is older than required (%s)", v, sc.Required)
+	case !sc.Required.Contains(v):
+		sc.Compatible = false
+		sc.Reason = fmt.Sprintf("version %s is newer than supported (%s)", v, sc.Required)
+	}
+
+	return sc
+}
+
+// CompatibilityCheck is the behavior of the compatibility check made on client creation.
+type CompatibilityCheck int
+
+const (
+	// CompatibilityCheckDisabled disables the compatibility check on client creation.
+	CompatibilityCheckDisabled CompatibilityCheck = iota
+	// CompatibilityCheckWarn logs a warning for the incompatible or unreachable services.
+	CompatibilityCheckWarn
+	// CompatibilityCheckFail makes the client creation fail if a service is
+	// incompatible or unreachable.
+	CompatibilityCheckFail
+)
+
+// compatibilityCheckTimeout is the maximum duration of the compatibility check
+// made on client creation.
+const compatibilityCheckTimeout = 10 * time.Second
+
+// WithCompatibilityCheck checks the compatibility of the given services (all
+// if none) with this client on its creation.
+func WithCompatibilityCheck(check CompatibilityCheck, services ...Service) func(*client) {
+	return func(c *client) {
+		c.compatibility.check = check
+		c.compatibility.services = services
+	}
+}
+
+// checkCompatibilityOnCreation checks the compatibility of the services as
+// configured with WithCompatibilityCheck.
+func (c *client) checkCompatibilityOnCreation() error {
+	if c.compatibility.check == CompatibilityCheckDisabled {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), compatibilityCheckTimeout)
+	defer cancel()
+
+	res, err := c.CheckCompatibility(ctx, c.compatibility.services...)
+	for _, sc := range res {
+		if sc.Reason != "" {
+			c.temporal.logger.Warn("Service compatibility",
+				"service", sc.Service, "version", sc.Version, "required", sc.Required, "reason", sc.Reason)
+		}
+	}
+
+	if err != nil && c.compatibility.check == CompatibilityCheckFail {
+		return err
+	} else if err != nil {
+		c.temporal.logger.Warn("Service compatibility check failed", "error", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	backtestsapi "github.com/cryptellation/backtests/api"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	temporalclient "go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/mocks"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Version
+		str      string
+		err      error
+	}{
+		{input: "v1.2.3", expected: Version{Major: 1, Minor: 2, Patch: 3}, str: "v1.2.3"},
+		{input: "1.2.3", expected: Version{Major: 1, Minor: 2, Patch: 3}, str: "v1.2.3"},
+		{input: " v10.20.30 ", expected: Version{Major: 10, Minor: 20, Patch: 30}, str: "v10.20.30"},
+		{input: "v1.2.3-rc1", expected: Version{Major: 1, Minor: 2, Patch: 3, Suffix: "-rc1"}, str: "v1.2.3-rc1"},
+		{input: "devel", expected: Version{Development: true}, str: "devel"},
+		{input: "devel-abc", expected: Version{Development: true, Suffix: "-abc"}, str: "devel-abc"},
+		{input: "v1.2", err: ErrInvalidVersion},
+		{input: "", err: ErrInvalidVersion},
+		{input: "latest", err: ErrInvalidVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			v, err := ParseVersion(tt.input)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				require.Panics(t, func() { MustParseVersion(tt.input) })
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, v)
+			require.Equal(t, tt.str, v.String())
+		})
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	tests := []struct {
+		v, other string
+		expected int
+	}{
+		{v: "v1.2.3", other: "v1.2.3", expected: 0},
+		{v: "v1.2.3-rc1", other: "v1.2.3", expected: 0},
+		{v: "v1.2.3", other: "v1.2.4", expected: -1},
+		{v: "v1.3.0", other: "v1.2.9", expected: 1},
+		{v: "v2.0.0", other: "v1.9.9", expected: 1},
+		{v: "v0.9.9", other: "v1.0.0", expected: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.v+" "+tt.other, func(t *testing.T) {
+			require.Equal(t, tt.expected, MustParseVersion(tt.v).Compare(MustParseVersion(tt.other)))
+			require.Equal(t, -tt.expected, MustParseVersion(tt.other).Compare(MustParseVersion(tt.v)))
+		})
+	}
+}
+
+func TestVersionRange(t *testing.T) {
+	r := VersionRange{Min: MustParseVersion("v1.2.0"), Max: MustParseVersion("v2.0.0")}
+	require.Equal(t, ">=v1.2.0, <v2.0.0", r.String())
+
+	tests := []struct {
+		version  string
+		expected bool
+	}{
+		{version: "v1.1.9", expected: false},
+		{version: "v1.2.0", expected: true},
+		{version: "v1.9.9", expected: true},
+		{version: "v2.0.0", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			require.Equal(t, tt.expected, r.Contains(MustParseVersion(tt.version)))
+		})
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	required := CompatibilityTable[ServiceBacktests]
+
+	tests := []struct {
+		name       string
+		version    Version
+		compatible bool
+		reason     bool
+	}{
+		{name: "minimum", version: required.Min, compatible: true},
+		{name: "newer minor", version: Version{Major: required.Min.Major, Minor: required.Min.Minor + 1}, compatible: true},
+		{name: "older", version: Version{Major: 0, Minor: 1}, reason: true},
+		{name: "next major", version: required.Max, reason: true},
+		{name: "development", version: Version{Development: true}, compatible: true, reason: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := checkCompatibility(ServiceBacktests, tt.version)
+			require.Equal(t, ServiceBacktests, sc.Service)
+			require.Equal(t, required, sc.Required)
+			require.Equal(t, tt.compatible, sc.Compatible)
+			require.Equal(t, tt.reason, sc.Reason != "")
+		})
+	}
+}
+
+// infoClient returns a temporal client whose backtests service reports the version.
+func infoClient(version string) *mocks.Client {
+	run := &mocks.WorkflowRun{}
+	run.On("Get", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*backtestsapi.ServiceInfoResults).Version = version
+	})
+
+	temporal := &mocks.Client{}
+	temporal.On("ExecuteWorkflow", mock.Anything, onTaskQueue(backtestsapi.WorkerTaskQueueName),
+		backtestsapi.ServiceInfoWorkflowName).Return(temporalclient.WorkflowRun(run), nil)
+	return temporal
+}
+
+func TestCompatibilityCheckOnCreation(t *testing.T) {
+	tests := []struct {
+		name    string
+		check   CompatibilityCheck
+		version string
+		err     error
+	}{
+		{name: "compatible", check: CompatibilityCheckFail, version: "v1.9.0"},
+		{name: "incompatible", check: CompatibilityCheckFail, version: "v0.1.0", err: ErrIncompatibleVersion},
+		{name: "invalid", check: CompatibilityCheckFail, version: "latest", err: ErrInvalidVersion},
+		{name: "incompatible warning", check: CompatibilityCheckWarn, version: "v0.1.0"},
+		{name: "disabled", check: CompatibilityCheckDisabled, version: "v0.1.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(WithTemporalClient(infoClient(tt.version)),
+				WithCompatibilityCheck(tt.check, ServiceBacktests))
+			if tt.err == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestServicesVersions(t *testing.T) {
+	cl, err := New(WithTemporalClient(infoClient("v1.2.5")))
+	require.NoError(t, err)
+
+	versions, err := cl.ServicesVersions(context.Background(), ServiceBacktests)
+	require.NoError(t, err)
+	require.Equal(t, map[Service]Version{ServiceBacktests: MustParseVersion("v1.2.5")}, versions)
+
+	res, err := cl.CheckCompatibility(context.Background(), ServiceBacktests)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.True(t, res[0].Compatible)
+}
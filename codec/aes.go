@@ -0,0 +1,126 @@
+package codec
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+	"google.golang.org/protobuf/proto"
+)
+
+// EncryptionEncoding is the encoding of the payloads encrypted with AES-GCM.
+const EncryptionEncoding = "binary/encrypted"
+
+// MetadataEncryptionKeyID is the payload metadata holding the ID of the key
+// used to encrypt it.
+const MetadataEncryptionKeyID = "encryption-key-id"
+
+var (
+	// ErrUnknownKey is returned when a payload has been encrypted with a key
+	// that is not known by the codec.
+	ErrUnknownKey = errors.New("unknown encryption key")
+)
+
+// AESGCMOptions are the options of the AES-GCM codec.
+type AESGCMOptions struct {
+	// KeyID is the ID of the key used to encrypt the payloads.
+	KeyID string
+	// Keys are the AES keys (16, 24 or 32 bytes) by ID. Keys other than the
+	// one of KeyID are only used to decrypt, which allows key rotation.
+	Keys map[string][]byte
+}
+
+type aesGCMCodec struct {
+	keyID string
+	aeads map[string]cipher.AEAD
+}
+
+// NewAESGCMCodec returns a codec encrypting the payloads with AES-GCM.
+func NewAESGCMCodec(options AESGCMOptions) (converter.PayloadCodec, error) {
+	if _, ok := options.Keys[options.KeyID]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, options.KeyID)
+	}
+
+	aeads := make(map[string]cipher.AEAD, len(options.Keys))
+	for id, key := range options.Keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		aeads[id] = aead
+	}
+
+	return &aesGCMCodec{
+		keyID: options.KeyID,
+		aeads: aeads,
+	}, nil
+}
+
+// Encode encrypts the payloads.
+func (c *aesGCMCodec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	aead := c.aeads[c.keyID]
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		b, err := proto.Marshal(p)
+		if err != nil {
+			return payloads, err
+		}
+
+		// Prepend the random nonce to the encrypted data
+		nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(b)+aead.Overhead())
+		if _, err := rand.Read(nonce); err != nil {
+			return payloads, err
+		}
+
+		result[i] = &commonpb.Payload{
+			Metadata: map[string][]byte{
+				converter.MetadataEncoding: []byte(EncryptionEncoding),
+				MetadataEncryptionKeyID:    []byte(c.keyID),
+			},
+			Data: aead.Seal(nonce, nonce, b, nil),
+		}
+	}
+	return result, nil
+}
+
+// Decode decrypts the payloads encrypted by the codec.
+func (c *aesGCMCodec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		if string(p.GetMetadata()[converter.MetadataEncoding]) != EncryptionEncoding {
+			result[i] = p
+			continue
+		}
+
+		keyID := string(p.GetMetadata()[MetadataEncryptionKeyID])
+		aead, ok := c.aeads[keyID]
+		if !ok {
+			return payloads, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+		}
+
+		data := p.GetData()
+		if len(data) < aead.NonceSize() {
+			return payloads, errors.New("encrypted payload too short")
+		}
+
+		b, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+		if err != nil {
+			return payloads, err
+		}
+
+		result[i] = &commonpb.Payload{}
+		if err := proto.Unmarshal(b, result[i]); err != nil {
+			return payloads, err
+		}
+	}
+	return result, nil
+}
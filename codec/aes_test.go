@@ -0,0 +1,184 @@
+package codec
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	oldKey = bytes.Repeat([]byte{1}, 32)
+	newKey = bytes.Repeat([]byte{2}, 32)
+)
+
+func TestNewAESGCMCodec(t *testing.T) {
+	tests := []struct {
+		name    string
+		options AESGCMOptions
+		fails   bool
+		err     error
+	}{
+		{
+			name:    "AES-128",
+			options: AESGCMOptions{KeyID: "k", Keys: map[string][]byte{"k": bytes.Repeat([]byte{1}, 16)}},
+		},
+		{
+			name:    "AES-256 with rotated keys",
+			options: AESGCMOptions{KeyID: "new", Keys: map[string][]byte{"old": oldKey, "new": newKey}},
+		},
+		{
+			name:    "unknown key ID",
+			options: AESGCMOptions{KeyID: "new", Keys: map[string][]byte{"old": oldKey}},
+			err:     ErrUnknownKey,
+		},
+		{
+			name:    "no key",
+			options: AESGCMOptions{KeyID: "new"},
+			err:     ErrUnknownKey,
+		},
+		{
+			name:    "invalid key size",
+			options: AESGCMOptions{KeyID: "k", Keys: map[string][]byte{"k": []byte("short")}},
+			fails:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAESGCMCodec(tt.options)
+			switch {
+			case tt.err != nil:
+				require.ErrorIs(t, err, tt.err)
+			case tt.fails:
+				require.Error(t, err)
+			default:
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAESGCMCodecKeyRotation(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoder AESGCMOptions
+		decoder AESGCMOptions
+		fails   bool
+		err     error
+	}{
+		{
+			name:    "same key",
+			encoder: AESGCMOptions{KeyID: "old", Keys: map[string][]byte{"old": oldKey}},
+			decoder: AESGCMOptions{KeyID: "old", Keys: map[string][]byte{"old": oldKey}},
+		},
+		{
+			name:    "decoded after rotation",
+			encoder: AESGCMOptions{KeyID: "old", Keys: map[string][]byte{"old": oldKey}},
+			decoder: AESGCMOptions{KeyID: "new", Keys: map[string][]byte{"old": oldKey, "new": newKey}},
+		},
+		{
+			name:    "decoded before rotation",
+			encoder: AESGCMOptions{KeyID: "new", Keys: map[string][]byte{"old": oldKey, "new": newKey}},
+			decoder: AESGCMOptions{KeyID: "old", Keys: map[string][]byte{"old": oldKey}},
+			err:     ErrUnknownKey,
+		},
+		{
+			name:    "old key removed",
+			encoder: AESGCMOptions{KeyID: "old", Keys: map[string][]byte{"old": oldKey}},
+			decoder: AESGCMOptions{KeyID: "new", Keys: map[string][]byte{"new": newKey}},
+			err:     ErrUnknownKey,
+		},
+		{
+			name:    "key replaced under the same ID",
+			encoder: AESGCMOptions{KeyID: "k", Keys: map[string][]byte{"k": oldKey}},
+			decoder: AESGCMOptions{KeyID: "k", Keys: map[string][]byte{"k": newKey}},
+			fails:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoder, err := NewAESGCMCodec(tt.encoder)
+			require.NoError(t, err)
+			decoder, err := NewAESGCMCodec(tt.decoder)
+			require.NoError(t, err)
+
+			p := payload(t, "cryptellation")
+			encoded, err := encoder.Encode([]*commonpb.Payload{p})
+			require.NoError(t, err)
+			require.Equal(t, EncryptionEncoding, string(encoded[0].GetMetadata()[converter.MetadataEncoding]))
+			require.Equal(t, tt.encoder.KeyID, string(encoded[0].GetMetadata()[MetadataEncryptionKeyID]))
+			require.NotContains(t, string(encoded[0].GetData()), "cryptellation")
+
+			decoded, err := decoder.Decode(encoded)
+			switch {
+			case tt.err != nil:
+				require.ErrorIs(t, err, tt.err)
+			case tt.fails:
+				require.Error(t, err)
+			default:
+				require.NoError(t, err)
+				require.True(t, proto.Equal(p, decoded[0]))
+			}
+		})
+	}
+}
+
+func TestAESGCMCodecDecode(t *testing.T) {
+	c, err := NewAESGCMCodec(AESGCMOptions{KeyID: "k", Keys: map[string][]byte{"k": oldKey}})
+	require.NoError(t, err)
+	encoded, err := c.Encode([]*commonpb.Payload{payload(t, "cryptellation")})
+	require.NoError(t, err)
+
+	encrypted := func(data []byte) *commonpb.Payload {
+		return &commonpb.Payload{
+			Metadata: map[string][]byte{
+				converter.MetadataEncoding: []byte(EncryptionEncoding),
+				MetadataEncryptionKeyID:    []byte("k"),
+			},
+			Data: data,
+		}
+	}
+	tampered := bytes.Clone(encoded[0].GetData())
+	tampered[len(tampered)-1] ^= 0xff
+
+	tests := []struct {
+		name    string
+		payload *commonpb.Payload
+		err     bool
+	}{
+		{name: "encrypted payload", payload: encoded[0]},
+		{name: "payload of another encoding", payload: payload(t, "cryptellation")},
+		{name: "tampered payload", payload: encrypted(tampered), err: true},
+		{name: "payload too short", payload: encrypted([]byte("short")), err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := c.Decode([]*commonpb.Payload{tt.payload})
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var value string
+			require.NoError(t, converter.GetDefaultDataConverter().FromPayload(decoded[0], &value))
+			require.Equal(t, "cryptellation", value)
+		})
+	}
+}
+
+func TestAESGCMCodecNonce(t *testing.T) {
+	c, err := NewAESGCMCodec(AESGCMOptions{KeyID: "k", Keys: map[string][]byte{"k": oldKey}})
+	require.NoError(t, err)
+
+	p := payload(t, "cryptellation")
+	encoded, err := c.Encode([]*commonpb.Payload{p, p})
+	require.NoError(t, err)
+	require.NotEqual(t, encoded[0].GetData(), encoded[1].GetData())
+}
@@ -0,0 +1,96 @@
+package codec
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"go.temporal.io/sdk/converter"
+)
+
+var (
+	// ErrNoAuthorizer is returned when a codec server handler is created
+	// without authorizer.
+	ErrNoAuthorizer = errors.New("codec server requires an authorizer")
+	// ErrUnauthorized is returned by the authorizers when a request is not
+	// authorized.
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+// Authorizer authorizes the requests to the codec server. It returns an error
+// if the request must be rejected.
+type Authorizer func(r *http.Request) error
+
+// BearerTokenAuthorizer returns an authorizer accepting the requests with one
+// of the given tokens in their Authorization header (e.g. "Bearer <token>"),
+// as sent by the Temporal UI and CLI when configured with an access token.
+func BearerTokenAuthorizer(tokens ...string) Authorizer {
+	return func(r *http.Request) error {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			return ErrUnauthorized
+		}
+
+		for _, t := range tokens {
+			if t != "" && subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+				return nil
+			}
+		}
+		return ErrUnauthorized
+	}
+}
+
+// NewDataConverter returns a data converter encoding the payloads with the
+// given codecs. As in Temporal, the first codec is the outermost: to compress
+// then encrypt the payloads, the encryption codec comes first.
+//
+// The same data converter must be used by every client and worker of the
+// Cryptellation stack, including the services workers.
+func NewDataConverter(codecs ...converter.PayloadCodec) converter.DataConverter {
+	return converter.NewCodecDataConverter(converter.GetDefaultDataConverter(), codecs...)
+}
+
+// NewHTTPHandler returns a codec server handler decoding and encoding the
+// payloads with the given codecs, in the same order as NewDataConverter, so
+// the Temporal UI or CLI can display them.
+//
+// Anyone allowed by the authorizer can decode every payload, which defeats
+// their encryption: the authorizer is mandatory and the handler must never be
+// exposed without a real authentication in front of it.
+// Requests from the given origins (e.g. the Temporal UI address) are allowed
+// by CORS.
+func NewHTTPHandler(
+	codecs []converter.PayloadCodec,
+	authorizer Authorizer,
+	allowedOrigins ...string,
+) (http.Handler, error) {
+	if authorizer == nil {
+		return nil, ErrNoAuthorizer
+	}
+	handler := converter.NewPayloadCodecHTTPHandler(codecs...)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" && slices.Contains(allowedOrigins, origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization,Content-Type,X-Namespace")
+			w.Header().Set("Access-Control-Allow-Methods", "POST,OPTIONS")
+		}
+
+		// Answer the CORS preflight requests, that carry no credentials
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if err := authorizer(r); err != nil {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	}), nil
+}
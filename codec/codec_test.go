@@ -0,0 +1,206 @@
+package codec
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestDataConverterRoundTrip(t *testing.T) {
+	zstdCodec, err := NewZstdCodec(ZstdOptions{})
+	require.NoError(t, err)
+	aesCodec, err := NewAESGCMCodec(AESGCMOptions{KeyID: "k", Keys: map[string][]byte{"k": oldKey}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		codecs   []converter.PayloadCodec
+		value    string
+		encoding string
+	}{
+		{
+			name:     "without codec",
+			value:    "cryptellation",
+			encoding: converter.MetadataEncodingJSON,
+		},
+		{
+			name:     "compressed",
+			codecs:   []converter.PayloadCodec{zstdCodec},
+			value:    strings.Repeat("cryptellation", 100),
+			encoding: ZstdEncoding,
+		},
+		{
+			name:     "compressed then encrypted",
+			codecs:   []converter.PayloadCodec{aesCodec, zstdCodec},
+			value:    strings.Repeat("cryptellation", 100),
+			encoding: EncryptionEncoding,
+		},
+		{
+			name:     "encrypted small payload",
+			codecs:   []converter.PayloadCodec{aesCodec, zstdCodec},
+			value:    "cryptellation",
+			encoding: EncryptionEncoding,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dc := NewDataConverter(tt.codecs...)
+
+			p, err := dc.ToPayload(tt.value)
+			require.NoError(t, err)
+			require.Equal(t, tt.encoding, string(p.GetMetadata()[converter.MetadataEncoding]))
+
+			var value string
+			require.NoError(t, dc.FromPayload(p, &value))
+			require.Equal(t, tt.value, value)
+		})
+	}
+}
+
+func TestBearerTokenAuthorizer(t *testing.T) {
+	authorizer := BearerTokenAuthorizer("token", "")
+
+	tests := []struct {
+		name   string
+		header string
+		err    error
+	}{
+		{name: "valid token", header: "Bearer token"},
+		{name: "invalid token", header: "Bearer other", err: ErrUnauthorized},
+		{name: "empty token", header: "Bearer ", err: ErrUnauthorized},
+		{name: "other scheme", header: "Basic token", err: ErrUnauthorized},
+		{name: "no header", err: ErrUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/encode", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			require.ErrorIs(t, authorizer(r), tt.err)
+		})
+	}
+}
+
+func TestNewHTTPHandler(t *testing.T) {
+	_, err := NewHTTPHandler(nil, nil)
+	require.ErrorIs(t, err, ErrNoAuthorizer)
+
+	aesCodec, err := NewAESGCMCodec(AESGCMOptions{KeyID: "k", Keys: map[string][]byte{"k": oldKey}})
+	require.NoError(t, err)
+	handler, err := NewHTTPHandler(
+		[]converter.PayloadCodec{aesCodec},
+		BearerTokenAuthorizer("token"),
+		"http://localhost:8233")
+	require.NoError(t, err)
+
+	body, err := protojson.Marshal(&commonpb.Payloads{Payloads: []*commonpb.Payload{payload(t, "cryptellation")}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		origin string
+		token  string
+		status int
+		cors   bool
+	}{
+		{
+			name:   "authorized",
+			method: http.MethodPost,
+			path:   "/encode",
+			token:  "token",
+			status: http.StatusOK,
+		},
+		{
+			name:   "unauthorized",
+			method: http.MethodPost,
+			path:   "/encode",
+			token:  "other",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "preflight from allowed origin",
+			method: http.MethodOptions,
+			path:   "/decode",
+			origin: "http://localhost:8233",
+			status: http.StatusNoContent,
+			cors:   true,
+		},
+		{
+			name:   "preflight from other origin",
+			method: http.MethodOptions,
+			path:   "/decode",
+			origin: "http://example.com",
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "unauthorized from allowed origin",
+			method: http.MethodPost,
+			path:   "/decode",
+			origin: "http://localhost:8233",
+			status: http.StatusUnauthorized,
+			cors:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(string(body)))
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			require.Equal(t, tt.status, w.Code)
+			if tt.cors {
+				require.Equal(t, tt.origin, w.Header().Get("Access-Control-Allow-Origin"))
+			} else {
+				require.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+			}
+		})
+	}
+}
+
+func TestHTTPHandlerRoundTrip(t *testing.T) {
+	aesCodec, err := NewAESGCMCodec(AESGCMOptions{KeyID: "k", Keys: map[string][]byte{"k": oldKey}})
+	require.NoError(t, err)
+	handler, err := NewHTTPHandler([]converter.PayloadCodec{aesCodec}, BearerTokenAuthorizer("token"))
+	require.NoError(t, err)
+
+	call := func(path string, payloads *commonpb.Payloads) *commonpb.Payloads {
+		body, err := protojson.Marshal(payloads)
+		require.NoError(t, err)
+
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(string(body)))
+		r.Header.Set("Authorization", "Bearer token")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res commonpb.Payloads
+		require.NoError(t, protojson.Unmarshal(w.Body.Bytes(), &res))
+		return &res
+	}
+
+	encoded := call("/encode", &commonpb.Payloads{Payloads: []*commonpb.Payload{payload(t, "cryptellation")}})
+	require.Equal(t, EncryptionEncoding, string(encoded.Payloads[0].GetMetadata()[converter.MetadataEncoding]))
+
+	decoded := call("/decode", encoded)
+	var value string
+	require.NoError(t, converter.GetDefaultDataConverter().FromPayload(decoded.Payloads[0], &value))
+	require.Equal(t, "cryptellation", value)
+}
@@ -0,0 +1,115 @@
+package codec
+
+import (
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+	"google.golang.org/protobuf/proto"
+)
+
+// ZstdEncoding is the encoding of the payloads compressed with zstd.
+const ZstdEncoding = "binary/zstd"
+
+// defaultMaxDecodedSize is the default maximum size of a decompressed payload.
+const defaultMaxDecodedSize = 16 << 20
+
+// ZstdOptions are the options of the zstd codec.
+type ZstdOptions struct {
+	// MinSize is the minimum size of the payloads to compress, as smaller
+	// ones are not worth it. Defaults to 256 bytes.
+	MinSize int
+	// Level is the compression level. Defaults to zstd.SpeedDefault.
+	Level zstd.EncoderLevel
+	// MaxDecodedSize is the maximum size of a decompressed payload, so a small
+	// payload can not make the codec allocate gigabytes. Payloads above it
+	// are rejected. Defaults to 16 MiB.
+	MaxDecodedSize uint64
+}
+
+type zstdCodec struct {
+	options ZstdOptions
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// NewZstdCodec returns a codec compressing the payloads with zstd. Payloads
+// are only compressed if it reduces their size.
+func NewZstdCodec(options ZstdOptions) (converter.PayloadCodec, error) {
+	if options.MinSize == 0 {
+		options.MinSize = 256
+	}
+	if options.Level == 0 {
+		options.Level = zstd.SpeedDefault
+	}
+	if options.MaxDecodedSize == 0 {
+		options.MaxDecodedSize = defaultMaxDecodedSize
+	}
+
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(options.Level))
+	if err != nil {
+		return nil, err
+	}
+
+	decoder, err := zstd.NewReader(nil,
+		zstd.WithDecoderMaxMemory(options.MaxDecodedSize),
+		zstd.WithDecoderMaxWindow(max(options.MaxDecodedSize, zstd.MinWindowSize)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &zstdCodec{
+		options: options,
+		encoder: encoder,
+		decoder: decoder,
+	}, nil
+}
+
+// Encode compresses the payloads.
+func (c *zstdCodec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		b, err := proto.Marshal(p)
+		if err != nil {
+			return payloads, err
+		}
+
+		// Only compress if it is worth it
+		result[i] = p
+		if len(b) < c.options.MinSize {
+			continue
+		}
+
+		compressed := c.encoder.EncodeAll(b, nil)
+		if len(compressed) < len(b) {
+			result[i] = &commonpb.Payload{
+				Metadata: map[string][]byte{converter.MetadataEncoding: []byte(ZstdEncoding)},
+				Data:     compressed,
+			}
+		}
+	}
+	return result, nil
+}
+
+// Decode decompresses the payloads compressed by the codec.
+func (c *zstdCodec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		if string(p.GetMetadata()[converter.MetadataEncoding]) != ZstdEncoding {
+			result[i] = p
+			continue
+		}
+
+		b, err := c.decoder.DecodeAll(p.GetData(), nil)
+		if err != nil {
+			return payloads, fmt.Errorf("decompressing payload: %w", err)
+		}
+
+		result[i] = &commonpb.Payload{}
+		if err := proto.Unmarshal(b, result[i]); err != nil {
+			return payloads, err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,133 @@
+package codec
+
+import (
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+	"google.golang.org/protobuf/proto"
+)
+
+// payload returns the payload of the value with the default data converter.
+func payload(t *testing.T, value any) *commonpb.Payload {
+	p, err := converter.GetDefaultDataConverter().ToPayload(value)
+	require.NoError(t, err)
+	return p
+}
+
+// randomBytes returns n random bytes, that can not be compressed.
+func randomBytes(t *testing.T, n int) []byte {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	return b
+}
+
+func TestZstdCodecRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    ZstdOptions
+		payload    *commonpb.Payload
+		compressed bool
+	}{
+		{
+			name:       "compressible payload",
+			payload:    payload(t, strings.Repeat("cryptellation", 100)),
+			compressed: true,
+		},
+		{
+			name:    "small payload",
+			payload: payload(t, "cryptellation"),
+		},
+		{
+			name:    "payload smaller than the minimum size",
+			options: ZstdOptions{MinSize: 4096},
+			payload: payload(t, strings.Repeat("cryptellation", 100)),
+		},
+		{
+			name:    "incompressible payload",
+			payload: payload(t, randomBytes(t, 2048)),
+		},
+		{
+			name:    "empty payload",
+			payload: &commonpb.Payload{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewZstdCodec(tt.options)
+			require.NoError(t, err)
+
+			encoded, err := c.Encode([]*commonpb.Payload{tt.payload})
+			require.NoError(t, err)
+			require.Len(t, encoded, 1)
+			isCompressed := string(encoded[0].GetMetadata()[converter.MetadataEncoding]) == ZstdEncoding
+			require.Equal(t, tt.compressed, isCompressed)
+			if tt.compressed {
+				require.Less(t, proto.Size(encoded[0]), proto.Size(tt.payload))
+			}
+
+			decoded, err := c.Decode(encoded)
+			require.NoError(t, err)
+			require.Len(t, decoded, 1)
+			require.True(t, proto.Equal(tt.payload, decoded[0]))
+		})
+	}
+}
+
+func TestZstdCodecDecode(t *testing.T) {
+	encoder, err := NewZstdCodec(ZstdOptions{})
+	require.NoError(t, err)
+	encoded, err := encoder.Encode([]*commonpb.Payload{payload(t, strings.Repeat("cryptellation", 1000))})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		options ZstdOptions
+		payload *commonpb.Payload
+		err     bool
+	}{
+		{
+			name:    "compressed payload",
+			payload: encoded[0],
+		},
+		{
+			name:    "payload of another encoding",
+			payload: payload(t, "cryptellation"),
+		},
+		{
+			name:    "payload above the maximum decoded size",
+			options: ZstdOptions{MaxDecodedSize: 1024},
+			payload: encoded[0],
+			err:     true,
+		},
+		{
+			name: "corrupted payload",
+			payload: &commonpb.Payload{
+				Metadata: map[string][]byte{converter.MetadataEncoding: []byte(ZstdEncoding)},
+				Data:     []byte("not zstd"),
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewZstdCodec(tt.options)
+			require.NoError(t, err)
+
+			decoded, err := c.Decode([]*commonpb.Payload{tt.payload})
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, decoded, 1)
+			require.NotEqual(t, ZstdEncoding, string(decoded[0].GetMetadata()[converter.MetadataEncoding]))
+		})
+	}
+}
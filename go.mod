@@ -3,6 +3,7 @@ module github.com/cryptellation/go-clients
 go 1.23.8
 
 require (
+	github.com/coder/websocket v1.8.15
 	github.com/cryptellation/backtests v1.2.4
 	github.com/cryptellation/candlesticks v1.1.0
 	github.com/cryptellation/exchanges v1.2.0
@@ -10,13 +11,18 @@ require (
 	github.com/cryptellation/runtime v1.8.1
 	github.com/cryptellation/sma v1.1.0
 	github.com/cryptellation/ticks v1.3.1
+	github.com/cryptellation/timeseries v1.2.0
 	github.com/google/uuid v1.6.0
+	github.com/klauspost/compress v1.18.0
+	github.com/stretchr/testify v1.10.0
+	go.temporal.io/api v1.50.0
 	go.temporal.io/sdk v1.34.0
 	golang.org/x/sync v0.15.0
+	golang.org/x/time v0.12.0
+	google.golang.org/protobuf v1.36.6
 )
 
 require (
-	github.com/cryptellation/timeseries v1.2.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/facebookgo/clock v0.0.0-20150410010913-600d898af40a // indirect
 	github.com/gogo/protobuf v1.3.2 // indirect
@@ -29,15 +35,11 @@ require (
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/robfig/cron v1.2.0 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
-	github.com/stretchr/testify v1.10.0 // indirect
-	go.temporal.io/api v1.50.0 // indirect
 	golang.org/x/net v0.41.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.26.0 // indirect
-	golang.org/x/time v0.12.0 // indirect
 	google.golang.org/genproto/googleapis/api v0.0.0-20250603155806-513f23925822 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250603155806-513f23925822 // indirect
 	google.golang.org/grpc v1.73.0 // indirect
-	google.golang.org/protobuf v1.36.6 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,182 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	nethttp "net/http"
+
+	backtestsapi "github.com/cryptellation/backtests/api"
+	backtestsclient "github.com/cryptellation/backtests/pkg/clients"
+	forwardtestsapi "github.com/cryptellation/forwardtests/api"
+	forwardtestsclient "github.com/cryptellation/forwardtests/pkg/clients"
+	"github.com/cryptellation/go-clients/client"
+	"github.com/google/uuid"
+)
+
+// AsyncResponse is the response of the operations started asynchronously.
+// Its IDs are used to get the status of the operation and its results.
+type AsyncResponse struct {
+	WorkflowID string `json:"workflow_id"`
+	RunID      string `json:"run_id"`
+	Status     string `json:"status"`
+	// ID is the ID of the created backtest or forwardtest, once completed.
+	ID *uuid.UUID `json:"id,omitempty"`
+	// Error is the error of a failed operation.
+	Error string `json:"error,omitempty"`
+}
+
+// writeAccepted writes the response of an operation started asynchronously,
+// with the location of its status.
+func writeAccepted(w nethttp.ResponseWriter, location, workflowID, runID string) {
+	w.Header().Set("Location", fmt.Sprintf("%s/%s/%s", location, workflowID, runID))
+	writeJSON(w, nethttp.StatusAccepted, AsyncResponse{
+		WorkflowID: workflowID,
+		RunID:      runID,
+		Status:     client.WorkflowStatusRunning.String(),
+	})
+}
+
+// writeAsyncStatus writes the status of an operation started asynchronously,
+// with the ID returned by id once completed, if not nil.
+func writeAsyncStatus[R any](
+	ctx context.Context,
+	w nethttp.ResponseWriter,
+	h client.WorkflowHandle[R],
+	id func(R) uuid.UUID,
+) {
+	status, err := h.Status(ctx)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	res := AsyncResponse{
+		WorkflowID: h.WorkflowID,
+		RunID:      h.RunID,
+		Status:     status.String(),
+	}
+
+	// Get the results of the operations that are done
+	if status.Done() && (id != nil || status != client.WorkflowStatusCompleted) {
+		r, err := h.Get(ctx)
+		switch {
+		case err != nil:
+			res.Error = err.Error()
+		case id != nil:
+			resID := id(r)
+			res.ID = &resID
+		}
+	}
+
+	writeJSON(w, nethttp.StatusOK, res)
+}
+
+// createBacktestAsync starts the creation of a backtest.
+func (s *Server) createBacktestAsync(w nethttp.ResponseWriter, r *nethttp.Request) {
+	var req CreateBacktestRequest
+	if err := readJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(req.Metadata) > 0 {
+		writeError(w, fmt.Errorf("%w: metadata: not supported on asynchronous creations", client.ErrInvalidParams))
+		return
+	}
+
+	params, err := req.toParams()
+	if err != nil {
+		writeError(w, fmt.Errorf("%w: %w", client.ErrInvalidParams, err))
+		return
+	}
+
+	h, err := s.client.NewBacktestAsync(creationContext(r, nil), params)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeAccepted(w, "/async/backtests", h.WorkflowID, h.RunID)
+}
+
+// getBacktestAsync serves the status of a backtest creation.
+func (s *Server) getBacktestAsync(w nethttp.ResponseWriter, r *nethttp.Request) {
+	h := s.client.AttachNewBacktest(r.PathValue("workflow_id"), r.PathValue("run_id"))
+	writeAsyncStatus(r.Context(), w, h, func(bt backtestsclient.Backtest) uuid.UUID {
+		return bt.ID
+	})
+}
+
+// runBacktest starts the run of a backtest until its end.
+func (s *Server) runBacktest(w nethttp.ResponseWriter, r *nethttp.Request) {
+	id, err := pathID(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Check the backtest exists before starting its run
+	if _, err := s.client.GetBacktest(r.Context(), backtestsapi.GetBacktestWorkflowParams{BacktestID: id}); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	h, err := s.client.RunBacktestAsync(creationContext(r, nil), backtestsapi.RunBacktestWorkflowParams{BacktestID: id})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeAccepted(w, "/async/runs", h.WorkflowID, h.RunID)
+}
+
+// getBacktestRunAsync serves the status of a backtest run.
+func (s *Server) getBacktestRunAsync(w nethttp.ResponseWriter, r *nethttp.Request) {
+	h := s.client.AttachRunBacktest(r.PathValue("workflow_id"), r.PathValue("run_id"))
+	writeAsyncStatus[backtestsapi.RunBacktestWorkflowResults](r.Context(), w, h, nil)
+}
+
+// createForwardtestAsync starts the creation of a forwardtest.
+func (s *Server) createForwardtestAsync(w nethttp.ResponseWriter, r *nethttp.Request) {
+	var req CreateForwardtestRequest
+	if err := readJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(req.Metadata) > 0 {
+		writeError(w, fmt.Errorf("%w: metadata: not supported on asynchronous creations", client.ErrInvalidParams))
+		return
+	}
+
+	cbs, err := req.Callbacks.toRuntime()
+	if err != nil {
+		writeError(w, fmt.Errorf("%w: %w", client.ErrInvalidParams, err))
+		return
+	}
+
+	h, err := s.client.NewForwardtestAsync(creationContext(r, nil), forwardtestsapi.CreateForwardtestWorkflowParams{
+		Accounts:  req.Accounts,
+		Callbacks: cbs,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeAccepted(w, "/async/forwardtests", h.WorkflowID, h.RunID)
+}
+
+// getForwardtestAsync serves the status of a forwardtest creation.
+func (s *Server) getForwardtestAsync(w nethttp.ResponseWriter, r *nethttp.Request) {
+	h := s.client.AttachNewForwardtest(r.PathValue("workflow_id"), r.PathValue("run_id"))
+	writeAsyncStatus(r.Context(), w, h, func(ft forwardtestsclient.Forwardtest) uuid.UUID {
+		return ft.ID
+	})
+}
+
+// cancelAsync requests the cancellation of an operation started asynchronously.
+func (s *Server) cancelAsync(w nethttp.ResponseWriter, r *nethttp.Request) {
+	// Any handle can cancel the workflow, whatever its results
+	h := s.client.AttachRunBacktest(r.PathValue("workflow_id"), r.PathValue("run_id"))
+	if err := h.Cancel(r.Context()); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(nethttp.StatusNoContent)
+}
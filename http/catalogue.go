@@ -0,0 +1,112 @@
+package http
+
+import (
+	"context"
+	nethttp "net/http"
+
+	"github.com/cryptellation/exchanges/pkg/exchange"
+)
+
+// CatalogueExchange is the JSON representation of an exchange of the
+// catalogue, with its fees.
+type CatalogueExchange struct {
+	Exchange
+	MakerFee float64 `json:"maker_fee"`
+	TakerFee float64 `json:"taker_fee"`
+}
+
+// SupportsResponse is the response of the catalogue lookups, set for the
+// pair and period given in the query.
+type SupportsResponse struct {
+	Pair   *bool `json:"pair,omitempty"`
+	Period *bool `json:"period,omitempty"`
+}
+
+// catalogueExchange returns the JSON representation of an exchange of the catalogue.
+func (s *Server) catalogueExchange(ctx context.Context, exch exchange.Exchange) (CatalogueExchange, error) {
+	fees, err := s.client.Catalogue().Fees(ctx, exch.Name)
+	if err != nil {
+		return CatalogueExchange{}, err
+	}
+
+	return CatalogueExchange{
+		Exchange: Exchange{
+			Name:         exch.Name,
+			Periods:      exch.Periods,
+			Pairs:        exch.Pairs,
+			Fees:         exch.Fees,
+			LastSyncTime: exch.LastSyncTime,
+		},
+		MakerFee: fees.Maker,
+		TakerFee: fees.Taker,
+	}, nil
+}
+
+// listCatalogueExchanges serves the exchanges of the catalogue, sorted by name.
+func (s *Server) listCatalogueExchanges(w nethttp.ResponseWriter, r *nethttp.Request) {
+	list, err := s.client.Catalogue().Exchanges(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	res := make([]CatalogueExchange, len(list))
+	for i, exch := range list {
+		if res[i], err = s.catalogueExchange(r.Context(), exch); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	writeJSON(w, nethttp.StatusOK, res)
+}
+
+// getCatalogueExchange serves an exchange of the catalogue.
+func (s *Server) getCatalogueExchange(w nethttp.ResponseWriter, r *nethttp.Request) {
+	exch, err := s.client.Catalogue().Exchange(r.Context(), r.PathValue("name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	res, err := s.catalogueExchange(r.Context(), exch)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, nethttp.StatusOK, res)
+}
+
+// getCatalogueSupports serves whether the pair and period of the query are
+// supported by an exchange of the catalogue.
+func (s *Server) getCatalogueSupports(w nethttp.ResponseWriter, r *nethttp.Request) {
+	name := r.PathValue("name")
+	q := newQuery(r.URL.Query())
+	pair := q.String("pair", false)
+	per := q.Period("period", false)
+	if pair == "" && per == "" {
+		q.fail("pair", "is required without period")
+	}
+	if err := q.Err(); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var res SupportsResponse
+	if pair != "" {
+		ok, err := s.client.Catalogue().SupportsPair(r.Context(), name, pair)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		res.Pair = &ok
+	}
+	if per != "" {
+		ok, err := s.client.Catalogue().SupportsPeriod(r.Context(), name, per)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		res.Period = &ok
+	}
+	writeJSON(w, nethttp.StatusOK, res)
+}
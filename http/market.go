@@ -0,0 +1,157 @@
+package http
+
+import (
+	nethttp "net/http"
+	"time"
+
+	candlesticksapi "github.com/cryptellation/candlesticks/api"
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
+	exchangesapi "github.com/cryptellation/exchanges/api"
+	"github.com/cryptellation/go-clients/resample"
+	smaapi "github.com/cryptellation/sma/api"
+)
+
+// Exchange is the JSON representation of an exchange.
+type Exchange struct {
+	Name         string    `json:"name"`
+	Periods      []string  `json:"periods"`
+	Pairs        []string  `json:"pairs"`
+	Fees         float64   `json:"fees"`
+	LastSyncTime time.Time `json:"last_sync_time"`
+}
+
+// ListExchangesResponse is the response of the exchanges list.
+type ListExchangesResponse struct {
+	Exchanges []string `json:"exchanges"`
+}
+
+// ListCandlesticksResponse is the response of the candlesticks list.
+type ListCandlesticksResponse struct {
+	Exchange     string                    `json:"exchange"`
+	Pair         string                    `json:"pair"`
+	Period       string                    `json:"period"`
+	Candlesticks []candlestick.Candlestick `json:"candlesticks"`
+}
+
+// SMAPoint is the JSON representation of a simple moving average point.
+type SMAPoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// ListSMAResponse is the response of the SMA list.
+type ListSMAResponse struct {
+	Points []SMAPoint `json:"points"`
+}
+
+// listExchanges serves the list of the exchanges.
+func (s *Server) listExchanges(w nethttp.ResponseWriter, r *nethttp.Request) {
+	res, err := s.client.ListExchanges(r.Context(), exchangesapi.ListExchangesWorkflowParams{})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, nethttp.StatusOK, ListExchangesResponse{Exchanges: res.List})
+}
+
+// getExchange serves an exchange.
+func (s *Server) getExchange(w nethttp.ResponseWriter, r *nethttp.Request) {
+	res, err := s.client.GetExchange(r.Context(), exchangesapi.GetExchangeWorkflowParams{
+		Name: r.PathValue("name"),
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, nethttp.StatusOK, Exchange{
+		Name:         res.Exchange.Name,
+		Periods:      res.Exchange.Periods,
+		Pairs:        res.Exchange.Pairs,
+		Fees:         res.Exchange.Fees,
+		LastSyncTime: res.Exchange.LastSyncTime,
+	})
+}
+
+// listCandlesticks serves candlesticks, resampled if a resample duration is given.
+func (s *Server) listCandlesticks(w nethttp.ResponseWriter, r *nethttp.Request) {
+	q := newQuery(r.URL.Query())
+	params := candlesticksapi.ListCandlesticksWorkflowParams{
+		Exchange: q.String("exchange", true),
+		Pair:     q.String("pair", true),
+		Period:   q.Period("period", true),
+		Start:    q.Time("start", false),
+		End:      q.Time("end", false),
+		Limit:    uint(q.Int("limit", false, 0)), //nolint:gosec // Checked as positive
+	}
+	to := q.Duration("resample", false)
+	if err := q.Err(); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Get candlesticks
+	var (
+		list []candlestick.Candlestick
+		err  error
+		per  = params.Period.String()
+	)
+	if to == 0 {
+		var res candlesticksapi.ListCandlesticksWorkflowResults
+		res, err = s.client.ListCandlesticks(r.Context(), params)
+		list = res.List
+	} else {
+		list, err = s.client.ListResampledCandlesticks(r.Context(), params, to, resample.WithoutUncomplete())
+		per = to.String()
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, nethttp.StatusOK, ListCandlesticksResponse{
+		Exchange:     params.Exchange,
+		Pair:         params.Pair,
+		Period:       per,
+		Candlesticks: list,
+	})
+}
+
+// listSMA serves simple moving averages.
+func (s *Server) listSMA(w nethttp.ResponseWriter, r *nethttp.Request) {
+	q := newQuery(r.URL.Query())
+	params := smaapi.ListWorkflowParams{
+		Exchange:     q.String("exchange", true),
+		Pair:         q.String("pair", true),
+		Period:       q.Period("period", true),
+		PeriodNumber: q.Int("period_number", true, 1),
+		PriceType:    candlestick.PriceType(q.String("price_type", false)),
+	}
+	if start := q.Time("start", true); start != nil {
+		params.Start = *start
+	}
+	if end := q.Time("end", true); end != nil {
+		params.End = *end
+	}
+	if params.PriceType == "" {
+		params.PriceType = candlestick.PriceTypeIsClose
+	} else if err := params.PriceType.Validate(); err != nil {
+		q.fail("price_type", "must be one of open, high, low, close")
+	}
+	if err := q.Err(); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	res, err := s.client.ListSMA(r.Context(), params)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	points := make([]SMAPoint, len(res.Data))
+	for i, p := range res.Data {
+		points[i] = SMAPoint{Time: p.Time, Value: p.Value}
+	}
+	writeJSON(w, nethttp.StatusOK, ListSMAResponse{Points: points})
+}
@@ -0,0 +1,17 @@
+package http
+
+import (
+	_ "embed"
+	nethttp "net/http"
+)
+
+// OpenAPI is the OpenAPI specification of the gateway.
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+// getOpenAPI serves the OpenAPI specification of the gateway.
+func (s *Server) getOpenAPI(w nethttp.ResponseWriter, _ *nethttp.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(OpenAPI)
+}
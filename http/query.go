@@ -0,0 +1,192 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cryptellation/candlesticks/pkg/period"
+	"github.com/cryptellation/go-clients/client"
+	"github.com/google/uuid"
+)
+
+// query parses and validates the query parameters, collecting the errors.
+type query struct {
+	values url.Values
+	errs   []error
+}
+
+func newQuery(values url.Values) *query {
+	return &query{values: values}
+}
+
+func (q *query) fail(name, format string, args ...any) {
+	q.errs = append(q.errs, fmt.Errorf("%s: %s", name, fmt.Sprintf(format, args...)))
+}
+
+// get returns the raw value of the parameter, or an error if it is required and missing.
+func (q *query) get(name string, required bool) string {
+	v := q.values.Get(name)
+	if v == "" && required {
+		q.fail(name, "is required")
+	}
+	return v
+}
+
+func (q *query) String(name string, required bool) string {
+	return q.get(name, required)
+}
+
+func (q *query) Time(name string, required bool) *time.Time {
+	v := q.get(name, required)
+	if v == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		q.fail(name, "must be a RFC3339 time")
+		return nil
+	}
+	return &t
+}
+
+func (q *query) Period(name string, required bool) period.Symbol {
+	v := period.Symbol(q.get(name, required))
+	if v == "" {
+		return v
+	}
+
+	if err := v.Validate(); err != nil {
+		q.fail(name, "must be a valid period (M1, M5, H1, D1, etc.)")
+	}
+	return v
+}
+
+func (q *query) Int(name string, required bool, minimum int) int {
+	v := q.get(name, required)
+	if v == "" {
+		return 0
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil || i < minimum {
+		q.fail(name, "must be an integer greater or equal to %d", minimum)
+		return 0
+	}
+	return i
+}
+
+func (q *query) Duration(name string, required bool) time.Duration {
+	v := q.get(name, required)
+	if v == "" {
+		return 0
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		q.fail(name, "must be a positive duration (e.g. 1h, 4h, 24h)")
+		return 0
+	}
+	return d
+}
+
+func (q *query) Bool(name string) bool {
+	v := q.get(name, false)
+	if v == "" {
+		return false
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		q.fail(name, "must be a boolean")
+		return false
+	}
+	return b
+}
+
+// Err returns the validation errors, if any.
+func (q *query) Err() error {
+	if len(q.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", client.ErrInvalidParams, errors.Join(q.errs...))
+}
+
+// runQuery parses the query of the backtests and forwardtests lists.
+func runQuery(values url.Values) (client.Query, error) {
+	q := newQuery(values)
+	rq := client.NewQuery()
+
+	for _, status := range values["status"] {
+		switch s := client.RunStatus(status); s {
+		case client.RunStatusReady, client.RunStatusRunning, client.RunStatusFinished:
+			rq = rq.WithStatus(s)
+		default:
+			q.fail("status", "must be one of ready, running or finished")
+		}
+	}
+
+	from, to := q.Time("from", false), q.Time("to", false)
+	if from != nil || to != nil {
+		var f, t time.Time
+		if from != nil {
+			f = *from
+		}
+		if to != nil {
+			t = *to
+		}
+		rq = rq.StartedBetween(f, t)
+	}
+
+	if exchange := q.String("exchange", false); exchange != "" {
+		rq = rq.OnExchange(exchange)
+	}
+	if pair := q.String("pair", false); pair != "" {
+		rq = rq.OnPair(pair)
+	}
+	if strategy := q.String("strategy", false); strategy != "" {
+		rq = rq.WithStrategy(strategy)
+	}
+	for _, tag := range values["tag"] {
+		key, value, ok := strings.Cut(tag, ":")
+		if !ok || key == "" {
+			q.fail("tag", "must be a key:value pair")
+			continue
+		}
+		rq = rq.WithTag(key, value)
+	}
+	if q.Bool("metadata") {
+		rq = rq.IncludeMetadata()
+	}
+
+	if field := client.SortField(q.String("sort", false)); field != "" {
+		switch field {
+		case client.SortByStartTime, client.SortByEndTime, client.SortByStrategy:
+			rq = rq.SortBy(field, q.Bool("ascending"))
+		default:
+			q.fail("sort", "must be one of start_time, end_time or strategy")
+		}
+	}
+
+	if limit := q.Int("limit", false, 0); limit > 0 {
+		rq = rq.Limit(limit)
+	}
+	if cursor := q.String("cursor", false); cursor != "" {
+		rq = rq.After(cursor)
+	}
+
+	return rq, q.Err()
+}
+
+// pathID parses the ID in the path of the request.
+func pathID(value string) (uuid.UUID, error) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: id: must be a UUID", client.ErrInvalidParams)
+	}
+	return id, nil
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	nethttp "net/http"
+
+	"github.com/cryptellation/go-clients/client"
+)
+
+// maxBodySize is the maximum size of the request bodies.
+const maxBodySize = 1 << 20
+
+// errBodyTooLarge is returned when a request body is larger than maxBodySize.
+var errBodyTooLarge = fmt.Errorf("request body larger than %d bytes", maxBodySize)
+
+// ErrorResponse is the body of the error responses.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w nethttp.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes the error with the status corresponding to its kind.
+func writeError(w nethttp.ResponseWriter, err error) {
+	writeJSON(w, errorStatus(err), ErrorResponse{Error: err.Error()})
+}
+
+// writeUnauthorized writes the error of a request rejected by the authorizer.
+func writeUnauthorized(w nethttp.ResponseWriter, err error) {
+	status := nethttp.StatusUnauthorized
+	if errors.Is(err, client.ErrPermissionDenied) {
+		status = nethttp.StatusForbidden
+	}
+	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, errBodyTooLarge):
+		return nethttp.StatusRequestEntityTooLarge
+	case errors.Is(err, client.ErrInvalidParams):
+		return nethttp.StatusBadRequest
+	case errors.Is(err, client.ErrNotFound):
+		return nethttp.StatusNotFound
+	case errors.Is(err, client.ErrAlreadyExists):
+		return nethttp.StatusConflict
+	case errors.Is(err, client.ErrPermissionDenied):
+		return nethttp.StatusForbidden
+	case errors.Is(err, client.ErrNotImplemented):
+		return nethttp.StatusNotImplemented
+	case errors.Is(err, client.ErrUnavailable):
+		return nethttp.StatusServiceUnavailable
+	case errors.Is(err, client.ErrTimeout):
+		return nethttp.StatusGatewayTimeout
+	default:
+		return nethttp.StatusInternalServerError
+	}
+}
+
+// readJSON decodes the body of the request, rejecting unknown fields and the
+// bodies larger than maxBodySize.
+func readJSON(w nethttp.ResponseWriter, r *nethttp.Request, body any) error {
+	dec := json.NewDecoder(nethttp.MaxBytesReader(w, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+
+	var maxBytes *nethttp.MaxBytesError
+	err := dec.Decode(body)
+	switch {
+	case errors.As(err, &maxBytes):
+		return errBodyTooLarge
+	case err != nil:
+		return fmt.Errorf("%w: invalid body: %w", client.ErrInvalidParams, err)
+	default:
+		return nil
+	}
+}
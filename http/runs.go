@@ -0,0 +1,457 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	nethttp "net/http"
+	"time"
+
+	backtestsapi "github.com/cryptellation/backtests/api"
+	"github.com/cryptellation/backtests/pkg/backtest"
+	"github.com/cryptellation/candlesticks/pkg/period"
+	forwardtestsapi "github.com/cryptellation/forwardtests/api"
+	forwardtestsclient "github.com/cryptellation/forwardtests/pkg/clients"
+	"github.com/cryptellation/go-clients/client"
+	"github.com/cryptellation/runtime"
+	"github.com/cryptellation/runtime/account"
+	"github.com/cryptellation/runtime/order"
+	"github.com/google/uuid"
+)
+
+// IdempotencyKeyHeader is the header holding the idempotency key of the
+// creation requests (see client.WithIdempotencyKey).
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Callback is the JSON representation of a callback workflow.
+type Callback struct {
+	Name             string `json:"name"`
+	TaskQueue        string `json:"task_queue"`
+	ExecutionTimeout string `json:"execution_timeout,omitempty"`
+}
+
+func (c Callback) toRuntime(name string) (runtime.CallbackWorkflow, error) {
+	cw := runtime.CallbackWorkflow{
+		Name:          c.Name,
+		TaskQueueName: c.TaskQueue,
+	}
+
+	if c.ExecutionTimeout != "" {
+		d, err := time.ParseDuration(c.ExecutionTimeout)
+		if err != nil {
+			return cw, fmt.Errorf("%s: execution_timeout: must be a duration", name)
+		}
+		cw.ExecutionTimeout = d
+	}
+
+	if err := cw.Validate(); err != nil {
+		return cw, fmt.Errorf("%s: %w", name, err)
+	}
+	return cw, nil
+}
+
+// Callbacks is the JSON representation of the callbacks of a run.
+type Callbacks struct {
+	OnInit      Callback `json:"on_init"`
+	OnNewPrices Callback `json:"on_new_prices"`
+	OnExit      Callback `json:"on_exit"`
+}
+
+func (c Callbacks) toRuntime() (cbs runtime.Callbacks, err error) {
+	if cbs.OnInitCallback, err = c.OnInit.toRuntime("on_init"); err != nil {
+		return cbs, err
+	}
+	if cbs.OnNewPricesCallback, err = c.OnNewPrices.toRuntime("on_new_prices"); err != nil {
+		return cbs, err
+	}
+	if cbs.OnExitCallback, err = c.OnExit.toRuntime("on_exit"); err != nil {
+		return cbs, err
+	}
+	return cbs, nil
+}
+
+// CreateBacktestRequest is the request to create a backtest.
+type CreateBacktestRequest struct {
+	Accounts    map[string]account.Account `json:"accounts"`
+	StartTime   time.Time                  `json:"start_time"`
+	EndTime     *time.Time                 `json:"end_time,omitempty"`
+	Mode        string                     `json:"mode,omitempty"`
+	PricePeriod string                     `json:"price_period,omitempty"`
+	Callbacks   Callbacks                  `json:"callbacks"`
+	Metadata    client.Metadata            `json:"metadata,omitempty"`
+}
+
+func (req CreateBacktestRequest) toParams() (client.NewBacktestParams, error) {
+	params := client.NewBacktestParams{
+		Parameters: backtest.Parameters{
+			Accounts:  req.Accounts,
+			StartTime: req.StartTime,
+			EndTime:   req.EndTime,
+		},
+	}
+
+	if req.Mode != "" {
+		mode := backtest.Mode(req.Mode)
+		if err := mode.Validate(); err != nil {
+			return params, fmt.Errorf("mode: %w", err)
+		}
+		params.Parameters.Mode = &mode
+	}
+
+	if req.PricePeriod != "" {
+		per := period.Symbol(req.PricePeriod)
+		if err := per.Validate(); err != nil {
+			return params, fmt.Errorf("price_period: %w", err)
+		}
+		params.Parameters.PricePeriod = &per
+	}
+
+	cbs, err := req.Callbacks.toRuntime()
+	params.Callbacks = cbs
+	return params, err
+}
+
+// Backtest is the JSON representation of a backtest.
+type Backtest struct {
+	ID       uuid.UUID       `json:"id"`
+	Metadata client.Metadata `json:"metadata,omitempty"`
+	// MetadataError is the error saving the metadata of a created backtest.
+	MetadataError string `json:"metadata_error,omitempty"`
+}
+
+// CreateForwardtestRequest is the request to create a forwardtest.
+type CreateForwardtestRequest struct {
+	Accounts  map[string]account.Account `json:"accounts"`
+	Callbacks Callbacks                  `json:"callbacks"`
+	Metadata  client.Metadata            `json:"metadata,omitempty"`
+}
+
+// Forwardtest is the JSON representation of a forwardtest.
+type Forwardtest struct {
+	ID        uuid.UUID                  `json:"id"`
+	UpdatedAt *time.Time                 `json:"updated_at,omitempty"`
+	Accounts  map[string]account.Account `json:"accounts,omitempty"`
+	Orders    []order.Order              `json:"orders,omitempty"`
+	Status    string                     `json:"status,omitempty"`
+	Metadata  client.Metadata            `json:"metadata,omitempty"`
+	// MetadataError is the error saving the metadata of a created forwardtest.
+	MetadataError string `json:"metadata_error,omitempty"`
+}
+
+// BalanceResponse is the response of the forwardtest balance.
+type BalanceResponse struct {
+	Balance float64 `json:"balance"`
+}
+
+// creationContext adds the idempotency key of the request and the metadata
+// of the created run to the context, if any.
+func creationContext(r *nethttp.Request, md client.Metadata) context.Context {
+	ctx := r.Context()
+	if key := r.Header.Get(IdempotencyKeyHeader); key != "" {
+		ctx = client.WithIdempotencyKey(ctx, key)
+	}
+	if len(md) > 0 {
+		ctx = client.WithMetadata(ctx, md)
+	}
+	return ctx
+}
+
+// listBacktests serves the list of the backtests.
+func (s *Server) listBacktests(w nethttp.ResponseWriter, r *nethttp.Request) {
+	list, err := s.client.ListBacktestsWithMetadata(r.Context(), backtestsapi.ListBacktestsWorkflowParams{})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	res := make([]Backtest, len(list))
+	for i, bt := range list {
+		res[i] = Backtest{ID: bt.ID, Metadata: bt.Metadata}
+	}
+	writeJSON(w, nethttp.StatusOK, res)
+}
+
+// QueryBacktestsResponse is the response of the backtests query.
+type QueryBacktestsResponse struct {
+	Backtests []backtest.Backtest `json:"backtests"`
+	// Metadata are the metadata of the backtests that have some, when requested.
+	Metadata   map[uuid.UUID]client.Metadata `json:"metadata,omitempty"`
+	NextCursor string                        `json:"next_cursor,omitempty"`
+}
+
+// queryBacktests serves the backtests matching the query.
+func (s *Server) queryBacktests(w nethttp.ResponseWriter, r *nethttp.Request) {
+	q, err := runQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	page, err := s.client.QueryBacktests(r.Context(), q)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, nethttp.StatusOK, QueryBacktestsResponse{
+		Backtests:  page.Items,
+		Metadata:   page.Metadata,
+		NextCursor: page.NextCursor,
+	})
+}
+
+// createBacktest creates a backtest.
+func (s *Server) createBacktest(w nethttp.ResponseWriter, r *nethttp.Request) {
+	var req CreateBacktestRequest
+	if err := readJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	params, err := req.toParams()
+	if err != nil {
+		writeError(w, fmt.Errorf("%w: %w", client.ErrInvalidParams, err))
+		return
+	}
+
+	bt, err := s.client.NewBacktest(creationContext(r, req.Metadata), params.Parameters, params.Callbacks)
+	var mdErr *client.MetadataError
+	switch {
+	case errors.As(err, &mdErr):
+		writeJSON(w, nethttp.StatusCreated, Backtest{ID: bt.ID, MetadataError: mdErr.Error()})
+		return
+	case err != nil:
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, nethttp.StatusCreated, Backtest{ID: bt.ID, Metadata: req.Metadata})
+}
+
+// getBacktest serves a backtest.
+func (s *Server) getBacktest(w nethttp.ResponseWriter, r *nethttp.Request) {
+	id, err := pathID(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	bt, err := s.client.GetBacktestWithMetadata(r.Context(), backtestsapi.GetBacktestWorkflowParams{BacktestID: id})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, nethttp.StatusOK, Backtest{ID: bt.ID, Metadata: bt.Metadata})
+}
+
+// listForwardtests serves the list of the forwardtests.
+func (s *Server) listForwardtests(w nethttp.ResponseWriter, r *nethttp.Request) {
+	list, err := s.client.ListForwardtestsWithMetadata(r.Context(), forwardtestsapi.ListForwardtestsWorkflowParams{})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	res := make([]Forwardtest, len(list))
+	for i, ft := range list {
+		res[i] = Forwardtest{ID: ft.ID, Metadata: ft.Metadata}
+	}
+	writeJSON(w, nethttp.StatusOK, res)
+}
+
+// QueryForwardtestsResponse is the response of the forwardtests query.
+type QueryForwardtestsResponse struct {
+	Forwardtests []Forwardtest `json:"forwardtests"`
+	NextCursor   string        `json:"next_cursor,omitempty"`
+}
+
+// queryForwardtests serves the forwardtests matching the query.
+func (s *Server) queryForwardtests(w nethttp.ResponseWriter, r *nethttp.Request) {
+	q, err := runQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	page, err := s.client.QueryForwardtests(r.Context(), q)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	res := QueryForwardtestsResponse{
+		Forwardtests: make([]Forwardtest, len(page.Items)),
+		NextCursor:   page.NextCursor,
+	}
+	for i, ft := range page.Items {
+		res.Forwardtests[i] = Forwardtest{
+			ID:        ft.ID,
+			UpdatedAt: &ft.UpdatedAt,
+			Accounts:  ft.Accounts,
+			Orders:    ft.Orders,
+			Status:    ft.Status.String(),
+			Metadata:  page.Metadata[ft.ID],
+		}
+	}
+	writeJSON(w, nethttp.StatusOK, res)
+}
+
+// createForwardtest creates a forwardtest.
+func (s *Server) createForwardtest(w nethttp.ResponseWriter, r *nethttp.Request) {
+	var req CreateForwardtestRequest
+	if err := readJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	cbs, err := req.Callbacks.toRuntime()
+	if err != nil {
+		writeError(w, fmt.Errorf("%w: %w", client.ErrInvalidParams, err))
+		return
+	}
+
+	ft, err := s.client.NewForwardtest(creationContext(r, req.Metadata), forwardtestsapi.CreateForwardtestWorkflowParams{
+		Accounts:  req.Accounts,
+		Callbacks: cbs,
+	})
+	var mdErr *client.MetadataError
+	switch {
+	case errors.As(err, &mdErr):
+		writeJSON(w, nethttp.StatusCreated, Forwardtest{ID: ft.ID, MetadataError: mdErr.Error()})
+		return
+	case err != nil:
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, nethttp.StatusCreated, Forwardtest{ID: ft.ID, Metadata: req.Metadata})
+}
+
+// forwardtest returns the handle of the forwardtest whose ID is in the path.
+func (s *Server) forwardtest(r *nethttp.Request) (forwardtestsclient.Forwardtest, error) {
+	id, err := pathID(r.PathValue("id"))
+	if err != nil {
+		return forwardtestsclient.Forwardtest{}, err
+	}
+
+	return s.client.GetForwardtest(r.Context(), forwardtestsapi.GetForwardtestWorkflowParams{ForwardtestID: id})
+}
+
+// getForwardtest serves a forwardtest, with its metadata.
+func (s *Server) getForwardtest(w nethttp.ResponseWriter, r *nethttp.Request) {
+	id, err := pathID(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	handle, err := s.client.GetForwardtestWithMetadata(r.Context(),
+		forwardtestsapi.GetForwardtestWorkflowParams{ForwardtestID: id})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	ft, err := handle.Get(r.Context())
+	if err != nil {
+		writeError(w, client.MapError(err))
+		return
+	}
+
+	writeJSON(w, nethttp.StatusOK, Forwardtest{
+		ID:        ft.ID,
+		UpdatedAt: &ft.UpdatedAt,
+		Accounts:  ft.Accounts,
+		Orders:    ft.Orders,
+		Status:    ft.Status.String(),
+		Metadata:  handle.Metadata,
+	})
+}
+
+// listForwardtestAccounts serves the accounts of a forwardtest.
+func (s *Server) listForwardtestAccounts(w nethttp.ResponseWriter, r *nethttp.Request) {
+	ft, err := s.forwardtest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	accounts, err := ft.ListAccounts(r.Context())
+	if err != nil {
+		writeError(w, client.MapError(err))
+		return
+	}
+	writeJSON(w, nethttp.StatusOK, accounts)
+}
+
+// getForwardtestBalance serves the balance of a forwardtest.
+func (s *Server) getForwardtestBalance(w nethttp.ResponseWriter, r *nethttp.Request) {
+	ft, err := s.forwardtest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	balance, err := ft.GetBalance(r.Context())
+	if err != nil {
+		writeError(w, client.MapError(err))
+		return
+	}
+	writeJSON(w, nethttp.StatusOK, BalanceResponse{Balance: balance})
+}
+
+// createForwardtestOrder creates an order on a forwardtest.
+func (s *Server) createForwardtestOrder(w nethttp.ResponseWriter, r *nethttp.Request) {
+	ft, err := s.forwardtest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var o order.Order
+	if err := readJSON(w, r, &o); err != nil {
+		writeError(w, err)
+		return
+	}
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	if err := o.Validate(); err != nil {
+		writeError(w, fmt.Errorf("%w: %w", client.ErrInvalidParams, err))
+		return
+	}
+
+	if _, err := ft.CreateOrder(r.Context(), o); err != nil {
+		writeError(w, client.MapError(err))
+		return
+	}
+	writeJSON(w, nethttp.StatusCreated, o)
+}
+
+// runForwardtest starts a forwardtest.
+func (s *Server) runForwardtest(w nethttp.ResponseWriter, r *nethttp.Request) {
+	ft, err := s.forwardtest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := ft.Run(r.Context()); err != nil {
+		writeError(w, client.MapError(err))
+		return
+	}
+	w.WriteHeader(nethttp.StatusNoContent)
+}
+
+// stopForwardtest stops a forwardtest.
+func (s *Server) stopForwardtest(w nethttp.ResponseWriter, r *nethttp.Request) {
+	ft, err := s.forwardtest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := ft.Stop(r.Context()); err != nil {
+		writeError(w, client.MapError(err))
+		return
+	}
+	w.WriteHeader(nethttp.StatusNoContent)
+}
@@ -0,0 +1,213 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	nethttp "net/http"
+	"time"
+
+	"github.com/cryptellation/go-clients/client"
+	"github.com/cryptellation/go-clients/report"
+	"github.com/cryptellation/runtime/account"
+	"github.com/google/uuid"
+)
+
+// ScheduleTemplate is the JSON representation of the template of the
+// backtests created by a schedule.
+type ScheduleTemplate struct {
+	Accounts    map[string]account.Account `json:"accounts"`
+	Window      string                     `json:"window"`
+	Mode        string                     `json:"mode,omitempty"`
+	PricePeriod string                     `json:"price_period,omitempty"`
+	Callbacks   Callbacks                  `json:"callbacks"`
+	Metadata    client.Metadata            `json:"metadata,omitempty"`
+	QuoteAsset  string                     `json:"quote_asset,omitempty"`
+}
+
+func (t ScheduleTemplate) toTemplate() (client.BacktestTemplate, error) {
+	tmpl := client.BacktestTemplate{
+		Accounts:   t.Accounts,
+		Metadata:   t.Metadata,
+		QuoteAsset: t.QuoteAsset,
+	}
+
+	window, err := time.ParseDuration(t.Window)
+	if err != nil {
+		return tmpl, errors.New("window: must be a duration")
+	}
+	tmpl.Window = window
+
+	// Validate the backtest parameters as the backtests creations do
+	params, err := CreateBacktestRequest{
+		Mode:        t.Mode,
+		PricePeriod: t.PricePeriod,
+		Callbacks:   t.Callbacks,
+	}.toParams()
+	if err != nil {
+		return tmpl, err
+	}
+	tmpl.Mode = params.Parameters.Mode
+	tmpl.PricePeriod = params.Parameters.PricePeriod
+	tmpl.Callbacks = params.Callbacks
+	return tmpl, nil
+}
+
+// CreateScheduleRequest is the request to create a backtests schedule.
+type CreateScheduleRequest struct {
+	ID        string           `json:"id"`
+	Cron      []string         `json:"cron,omitempty"`
+	Every     string           `json:"every,omitempty"`
+	TimeZone  string           `json:"time_zone,omitempty"`
+	TaskQueue string           `json:"task_queue"`
+	Template  ScheduleTemplate `json:"template"`
+	Paused    bool             `json:"paused,omitempty"`
+	Note      string           `json:"note,omitempty"`
+}
+
+func (req CreateScheduleRequest) toSchedule() (client.BacktestSchedule, error) {
+	s := client.BacktestSchedule{
+		ID:        req.ID,
+		Cron:      req.Cron,
+		TimeZone:  req.TimeZone,
+		TaskQueue: req.TaskQueue,
+		Paused:    req.Paused,
+		Note:      req.Note,
+	}
+
+	if req.Every != "" {
+		every, err := time.ParseDuration(req.Every)
+		if err != nil {
+			return s, errors.New("every: must be a duration")
+		}
+		s.Every = every
+	}
+
+	tmpl, err := req.Template.toTemplate()
+	if err != nil {
+		return s, fmt.Errorf("template: %w", err)
+	}
+	s.Template = tmpl
+	return s, nil
+}
+
+// Schedule is the JSON representation of a backtests schedule.
+type Schedule struct {
+	ID       string      `json:"id"`
+	Paused   bool        `json:"paused"`
+	Note     string      `json:"note,omitempty"`
+	NextRuns []time.Time `json:"next_runs,omitempty"`
+	LastRuns []time.Time `json:"last_runs,omitempty"`
+}
+
+// ScheduledRun is the JSON representation of a run of a backtests schedule.
+type ScheduledRun struct {
+	WorkflowID string    `json:"workflow_id"`
+	RunID      string    `json:"run_id"`
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	// The following fields are only set if the run is completed.
+	BacktestID *uuid.UUID      `json:"backtest_id,omitempty"`
+	StartTime  *time.Time      `json:"start_time,omitempty"`
+	EndTime    *time.Time      `json:"end_time,omitempty"`
+	Summary    *report.Summary `json:"summary,omitempty"`
+	// Error is the error of a failed run.
+	Error string `json:"error,omitempty"`
+}
+
+// listSchedules serves the list of the backtests schedules.
+func (s *Server) listSchedules(w nethttp.ResponseWriter, r *nethttp.Request) {
+	list, err := s.client.ListBacktestSchedules(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	res := make([]Schedule, len(list))
+	for i, info := range list {
+		res[i] = Schedule{
+			ID:       info.ID,
+			Paused:   info.Paused,
+			Note:     info.Note,
+			NextRuns: info.NextRuns,
+			LastRuns: info.LastRuns,
+		}
+	}
+	writeJSON(w, nethttp.StatusOK, res)
+}
+
+// createSchedule creates a backtests schedule.
+func (s *Server) createSchedule(w nethttp.ResponseWriter, r *nethttp.Request) {
+	var req CreateScheduleRequest
+	if err := readJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	schedule, err := req.toSchedule()
+	if err != nil {
+		writeError(w, fmt.Errorf("%w: %w", client.ErrInvalidParams, err))
+		return
+	}
+
+	if err := s.client.CreateBacktestSchedule(r.Context(), schedule); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, nethttp.StatusCreated, Schedule{ID: schedule.ID, Paused: schedule.Paused, Note: schedule.Note})
+}
+
+// pauseSchedule pauses a backtests schedule, with the note of the query.
+func (s *Server) pauseSchedule(w nethttp.ResponseWriter, r *nethttp.Request) {
+	if err := s.client.PauseBacktestSchedule(r.Context(), r.PathValue("id"), r.URL.Query().Get("note")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(nethttp.StatusNoContent)
+}
+
+// unpauseSchedule unpauses a backtests schedule, with the note of the query.
+func (s *Server) unpauseSchedule(w nethttp.ResponseWriter, r *nethttp.Request) {
+	if err := s.client.UnpauseBacktestSchedule(r.Context(), r.PathValue("id"), r.URL.Query().Get("note")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(nethttp.StatusNoContent)
+}
+
+// deleteSchedule deletes a backtests schedule.
+func (s *Server) deleteSchedule(w nethttp.ResponseWriter, r *nethttp.Request) {
+	if err := s.client.DeleteBacktestSchedule(r.Context(), r.PathValue("id")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(nethttp.StatusNoContent)
+}
+
+// listScheduledRuns serves the runs of a backtests schedule, oldest first.
+func (s *Server) listScheduledRuns(w nethttp.ResponseWriter, r *nethttp.Request) {
+	runs, err := s.client.ListScheduledRuns(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	res := make([]ScheduledRun, len(runs))
+	for i, run := range runs {
+		res[i] = ScheduledRun{
+			WorkflowID: run.WorkflowID,
+			RunID:      run.RunID,
+			Status:     run.Status.String(),
+			StartedAt:  run.StartedAt,
+		}
+		if run.Results != nil {
+			res[i].BacktestID = &run.Results.BacktestID
+			res[i].StartTime = &run.Results.StartTime
+			res[i].EndTime = &run.Results.EndTime
+			res[i].Summary = &run.Results.Summary
+		}
+		if run.Error != nil {
+			res[i].Error = run.Error.Error()
+		}
+	}
+	writeJSON(w, nethttp.StatusOK, res)
+}
@@ -0,0 +1,171 @@
+package http
+
+import (
+	"errors"
+	nethttp "net/http"
+	"strings"
+
+	"github.com/cryptellation/go-clients/client"
+	"github.com/cryptellation/go-clients/codec"
+	"go.temporal.io/sdk/worker"
+)
+
+var (
+	// ErrNoAuthorizer is returned when a gateway is created without authorizer.
+	ErrNoAuthorizer = errors.New("gateway requires an authorizer")
+)
+
+const (
+	// TokenQueryParameter is the query parameter holding the bearer token of
+	// the WebSocket requests, as browsers can not set their headers.
+	TokenQueryParameter = "access_token"
+	// TokenSubprotocolPrefix is the prefix of the WebSocket subprotocol holding
+	// the bearer token (e.g. "bearer.<token>"), an alternative to the query
+	// parameter that keeps the token out of the URLs and their logs.
+	TokenSubprotocolPrefix = "bearer."
+)
+
+// Authorizer authorizes the requests to the gateway. It returns an error if
+// the request must be rejected. It is the same as the codec server one, so
+// codec.BearerTokenAuthorizer can be used for both.
+type Authorizer = codec.Authorizer
+
+// Server is a REST/JSON gateway serving the operations of a client.Client.
+// As it can create, run and stop backtests and forwardtests, and pass orders,
+// every request goes through its authorizer before being served.
+type Server struct {
+	client     client.Client
+	authorizer Authorizer
+	mux        *nethttp.ServeMux
+	ticks      *ticksHub
+	origins    []string
+}
+
+// Options is a function that modifies the server configuration.
+type Options func(*Server)
+
+// WithTicksWorker sets the worker, listening on the given task queue, that
+// receives the ticks forwarded on the WebSocket endpoint. The worker must be
+// started by the caller. Without it, the ticks endpoint is not available.
+// A single callback workflow is registered on the worker for the server.
+func WithTicksWorker(w worker.Worker, taskQueue string) func(*Server) {
+	return func(s *Server) {
+		s.ticks = newTicksHub(s.client, w, taskQueue)
+	}
+}
+
+// WithAllowedOrigins sets the host patterns (e.g. "app.example.com" or
+// "*.example.com") of the pages allowed to open WebSocket connections, in
+// addition to the gateway host. By default, only the pages served by the
+// gateway host can, so other sites can not use the credentials of a browser.
+func WithAllowedOrigins(patterns ...string) func(*Server) {
+	return func(s *Server) {
+		s.origins = append(s.origins, patterns...)
+	}
+}
+
+// New creates a new gateway serving the operations of the client. The
+// authorizer is mandatory: requests it rejects are answered with a 401 status,
+// or 403 if it returns client.ErrPermissionDenied.
+func New(cl client.Client, authorizer Authorizer, opts ...Options) (*Server, error) {
+	if authorizer == nil {
+		return nil, ErrNoAuthorizer
+	}
+
+	s := &Server{
+		client:     cl,
+		authorizer: authorizer,
+		mux:        nethttp.NewServeMux(),
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	// Register routes
+	s.mux.HandleFunc("GET /openapi.json", s.getOpenAPI)
+	s.mux.HandleFunc("GET /info", s.getInfo)
+	s.mux.HandleFunc("GET /exchanges", s.listExchanges)
+	s.mux.HandleFunc("GET /exchanges/{name}", s.getExchange)
+	s.mux.HandleFunc("GET /candlesticks", s.listCandlesticks)
+	s.mux.HandleFunc("GET /sma", s.listSMA)
+	s.mux.HandleFunc("GET /backtests", s.listBacktests)
+	s.mux.HandleFunc("GET /backtests/query", s.queryBacktests)
+	s.mux.HandleFunc("POST /backtests", s.createBacktest)
+	s.mux.HandleFunc("GET /backtests/{id}", s.getBacktest)
+	s.mux.HandleFunc("POST /backtests/{id}/run", s.runBacktest)
+	s.mux.HandleFunc("GET /forwardtests", s.listForwardtests)
+	s.mux.HandleFunc("GET /forwardtests/query", s.queryForwardtests)
+	s.mux.HandleFunc("POST /forwardtests", s.createForwardtest)
+	s.mux.HandleFunc("GET /forwardtests/{id}", s.getForwardtest)
+	s.mux.HandleFunc("GET /forwardtests/{id}/accounts", s.listForwardtestAccounts)
+	s.mux.HandleFunc("GET /forwardtests/{id}/balance", s.getForwardtestBalance)
+	s.mux.HandleFunc("POST /forwardtests/{id}/orders", s.createForwardtestOrder)
+	s.mux.HandleFunc("POST /forwardtests/{id}/run", s.runForwardtest)
+	s.mux.HandleFunc("POST /forwardtests/{id}/stop", s.stopForwardtest)
+	s.mux.HandleFunc("POST /async/backtests", s.createBacktestAsync)
+	s.mux.HandleFunc("GET /async/backtests/{workflow_id}/{run_id}", s.getBacktestAsync)
+	s.mux.HandleFunc("GET /async/runs/{workflow_id}/{run_id}", s.getBacktestRunAsync)
+	s.mux.HandleFunc("POST /async/forwardtests", s.createForwardtestAsync)
+	s.mux.HandleFunc("GET /async/forwardtests/{workflow_id}/{run_id}", s.getForwardtestAsync)
+	s.mux.HandleFunc("DELETE /async/{workflow_id}/{run_id}", s.cancelAsync)
+	s.mux.HandleFunc("GET /schedules", s.listSchedules)
+	s.mux.HandleFunc("POST /schedules", s.createSchedule)
+	s.mux.HandleFunc("DELETE /schedules/{id}", s.deleteSchedule)
+	s.mux.HandleFunc("POST /schedules/{id}/pause", s.pauseSchedule)
+	s.mux.HandleFunc("POST /schedules/{id}/unpause", s.unpauseSchedule)
+	s.mux.HandleFunc("GET /schedules/{id}/runs", s.listScheduledRuns)
+	s.mux.HandleFunc("GET /catalogue/exchanges", s.listCatalogueExchanges)
+	s.mux.HandleFunc("GET /catalogue/exchanges/{name}", s.getCatalogueExchange)
+	s.mux.HandleFunc("GET /catalogue/exchanges/{name}/supports", s.getCatalogueSupports)
+	s.mux.Handle("GET /ticks", s.ticksHandler())
+
+	return s, nil
+}
+
+// ServeHTTP serves the HTTP requests, once authorized. The WebSocket requests
+// without Authorization header can give their bearer token with the
+// TokenQueryParameter query parameter or a TokenSubprotocolPrefix subprotocol.
+func (s *Server) ServeHTTP(w nethttp.ResponseWriter, r *nethttp.Request) {
+	if err := s.authorizer(withWebSocketToken(r)); err != nil {
+		writeUnauthorized(w, err)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// getInfo serves the information about the services.
+func (s *Server) getInfo(w nethttp.ResponseWriter, r *nethttp.Request) {
+	res, err := s.client.ServicesInfo(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, nethttp.StatusOK, res)
+}
+
+// withWebSocketToken returns the request with the bearer token of a WebSocket
+// request in its Authorization header, so it can be checked by the authorizer.
+// Other requests are returned unchanged.
+func withWebSocketToken(r *nethttp.Request) *nethttp.Request {
+	if r.Header.Get("Authorization") != "" || !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return r
+	}
+
+	token := r.URL.Query().Get(TokenQueryParameter)
+	for _, values := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(values, ",") {
+			if t, ok := strings.CutPrefix(strings.TrimSpace(protocol), TokenSubprotocolPrefix); ok {
+				token = t
+			}
+		}
+	}
+	if token == "" {
+		return r
+	}
+
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
@@ -0,0 +1,417 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	backtestsapi "github.com/cryptellation/backtests/api"
+	"github.com/cryptellation/backtests/pkg/backtest"
+	backtestsclient "github.com/cryptellation/backtests/pkg/clients"
+	"github.com/cryptellation/go-clients/client"
+	"github.com/cryptellation/go-clients/codec"
+	ticksapi "github.com/cryptellation/ticks/api"
+	ticksclient "github.com/cryptellation/ticks/pkg/clients"
+	"github.com/cryptellation/ticks/pkg/tick"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	enums "go.temporal.io/api/enums/v1"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/mocks"
+)
+
+const testToken = "token"
+
+// fakeClient is a client whose methods used by the tests are overridden, the
+// others being served by a client on a mocked Temporal client.
+type fakeClient struct {
+	client.Client
+
+	backtestID uuid.UUID
+	err        error
+
+	mu        sync.Mutex
+	listening []tick.Subscription
+}
+
+func (c *fakeClient) ServicesInfo(context.Context) (map[string]any, error) {
+	return map[string]any{"backtests": "v1.0.0"}, c.err
+}
+
+func (c *fakeClient) GetBacktest(
+	_ context.Context,
+	params backtestsapi.GetBacktestWorkflowParams,
+) (backtestsclient.Backtest, error) {
+	if params.BacktestID != c.backtestID {
+		return backtestsclient.Backtest{}, fmt.Errorf("%w: backtest %s", client.ErrNotFound, params.BacktestID)
+	}
+	return backtestsclient.Backtest{ID: params.BacktestID}, nil
+}
+
+func (c *fakeClient) GetBacktestWithMetadata(
+	ctx context.Context,
+	params backtestsapi.GetBacktestWorkflowParams,
+) (client.Backtest, error) {
+	bt, err := c.GetBacktest(ctx, params)
+	return client.Backtest{Backtest: bt, Metadata: client.Metadata{client.MetadataAuthor: "me"}}, err
+}
+
+func (c *fakeClient) RunBacktestAsync(
+	_ context.Context,
+	_ backtestsapi.RunBacktestWorkflowParams,
+) (client.WorkflowHandle[backtestsapi.RunBacktestWorkflowResults], error) {
+	return client.WorkflowHandle[backtestsapi.RunBacktestWorkflowResults]{WorkflowID: "wid", RunID: "rid"}, c.err
+}
+
+func (c *fakeClient) ListBacktestSchedules(context.Context) ([]client.ScheduleInfo, error) {
+	return []client.ScheduleInfo{{ID: "nightly", Paused: true}}, c.err
+}
+
+func (c *fakeClient) QueryBacktests(context.Context, client.Query) (client.Page[backtest.Backtest], error) {
+	return client.Page[backtest.Backtest]{}, c.err
+}
+
+func (c *fakeClient) ListenToTicks(_ context.Context, _ ticksclient.ListenerParams, exchange, pair string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listening = append(c.listening, tick.Subscription{Exchange: exchange, Pair: pair})
+	return c.err
+}
+
+func (c *fakeClient) StopListeningToTicks(_ context.Context, _ uuid.UUID, exchange, pair string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, sub := range c.listening {
+		if sub == (tick.Subscription{Exchange: exchange, Pair: pair}) {
+			c.listening = append(c.listening[:i], c.listening[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// newTestServer returns a gateway on the fake client, accepting the test
+// token, and its mocked Temporal client.
+func newTestServer(t *testing.T, fake *fakeClient, opts ...Options) (*httptest.Server, *mocks.Client) {
+	temporal := &mocks.Client{}
+	cl, err := client.New(client.WithTemporalClient(temporal))
+	require.NoError(t, err)
+	fake.Client = cl
+
+	s, err := New(fake, codec.BearerTokenAuthorizer(testToken), opts...)
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts, temporal
+}
+
+// do sends the request with the test token and returns the response status
+// and body.
+func do(t *testing.T, ts *httptest.Server, method, path, body string) (int, string) {
+	req, err := nethttp.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+
+	resp, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(b)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(&fakeClient{}, nil)
+	require.ErrorIs(t, err, ErrNoAuthorizer)
+}
+
+func TestServerAuthorization(t *testing.T) {
+	forbidden := func(r *nethttp.Request) error {
+		if r.Header.Get("Authorization") == "Bearer forbidden" {
+			return fmt.Errorf("%w: read only token", client.ErrPermissionDenied)
+		}
+		return codec.BearerTokenAuthorizer(testToken)(r)
+	}
+
+	s, err := New(&fakeClient{}, forbidden)
+	require.NoError(t, err)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		header   string
+		query    string
+		upgrade  bool
+		expected int
+	}{
+		{name: "no token", expected: nethttp.StatusUnauthorized},
+		{name: "invalid token", header: "Bearer other", expected: nethttp.StatusUnauthorized},
+		{name: "valid token", header: "Bearer " + testToken, expected: nethttp.StatusOK},
+		{name: "permission denied", header: "Bearer forbidden", expected: nethttp.StatusForbidden},
+		{name: "query token without upgrade", query: testToken, expected: nethttp.StatusUnauthorized},
+		{name: "query token on upgrade", query: testToken, upgrade: true, expected: nethttp.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := nethttp.NewRequest(nethttp.MethodGet, ts.URL+"/info", nil)
+			require.NoError(t, err)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.query != "" {
+				req.URL.RawQuery = url.Values{TokenQueryParameter: {tt.query}}.Encode()
+			}
+			if tt.upgrade {
+				req.Header.Set("Upgrade", "websocket")
+			}
+
+			resp, err := ts.Client().Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, tt.expected, resp.StatusCode)
+		})
+	}
+}
+
+func TestWithWebSocketToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   nethttp.Header
+		query    string
+		expected string
+	}{
+		{name: "not an upgrade", header: nethttp.Header{}, query: "?access_token=t"},
+		{
+			name:     "header kept",
+			header:   nethttp.Header{"Upgrade": {"websocket"}, "Authorization": {"Bearer h"}},
+			query:    "?access_token=t",
+			expected: "Bearer h",
+		},
+		{name: "query", header: nethttp.Header{"Upgrade": {"websocket"}}, query: "?access_token=t", expected: "Bearer t"},
+		{
+			name: "subprotocol",
+			header: nethttp.Header{
+				"Upgrade":                {"websocket"},
+				"Sec-Websocket-Protocol": {TicksSubprotocol + ", bearer.t"},
+			},
+			expected: "Bearer t",
+		},
+		{name: "no token", header: nethttp.Header{"Upgrade": {"websocket"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(nethttp.MethodGet, "/ticks"+tt.query, nil)
+			r.Header = tt.header
+			require.Equal(t, tt.expected, withWebSocketToken(r).Header.Get("Authorization"))
+		})
+	}
+}
+
+func TestServerRoutes(t *testing.T) {
+	id := uuid.New()
+	ts, _ := newTestServer(t, &fakeClient{backtestID: id})
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		expected int
+		contains string
+	}{
+		{name: "openapi", method: "GET", path: "/openapi.json", expected: 200, contains: "/async/backtests"},
+		{name: "unknown route", method: "GET", path: "/unknown", expected: 404},
+		{name: "wrong method", method: "DELETE", path: "/backtests", expected: 405},
+		{name: "get backtest", method: "GET", path: "/backtests/" + id.String(), expected: 200, contains: `"author":"me"`},
+		{name: "invalid ID", method: "GET", path: "/backtests/invalid", expected: 400, contains: "UUID"},
+		{name: "missing backtest", method: "GET", path: "/backtests/" + uuid.NewString(), expected: 404},
+		{name: "run backtest", method: "POST", path: "/backtests/" + id.String() + "/run", expected: 202, contains: "wid"},
+		{name: "run missing backtest", method: "POST", path: "/backtests/" + uuid.NewString() + "/run", expected: 404},
+		{name: "list schedules", method: "GET", path: "/schedules", expected: 200, contains: "nightly"},
+		{name: "invalid query", method: "GET", path: "/backtests/query?status=done&limit=-1", expected: 400},
+		{name: "invalid body", method: "POST", path: "/backtests", body: "{", expected: 400},
+		{name: "unknown field", method: "POST", path: "/backtests", body: `{"unknown":1}`, expected: 400},
+		{
+			name:     "body too large",
+			method:   "POST",
+			path:     "/backtests",
+			body:     `{"metadata":{"k":"` + strings.Repeat("a", maxBodySize) + `"}}`,
+			expected: 413,
+		},
+		{
+			name:     "async metadata",
+			method:   "POST",
+			path:     "/async/backtests",
+			body:     `{"metadata":{"k":"v"}}`,
+			expected: 400,
+			contains: "metadata",
+		},
+		{name: "invalid schedule", method: "POST", path: "/schedules", body: `{"id":"s"}`, expected: 400},
+		{name: "catalogue supports", method: "GET", path: "/catalogue/exchanges/binance/supports", expected: 400},
+		{name: "ticks without worker", method: "GET", path: "/ticks", expected: 501},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := do(t, ts, tt.method, tt.path, tt.body)
+			require.Equal(t, tt.expected, status, body)
+			require.Contains(t, body, tt.contains)
+		})
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{err: errBodyTooLarge, expected: nethttp.StatusRequestEntityTooLarge},
+		{err: client.ErrInvalidParams, expected: nethttp.StatusBadRequest},
+		{err: client.ErrInvalidCursor, expected: nethttp.StatusBadRequest},
+		{err: client.ErrNotFound, expected: nethttp.StatusNotFound},
+		{err: client.ErrAlreadyExists, expected: nethttp.StatusConflict},
+		{err: client.ErrPermissionDenied, expected: nethttp.StatusForbidden},
+		{err: client.ErrNotImplemented, expected: nethttp.StatusNotImplemented},
+		{err: client.ErrUnavailable, expected: nethttp.StatusServiceUnavailable},
+		{err: client.ErrTimeout, expected: nethttp.StatusGatewayTimeout},
+		{err: fmt.Errorf("wrapped: %w", client.ErrNotFound), expected: nethttp.StatusNotFound},
+		{err: context.Canceled, expected: nethttp.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			require.Equal(t, tt.expected, errorStatus(tt.err))
+		})
+	}
+}
+
+func TestServerErrorMapping(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{err: client.ErrUnavailable, expected: nethttp.StatusServiceUnavailable},
+		{err: client.ErrTimeout, expected: nethttp.StatusGatewayTimeout},
+		{err: client.ErrInvalidCursor, expected: nethttp.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			ts, _ := newTestServer(t, &fakeClient{err: tt.err})
+			status, body := do(t, ts, "GET", "/backtests/query", "")
+			require.Equal(t, tt.expected, status)
+			require.Contains(t, body, tt.err.Error())
+		})
+	}
+}
+
+func TestServerAsyncStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   enums.WorkflowExecutionStatus
+		expected client.WorkflowStatus
+		err      string
+	}{
+		{name: "running", status: enums.WORKFLOW_EXECUTION_STATUS_RUNNING, expected: client.WorkflowStatusRunning},
+		{name: "completed", status: enums.WORKFLOW_EXECUTION_STATUS_COMPLETED, expected: client.WorkflowStatusCompleted},
+		{
+			name:     "failed",
+			status:   enums.WORKFLOW_EXECUTION_STATUS_FAILED,
+			expected: client.WorkflowStatusFailed,
+			err:      "backtest failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, temporal := newTestServer(t, &fakeClient{})
+			temporal.On("DescribeWorkflowExecution", mock.Anything, "wid", "rid").
+				Return(&workflowservice.DescribeWorkflowExecutionResponse{
+					WorkflowExecutionInfo: &workflowpb.WorkflowExecutionInfo{Status: tt.status},
+				}, nil)
+			if tt.err != "" {
+				run := &mocks.WorkflowRun{}
+				run.On("Get", mock.Anything, mock.Anything).Return(fmt.Errorf("%s", tt.err))
+				temporal.On("GetWorkflow", mock.Anything, "wid", "rid").Return(run)
+			}
+
+			status, body := do(t, ts, "GET", "/async/runs/wid/rid", "")
+			require.Equal(t, nethttp.StatusOK, status)
+
+			var res AsyncResponse
+			require.NoError(t, json.Unmarshal([]byte(body), &res))
+			require.Equal(t, AsyncResponse{
+				WorkflowID: "wid",
+				RunID:      "rid",
+				Status:     tt.expected.String(),
+				Error:      tt.err,
+			}, res)
+		})
+	}
+}
+
+func TestServerTicks(t *testing.T) {
+	fake := &fakeClient{}
+	var s *Server
+	ts, _ := newTestServer(t, fake, func(srv *Server) {
+		srv.ticks = newTicksHub(srv.client, nil, "queue")
+		s = srv
+	})
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ticks?exchange=binance&pair=BTC/USDT"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Rejected origin
+	_, resp, err := websocket.Dial(ctx, wsURL+"&"+TokenQueryParameter+"="+testToken, &websocket.DialOptions{
+		HTTPHeader: nethttp.Header{"Origin": {"https://evil.example.com"}},
+	})
+	require.Error(t, err)
+	require.Equal(t, nethttp.StatusForbidden, resp.StatusCode)
+
+	// Token as subprotocol
+	conn, _, err := websocket.Dial(ctx, wsURL, &websocket.DialOptions{
+		Subprotocols: []string{TicksSubprotocol, TokenSubprotocolPrefix + testToken},
+	})
+	require.NoError(t, err)
+	require.Equal(t, TicksSubprotocol, conn.Subprotocol())
+
+	// Wait for the subscription, with the pair in the ticks notation
+	sub := tick.Subscription{Exchange: "binance", Pair: "BTC-USDT"}
+	require.Eventually(t, func() bool {
+		s.ticks.connsMu.RLock()
+		defer s.ticks.connsMu.RUnlock()
+		return len(s.ticks.conns[sub]) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// Receive a tick
+	sent := tick.Tick{Exchange: "binance", Pair: "BTC-USDT", Price: 42, Time: time.Unix(0, 0).UTC()}
+	require.NoError(t, s.ticks.dispatch(nil, ticksapi.ListenToTicksCallbackWorkflowParams{Tick: sent}))
+	var received tick.Tick
+	require.NoError(t, wsjson.Read(ctx, conn, &received))
+	require.Equal(t, sent, received)
+
+	// Stop listening once closed
+	require.NoError(t, conn.Close(websocket.StatusNormalClosure, ""))
+	require.Eventually(t, func() bool {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		return len(fake.listening) == 0
+	}, time.Second, 10*time.Millisecond)
+}
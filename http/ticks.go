@@ -0,0 +1,229 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	nethttp "net/http"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/cryptellation/go-clients/client"
+	ticksapi "github.com/cryptellation/ticks/api"
+	ticksclient "github.com/cryptellation/ticks/pkg/clients"
+	"github.com/cryptellation/ticks/pkg/tick"
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	// ticksBufferSize is the number of ticks buffered for each WebSocket
+	// connection. Ticks are dropped when a connection is too slow.
+	ticksBufferSize = 64
+	// ticksStopTimeout is the maximum duration to stop listening to ticks
+	// once the last WebSocket connection of a pair is closed.
+	ticksStopTimeout = 10 * time.Second
+	// ticksWriteTimeout is the maximum duration to write a message on a
+	// WebSocket connection before closing it.
+	ticksWriteTimeout = 10 * time.Second
+	// ticksCallbackPrefix is the prefix of the callback receiving the ticks.
+	ticksCallbackPrefix = "HTTPGatewayTicks"
+)
+
+// TicksSubprotocol is the WebSocket subprotocol of the ticks endpoint. Clients
+// passing their token as a subprotocol (see TokenSubprotocolPrefix) must also
+// request it, as browsers reject the connections without agreed subprotocol.
+const TicksSubprotocol = "cryptellation.ticks"
+
+// ticksHub listens to the ticks with a single requester and callback for the
+// whole server, and fans them out to the WebSocket connections. Each pair is
+// listened to while at least one connection streams it.
+type ticksHub struct {
+	client      client.Client
+	worker      worker.Worker
+	taskQueue   string
+	requesterID uuid.UUID
+
+	// mu serializes the listening changes
+	mu         sync.Mutex
+	registered bool
+
+	// connsMu protects the connections, read by the callback
+	connsMu sync.RWMutex
+	conns   map[tick.Subscription]map[chan tick.Tick]struct{}
+}
+
+func newTicksHub(cl client.Client, w worker.Worker, taskQueue string) *ticksHub {
+	return &ticksHub{
+		client:      cl,
+		worker:      w,
+		taskQueue:   taskQueue,
+		requesterID: uuid.New(),
+		conns:       make(map[tick.Subscription]map[chan tick.Tick]struct{}),
+	}
+}
+
+// subscribe returns a channel receiving the ticks of the pair, listening to
+// them if it is the first connection on the pair.
+func (h *ticksHub) subscribe(ctx context.Context, sub tick.Subscription) (chan tick.Tick, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Listen to the pair if it is not already
+	h.connsMu.RLock()
+	listening := len(h.conns[sub]) > 0
+	h.connsMu.RUnlock()
+	if !listening {
+		err := h.client.ListenToTicks(ctx, ticksclient.ListenerParams{
+			RequesterID:        h.requesterID,
+			CallbackNamePrefix: ticksCallbackPrefix,
+			Callback:           h.dispatch,
+			Worker:             registerOnceWorker{Worker: h.worker, registered: &h.registered},
+			TaskQueue:          h.taskQueue,
+		}, sub.Exchange, sub.Pair)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Add the connection
+	ch := make(chan tick.Tick, ticksBufferSize)
+	h.connsMu.Lock()
+	if h.conns[sub] == nil {
+		h.conns[sub] = make(map[chan tick.Tick]struct{})
+	}
+	h.conns[sub][ch] = struct{}{}
+	h.connsMu.Unlock()
+
+	return ch, nil
+}
+
+// unsubscribe removes the connection, and stops listening to the pair if it
+// was the last connection on it.
+func (h *ticksHub) unsubscribe(sub tick.Subscription, ch chan tick.Tick) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.connsMu.Lock()
+	delete(h.conns[sub], ch)
+	last := len(h.conns[sub]) == 0
+	if last {
+		delete(h.conns, sub)
+	}
+	h.connsMu.Unlock()
+
+	if last {
+		ctx, cancel := context.WithTimeout(context.Background(), ticksStopTimeout)
+		defer cancel()
+		_ = h.client.StopListeningToTicks(ctx, h.requesterID, sub.Exchange, sub.Pair)
+	}
+}
+
+// dispatch is the callback receiving the ticks. It only hands them to the
+// connections, dropping them for the connections that are too slow, so it
+// never blocks the workflow.
+func (h *ticksHub) dispatch(_ workflow.Context, params ticksapi.ListenToTicksCallbackWorkflowParams) error {
+	sub := tick.Subscription{Exchange: params.Tick.Exchange, Pair: params.Tick.Pair}
+
+	h.connsMu.RLock()
+	defer h.connsMu.RUnlock()
+	for ch := range h.conns[sub] {
+		select {
+		case ch <- params.Tick:
+		default: // Drop the tick if the connection is too slow
+		}
+	}
+	return nil
+}
+
+// registerOnceWorker is a worker registering the ticks callback only once, as
+// the ticks client registers it each time a pair is listened to.
+type registerOnceWorker struct {
+	worker.Worker
+	registered *bool
+}
+
+// RegisterWorkflowWithOptions registers the workflow if it is not already.
+func (w registerOnceWorker) RegisterWorkflowWithOptions(wf any, options workflow.RegisterOptions) {
+	if *w.registered {
+		return
+	}
+	w.Worker.RegisterWorkflowWithOptions(wf, options)
+	*w.registered = true
+}
+
+// ticksHandler serves the ticks of an exchange pair on a WebSocket, as JSON messages.
+func (s *Server) ticksHandler() nethttp.Handler {
+	return nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if s.ticks == nil {
+			writeError(w, fmt.Errorf("%w: no worker to receive ticks", client.ErrNotImplemented))
+			return
+		}
+
+		q := newQuery(r.URL.Query())
+		exchange := q.String("exchange", true)
+		pair := q.String("pair", true)
+		if err := q.Err(); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		// Use the notation of the ticks to dispatch them
+		pair, err := client.NormalizePair(pair)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		// Accept the connection, checking its origin
+		conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+			Subprotocols:   []string{TicksSubprotocol},
+			OriginPatterns: s.origins,
+		})
+		if err != nil {
+			return // The response has already been written
+		}
+
+		sub := tick.Subscription{Exchange: exchange, Pair: pair}
+		s.streamTicks(r.Context(), conn, sub)
+	})
+}
+
+// streamTicks listens to the ticks and sends them on the connection until it is closed.
+func (s *Server) streamTicks(ctx context.Context, conn *websocket.Conn, sub tick.Subscription) {
+	defer conn.CloseNow()
+
+	// Listen to ticks
+	ticks, err := s.ticks.subscribe(ctx, sub)
+	if err != nil {
+		_ = writeWebSocketJSON(ctx, conn, ErrorResponse{Error: err.Error()})
+		_ = conn.Close(websocket.StatusInternalError, "listening to ticks failed")
+		return
+	}
+	defer s.ticks.unsubscribe(sub, ticks)
+
+	// Detect the closing of the connection, ignoring the messages received
+	ctx = conn.CloseRead(ctx)
+
+	// Forward ticks
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-ticks:
+			if err := writeWebSocketJSON(ctx, conn, t); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeWebSocketJSON writes a JSON message on the connection, giving up after
+// ticksWriteTimeout so a stuck connection is closed.
+func writeWebSocketJSON(ctx context.Context, conn *websocket.Conn, v any) error {
+	ctx, cancel := context.WithTimeout(ctx, ticksWriteTimeout)
+	defer cancel()
+	return wsjson.Write(ctx, conn, v)
+}
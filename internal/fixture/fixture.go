@@ -0,0 +1,82 @@
+// Package fixture provides the market data shared by the tests of the module:
+// times, candlesticks and ticks laid out minute by minute from a fixed start.
+package fixture
+
+import (
+	"time"
+
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
+	"github.com/cryptellation/ticks/pkg/tick"
+)
+
+// Start is the time of the first minute of the fixtures.
+var Start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Minute returns the time of the nth minute after Start.
+func Minute(n int) time.Time {
+	return Start.Add(time.Duration(n) * time.Minute)
+}
+
+// Minutes returns the number of minutes between Start and the time.
+func Minutes(t time.Time) int {
+	return int(t.Sub(Start) / time.Minute)
+}
+
+// Candlestick returns a one minute candlestick at the nth minute, with the
+// price as open, high, low and close, and a volume of 1.
+func Candlestick(n int, price float64) candlestick.Candlestick {
+	return candlestick.Candlestick{
+		Time:   Minute(n),
+		Open:   price,
+		High:   price,
+		Low:    price,
+		Close:  price,
+		Volume: 1,
+	}
+}
+
+// Closes returns one minute candlesticks from Start with the given close
+// prices, each opening at the previous close.
+func Closes(prices ...float64) []candlestick.Candlestick {
+	list := make([]candlestick.Candlestick, len(prices))
+	for i, p := range prices {
+		list[i] = Candlestick(i, p)
+		if i > 0 {
+			list[i].Open = prices[i-1]
+			list[i].High = max(p, prices[i-1])
+			list[i].Low = min(p, prices[i-1])
+		}
+	}
+	return list
+}
+
+// Exchange and Pair are the market of the ticks fixtures.
+const (
+	Exchange = "binance"
+	Pair     = "BTC-USDT"
+)
+
+// Tick returns a tick of the fixtures market at the nth minute.
+func Tick(n int, price float64) tick.Tick {
+	return TickOn(n, Exchange, price)
+}
+
+// TickOn returns a tick of the fixtures pair on the exchange at the nth minute.
+func TickOn(n int, exchange string, price float64) tick.Tick {
+	return tick.Tick{
+		Time:     Minute(n),
+		Exchange: exchange,
+		Pair:     Pair,
+		Price:    price,
+	}
+}
+
+// Ticks returns a tick of the fixtures market for each price, one per minute
+// from Start.
+func Ticks(prices ...float64) []tick.Tick {
+	list := make([]tick.Tick, len(prices))
+	for i, p := range prices {
+		list[i] = Tick(i, p)
+	}
+	return list
+}
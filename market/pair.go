@@ -0,0 +1,196 @@
+package market
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/cryptellation/candlesticks/pkg/pair"
+	"github.com/cryptellation/exchanges/pkg/exchange"
+)
+
+var (
+	// ErrInvalidPair is returned when a pair can not be parsed. It is the
+	// error returned by the services for invalid pairs.
+	ErrInvalidPair = pair.ErrInvalidPair
+	// ErrInvalidAsset is returned when an asset symbol is invalid.
+	ErrInvalidAsset = errors.New("invalid asset symbol")
+	// ErrUnknownPair is returned when a pair is not listed on an exchange.
+	ErrUnknownPair = errors.New("pair not listed on exchange")
+	// ErrAmbiguousPair is returned when a pair without separator is
+	// normalized, as it can not be split without guessing.
+	ErrAmbiguousPair = fmt.Errorf("%w: ambiguous without separator", ErrInvalidPair)
+)
+
+// separators are the separators of the common pair notations.
+const separators = "-/_: "
+
+// QuoteAssets are the quote assets used to split pairs written without
+// separator (e.g. BTCUSDT), from the longest to the shortest match.
+var QuoteAssets = []Asset{
+	"FDUSD", "USDT", "USDC", "BUSD", "TUSD", "DAI", "USD", "EUR", "GBP",
+	"TRY", "BRL", "JPY", "BTC", "ETH", "BNB",
+}
+
+// Asset is the symbol of an asset, in upper case (e.g. BTC).
+type Asset string
+
+// ParseAsset parses an asset symbol, case insensitive.
+func ParseAsset(s string) (Asset, error) {
+	a := Asset(strings.ToUpper(strings.TrimSpace(s)))
+	return a, a.Validate()
+}
+
+// String returns the symbol of the asset.
+func (a Asset) String() string {
+	return string(a)
+}
+
+// Validate checks that the asset is a non-empty upper case alphanumeric symbol.
+func (a Asset) Validate() error {
+	if a == "" {
+		return fmt.Errorf("%w: empty", ErrInvalidAsset)
+	}
+
+	for _, r := range a {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return fmt.Errorf("%w: %q", ErrInvalidAsset, string(a))
+		}
+	}
+	return nil
+}
+
+// Pair is a trading pair, made of a base and a quote asset.
+type Pair struct {
+	Base  Asset
+	Quote Asset
+}
+
+// NewPair creates a pair from its base and quote assets.
+func NewPair(base, quote Asset) Pair {
+	return Pair{Base: base, Quote: quote}
+}
+
+// ParsePair parses a pair written in one of the common notations, case
+// insensitive: BTC-USDT, BTC/USDT, BTC_USDT, BTC:USDT or BTCUSDT. Pairs
+// without separator are split on the known quote assets (see QuoteAssets).
+func ParsePair(s string) (Pair, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+
+	// Split on the separator
+	if i := strings.IndexAny(s, separators); i >= 0 {
+		p := Pair{Base: Asset(s[:i]), Quote: Asset(s[i+1:])}
+		if err := p.Validate(); err != nil {
+			return Pair{}, fmt.Errorf("parsing pair %q: %w", s, err)
+		}
+		return p, nil
+	}
+
+	// Split on the known quote assets
+	quotes := slices.Clone(QuoteAssets)
+	sort.SliceStable(quotes, func(i, j int) bool {
+		return len(quotes[i]) > len(quotes[j])
+	})
+	for _, q := range quotes {
+		if base, ok := strings.CutSuffix(s, string(q)); ok && base != "" {
+			return Pair{Base: Asset(base), Quote: q}, nil
+		}
+	}
+
+	return Pair{}, fmt.Errorf("%w: %q has no separator nor known quote asset", ErrInvalidPair, s)
+}
+
+// MustParsePair parses a pair like ParsePair and panics on error.
+func MustParsePair(s string) Pair {
+	p, err := ParsePair(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// ParsePairOn parses a pair like ParsePair and checks that it is listed on
+// the exchange. Pairs without separator are matched against the pairs of the
+// exchange before the known quote assets.
+func ParsePairOn(exch exchange.Exchange, s string) (Pair, error) {
+	// Match pairs without separator against the exchange pairs
+	compact := strings.ToUpper(strings.TrimSpace(s))
+	if !strings.ContainsAny(compact, separators) {
+		for _, listed := range exch.Pairs {
+			p, err := ParsePair(listed)
+			if err == nil && string(p.Base+p.Quote) == compact {
+				return p, nil
+			}
+		}
+	}
+
+	p, err := ParsePair(s)
+	if err != nil {
+		return Pair{}, err
+	}
+	return p, p.ValidateOn(exch)
+}
+
+// NormalizePair parses a pair written with a separator in one of the common
+// notations and returns it in the notation of the services (e.g. BTC-USDT).
+// Pairs without separator return ErrAmbiguousPair, as the known quote assets
+// can split them wrongly (e.g. an exchange can list BTCUSDT as BTCU-SDT): they
+// are parsed with ParsePairOn, against the pairs of their exchange.
+func NormalizePair(s string) (string, error) {
+	if !strings.ContainsAny(strings.TrimSpace(s), separators) {
+		return "", fmt.Errorf("%w: %q", ErrAmbiguousPair, s)
+	}
+
+	p, err := ParsePair(s)
+	if err != nil {
+		return "", err
+	}
+	return p.String(), nil
+}
+
+// String returns the pair in the notation of the services (e.g. BTC-USDT).
+func (p Pair) String() string {
+	return pair.FormatPair(string(p.Base), string(p.Quote))
+}
+
+// Validate checks that both assets are valid.
+func (p Pair) Validate() error {
+	if err := p.Base.Validate(); err != nil {
+		return fmt.Errorf("%w: base: %w", ErrInvalidPair, err)
+	}
+	if err := p.Quote.Validate(); err != nil {
+		return fmt.Errorf("%w: quote: %w", ErrInvalidPair, err)
+	}
+	return nil
+}
+
+// ValidateOn checks that the pair is listed on the exchange. Exchanges
+// without listed pairs accept every pair.
+func (p Pair) ValidateOn(exch exchange.Exchange) error {
+	if len(exch.Pairs) == 0 || slices.Contains(exch.Pairs, p.String()) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s on %s", ErrUnknownPair, p, exch.Name)
+}
+
+// Inverse returns the pair with the base and quote assets swapped.
+func (p Pair) Inverse() Pair {
+	return Pair{Base: p.Quote, Quote: p.Base}
+}
+
+// MarshalText marshals the pair in the notation of the services.
+func (p Pair) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText parses the pair from one of the common notations.
+func (p *Pair) UnmarshalText(text []byte) error {
+	parsed, err := ParsePair(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
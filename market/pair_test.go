@@ -0,0 +1,183 @@
+package market
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cryptellation/exchanges/pkg/exchange"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAsset(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Asset
+		err      error
+	}{
+		{name: "upper case", input: "BTC", expected: "BTC"},
+		{name: "lower case", input: "usdt", expected: "USDT"},
+		{name: "spaces", input: " eth ", expected: "ETH"},
+		{name: "digits", input: "1inch", expected: "1INCH"},
+		{name: "empty", input: "", err: ErrInvalidAsset},
+		{name: "invalid character", input: "BT$", err: ErrInvalidAsset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := ParseAsset(tt.input)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, a)
+		})
+	}
+}
+
+func TestParsePair(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Pair
+		err      error
+	}{
+		{name: "dash", input: "BTC-USDT", expected: NewPair("BTC", "USDT")},
+		{name: "slash", input: "BTC/USDT", expected: NewPair("BTC", "USDT")},
+		{name: "underscore", input: "BTC_USDT", expected: NewPair("BTC", "USDT")},
+		{name: "colon", input: "BTC:USDT", expected: NewPair("BTC", "USDT")},
+		{name: "space", input: "BTC USDT", expected: NewPair("BTC", "USDT")},
+		{name: "lower case", input: " eth/btc ", expected: NewPair("ETH", "BTC")},
+		{name: "compact", input: "BTCUSDT", expected: NewPair("BTC", "USDT")},
+		{name: "compact with longest quote", input: "ETHFDUSD", expected: NewPair("ETH", "FDUSD")},
+		{name: "compact with short quote", input: "btcusd", expected: NewPair("BTC", "USD")},
+		{name: "compact crypto quote", input: "ETHBTC", expected: NewPair("ETH", "BTC")},
+		{name: "empty", input: "", err: ErrInvalidPair},
+		{name: "unknown quote", input: "BTCXYZ", err: ErrInvalidPair},
+		{name: "quote only", input: "USDT", err: ErrInvalidPair},
+		{name: "empty base", input: "-USDT", err: ErrInvalidPair},
+		{name: "empty quote", input: "BTC-", err: ErrInvalidPair},
+		{name: "invalid asset", input: "BTC-US$", err: ErrInvalidAsset},
+		{name: "several separators", input: "BTC-USDT-PERP", err: ErrInvalidPair},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParsePair(tt.input)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, p)
+		})
+	}
+}
+
+func TestParsePairOn(t *testing.T) {
+	exch := exchange.Exchange{
+		Name:  "binance",
+		Pairs: []string{"BTC-USDT", "WBTC-BTC", "ETH-BTC"},
+	}
+
+	tests := []struct {
+		name     string
+		exchange exchange.Exchange
+		input    string
+		expected Pair
+		err      error
+	}{
+		{name: "listed", exchange: exch, input: "eth/btc", expected: NewPair("ETH", "BTC")},
+		{name: "compact listed", exchange: exch, input: "BTCUSDT", expected: NewPair("BTC", "USDT")},
+		{name: "compact matched on the exchange", exchange: exch, input: "WBTCBTC", expected: NewPair("WBTC", "BTC")},
+		{name: "not listed", exchange: exch, input: "ETH-USDT", err: ErrUnknownPair},
+		{name: "invalid", exchange: exch, input: "BTC-", err: ErrInvalidPair},
+		{
+			name:     "exchange without pairs",
+			exchange: exchange.Exchange{Name: "unknown"},
+			input:    "ETH-USDT",
+			expected: NewPair("ETH", "USDT"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParsePairOn(tt.exchange, tt.input)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, p)
+		})
+	}
+}
+
+func TestNormalizePair(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		err      error
+	}{
+		{name: "services notation", input: "BTC-USDT", expected: "BTC-USDT"},
+		{name: "slash", input: "eth/btc", expected: "ETH-BTC"},
+		{name: "spaces", input: " sol usdc ", expected: "SOL-USDC"},
+		{name: "compact", input: "SOLUSDC", err: ErrAmbiguousPair},
+		{name: "invalid", input: "BTC", err: ErrInvalidPair},
+		{name: "invalid asset", input: "BTC-US$", err: ErrInvalidAsset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NormalizePair(tt.input)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, s)
+		})
+	}
+}
+
+func TestPairInverse(t *testing.T) {
+	require.Equal(t, NewPair("USDT", "BTC"), MustParsePair("BTC-USDT").Inverse())
+}
+
+func TestMustParsePair(t *testing.T) {
+	require.Panics(t, func() {
+		MustParsePair("BTC")
+	})
+}
+
+func TestPairJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Pair
+		err      bool
+	}{
+		{name: "services notation", input: `"BTC-USDT"`, expected: NewPair("BTC", "USDT")},
+		{name: "other notation", input: `"btc/usdt"`, expected: NewPair("BTC", "USDT")},
+		{name: "invalid", input: `"BTC"`, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Pair
+			err := json.Unmarshal([]byte(tt.input), &p)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, p)
+
+			content, err := json.Marshal(p)
+			require.NoError(t, err)
+			require.JSONEq(t, `"`+tt.expected.String()+`"`, string(content))
+		})
+	}
+}
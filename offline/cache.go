@@ -0,0 +1,251 @@
+package offline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	candlesticksapi "github.com/cryptellation/candlesticks/api"
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
+	"github.com/cryptellation/timeseries"
+)
+
+// Cache is a candlesticks source backed by a local directory.
+// Candlesticks missing from the directory are fetched from the upstream
+// source, if any, and stored for the next calls. Without upstream source,
+// the cache only serves what has already been downloaded.
+//
+// Each file is read once and kept in memory, with the time ranges already
+// requested to the upstream source: candlesticks still missing in these
+// ranges do not exist upstream and are not requested again.
+type Cache struct {
+	dir      string
+	upstream CandlesticksSource
+	entries  map[candlestick.ListMetadata]*cacheEntry
+	mu       sync.Mutex
+}
+
+// cacheEntry is the content of a cache file loaded in memory.
+type cacheEntry struct {
+	// candlesticks are the cached candlesticks, sorted by time
+	candlesticks []candlestick.Candlestick
+	// known are the ranges where every existing candlestick is cached
+	known []timeseries.TimeRange
+}
+
+// NewCache creates a new local candlesticks cache in the given directory.
+// The upstream source can be nil to work completely offline.
+func NewCache(dir string, upstream CandlesticksSource) *Cache {
+	return &Cache{
+		dir:      dir,
+		upstream: upstream,
+		entries:  make(map[candlestick.ListMetadata]*cacheEntry),
+	}
+}
+
+// ListCandlesticks lists candlesticks from the cache, downloading the missing
+// ones from the upstream source when there is one.
+func (c *Cache) ListCandlesticks(
+	ctx context.Context,
+	params candlesticksapi.ListCandlesticksWorkflowParams,
+) (candlesticksapi.ListCandlesticksWorkflowResults, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	md := candlestick.ListMetadata{
+		Exchange: params.Exchange,
+		Pair:     params.Pair,
+		Period:   params.Period,
+	}
+
+	// Get what is already in cache
+	e, err := c.entry(md)
+	if err != nil {
+		return candlesticksapi.ListCandlesticksWorkflowResults{}, err
+	}
+
+	// Download missing candlesticks if possible
+	start, end := params.Period.RoundInterval(params.Start, params.End)
+	if c.upstream != nil {
+		if err := c.download(ctx, md, e, start, end); err != nil {
+			return candlesticksapi.ListCandlesticksWorkflowResults{}, err
+		}
+	}
+
+	return candlesticksapi.ListCandlesticksWorkflowResults{
+		List: e.extract(start, end, params.Limit),
+	}, nil
+}
+
+// download downloads the candlesticks of the range that are not known yet,
+// from the first unknown time to the last one.
+func (c *Cache) download(
+	ctx context.Context,
+	md candlestick.ListMetadata,
+	e *cacheEntry,
+	start, end time.Time,
+) error {
+	unknown := e.unknown(start, end, md.Period.Duration())
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	start, end = unknown[0].Start, unknown[len(unknown)-1].End
+	res, err := c.upstream.ListCandlesticks(ctx, candlesticksapi.ListCandlesticksWorkflowParams{
+		Exchange: md.Exchange,
+		Pair:     md.Pair,
+		Period:   md.Period,
+		Start:    &start,
+		End:      &end,
+	})
+	if err != nil {
+		return fmt.Errorf("downloading candlesticks into cache: %w", err)
+	}
+
+	if err := e.add(md, timeseries.TimeRange{Start: start, End: end}, res.List); err != nil {
+		return err
+	}
+
+	return c.write(md, e)
+}
+
+// entry returns the cache entry, reading its file on first access.
+func (c *Cache) entry(md candlestick.ListMetadata) (*cacheEntry, error) {
+	if e, ok := c.entries[md]; ok {
+		return e, nil
+	}
+
+	e, err := c.read(md)
+	if err != nil {
+		return nil, err
+	}
+
+	c.entries[md] = e
+	return e, nil
+}
+
+// path returns the path of the cache file. The exchange and pair are escaped,
+// so they stay a single directory level inside the cache directory.
+func (c *Cache) path(md candlestick.ListMetadata) string {
+	return filepath.Join(c.dir,
+		escapePathElement(md.Exchange), escapePathElement(md.Pair), md.Period.String()+".json")
+}
+
+// escapePathElement escapes a name to use it as a file or directory name:
+// separators and dots are escaped, so it cannot be a relative path.
+func escapePathElement(name string) string {
+	return strings.ReplaceAll(url.QueryEscape(name), ".", "%2E")
+}
+
+func (c *Cache) read(md candlestick.ListMetadata) (*cacheEntry, error) {
+	f, err := ReadFile(c.path(md))
+	if errors.Is(err, fs.ErrNotExist) {
+		return &cacheEntry{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Consider the ranges of consecutive candlesticks as known
+	e := &cacheEntry{}
+	if err := e.add(md, timeseries.TimeRange{}, f.Candlesticks); err != nil {
+		return nil, err
+	}
+	for i, cs := range e.candlesticks {
+		if i > 0 && cs.Time.Equal(e.candlesticks[i-1].Time.Add(md.Period.Duration())) {
+			e.known[len(e.known)-1].End = cs.Time
+			continue
+		}
+		e.known = append(e.known, timeseries.TimeRange{Start: cs.Time, End: cs.Time})
+	}
+
+	return e, nil
+}
+
+func (c *Cache) write(md candlestick.ListMetadata, e *cacheEntry) error {
+	p := c.path(md)
+	if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+		return err
+	}
+
+	return WriteFile(p, File{
+		Exchange:     md.Exchange,
+		Pair:         md.Pair,
+		Period:       md.Period,
+		Candlesticks: e.candlesticks,
+	})
+}
+
+// add adds candlesticks to the entry and marks the range as known, if not zero.
+func (e *cacheEntry) add(
+	md candlestick.ListMetadata,
+	known timeseries.TimeRange,
+	list []candlestick.Candlestick,
+) error {
+	if len(list) > 0 {
+		l := candlestick.NewListWithMetadata(md)
+		for _, cs := range append(e.candlesticks, list...) {
+			if err := l.Set(cs); err != nil {
+				return err
+			}
+		}
+		e.candlesticks = l.ToArray()
+	}
+
+	if known.Start.IsZero() {
+		return nil
+	}
+
+	merged, err := timeseries.MergeTimeRanges(e.known, []timeseries.TimeRange{known})
+	if err != nil {
+		return err
+	}
+	e.known = merged
+	return nil
+}
+
+// unknown returns the parts of the range that are not known, in order.
+// The ranges include their start and end, separated by the interval.
+func (e *cacheEntry) unknown(start, end time.Time, interval time.Duration) []timeseries.TimeRange {
+	unknown := make([]timeseries.TimeRange, 0, 1)
+	for _, k := range e.known {
+		if k.End.Before(start) {
+			continue
+		} else if k.Start.After(end) {
+			break
+		}
+
+		if k.Start.After(start) {
+			unknown = append(unknown, timeseries.TimeRange{Start: start, End: k.Start.Add(-interval)})
+		}
+		start = k.End.Add(interval)
+		if start.After(end) {
+			return unknown
+		}
+	}
+
+	return append(unknown, timeseries.TimeRange{Start: start, End: end})
+}
+
+// extract returns the candlesticks between start and end, inclusive, up to
+// the limit if not zero.
+func (e *cacheEntry) extract(start, end time.Time, limit uint) []candlestick.Candlestick {
+	from := sort.Search(len(e.candlesticks), func(i int) bool {
+		return !e.candlesticks[i].Time.Before(start)
+	})
+	to := sort.Search(len(e.candlesticks), func(i int) bool {
+		return e.candlesticks[i].Time.After(end)
+	})
+	if limit > 0 && to-from > int(limit) {
+		to = from + int(limit)
+	}
+
+	return append([]candlestick.Candlestick(nil), e.candlesticks[from:max(from, to)]...)
+}
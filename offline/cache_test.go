@@ -0,0 +1,150 @@
+package offline
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	candlesticksapi "github.com/cryptellation/candlesticks/api"
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
+	"github.com/cryptellation/candlesticks/pkg/period"
+	"github.com/cryptellation/go-clients/internal/fixture"
+	"github.com/stretchr/testify/require"
+)
+
+// countingSource is a candlesticks source that counts the calls it receives.
+type countingSource struct {
+	source CandlesticksSource
+	calls  []candlesticksapi.ListCandlesticksWorkflowParams
+}
+
+func (s *countingSource) ListCandlesticks(
+	ctx context.Context,
+	params candlesticksapi.ListCandlesticksWorkflowParams,
+) (candlesticksapi.ListCandlesticksWorkflowResults, error) {
+	s.calls = append(s.calls, params)
+	return s.source.ListCandlesticks(ctx, params)
+}
+
+// newUpstream returns a source with the candlesticks of the prices, one per
+// minute from the fixtures start.
+func newUpstream(t *testing.T, prices ...float64) *countingSource {
+	src, err := NewFileSource()
+	require.NoError(t, err)
+	require.NoError(t, src.Add(File{
+		Exchange:     fixture.Exchange,
+		Pair:         fixture.Pair,
+		Period:       period.M1,
+		Candlesticks: fixture.Closes(prices...),
+	}))
+	return &countingSource{source: src}
+}
+
+// list lists the candlesticks of the fixtures market between the minutes.
+func list(t *testing.T, src CandlesticksSource, from, to int, limit uint) []candlestick.Candlestick {
+	start, end := fixture.Minute(from), fixture.Minute(to)
+	res, err := src.ListCandlesticks(context.Background(), candlesticksapi.ListCandlesticksWorkflowParams{
+		Exchange: fixture.Exchange,
+		Pair:     fixture.Pair,
+		Period:   period.M1,
+		Start:    &start,
+		End:      &end,
+		Limit:    limit,
+	})
+	require.NoError(t, err)
+	return res.List
+}
+
+// minutes returns the minutes of the candlesticks.
+func minutes(list []candlestick.Candlestick) []int {
+	m := make([]int, len(list))
+	for i, cs := range list {
+		m[i] = fixture.Minutes(cs.Time)
+	}
+	return m
+}
+
+func TestCacheListCandlesticks(t *testing.T) {
+	upstream := newUpstream(t, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	cache := NewCache(t.TempDir(), upstream)
+
+	tests := []struct {
+		name     string
+		from, to int
+		limit    uint
+		expected []int
+		calls    int
+	}{
+		{name: "downloaded", from: 2, to: 5, expected: []int{2, 3, 4, 5}, calls: 1},
+		{name: "cached", from: 3, to: 4, expected: []int{3, 4}, calls: 1},
+		{name: "limited", from: 2, to: 5, limit: 1, expected: []int{2}, calls: 1},
+		{name: "partly cached", from: 4, to: 7, expected: []int{4, 5, 6, 7}, calls: 2},
+		{name: "missing upstream", from: 8, to: 12, expected: []int{8, 9}, calls: 3},
+		{name: "known missing", from: 9, to: 11, expected: []int{9}, calls: 3},
+		{name: "before cached", from: 0, to: 3, expected: []int{0, 1, 2, 3}, calls: 4},
+		{name: "fully known", from: 0, to: 12, limit: 3, expected: []int{0, 1, 2}, calls: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, minutes(list(t, cache, tt.from, tt.to, tt.limit)))
+			require.Len(t, upstream.calls, tt.calls)
+		})
+	}
+}
+
+func TestCacheFile(t *testing.T) {
+	dir := t.TempDir()
+	upstream := newUpstream(t, 1, 2, 3, 4, 5)
+	expected := list(t, NewCache(dir, upstream), 0, 2, 0)
+
+	// Serve from the files without upstream
+	require.Equal(t, expected, list(t, NewCache(dir, nil), 0, 2, 0))
+	require.Empty(t, list(t, NewCache(dir, nil), 3, 4, 0))
+
+	// Only download what is not in the files
+	upstream.calls = nil
+	require.Equal(t, []int{0, 1, 2, 3, 4}, minutes(list(t, NewCache(dir, upstream), 0, 4, 0)))
+	require.Len(t, upstream.calls, 1)
+	require.Equal(t, fixture.Minute(3), *upstream.calls[0].Start)
+}
+
+func TestCachePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		exchange string
+		pair     string
+		expected string
+	}{
+		{name: "plain", exchange: "binance", pair: "BTC-USDT", expected: "binance/BTC-USDT/M1.json"},
+		{name: "separator", exchange: "binance", pair: "BTC/USDT", expected: "binance/BTC%2FUSDT/M1.json"},
+		{name: "parent", exchange: "..", pair: "..", expected: "%2E%2E/%2E%2E/M1.json"},
+		{name: "relative", exchange: "../../etc", pair: "x", expected: "%2E%2E%2F%2E%2E%2Fetc/x/M1.json"},
+		{name: "absolute", exchange: "/tmp", pair: "x", expected: "%2Ftmp/x/M1.json"},
+	}
+
+	dir := t.TempDir()
+	cache := NewCache(dir, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := cache.path(candlestick.ListMetadata{Exchange: tt.exchange, Pair: tt.pair, Period: period.M1})
+			require.Equal(t, filepath.Join(dir, filepath.FromSlash(tt.expected)), p)
+			require.True(t, strings.HasPrefix(p, dir+string(filepath.Separator)))
+		})
+	}
+}
+
+func TestFileSourceNoData(t *testing.T) {
+	src := newUpstream(t, 1, 2, 3)
+	start := fixture.Minute(0)
+	_, err := src.ListCandlesticks(context.Background(), candlesticksapi.ListCandlesticksWorkflowParams{
+		Exchange: "kraken",
+		Pair:     fixture.Pair,
+		Period:   period.M1,
+		Start:    &start,
+		End:      &start,
+	})
+	require.ErrorIs(t, err, ErrNoData)
+	require.Empty(t, list(t, src, 5, 6, 0))
+}
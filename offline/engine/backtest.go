@@ -0,0 +1,289 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	backtestsapi "github.com/cryptellation/backtests/api"
+	"github.com/cryptellation/backtests/pkg/backtest"
+	candlesticksapi "github.com/cryptellation/candlesticks/api"
+	"github.com/cryptellation/runtime"
+	"github.com/cryptellation/runtime/account"
+	"github.com/cryptellation/ticks/pkg/tick"
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+var (
+	// ErrBacktestDone is returned when an order is passed on a finished backtest.
+	ErrBacktestDone = errors.New("backtest is done")
+)
+
+// RunBacktest runs a backtest of the runnable in-process and returns the
+// backtest in its final state (accounts, orders, etc.).
+func (e *Engine) RunBacktest(
+	ctx context.Context,
+	params backtest.Parameters,
+	r runtime.Runnable,
+) (backtest.Backtest, error) {
+	// Copy accounts as they will be modified during the run
+	params.Accounts = copyAccounts(params.Accounts)
+
+	bt, err := backtest.New(params, callbacksFromRunnable(r))
+	if err != nil {
+		return backtest.Backtest{}, err
+	}
+
+	run := &backtestRun{
+		engine:   e,
+		runnable: r,
+		backtest: bt,
+	}
+	err = run.run(ctx)
+
+	return run.backtest, err
+}
+
+func copyAccounts(accounts map[string]account.Account) map[string]account.Account {
+	cp := make(map[string]account.Account, len(accounts))
+	for exch, a := range accounts {
+		balances := make(map[string]float64, len(a.Balances))
+		for asset, qty := range a.Balances {
+			balances[asset] = qty
+		}
+		cp[exch] = account.Account{Balances: balances}
+	}
+	return cp
+}
+
+type backtestRun struct {
+	engine   *Engine
+	runnable runtime.Runnable
+	backtest backtest.Backtest
+}
+
+func (r *backtestRun) run(ctx context.Context) error {
+	env := r.engine.newEnvironment(ctx, r.runnable, r.backtest.StartTime)
+	r.register(ctx, env)
+
+	return executeRun(env, func(wfCtx workflow.Context) error {
+		// Init the backtest from client side
+		if err := r.exec(wfCtx, r.backtest.Callbacks.OnInitCallback, runtime.OnInitCallbackWorkflowParams{
+			Context: r.runtimeContext(r.backtest.StartTime),
+		}); err != nil {
+			return fmt.Errorf("initializing backtest: %w", err)
+		}
+
+		// Loop on backtest events
+		if err := r.loop(ctx, wfCtx); err != nil {
+			return fmt.Errorf("looping through backtest events: %w", err)
+		}
+
+		// Exit the backtest from client side
+		if err := r.exec(wfCtx, r.backtest.Callbacks.OnExitCallback, runtime.OnExitCallbackWorkflowParams{
+			Context: r.runtimeContext(r.backtest.EndTime),
+		}); err != nil {
+			return fmt.Errorf("exiting backtest: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (r *backtestRun) loop(ctx context.Context, wfCtx workflow.Context) error {
+	for finished := false; !finished; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Get prices
+		prices, err := r.readActualPrices(ctx)
+		if err != nil {
+			return fmt.Errorf("cannot read actual prices: %w", err)
+		}
+		if len(prices) == 0 {
+			r.backtest.SetCurrentTime(r.backtest.EndTime)
+			break
+		} else if !prices[0].Time.Equal(r.backtest.CurrentCandlestick.Time) {
+			r.backtest.SetCurrentTime(prices[0].Time)
+		}
+
+		// Execute backtest with these prices
+		if err := r.exec(wfCtx, r.backtest.Callbacks.OnNewPricesCallback, runtime.OnNewPricesCallbackWorkflowParams{
+			Context: r.runtimeContext(prices[0].Time),
+			Ticks:   prices,
+		}); err != nil {
+			return fmt.Errorf("cannot execute backtest: %w", err)
+		}
+
+		// Advance backtest
+		finished, err = r.backtest.Advance()
+		if err != nil {
+			return fmt.Errorf("cannot advance backtest: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *backtestRun) readActualPrices(ctx context.Context) ([]tick.Tick, error) {
+	prices := make([]tick.Tick, 0, len(r.backtest.PricesSubscriptions))
+	for _, sub := range r.backtest.PricesSubscriptions {
+		res, err := r.engine.source.ListCandlesticks(ctx, candlesticksapi.ListCandlesticksWorkflowParams{
+			Exchange: sub.Exchange,
+			Pair:     sub.Pair,
+			Period:   r.backtest.PricePeriod,
+			Start:    &r.backtest.CurrentCandlestick.Time,
+			End:      &r.backtest.EndTime,
+			Limit:    1,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Get the first candlestick if possible
+		if len(res.List) == 0 {
+			continue
+		}
+		cs := res.List[0]
+
+		// Create tick from candlesticks
+		prices = append(prices, tick.FromCandlestick(
+			sub.Exchange, sub.Pair, r.backtest.CurrentCandlestick.Price, cs.Time, cs))
+	}
+
+	// Only keep the earliest same time ticks for time consistency
+	_, prices = tick.OnlyKeepEarliestSameTime(prices, r.backtest.EndTime)
+	return prices, nil
+}
+
+func (r *backtestRun) runtimeContext(now time.Time) runtime.Context {
+	return runtime.Context{
+		ID:              r.backtest.ID,
+		Mode:            runtime.ModeBacktest,
+		Now:             now,
+		ParentTaskQueue: TaskQueueName,
+	}
+}
+
+// exec executes a callback of the runnable at the current time of the backtest.
+func (r *backtestRun) exec(ctx workflow.Context, callback runtime.CallbackWorkflow, params any) error {
+	return execCallback(ctx, callback, r.backtest.CurrentCandlestick.Time, params)
+}
+
+// register registers the backtests service workflows, served by this run.
+func (r *backtestRun) register(ctx context.Context, env *testsuite.TestWorkflowEnvironment) {
+	env.RegisterWorkflowWithOptions(r.subscribeToPriceWorkflow, workflow.RegisterOptions{
+		Name: backtestsapi.SubscribeToPriceWorkflowName,
+	})
+	env.RegisterWorkflowWithOptions(
+		func(_ workflow.Context, params backtestsapi.CreateBacktestOrderWorkflowParams) (
+			backtestsapi.CreateBacktestOrderWorkflowResults, error,
+		) {
+			return backtestsapi.CreateBacktestOrderWorkflowResults{}, r.createOrder(ctx, params)
+		},
+		workflow.RegisterOptions{Name: backtestsapi.CreateBacktestOrderWorkflowName})
+	env.RegisterWorkflowWithOptions(r.getBacktestWorkflow, workflow.RegisterOptions{
+		Name: backtestsapi.GetBacktestWorkflowName,
+	})
+	env.RegisterWorkflowWithOptions(r.getBacktestAccountsWorkflow, workflow.RegisterOptions{
+		Name: backtestsapi.GetBacktestAccountsWorkflowName,
+	})
+	env.RegisterWorkflowWithOptions(r.getBacktestOrdersWorkflow, workflow.RegisterOptions{
+		Name: backtestsapi.GetBacktestOrdersWorkflowName,
+	})
+}
+
+func (r *backtestRun) checkID(id uuid.UUID) error {
+	if id != r.backtest.ID {
+		return fmt.Errorf("unknown backtest %q in offline run %q", id, r.backtest.ID)
+	}
+	return nil
+}
+
+func (r *backtestRun) subscribeToPriceWorkflow(
+	_ workflow.Context,
+	params backtestsapi.SubscribeToPriceWorkflowParams,
+) (backtestsapi.SubscribeToPriceWorkflowResults, error) {
+	if err := r.checkID(params.BacktestID); err != nil {
+		return backtestsapi.SubscribeToPriceWorkflowResults{}, err
+	}
+
+	if _, err := r.backtest.CreateTickSubscription(params.Exchange, params.Pair); err != nil {
+		return backtestsapi.SubscribeToPriceWorkflowResults{}, fmt.Errorf("cannot create subscription: %w", err)
+	}
+
+	return backtestsapi.SubscribeToPriceWorkflowResults{}, nil
+}
+
+func (r *backtestRun) createOrder(ctx context.Context, params backtestsapi.CreateBacktestOrderWorkflowParams) error {
+	if err := r.checkID(params.BacktestID); err != nil {
+		return err
+	}
+
+	// Create a new ID if not provided
+	if params.Order.ID == uuid.Nil {
+		params.Order.ID = uuid.New()
+	}
+
+	// Check if the backtest is done
+	if r.backtest.Done() {
+		return ErrBacktestDone
+	}
+
+	// Get candlestick for the time
+	res, err := r.engine.source.ListCandlesticks(ctx, candlesticksapi.ListCandlesticksWorkflowParams{
+		Exchange: params.Order.Exchange,
+		Pair:     params.Order.Pair,
+		Period:   r.backtest.PricePeriod,
+		Start:    &r.backtest.CurrentCandlestick.Time,
+		End:      &r.backtest.CurrentCandlestick.Time,
+	})
+	if err != nil {
+		return fmt.Errorf("could not get candlesticks: %w", err)
+	} else if len(res.List) == 0 {
+		return fmt.Errorf("%w: %d candlesticks retrieved", backtest.ErrNoDataForOrderValidation, len(res.List))
+	}
+
+	// Add order to backtest
+	if err := r.backtest.AddOrder(params.Order, res.List[0]); err != nil {
+		return err
+	}
+
+	// Detach the execution time from the backtest current time, as the backtest
+	// is kept in memory instead of being saved after each order
+	t := r.backtest.CurrentCandlestick.Time
+	r.backtest.Orders[len(r.backtest.Orders)-1].ExecutionTime = &t
+
+	return nil
+}
+
+func (r *backtestRun) getBacktestWorkflow(
+	_ workflow.Context,
+	params backtestsapi.GetBacktestWorkflowParams,
+) (backtestsapi.GetBacktestWorkflowResults, error) {
+	return backtestsapi.GetBacktestWorkflowResults{
+		Backtest: r.backtest,
+	}, r.checkID(params.BacktestID)
+}
+
+func (r *backtestRun) getBacktestAccountsWorkflow(
+	_ workflow.Context,
+	params backtestsapi.GetBacktestAccountsWorkflowParams,
+) (backtestsapi.GetBacktestAccountsWorkflowResults, error) {
+	return backtestsapi.GetBacktestAccountsWorkflowResults{
+		Accounts: r.backtest.Accounts,
+	}, r.checkID(params.BacktestID)
+}
+
+func (r *backtestRun) getBacktestOrdersWorkflow(
+	_ workflow.Context,
+	params backtestsapi.GetBacktestOrdersWorkflowParams,
+) (backtestsapi.GetBacktestOrdersWorkflowResults, error) {
+	return backtestsapi.GetBacktestOrdersWorkflowResults{
+		Orders: r.backtest.Orders,
+	}, r.checkID(params.BacktestID)
+}
@@ -0,0 +1,201 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cryptellation/backtests/pkg/backtest"
+	candlesticksapi "github.com/cryptellation/candlesticks/api"
+	exchangesapi "github.com/cryptellation/exchanges/api"
+	"github.com/cryptellation/exchanges/pkg/exchange"
+	"github.com/cryptellation/go-clients/client"
+	"github.com/cryptellation/go-clients/offline"
+	"github.com/cryptellation/runtime"
+	temporalLog "go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	// TaskQueueName is the task queue name given to the runnable as parent
+	// task queue when executed by the offline engine.
+	TaskQueueName = "CryptellationOfflineTaskQueue"
+
+	// defaultCallbackTimeout is the default wall-clock timeout for a callback execution.
+	defaultCallbackTimeout = time.Minute
+
+	// runWorkflowName is the name of the workflow of a run, that executes the
+	// callbacks of the runnable as its children.
+	runWorkflowName = "CryptellationOfflineRun"
+)
+
+// Engine is an in-process engine that runs strategies without any
+// Cryptellation service nor Temporal server.
+// Strategies are executed through the same callbacks and the same
+// wfclient.WfClient interface than on the Cryptellation stack.
+// The callbacks are executed with the Temporal test suite, which is why the
+// engine lives in its own package, apart from the offline data sources.
+type Engine struct {
+	source          offline.CandlesticksSource
+	exchanges       map[string]exchange.Exchange
+	logger          temporalLog.Logger
+	callbackTimeout time.Duration
+}
+
+// Options is a function that modifies the engine configuration.
+type Options func(*Engine)
+
+// WithLogger sets the logger used by the workflows executed in the engine.
+func WithLogger(logger temporalLog.Logger) Options {
+	return func(e *Engine) {
+		e.logger = logger
+	}
+}
+
+// WithExchanges sets the exchanges returned to the strategies when they call
+// GetExchange from the engine.
+func WithExchanges(exchanges ...exchange.Exchange) Options {
+	return func(e *Engine) {
+		for _, exch := range exchanges {
+			e.exchanges[exch.Name] = exch
+		}
+	}
+}
+
+// WithCallbackTimeout sets the maximum wall-clock duration of a callback execution.
+func WithCallbackTimeout(timeout time.Duration) Options {
+	return func(e *Engine) {
+		e.callbackTimeout = timeout
+	}
+}
+
+// New creates a new offline engine that uses the given candlesticks source.
+func New(source offline.CandlesticksSource, opts ...Options) *Engine {
+	e := &Engine{
+		source:          source,
+		exchanges:       make(map[string]exchange.Exchange),
+		logger:          &client.DummyLogger{},
+		callbackTimeout: defaultCallbackTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// callbacksFromRunnable returns the callbacks of a runnable, named the same
+// way than runtime.RegisterRunnable does.
+func callbacksFromRunnable(r runtime.Runnable) runtime.Callbacks {
+	return runtime.Callbacks{
+		OnInitCallback: runtime.CallbackWorkflow{
+			Name:          fmt.Sprintf("%s-OnInit", r.Name()),
+			TaskQueueName: TaskQueueName,
+		},
+		OnNewPricesCallback: runtime.CallbackWorkflow{
+			Name:          fmt.Sprintf("%s-OnNewPrices", r.Name()),
+			TaskQueueName: TaskQueueName,
+		},
+		OnExitCallback: runtime.CallbackWorkflow{
+			Name:          fmt.Sprintf("%s-OnExit", r.Name()),
+			TaskQueueName: TaskQueueName,
+		},
+	}
+}
+
+// newEnvironment creates the in-process workflow environment of a run, where the
+// runnable callbacks and the services workflows shared by every mode are registered.
+// The environment starts at the given time and is used for the whole run.
+func (e *Engine) newEnvironment(
+	ctx context.Context,
+	r runtime.Runnable,
+	now time.Time,
+) *testsuite.TestWorkflowEnvironment {
+	var suite testsuite.WorkflowTestSuite
+	suite.SetLogger(e.logger)
+	suite.SetDisableRegistrationAliasing(true)
+
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetStartTime(now)
+	env.SetTestTimeout(e.callbackTimeout)
+
+	// Register runnable callbacks
+	callbacks := callbacksFromRunnable(r)
+	env.RegisterWorkflowWithOptions(r.OnInit, workflow.RegisterOptions{
+		Name: callbacks.OnInitCallback.Name,
+	})
+	env.RegisterWorkflowWithOptions(r.OnNewPrices, workflow.RegisterOptions{
+		Name: callbacks.OnNewPricesCallback.Name,
+	})
+	env.RegisterWorkflowWithOptions(r.OnExit, workflow.RegisterOptions{
+		Name: callbacks.OnExitCallback.Name,
+	})
+
+	// Register services workflows
+	env.RegisterWorkflowWithOptions(
+		func(_ workflow.Context, params candlesticksapi.ListCandlesticksWorkflowParams) (
+			candlesticksapi.ListCandlesticksWorkflowResults, error,
+		) {
+			return e.source.ListCandlesticks(ctx, params)
+		},
+		workflow.RegisterOptions{Name: candlesticksapi.ListCandlesticksWorkflowName})
+	env.RegisterWorkflowWithOptions(e.getExchangeWorkflow, workflow.RegisterOptions{
+		Name: exchangesapi.GetExchangeWorkflowName,
+	})
+
+	return env
+}
+
+func (e *Engine) getExchangeWorkflow(
+	_ workflow.Context,
+	params exchangesapi.GetExchangeWorkflowParams,
+) (exchangesapi.GetExchangeWorkflowResults, error) {
+	exch, ok := e.exchanges[params.Name]
+	if !ok {
+		return exchangesapi.GetExchangeWorkflowResults{}, fmt.Errorf(
+			"%w: %q", backtest.ErrInvalidExchange, params.Name)
+	}
+
+	return exchangesapi.GetExchangeWorkflowResults{
+		Exchange: exch,
+	}, nil
+}
+
+// executeRun executes the run function as the workflow of the environment,
+// and returns its error as is.
+func executeRun(env *testsuite.TestWorkflowEnvironment, run func(ctx workflow.Context) error) error {
+	var runErr error
+	env.RegisterWorkflowWithOptions(func(ctx workflow.Context) error {
+		runErr = run(ctx)
+		return runErr
+	}, workflow.RegisterOptions{Name: runWorkflowName})
+
+	if err := execute(env, runWorkflowName); err != nil && runErr == nil {
+		return err
+	}
+	return runErr
+}
+
+// execCallback executes a callback of the runnable as a child of the run
+// workflow, once the workflow time has reached the given time.
+func execCallback(ctx workflow.Context, callback runtime.CallbackWorkflow, now time.Time, params any) error {
+	if d := now.Sub(workflow.Now(ctx)); d > 0 {
+		if err := workflow.Sleep(ctx, d); err != nil {
+			return err
+		}
+	}
+
+	return workflow.ExecuteChildWorkflow(ctx, callback.Name, params).Get(ctx, nil)
+}
+
+// execute executes a workflow in the environment and returns its error.
+func execute(env *testsuite.TestWorkflowEnvironment, name string, params ...any) error {
+	env.ExecuteWorkflow(name, params...)
+	if !env.IsWorkflowCompleted() {
+		return fmt.Errorf("workflow %q did not complete", name)
+	}
+
+	return env.GetWorkflowError()
+}
@@ -0,0 +1,257 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cryptellation/backtests/pkg/backtest"
+	"github.com/cryptellation/candlesticks/pkg/period"
+	"github.com/cryptellation/forwardtests/pkg/forwardtest"
+	"github.com/cryptellation/go-clients/internal/fixture"
+	"github.com/cryptellation/go-clients/offline"
+	"github.com/cryptellation/go-clients/wfclient"
+	"github.com/cryptellation/runtime"
+	"github.com/cryptellation/runtime/account"
+	"github.com/cryptellation/runtime/order"
+	"github.com/cryptellation/ticks/pkg/tick"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/workflow"
+)
+
+var errTestRunnable = errors.New("test runnable error")
+
+// testRunnable subscribes to the fixtures market, buys one unit on the first
+// prices and records the prices it receives, with the workflow time.
+type testRunnable struct {
+	prices []tick.Tick
+	times  []time.Time
+	exited bool
+	// failAt makes the runnable fail on the prices of the given minute.
+	failAt int
+}
+
+func (r *testRunnable) Name() string {
+	return "TestRunnable"
+}
+
+func (r *testRunnable) OnInit(ctx workflow.Context, params runtime.OnInitCallbackWorkflowParams) error {
+	return wfclient.NewWfClient().SubscribeToPrice(ctx, wfclient.SubscribeToPriceParams{
+		Context:  params.Context,
+		Exchange: fixture.Exchange,
+		Pair:     fixture.Pair,
+	})
+}
+
+func (r *testRunnable) OnNewPrices(ctx workflow.Context, params runtime.OnNewPricesCallbackWorkflowParams) error {
+	r.prices = append(r.prices, params.Ticks...)
+	r.times = append(r.times, workflow.Now(ctx))
+	if r.failAt > 0 && fixture.Minutes(params.Context.Now) == r.failAt {
+		return errTestRunnable
+	} else if len(r.prices) > 1 {
+		return nil
+	}
+
+	return wfclient.NewExtendedWfClient().CreateOrder(ctx, wfclient.CreateOrderParams{
+		Context: params.Context,
+		Order: order.Order{
+			Type:     order.TypeIsMarket,
+			Exchange: fixture.Exchange,
+			Pair:     fixture.Pair,
+			Side:     order.SideIsBuy,
+			Quantity: 1,
+		},
+	})
+}
+
+func (r *testRunnable) OnExit(_ workflow.Context, _ runtime.OnExitCallbackWorkflowParams) error {
+	r.exited = true
+	return nil
+}
+
+// newTestEngine returns an engine with the candlesticks of the prices, one
+// per minute from the fixtures start.
+func newTestEngine(t *testing.T, prices ...float64) *Engine {
+	src, err := offline.NewFileSource()
+	require.NoError(t, err)
+	require.NoError(t, src.Add(offline.File{
+		Exchange:     fixture.Exchange,
+		Pair:         fixture.Pair,
+		Period:       period.M1,
+		Candlesticks: fixture.Closes(prices...),
+	}))
+	return New(src)
+}
+
+// backtestParams returns the parameters of a backtest of the fixtures market
+// between the minutes.
+func backtestParams(from, to int) backtest.Parameters {
+	end := fixture.Minute(to)
+	return backtest.Parameters{
+		Accounts: map[string]account.Account{
+			fixture.Exchange: {Balances: map[string]float64{"USDT": 1000}},
+		},
+		StartTime: fixture.Minute(from),
+		EndTime:   &end,
+	}
+}
+
+func TestRunBacktest(t *testing.T) {
+	e := newTestEngine(t, 10, 11, 12, 13, 14, 15)
+	params := backtestParams(1, 4)
+	r := &testRunnable{}
+
+	bt, err := e.RunBacktest(context.Background(), params, r)
+	require.NoError(t, err)
+
+	// Prices are received once per candlestick before the end, at their time
+	prices := make([]float64, len(r.prices))
+	for i, p := range r.prices {
+		prices[i] = p.Price
+		require.Equal(t, fixture.Minute(i+1), p.Time)
+		require.Equal(t, p.Time, r.times[i])
+	}
+	require.Equal(t, []float64{11, 12, 13}, prices)
+	require.True(t, r.exited)
+
+	// The order is executed at the first close
+	require.Len(t, bt.Orders, 1)
+	require.Equal(t, 11.0, bt.Orders[0].Price)
+	require.Equal(t, fixture.Minute(1), *bt.Orders[0].ExecutionTime)
+	require.Equal(t, map[string]float64{"USDT": 989, "BTC": 1}, bt.Accounts[fixture.Exchange].Balances)
+
+	// The parameters accounts are left untouched
+	require.Equal(t, map[string]float64{"USDT": 1000}, params.Accounts[fixture.Exchange].Balances)
+}
+
+func TestRunBacktestErrors(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		runnable *testRunnable
+		expected error
+	}{
+		{
+			name:     "callback error",
+			ctx:      context.Background(),
+			runnable: &testRunnable{failAt: 1},
+			expected: errTestRunnable,
+		},
+		{
+			name:     "canceled",
+			ctx:      canceled,
+			runnable: &testRunnable{},
+			expected: context.Canceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestEngine(t, 10, 11, 12).RunBacktest(tt.ctx, backtestParams(0, 2), tt.runnable)
+			require.ErrorContains(t, err, tt.expected.Error())
+			require.False(t, tt.runnable.exited)
+		})
+	}
+}
+
+func TestReplayForwardtest(t *testing.T) {
+	// Record the ticks, with a tick of another market received at the same time
+	path := filepath.Join(t.TempDir(), "ticks.zst")
+	recorder, err := offline.NewTicksRecorder(path)
+	require.NoError(t, err)
+	recorded := append(fixture.Ticks(10, 11, 12), fixture.TickOn(1, "coinbase", 20))
+	for _, tk := range recorded {
+		require.NoError(t, recorder.Record(tk))
+	}
+	require.NoError(t, recorder.Close())
+	ticks, err := offline.ReadTicks(path)
+	require.NoError(t, err)
+
+	id := uuid.New()
+	params := forwardtestParams(id, ticks)
+	r := &testRunnable{}
+	ft, err := newTestEngine(t).ReplayForwardtest(context.Background(), params, r)
+	require.NoError(t, err)
+	require.Equal(t, id, ft.ID)
+	require.Equal(t, forwardtest.StatusFinished, ft.Status)
+	require.True(t, r.exited)
+
+	// Only the subscribed ticks are received, at their time
+	require.Len(t, r.prices, 3)
+	for i, p := range r.prices {
+		require.Equal(t, float64(10+i), p.Price)
+		require.True(t, fixture.Minute(i).Equal(r.times[i]))
+	}
+
+	// The order is executed at the first tick, with a generated ID as the service does
+	require.Len(t, ft.Orders, 1)
+	require.NotEqual(t, uuid.Nil, ft.Orders[0].ID)
+	require.Equal(t, 10.0, ft.Orders[0].Price)
+	require.True(t, fixture.Minute(0).Equal(*ft.Orders[0].ExecutionTime))
+	require.Equal(t, map[string]float64{"USDT": 990, "BTC": 1}, ft.Accounts[fixture.Exchange].Balances)
+
+	// The order IDs are generated on each replay
+	again, err := newTestEngine(t).ReplayForwardtest(context.Background(), params, &testRunnable{})
+	require.NoError(t, err)
+	require.NotEqual(t, ft.Orders[0].ID, again.Orders[0].ID)
+	require.Equal(t, ft.Accounts, again.Accounts)
+}
+
+func TestReplayForwardtestErrors(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		ticks    []tick.Tick
+		runnable *testRunnable
+		expected error
+	}{
+		{
+			name:     "no ticks",
+			ctx:      context.Background(),
+			runnable: &testRunnable{},
+			expected: ErrNoTicks,
+		},
+		{
+			name:     "callback error",
+			ctx:      context.Background(),
+			ticks:    fixture.Ticks(10, 11, 12),
+			runnable: &testRunnable{failAt: 1},
+			expected: errTestRunnable,
+		},
+		{
+			name:     "canceled",
+			ctx:      canceled,
+			ticks:    fixture.Ticks(10, 11, 12),
+			runnable: &testRunnable{},
+			expected: context.Canceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestEngine(t).ReplayForwardtest(tt.ctx, forwardtestParams(uuid.New(), tt.ticks), tt.runnable)
+			require.ErrorContains(t, err, tt.expected.Error())
+			require.False(t, tt.runnable.exited)
+		})
+	}
+}
+
+// forwardtestParams returns the parameters of a replay of the ticks.
+func forwardtestParams(id uuid.UUID, ticks []tick.Tick) ReplayParameters {
+	return ReplayParameters{
+		ID: id,
+		Accounts: map[string]account.Account{
+			fixture.Exchange: {Balances: map[string]float64{"USDT": 1000}},
+		},
+		Ticks: ticks,
+	}
+}
@@ -0,0 +1,285 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
+	forwardtestsapi "github.com/cryptellation/forwardtests/api"
+	"github.com/cryptellation/forwardtests/pkg/forwardtest"
+	"github.com/cryptellation/runtime"
+	"github.com/cryptellation/runtime/account"
+	"github.com/cryptellation/ticks/pkg/tick"
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+var (
+	// ErrNoTicks is returned when a replay has no ticks.
+	ErrNoTicks = errors.New("no ticks to replay")
+	// ErrNoTickForOrder is returned when an order is passed on a pair that
+	// has not received any tick yet.
+	ErrNoTickForOrder = errors.New("no tick to execute order")
+)
+
+// ReplayParameters are the parameters of a forwardtest replay.
+type ReplayParameters struct {
+	// ID is the ID of the replayed forwardtest. Reusing the ID of a recorded
+	// forwardtest gives the strategy the same context. Generated if empty.
+	ID uuid.UUID
+	// Accounts are the initial accounts of the forwardtest.
+	Accounts map[string]account.Account
+	// Ticks are the ticks to replay, in their recorded order (see offline.ReadTicks).
+	Ticks []tick.Tick
+	// Speed is the replay speed relative to the recording: 1 replays in real
+	// time and 60 replays an hour per minute. Zero replays as fast as possible.
+	Speed float64
+}
+
+// ReplayForwardtest runs a forwardtest of the runnable in-process, fed by
+// recorded ticks instead of the live ticks service, and returns the
+// forwardtest in its final state. The orders are handled as the forwardtests
+// service does: the orders without ID get a random one and the execution time
+// is the current time of the forwardtest, which is the time of the last
+// replayed ticks. The replay can still differ from the live run:
+//   - the execution times are the times of the ticks, where the live service
+//     uses its clock when the order is processed, a bit later;
+//   - the execution prices are the prices of the last ticks of the pairs,
+//     where the live service uses the close of the current minute candlestick;
+//   - the ticks received at the same time are grouped in a single callback,
+//     where the live service can deliver them separately.
+func (e *Engine) ReplayForwardtest(
+	ctx context.Context,
+	params ReplayParameters,
+	r runtime.Runnable,
+) (forwardtest.Forwardtest, error) {
+	if len(params.Ticks) == 0 {
+		return forwardtest.Forwardtest{}, ErrNoTicks
+	}
+
+	// Copy accounts as they will be modified during the run
+	ft, err := forwardtest.New(forwardtest.NewForwardtestParams{
+		Accounts:  copyAccounts(params.Accounts),
+		Callbacks: callbacksFromRunnable(r),
+	})
+	if err != nil {
+		return forwardtest.Forwardtest{}, err
+	}
+	if params.ID != uuid.Nil {
+		ft.ID = params.ID
+	}
+
+	run := &forwardtestRun{
+		engine:        e,
+		runnable:      r,
+		forwardtest:   ft,
+		speed:         params.Speed,
+		subscriptions: make(map[tick.Subscription]bool),
+		prices:        make(map[tick.Subscription]tick.Tick),
+	}
+	err = run.run(ctx, params.Ticks)
+
+	return run.forwardtest, err
+}
+
+type forwardtestRun struct {
+	engine        *Engine
+	runnable      runtime.Runnable
+	forwardtest   forwardtest.Forwardtest
+	speed         float64
+	subscriptions map[tick.Subscription]bool
+	prices        map[tick.Subscription]tick.Tick
+}
+
+func (r *forwardtestRun) run(ctx context.Context, ticks []tick.Tick) error {
+	// Init the forwardtest from client side
+	r.forwardtest.UpdatedAt = ticks[0].Time
+	r.forwardtest.Status = forwardtest.StatusRunning
+	if err := r.exec(ctx, r.forwardtest.Callbacks.OnInitCallback, runtime.OnInitCallbackWorkflowParams{
+		Context: r.runtimeContext(),
+	}); err != nil {
+		return fmt.Errorf("initializing forwardtest: %w", err)
+	}
+
+	// Replay the ticks
+	if err := r.loop(ctx, ticks); err != nil {
+		return fmt.Errorf("replaying ticks: %w", err)
+	}
+
+	// Exit the forwardtest from client side
+	if err := r.exec(ctx, r.forwardtest.Callbacks.OnExitCallback, runtime.OnExitCallbackWorkflowParams{
+		Context: r.runtimeContext(),
+	}); err != nil {
+		return fmt.Errorf("exiting forwardtest: %w", err)
+	}
+	r.forwardtest.Status = forwardtest.StatusFinished
+
+	return nil
+}
+
+func (r *forwardtestRun) loop(ctx context.Context, ticks []tick.Tick) error {
+	for start := 0; start < len(ticks); {
+		// Get the ticks received at the same time
+		end := start + 1
+		for end < len(ticks) && ticks[end].Time.Equal(ticks[start].Time) {
+			end++
+		}
+		group := ticks[start:end]
+		start = end
+
+		// Wait as long as in the recording
+		if err := r.wait(ctx, group[0].Time); err != nil {
+			return err
+		}
+		r.forwardtest.UpdatedAt = group[0].Time
+
+		// Update prices and keep the subscribed ticks
+		prices := make([]tick.Tick, 0, len(group))
+		for _, t := range group {
+			sub := tick.Subscription{Exchange: t.Exchange, Pair: t.Pair}
+			r.prices[sub] = t
+			if r.subscriptions[sub] {
+				prices = append(prices, t)
+			}
+		}
+		if len(prices) == 0 {
+			continue
+		}
+
+		// Execute forwardtest with these prices
+		if err := r.exec(ctx, r.forwardtest.Callbacks.OnNewPricesCallback, runtime.OnNewPricesCallbackWorkflowParams{
+			Context: r.runtimeContext(),
+			Ticks:   prices,
+		}); err != nil {
+			return fmt.Errorf("cannot execute forwardtest: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// wait waits the time between the current time and the next one, divided
+// by the replay speed.
+func (r *forwardtestRun) wait(ctx context.Context, next time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if r.speed <= 0 || !next.After(r.forwardtest.UpdatedAt) {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(float64(next.Sub(r.forwardtest.UpdatedAt)) / r.speed))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (r *forwardtestRun) runtimeContext() runtime.Context {
+	return runtime.Context{
+		ID:              r.forwardtest.ID,
+		Mode:            runtime.ModeForwardtest,
+		Now:             r.forwardtest.UpdatedAt,
+		ParentTaskQueue: TaskQueueName,
+	}
+}
+
+// exec executes a callback of the runnable in a new environment where the
+// forwardtests service workflows are served by this run.
+func (r *forwardtestRun) exec(ctx context.Context, callback runtime.CallbackWorkflow, params any) error {
+	env := r.engine.newEnvironment(ctx, r.runnable, r.forwardtest.UpdatedAt)
+	r.register(env)
+	return execute(env, callback.Name, params)
+}
+
+func (r *forwardtestRun) register(env *testsuite.TestWorkflowEnvironment) {
+	env.RegisterWorkflowWithOptions(r.subscribeToPriceWorkflow, workflow.RegisterOptions{
+		Name: forwardtestsapi.SubscribeToPriceWorkflowName,
+	})
+	env.RegisterWorkflowWithOptions(r.createOrderWorkflow, workflow.RegisterOptions{
+		Name: forwardtestsapi.CreateForwardtestOrderWorkflowName,
+	})
+	env.RegisterWorkflowWithOptions(r.getForwardtestWorkflow, workflow.RegisterOptions{
+		Name: forwardtestsapi.GetForwardtestWorkflowName,
+	})
+	env.RegisterWorkflowWithOptions(r.listForwardtestAccountsWorkflow, workflow.RegisterOptions{
+		Name: forwardtestsapi.ListForwardtestAccountsWorkflowName,
+	})
+}
+
+func (r *forwardtestRun) checkID(id uuid.UUID) error {
+	if id != r.forwardtest.ID {
+		return fmt.Errorf("unknown forwardtest %q in offline run %q", id, r.forwardtest.ID)
+	}
+	return nil
+}
+
+func (r *forwardtestRun) subscribeToPriceWorkflow(
+	_ workflow.Context,
+	params forwardtestsapi.SubscribeToPriceWorkflowParams,
+) (forwardtestsapi.SubscribeToPriceWorkflowResults, error) {
+	if err := r.checkID(params.ForwardtestID); err != nil {
+		return forwardtestsapi.SubscribeToPriceWorkflowResults{}, err
+	}
+
+	r.subscriptions[tick.Subscription{Exchange: params.Exchange, Pair: params.Pair}] = true
+	return forwardtestsapi.SubscribeToPriceWorkflowResults{}, nil
+}
+
+func (r *forwardtestRun) createOrderWorkflow(
+	_ workflow.Context,
+	params forwardtestsapi.CreateForwardtestOrderWorkflowParams,
+) (forwardtestsapi.CreateForwardtestOrderWorkflowResults, error) {
+	if err := r.checkID(params.ForwardtestID); err != nil {
+		return forwardtestsapi.CreateForwardtestOrderWorkflowResults{}, err
+	}
+
+	// Generate the ID if not provided, as the forwardtests service does
+	if params.Order.ID == uuid.Nil {
+		params.Order.ID = uuid.New()
+	}
+
+	// Get the last price of the pair
+	t, ok := r.prices[tick.Subscription{Exchange: params.Order.Exchange, Pair: params.Order.Pair}]
+	if !ok {
+		return forwardtestsapi.CreateForwardtestOrderWorkflowResults{}, fmt.Errorf(
+			"%w: %s on %s", ErrNoTickForOrder, params.Order.Pair, params.Order.Exchange)
+	}
+
+	// Add order to forwardtest
+	if err := r.forwardtest.AddOrder(params.Order, candlestick.Candlestick{Close: t.Price}); err != nil {
+		return forwardtestsapi.CreateForwardtestOrderWorkflowResults{}, err
+	}
+
+	// Use the forwardtest time as execution time, as the wall-clock time of
+	// the replay is not the time of the ticks
+	now := r.forwardtest.UpdatedAt
+	r.forwardtest.Orders[len(r.forwardtest.Orders)-1].ExecutionTime = &now
+
+	return forwardtestsapi.CreateForwardtestOrderWorkflowResults{}, nil
+}
+
+func (r *forwardtestRun) getForwardtestWorkflow(
+	_ workflow.Context,
+	params forwardtestsapi.GetForwardtestWorkflowParams,
+) (forwardtestsapi.GetForwardtestWorkflowResults, error) {
+	return forwardtestsapi.GetForwardtestWorkflowResults{
+		Forwardtest: r.forwardtest,
+	}, r.checkID(params.ForwardtestID)
+}
+
+func (r *forwardtestRun) listForwardtestAccountsWorkflow(
+	_ workflow.Context,
+	params forwardtestsapi.ListForwardtestAccountsWorkflowParams,
+) (forwardtestsapi.ListForwardtestAccountsWorkflowResults, error) {
+	return forwardtestsapi.ListForwardtestAccountsWorkflowResults{
+		Accounts: r.forwardtest.Accounts,
+	}, r.checkID(params.ForwardtestID)
+}
@@ -0,0 +1,139 @@
+package offline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	candlesticksapi "github.com/cryptellation/candlesticks/api"
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
+	"github.com/cryptellation/candlesticks/pkg/period"
+)
+
+var (
+	// ErrNoData is returned when a source has no candlesticks for the requested
+	// exchange, pair and period.
+	ErrNoData = errors.New("no candlesticks data")
+)
+
+// CandlesticksSource is a source of candlesticks for the offline engine.
+// The Cryptellation client implements this interface, so it can be used
+// directly or through a Cache.
+type CandlesticksSource interface {
+	// ListCandlesticks lists candlesticks with the same semantics as the
+	// candlesticks service.
+	ListCandlesticks(
+		ctx context.Context,
+		params candlesticksapi.ListCandlesticksWorkflowParams,
+	) (candlesticksapi.ListCandlesticksWorkflowResults, error)
+}
+
+// File is the content of a candlesticks file, as read by FileSource and
+// written by WriteFile.
+type File struct {
+	Exchange     string                    `json:"exchange"`
+	Pair         string                    `json:"pair"`
+	Period       period.Symbol             `json:"period"`
+	Candlesticks []candlestick.Candlestick `json:"candlesticks"`
+}
+
+// ReadFile reads a candlesticks file.
+func ReadFile(path string) (File, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return File{}, err
+	}
+
+	var f File
+	if err := json.Unmarshal(content, &f); err != nil {
+		return File{}, fmt.Errorf("decoding %q: %w", path, err)
+	}
+
+	return f, nil
+}
+
+// WriteFile writes a candlesticks file.
+func WriteFile(path string, f File) error {
+	content, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, content, 0o600)
+}
+
+// FileSource is a candlesticks source that serves candlesticks loaded from
+// files in memory.
+type FileSource struct {
+	lists map[candlestick.ListMetadata]*candlestick.List
+}
+
+// NewFileSource creates a new candlesticks source from candlesticks files.
+func NewFileSource(paths ...string) (*FileSource, error) {
+	src := &FileSource{
+		lists: make(map[candlestick.ListMetadata]*candlestick.List),
+	}
+
+	for _, p := range paths {
+		f, err := ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := src.Add(f); err != nil {
+			return nil, fmt.Errorf("loading %q: %w", p, err)
+		}
+	}
+
+	return src, nil
+}
+
+// Add adds the candlesticks of a file to the source.
+func (s *FileSource) Add(f File) error {
+	if err := f.Period.Validate(); err != nil {
+		return err
+	}
+
+	md := candlestick.ListMetadata{
+		Exchange: f.Exchange,
+		Pair:     f.Pair,
+		Period:   f.Period,
+	}
+
+	l, ok := s.lists[md]
+	if !ok {
+		l = candlestick.NewListWithMetadata(md)
+		s.lists[md] = l
+	}
+
+	for _, cs := range f.Candlesticks {
+		if err := l.Set(cs); err != nil {
+			return fmt.Errorf("adding candlestick at %s: %w", cs.Time, err)
+		}
+	}
+
+	return nil
+}
+
+// ListCandlesticks lists candlesticks from the loaded files.
+func (s *FileSource) ListCandlesticks(
+	_ context.Context,
+	params candlesticksapi.ListCandlesticksWorkflowParams,
+) (candlesticksapi.ListCandlesticksWorkflowResults, error) {
+	l, ok := s.lists[candlestick.ListMetadata{
+		Exchange: params.Exchange,
+		Pair:     params.Pair,
+		Period:   params.Period,
+	}]
+	if !ok {
+		return candlesticksapi.ListCandlesticksWorkflowResults{}, fmt.Errorf(
+			"%w: %s %s %s", ErrNoData, params.Exchange, params.Pair, params.Period)
+	}
+
+	start, end := params.Period.RoundInterval(params.Start, params.End)
+	return candlesticksapi.ListCandlesticksWorkflowResults{
+		List: l.Extract(start, end, params.Limit).ToArray(),
+	}, nil
+}
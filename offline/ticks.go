@@ -0,0 +1,277 @@
+package offline
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	ticksapi "github.com/cryptellation/ticks/api"
+	ticksclient "github.com/cryptellation/ticks/pkg/clients"
+	"github.com/cryptellation/ticks/pkg/tick"
+	"github.com/google/uuid"
+	"github.com/klauspost/compress/zstd"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	// ticksRecorderCallbackPrefix is the prefix of the callbacks receiving
+	// the ticks to record.
+	ticksRecorderCallbackPrefix = "OfflineTicksRecorder"
+	// ticksStopTimeout is the maximum duration to stop listening to ticks
+	// once the recording is over.
+	ticksStopTimeout = 10 * time.Second
+	// ticksRecordTimeout is the maximum duration to record a tick, waiting
+	// for the ticks received before.
+	ticksRecordTimeout = time.Minute
+	// ticksDedupWindow is the duration during which a recorded tick is
+	// remembered, to drop it if it is received again.
+	ticksDedupWindow = 10 * time.Minute
+)
+
+// TicksListener is a source of live ticks for the recorder. The Cryptellation
+// client implements this interface.
+type TicksListener interface {
+	// ListenToTicks listens to ticks from a specific exchange and trading pair.
+	ListenToTicks(ctx context.Context, listener ticksclient.ListenerParams, exchange, pair string) error
+	// StopListeningToTicks unregisters a callback workflow from ticks for a given exchange and pair.
+	StopListeningToTicks(ctx context.Context, listener uuid.UUID, exchange string, pair string) error
+}
+
+// tickKey identifies a tick with all its fields, to record it only once.
+type tickKey struct {
+	Exchange string
+	Pair     string
+	Time     int64
+	Price    float64
+}
+
+// TicksRecorder persists ticks to a zstd compressed file, with one JSON tick
+// per line, in the order they are received. Recordings are read back with
+// ReadTicks and replayed with the ReplayForwardtest method of the engine
+// package.
+type TicksRecorder struct {
+	file    *os.File
+	encoder *zstd.Encoder
+	json    *json.Encoder
+	mu      sync.Mutex
+	err     error
+
+	// seen are the times of the recorded ticks, pruned of the ones older than
+	// the dedup window
+	seen     map[tickKey]time.Time
+	prunedAt time.Time
+}
+
+// NewTicksRecorder creates a recorder writing to the given file, that is
+// truncated if it already exists.
+func NewTicksRecorder(path string) (*TicksRecorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder, err := zstd.NewWriter(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return &TicksRecorder{
+		file:    f,
+		encoder: encoder,
+		json:    json.NewEncoder(encoder),
+		seen:    make(map[tickKey]time.Time),
+	}, nil
+}
+
+// Record persists a tick. Ticks identical to an already recorded one are
+// dropped, so callbacks delivered again (e.g. on retries) do not duplicate
+// them, while different prices received at the same time are all kept. It is
+// safe for concurrent use.
+func (r *TicksRecorder) Record(t tick.Tick) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+
+	// Drop the tick if it is already recorded
+	key := tickKey{Exchange: t.Exchange, Pair: t.Pair, Time: t.Time.UnixNano(), Price: t.Price}
+	if _, ok := r.seen[key]; ok {
+		return nil
+	}
+
+	if err := r.json.Encode(t); err != nil {
+		r.err = fmt.Errorf("recording tick: %w", err)
+		return r.err
+	}
+	r.seen[key] = t.Time
+	r.prune(t.Time)
+	return nil
+}
+
+// prune forgets the ticks older than the dedup window, once per window.
+func (r *TicksRecorder) prune(now time.Time) {
+	if now.Sub(r.prunedAt) < ticksDedupWindow {
+		return
+	}
+
+	for key, t := range r.seen {
+		if now.Sub(t) > ticksDedupWindow {
+			delete(r.seen, key)
+		}
+	}
+	r.prunedAt = now
+}
+
+// Listen records the ticks of the given exchanges and pairs, received
+// through the listener, until the context is done. The callbacks receiving
+// the ticks are registered on the worker, that must listen on the task queue.
+// They record each tick in a local activity, so the file is never written
+// from the workflows, and wait for it to be written: no tick is dropped when
+// they are received faster than they are written.
+func (r *TicksRecorder) Listen(
+	ctx context.Context,
+	listener TicksListener,
+	w worker.Worker,
+	taskQueue string,
+	subscriptions ...tick.Subscription,
+) error {
+	callback := func(ctx workflow.Context, params ticksapi.ListenToTicksCallbackWorkflowParams) error {
+		// Errors of the recorder are kept by it, so there is no need to retry
+		ctx = workflow.WithLocalActivityOptions(ctx, workflow.LocalActivityOptions{
+			ScheduleToCloseTimeout: ticksRecordTimeout,
+			RetryPolicy:            &temporal.RetryPolicy{MaximumAttempts: 1},
+		})
+		return workflow.ExecuteLocalActivity(ctx, r.recordActivity, params.Tick).Get(ctx, nil)
+	}
+
+	// Listen to ticks, with one requester per subscription as each one has its callback
+	requesters := make(map[uuid.UUID]tick.Subscription, len(subscriptions))
+	err := func() error {
+		for _, sub := range subscriptions {
+			id := uuid.New()
+			err := listener.ListenToTicks(ctx, ticksclient.ListenerParams{
+				RequesterID:        id,
+				CallbackNamePrefix: ticksRecorderCallbackPrefix,
+				Callback:           callback,
+				Worker:             w,
+				TaskQueue:          taskQueue,
+			}, sub.Exchange, sub.Pair)
+			if err != nil {
+				return fmt.Errorf("listening to %s on %s: %w", sub.Pair, sub.Exchange, err)
+			}
+			requesters[id] = sub
+		}
+
+		<-ctx.Done()
+		return nil
+	}()
+
+	// Stop listening
+	stopCtx, cancel := context.WithTimeout(context.Background(), ticksStopTimeout)
+	defer cancel()
+	for id, sub := range requesters {
+		_ = listener.StopListeningToTicks(stopCtx, id, sub.Exchange, sub.Pair)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return errors.Join(err, r.err)
+}
+
+// recordActivity is the local activity recording a tick received by a callback.
+func (r *TicksRecorder) recordActivity(_ context.Context, t tick.Tick) error {
+	return r.Record(t)
+}
+
+// Close flushes the recorded ticks and closes the file.
+func (r *TicksRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return errors.Join(r.encoder.Close(), r.file.Close())
+}
+
+// TicksReader reads the ticks of a recording, in their recorded order.
+type TicksReader struct {
+	file    *os.File
+	decoder *zstd.Decoder
+	scanner *bufio.Scanner
+}
+
+// NewTicksReader opens a recording made by a TicksRecorder.
+func NewTicksReader(path string) (*TicksReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder, err := zstd.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return &TicksReader{
+		file:    f,
+		decoder: decoder,
+		scanner: bufio.NewScanner(decoder),
+	}, nil
+}
+
+// Next returns the next tick of the recording, or io.EOF at its end.
+func (r *TicksReader) Next() (tick.Tick, error) {
+	for r.scanner.Scan() {
+		line := r.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var t tick.Tick
+		if err := json.Unmarshal(line, &t); err != nil {
+			return tick.Tick{}, fmt.Errorf("decoding tick %q: %w", line, err)
+		}
+		return t, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return tick.Tick{}, err
+	}
+	return tick.Tick{}, io.EOF
+}
+
+// Close closes the recording.
+func (r *TicksReader) Close() error {
+	r.decoder.Close()
+	return r.file.Close()
+}
+
+// ReadTicks reads all the ticks of a recording made by a TicksRecorder.
+func ReadTicks(path string) ([]tick.Tick, error) {
+	r, err := NewTicksReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var ticks []tick.Tick
+	for {
+		t, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return ticks, nil
+		} else if err != nil {
+			return nil, err
+		}
+		ticks = append(ticks, t)
+	}
+}
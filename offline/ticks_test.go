@@ -0,0 +1,128 @@
+package offline
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/cryptellation/go-clients/internal/fixture"
+	ticksapi "github.com/cryptellation/ticks/api"
+	ticksclient "github.com/cryptellation/ticks/pkg/clients"
+	"github.com/cryptellation/ticks/pkg/tick"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestTicksRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ticks.zst")
+	r, err := NewTicksRecorder(path)
+	require.NoError(t, err)
+
+	other := fixture.Tick(1, 11)
+	other.Pair = "ETH-USDT"
+	recorded := []tick.Tick{
+		fixture.Tick(0, 10),
+		fixture.Tick(1, 11),
+		fixture.Tick(0, 10), // Delivered again
+		other,               // Same time, other pair
+		fixture.Tick(1, 12), // Same time, other price
+		fixture.Tick(2, 13),
+	}
+	for _, tk := range recorded {
+		require.NoError(t, r.Record(tk))
+	}
+	require.NoError(t, r.Close())
+
+	ticks, err := ReadTicks(path)
+	require.NoError(t, err)
+	require.Len(t, ticks, 5)
+	expected := []tick.Tick{recorded[0], recorded[1], recorded[3], recorded[4], recorded[5]}
+	for i, tk := range ticks {
+		require.True(t, expected[i].Time.Equal(tk.Time))
+		tk.Time = expected[i].Time
+		require.Equal(t, expected[i], tk)
+	}
+}
+
+func TestReadTicksErrors(t *testing.T) {
+	_, err := ReadTicks(filepath.Join(t.TempDir(), "missing.zst"))
+	require.Error(t, err)
+}
+
+// fakeTicksListener is a ticks listener calling the callbacks with the
+// ticks, in a test workflow environment.
+type fakeTicksListener struct {
+	ticks   []tick.Tick
+	mu      sync.Mutex
+	stopped []uuid.UUID
+	err     error
+}
+
+func (l *fakeTicksListener) ListenToTicks(
+	_ context.Context,
+	params ticksclient.ListenerParams,
+	_, _ string,
+) error {
+	if l.err != nil {
+		return l.err
+	}
+
+	for _, tk := range l.ticks {
+		var suite testsuite.WorkflowTestSuite
+		env := suite.NewTestWorkflowEnvironment()
+		env.ExecuteWorkflow(params.Callback, ticksapi.ListenToTicksCallbackWorkflowParams{Tick: tk})
+		if err := env.GetWorkflowError(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *fakeTicksListener) StopListeningToTicks(_ context.Context, listener uuid.UUID, _, _ string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stopped = append(l.stopped, listener)
+	return nil
+}
+
+func TestTicksRecorderListen(t *testing.T) {
+	errListen := errors.New("listen error")
+	ticks := make([]tick.Tick, 2048)
+	for i := range ticks {
+		ticks[i] = fixture.Tick(i, float64(i))
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "all ticks recorded", expected: len(ticks)},
+		{name: "listen error", err: errListen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ticks.zst")
+			r, err := NewTicksRecorder(path)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			listener := &fakeTicksListener{ticks: ticks, err: tt.err}
+			err = r.Listen(ctx, listener, nil, "queue", tick.Subscription{Exchange: fixture.Exchange, Pair: fixture.Pair})
+			require.ErrorIs(t, err, tt.err)
+			require.NoError(t, r.Close())
+
+			recorded, err := ReadTicks(path)
+			require.NoError(t, err)
+			require.Len(t, recorded, tt.expected)
+			if tt.err == nil {
+				require.Len(t, listener.stopped, 1)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package quality
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
+	"github.com/cryptellation/candlesticks/pkg/period"
+)
+
+var (
+	// ErrInvalidFillStrategy is returned when the fill strategy is invalid.
+	ErrInvalidFillStrategy = errors.New("invalid fill strategy")
+)
+
+// FillStrategy is the strategy used to fill the gaps of candlesticks.
+type FillStrategy string
+
+const (
+	// FillStrategyPrevious fills a gap with flat candlesticks at the close
+	// price of the previous candlestick.
+	FillStrategyPrevious FillStrategy = "previous"
+	// FillStrategyLinear fills a gap with candlesticks following a linear
+	// interpolation between the candlesticks around the gap.
+	FillStrategyLinear FillStrategy = "linear"
+)
+
+// String returns the string representation of the fill strategy.
+func (fs FillStrategy) String() string {
+	return string(fs)
+}
+
+// Validate checks if the fill strategy is valid.
+func (fs FillStrategy) Validate() error {
+	switch fs {
+	case FillStrategyPrevious, FillStrategyLinear:
+		return nil
+	default:
+		return ErrInvalidFillStrategy
+	}
+}
+
+// Fill returns the candlesticks ordered by time, without duplicates (the last
+// one is kept) nor misaligned timestamps, and with the gaps filled with the
+// given strategy. Filled candlesticks have no volume.
+// The range can be set with the WithRange option; other options are ignored.
+func Fill(
+	list []candlestick.Candlestick,
+	per period.Symbol,
+	strategy FillStrategy,
+	opts ...Options,
+) ([]candlestick.Candlestick, error) {
+	if err := per.Validate(); err != nil {
+		return nil, err
+	}
+	if err := strategy.Validate(); err != nil {
+		return nil, err
+	}
+
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	// Clean the candlesticks
+	clean := clean(list, per)
+	if len(clean) == 0 {
+		return clean, nil
+	}
+
+	// Fill every expected time
+	start, end := expectedRange(clean, per, cfg)
+	filled := make([]candlestick.Candlestick, 0, per.CountBetweenTimes(start, end)+1)
+	next := 0
+	for t := start; !t.After(end); t = t.Add(per.Duration()) {
+		for next < len(clean) && clean[next].Time.Before(t) {
+			next++
+		}
+
+		if next < len(clean) && clean[next].Time.Equal(t) {
+			filled = append(filled, clean[next])
+			continue
+		}
+
+		filled = append(filled, fillOne(t, filled, clean, next, per, strategy))
+	}
+
+	return filled, nil
+}
+
+// fillOne creates the candlestick at time t, knowing the already filled
+// candlesticks and the next existing candlestick.
+func fillOne(
+	t time.Time,
+	filled, clean []candlestick.Candlestick,
+	next int,
+	per period.Symbol,
+	strategy FillStrategy,
+) candlestick.Candlestick {
+	// Get the price before the gap, or after if the gap is at the beginning
+	var previous float64
+	switch {
+	case len(filled) > 0:
+		previous = filled[len(filled)-1].Close
+	case next < len(clean):
+		previous = clean[next].Open
+	}
+
+	// Compute the price at the end of the candlestick
+	price := previous
+	if strategy == FillStrategyLinear && len(filled) > 0 && next < len(clean) {
+		remaining := float64(per.CountBetweenTimes(t, clean[next].Time))
+		price = previous + (clean[next].Open-previous)/remaining
+	}
+
+	return candlestick.Candlestick{
+		Time:  t,
+		Open:  previous,
+		High:  math.Max(previous, price),
+		Low:   math.Min(previous, price),
+		Close: price,
+	}
+}
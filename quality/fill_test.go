@@ -0,0 +1,154 @@
+package quality
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
+	"github.com/cryptellation/candlesticks/pkg/period"
+	"github.com/cryptellation/go-clients/internal/fixture"
+	"github.com/stretchr/testify/require"
+)
+
+// ohlc is the minute and the prices of a candlestick, to compare them
+// regardless of the location of their time.
+type ohlc struct {
+	Minute                 int
+	Open, High, Low, Close float64
+	Volume                 float64
+}
+
+func toOHLC(list []candlestick.Candlestick) []ohlc {
+	res := make([]ohlc, len(list))
+	for i, cs := range list {
+		res[i] = ohlc{
+			Minute: fixture.Minutes(cs.Time),
+			Open:   cs.Open, High: cs.High, Low: cs.Low, Close: cs.Close,
+			Volume: cs.Volume,
+		}
+	}
+	return res
+}
+
+func TestFill(t *testing.T) {
+	tests := []struct {
+		name     string
+		list     []candlestick.Candlestick
+		strategy FillStrategy
+		opts     []Options
+		expected []ohlc
+	}{
+		{
+			name:     "no gap",
+			list:     []candlestick.Candlestick{fixture.Candlestick(0, 100), fixture.Candlestick(1, 110)},
+			strategy: FillStrategyPrevious,
+			expected: []ohlc{{0, 100, 100, 100, 100, 1}, {1, 110, 110, 110, 110, 1}},
+		},
+		{
+			name:     "previous",
+			list:     []candlestick.Candlestick{fixture.Candlestick(0, 100), fixture.Candlestick(3, 130)},
+			strategy: FillStrategyPrevious,
+			expected: []ohlc{
+				{0, 100, 100, 100, 100, 1},
+				{1, 100, 100, 100, 100, 0},
+				{2, 100, 100, 100, 100, 0},
+				{3, 130, 130, 130, 130, 1},
+			},
+		},
+		{
+			name:     "linear",
+			list:     []candlestick.Candlestick{fixture.Candlestick(0, 100), fixture.Candlestick(3, 130)},
+			strategy: FillStrategyLinear,
+			expected: []ohlc{
+				{0, 100, 100, 100, 100, 1},
+				{1, 100, 115, 100, 115, 0},
+				{2, 115, 130, 115, 130, 0},
+				{3, 130, 130, 130, 130, 1},
+			},
+		},
+		{
+			name:     "linear going down",
+			list:     []candlestick.Candlestick{fixture.Candlestick(0, 130), fixture.Candlestick(3, 100)},
+			strategy: FillStrategyLinear,
+			expected: []ohlc{
+				{0, 130, 130, 130, 130, 1},
+				{1, 130, 130, 115, 115, 0},
+				{2, 115, 115, 100, 100, 0},
+				{3, 100, 100, 100, 100, 1},
+			},
+		},
+		{
+			name:     "gaps at the edges of the range",
+			list:     []candlestick.Candlestick{fixture.Candlestick(1, 100), fixture.Candlestick(2, 110)},
+			strategy: FillStrategyLinear,
+			opts:     []Options{WithRange(fixture.Minute(0), fixture.Minute(3))},
+			expected: []ohlc{
+				{0, 100, 100, 100, 100, 0},
+				{1, 100, 100, 100, 100, 1},
+				{2, 110, 110, 110, 110, 1},
+				{3, 110, 110, 110, 110, 0},
+			},
+		},
+		{
+			name: "duplicates, misaligned and unordered",
+			list: []candlestick.Candlestick{
+				fixture.Candlestick(1, 110),
+				fixture.Candlestick(0, 100),
+				{Time: fixture.Minute(0).Add(time.Second), Open: 1, High: 1, Low: 1, Close: 1},
+				fixture.Candlestick(1, 120),
+			},
+			strategy: FillStrategyPrevious,
+			expected: []ohlc{{0, 100, 100, 100, 100, 1}, {1, 120, 120, 120, 120, 1}},
+		},
+		{
+			name:     "empty",
+			list:     nil,
+			strategy: FillStrategyPrevious,
+			expected: []ohlc{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filled, err := Fill(tt.list, period.M1, tt.strategy, tt.opts...)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, toOHLC(filled))
+
+			// Filled candlesticks must pass the check
+			r, err := Check(filled, period.M1, WithZeroVolumeRun(0), WithOutlierThreshold(0))
+			require.NoError(t, err)
+			require.Empty(t, r.IssuesOfKind(IssueKindGap, IssueKindInconsistentOHLC))
+		})
+	}
+}
+
+func TestFillInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		period   period.Symbol
+		strategy FillStrategy
+		err      error
+	}{
+		{
+			name:     "invalid strategy",
+			period:   period.M1,
+			strategy: FillStrategy("unknown"),
+			err:      ErrInvalidFillStrategy,
+		},
+		{
+			name:     "invalid period",
+			period:   period.Symbol("unknown"),
+			strategy: FillStrategyPrevious,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Fill(series(0, 2), tt.period, tt.strategy)
+			require.Error(t, err)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,426 @@
+package quality
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	candlesticksapi "github.com/cryptellation/candlesticks/api"
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
+	"github.com/cryptellation/candlesticks/pkg/period"
+	"github.com/cryptellation/timeseries"
+)
+
+const (
+	// DefaultZeroVolumeRun is the default minimal number of consecutive
+	// candlesticks without volume to report.
+	DefaultZeroVolumeRun = 3
+	// DefaultOutlierThreshold is the default threshold of the robust z-score
+	// of the close-to-close log returns above which a candlestick is an outlier.
+	DefaultOutlierThreshold = 10.0
+)
+
+// IssueKind is the kind of an issue detected in candlesticks.
+type IssueKind string
+
+const (
+	// IssueKindGap is a range of missing candlesticks.
+	IssueKindGap IssueKind = "gap"
+	// IssueKindDuplicate is a timestamp present several times.
+	IssueKindDuplicate IssueKind = "duplicate"
+	// IssueKindMisaligned is a timestamp not aligned on the period.
+	IssueKindMisaligned IssueKind = "misaligned"
+	// IssueKindUnordered is a timestamp before the previous one.
+	IssueKindUnordered IssueKind = "unordered"
+	// IssueKindInconsistentOHLC is a candlestick whose open, high, low and
+	// close are not consistent (high < low, open or close outside range, etc.).
+	IssueKindInconsistentOHLC IssueKind = "inconsistent_ohlc"
+	// IssueKindZeroVolume is a run of consecutive candlesticks without volume.
+	IssueKindZeroVolume IssueKind = "zero_volume"
+	// IssueKindOutlier is a candlestick whose price change is abnormal
+	// compared to the rest of the candlesticks.
+	IssueKindOutlier IssueKind = "outlier"
+	// IssueKindNoData is the absence of candlesticks when no expected range
+	// is set, so the missing candlesticks can not be located.
+	IssueKindNoData IssueKind = "no_data"
+)
+
+// String returns the string representation of the issue kind.
+func (k IssueKind) String() string {
+	return string(k)
+}
+
+// Issue is an issue detected in candlesticks.
+type Issue struct {
+	Kind IssueKind `json:"kind"`
+	// Start is the time of the first candlestick concerned by the issue.
+	Start time.Time `json:"start"`
+	// End is the time of the last candlestick concerned by the issue.
+	// It is the same as Start when only one candlestick is concerned.
+	End time.Time `json:"end"`
+	// Details is a human readable description of the issue.
+	Details string `json:"details"`
+}
+
+// String returns a string representation of the issue.
+func (i Issue) String() string {
+	switch {
+	case i.Start.IsZero() && i.End.IsZero():
+		return fmt.Sprintf("[%s] %s", i.Kind, i.Details)
+	case i.Start.Equal(i.End):
+		return fmt.Sprintf("[%s] %s: %s", i.Kind, i.Start.Format(time.RFC3339), i.Details)
+	default:
+		return fmt.Sprintf("[%s] %s -> %s: %s",
+			i.Kind, i.Start.Format(time.RFC3339), i.End.Format(time.RFC3339), i.Details)
+	}
+}
+
+// Report is the result of a candlesticks quality check.
+type Report struct {
+	Period   period.Symbol `json:"period"`
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	Expected int           `json:"expected"`
+	Received int           `json:"received"`
+	Issues   []Issue       `json:"issues"`
+}
+
+// OK returns true if no issue has been detected.
+func (r Report) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// IssuesOfKind returns the issues of the given kinds.
+func (r Report) IssuesOfKind(kinds ...IssueKind) []Issue {
+	issues := make([]Issue, 0)
+	for _, i := range r.Issues {
+		for _, k := range kinds {
+			if i.Kind == k {
+				issues = append(issues, i)
+				break
+			}
+		}
+	}
+	return issues
+}
+
+// Gaps returns the missing time ranges.
+func (r Report) Gaps() []timeseries.TimeRange {
+	gaps := r.IssuesOfKind(IssueKindGap)
+	tr := make([]timeseries.TimeRange, len(gaps))
+	for i, g := range gaps {
+		tr[i] = timeseries.TimeRange{Start: g.Start, End: g.End}
+	}
+	return tr
+}
+
+type config struct {
+	start, end       *time.Time
+	zeroVolumeRun    int
+	outlierThreshold float64
+}
+
+// Options is a function that modifies the check configuration.
+type Options func(*config)
+
+// WithRange sets the expected time range of the candlesticks. By default, it
+// is the range between the first and the last candlesticks, so missing
+// candlesticks at the edges are only detected with this option.
+func WithRange(start, end time.Time) Options {
+	return func(c *config) {
+		c.start, c.end = &start, &end
+	}
+}
+
+// WithZeroVolumeRun sets the minimal number of consecutive candlesticks
+// without volume to report. Zero disables the detection.
+func WithZeroVolumeRun(n int) Options {
+	return func(c *config) {
+		c.zeroVolumeRun = n
+	}
+}
+
+// WithOutlierThreshold sets the robust z-score threshold above which a
+// candlestick is considered as an outlier. Zero disables the detection.
+func WithOutlierThreshold(threshold float64) Options {
+	return func(c *config) {
+		c.outlierThreshold = threshold
+	}
+}
+
+// Check scans candlesticks, as returned by ListCandlesticks, for data
+// quality issues against the expected period.
+func Check(list []candlestick.Candlestick, per period.Symbol, opts ...Options) (Report, error) {
+	if err := per.Validate(); err != nil {
+		return Report{}, err
+	}
+
+	cfg := config{
+		zeroVolumeRun:    DefaultZeroVolumeRun,
+		outlierThreshold: DefaultOutlierThreshold,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	r := Report{
+		Period:   per,
+		Received: len(list),
+		Issues:   make([]Issue, 0),
+	}
+
+	// Without candlesticks nor range, there is no expected range to check
+	if len(list) == 0 && (cfg.start == nil || cfg.end == nil) {
+		r.Issues = append(r.Issues, Issue{
+			Kind:    IssueKindNoData,
+			Details: "no candlestick received and no expected range set",
+		})
+		return r, nil
+	}
+
+	r.Start, r.End = expectedRange(list, per, cfg)
+	if !r.End.Before(r.Start) {
+		r.Expected = int(per.CountBetweenTimes(r.Start, r.End)) + 1
+	}
+
+	r.Issues = append(r.Issues, checkTimestamps(list, per)...)
+	r.Issues = append(r.Issues, checkGaps(list, per, r.Start, r.End)...)
+	r.Issues = append(r.Issues, checkOHLC(list)...)
+	if cfg.zeroVolumeRun > 0 {
+		r.Issues = append(r.Issues, checkZeroVolume(list, cfg.zeroVolumeRun)...)
+	}
+	if cfg.outlierThreshold > 0 {
+		r.Issues = append(r.Issues, checkOutliers(clean(list, per), cfg.outlierThreshold)...)
+	}
+
+	sort.SliceStable(r.Issues, func(i, j int) bool {
+		return r.Issues[i].Start.Before(r.Issues[j].Start)
+	})
+
+	return r, nil
+}
+
+// CheckResults scans the results of a ListCandlesticks call, using the
+// requested time range as expected range.
+func CheckResults(
+	params candlesticksapi.ListCandlesticksWorkflowParams,
+	res candlesticksapi.ListCandlesticksWorkflowResults,
+	opts ...Options,
+) (Report, error) {
+	if params.Start != nil && params.End != nil && params.Limit == 0 {
+		opts = append([]Options{WithRange(*params.Start, *params.End)}, opts...)
+	}
+
+	return Check(res.List, params.Period, opts...)
+}
+
+func expectedRange(list []candlestick.Candlestick, per period.Symbol, cfg config) (start, end time.Time) {
+	s := sorted(list)
+	if len(s) > 0 {
+		start, end = s[0].Time, s[len(s)-1].Time
+	}
+
+	if cfg.start != nil {
+		start = *cfg.start
+	}
+	if cfg.end != nil {
+		end = *cfg.end
+	}
+
+	return per.RoundTime(start), per.RoundTime(end)
+}
+
+func sorted(list []candlestick.Candlestick) []candlestick.Candlestick {
+	s := make([]candlestick.Candlestick, len(list))
+	copy(s, list)
+	sort.SliceStable(s, func(i, j int) bool {
+		return s[i].Time.Before(s[j].Time)
+	})
+	return s
+}
+
+// clean returns the aligned candlesticks ordered by time and without
+// duplicates (the last one is kept).
+func clean(list []candlestick.Candlestick, per period.Symbol) []candlestick.Candlestick {
+	l := candlestick.NewList("", "", per)
+	for _, cs := range list {
+		if per.IsAligned(cs.Time) {
+			l.Data.Set(cs.Time, cs)
+		}
+	}
+	return l.ToArray()
+}
+
+func checkTimestamps(list []candlestick.Candlestick, per period.Symbol) []Issue {
+	issues := make([]Issue, 0)
+	seen := make(map[int64]bool, len(list))
+	for i, cs := range list {
+		if !per.IsAligned(cs.Time) {
+			issues = append(issues, newIssue(IssueKindMisaligned, cs.Time,
+				fmt.Sprintf("not aligned on %s period", per)))
+		}
+
+		if seen[cs.Time.UnixNano()] {
+			issues = append(issues, newIssue(IssueKindDuplicate, cs.Time, "timestamp already present"))
+		}
+		seen[cs.Time.UnixNano()] = true
+
+		if i > 0 && cs.Time.Before(list[i-1].Time) {
+			issues = append(issues, newIssue(IssueKindUnordered, cs.Time,
+				fmt.Sprintf("before previous timestamp %s", list[i-1].Time.Format(time.RFC3339))))
+		}
+	}
+	return issues
+}
+
+func checkGaps(list []candlestick.Candlestick, per period.Symbol, start, end time.Time) []Issue {
+	ts := timeseries.New[candlestick.Candlestick]()
+	for _, cs := range list {
+		ts.Set(cs.Time, cs)
+	}
+
+	ranges := ts.GetMissingRanges(start, end, per.Duration(), 0)
+	issues := make([]Issue, 0, len(ranges))
+	for _, tr := range ranges {
+		count := per.CountBetweenTimes(tr.Start, tr.End) + 1
+		issues = append(issues, Issue{
+			Kind:    IssueKindGap,
+			Start:   tr.Start,
+			End:     tr.End,
+			Details: fmt.Sprintf("%d missing candlestick(s)", count),
+		})
+	}
+	return issues
+}
+
+func checkOHLC(list []candlestick.Candlestick) []Issue {
+	issues := make([]Issue, 0)
+	for _, cs := range list {
+		var details string
+		switch {
+		case cs.Open <= 0 || cs.High <= 0 || cs.Low <= 0 || cs.Close <= 0:
+			details = "non-positive price"
+		case cs.Volume < 0:
+			details = "negative volume"
+		case cs.High < cs.Low:
+			details = fmt.Sprintf("high (%f) < low (%f)", cs.High, cs.Low)
+		case cs.Open > cs.High || cs.Open < cs.Low:
+			details = fmt.Sprintf("open (%f) outside range [%f, %f]", cs.Open, cs.Low, cs.High)
+		case cs.Close > cs.High || cs.Close < cs.Low:
+			details = fmt.Sprintf("close (%f) outside range [%f, %f]", cs.Close, cs.Low, cs.High)
+		default:
+			continue
+		}
+		issues = append(issues, newIssue(IssueKindInconsistentOHLC, cs.Time, details))
+	}
+	return issues
+}
+
+func checkZeroVolume(list []candlestick.Candlestick, minRun int) []Issue {
+	issues := make([]Issue, 0)
+	s := sorted(list)
+	for i := 0; i < len(s); {
+		if s[i].Volume != 0 {
+			i++
+			continue
+		}
+
+		j := i
+		for j+1 < len(s) && s[j+1].Volume == 0 {
+			j++
+		}
+
+		if run := j - i + 1; run >= minRun {
+			issues = append(issues, Issue{
+				Kind:    IssueKindZeroVolume,
+				Start:   s[i].Time,
+				End:     s[j].Time,
+				Details: fmt.Sprintf("%d consecutive candlestick(s) without volume", run),
+			})
+		}
+		i = j + 1
+	}
+	return issues
+}
+
+// checkOutliers detects outliers with the robust z-score of the log
+// returns, based on the median absolute deviation to be robust to the
+// outliers themselves. The trimmed mean absolute deviation is used as a
+// floor for the scale, as the median one collapses on very regular data.
+func checkOutliers(s []candlestick.Candlestick, threshold float64) []Issue {
+	issues := make([]Issue, 0)
+	if len(s) < 3 {
+		return issues
+	}
+
+	returns := make([]float64, 0, len(s)-1)
+	for i := 1; i < len(s); i++ {
+		if s[i-1].Close <= 0 || s[i].Close <= 0 {
+			returns = append(returns, 0)
+			continue
+		}
+		returns = append(returns, math.Log(s[i].Close/s[i-1].Close))
+	}
+
+	med := median(returns)
+	deviations := make([]float64, len(returns))
+	for i, r := range returns {
+		deviations[i] = math.Abs(r - med)
+	}
+	scale := math.Max(1.4826*median(deviations), 1.2533*trimmedMean(deviations, 0.1))
+	if scale == 0 {
+		return issues
+	}
+
+	for i, r := range returns {
+		z := (r - med) / scale
+		if math.Abs(z) > threshold {
+			cs := s[i+1]
+			issues = append(issues, newIssue(IssueKindOutlier, cs.Time,
+				fmt.Sprintf("close changed by %.2f%% from previous candlestick (score %.1f)",
+					(math.Exp(r)-1)*100, z)))
+		}
+	}
+	return issues
+}
+
+// trimmedMean returns the mean of the values without the given ratio of
+// the largest ones.
+func trimmedMean(values []float64, ratio float64) float64 {
+	s := make([]float64, len(values))
+	copy(s, values)
+	sort.Float64s(s)
+
+	n := len(s) - int(float64(len(s))*ratio)
+	if n <= 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, v := range s[:n] {
+		sum += v
+	}
+	return sum / float64(n)
+}
+
+func median(values []float64) float64 {
+	s := make([]float64, len(values))
+	copy(s, values)
+	sort.Float64s(s)
+
+	n := len(s)
+	if n%2 == 1 {
+		return s[n/2]
+	}
+	return (s[n/2-1] + s[n/2]) / 2
+}
+
+func newIssue(kind IssueKind, t time.Time, details string) Issue {
+	return Issue{
+		Kind:    kind,
+		Start:   t,
+		End:     t,
+		Details: details,
+	}
+}
@@ -0,0 +1,245 @@
+package quality
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	candlesticksapi "github.com/cryptellation/candlesticks/api"
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
+	"github.com/cryptellation/candlesticks/pkg/period"
+	"github.com/cryptellation/go-clients/internal/fixture"
+	"github.com/stretchr/testify/require"
+)
+
+// series returns consistent candlesticks at the given minutes.
+func series(minutes ...int) []candlestick.Candlestick {
+	list := make([]candlestick.Candlestick, len(minutes))
+	for i, n := range minutes {
+		list[i] = fixture.Candlestick(n, 100)
+	}
+	return list
+}
+
+// levelShift returns candlesticks oscillating around 100, then jumping to
+// oscillate around 150 at the 10th minute.
+func levelShift() []candlestick.Candlestick {
+	list := make([]candlestick.Candlestick, 20)
+	for i := range list {
+		price := 100.0 + float64(i%2)
+		if i >= 10 {
+			price += 50
+		}
+		list[i] = fixture.Candlestick(i, price)
+	}
+	return list
+}
+
+// summary returns the kind and time range of the issues, in minutes from the start of the fixtures.
+func summary(issues []Issue) []string {
+	s := make([]string, len(issues))
+	for i, issue := range issues {
+		if issue.Start.IsZero() {
+			s[i] = issue.Kind.String()
+			continue
+		}
+		s[i] = fmt.Sprintf("%s %d-%d", issue.Kind,
+			fixture.Minutes(issue.Start), fixture.Minutes(issue.End))
+	}
+	return s
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		list     []candlestick.Candlestick
+		opts     []Options
+		expected int
+		issues   []string
+	}{
+		{
+			name:     "complete",
+			list:     series(0, 1, 2, 3),
+			expected: 4,
+			issues:   []string{},
+		},
+		{
+			name:     "gap in the middle",
+			list:     series(0, 1, 4, 5),
+			expected: 6,
+			issues:   []string{"gap 2-3"},
+		},
+		{
+			name:     "gaps at the edges of the range",
+			list:     series(2, 3),
+			opts:     []Options{WithRange(fixture.Minute(0), fixture.Minute(5))},
+			expected: 6,
+			issues:   []string{"gap 0-1", "gap 4-5"},
+		},
+		{
+			name:     "empty list without range",
+			list:     nil,
+			expected: 0,
+			issues:   []string{"no_data"},
+		},
+		{
+			name:     "empty list with range",
+			list:     nil,
+			opts:     []Options{WithRange(fixture.Minute(0), fixture.Minute(2))},
+			expected: 3,
+			issues:   []string{"gap 0-2"},
+		},
+		{
+			name:     "duplicate",
+			list:     series(0, 1, 1, 2),
+			expected: 3,
+			issues:   []string{"duplicate 1-1"},
+		},
+		{
+			name: "misaligned",
+			list: append(series(0, 1), candlestick.Candlestick{
+				Time: fixture.Minute(1).Add(30 * time.Second), Open: 100, High: 100, Low: 100, Close: 100, Volume: 1,
+			}),
+			expected: 2,
+			issues:   []string{"misaligned 1-1"},
+		},
+		{
+			name:     "unordered",
+			list:     series(0, 2, 1),
+			expected: 3,
+			issues:   []string{"unordered 1-1"},
+		},
+		{
+			name: "inconsistent OHLC",
+			list: []candlestick.Candlestick{
+				fixture.Candlestick(0, 100),
+				{Time: fixture.Minute(1), Open: 100, High: 90, Low: 95, Close: 92, Volume: 1},
+				{Time: fixture.Minute(2), Open: 120, High: 110, Low: 90, Close: 100, Volume: 1},
+				{Time: fixture.Minute(3), Open: 0, High: 100, Low: 90, Close: 95, Volume: 1},
+			},
+			expected: 4,
+			issues:   []string{"inconsistent_ohlc 1-1", "inconsistent_ohlc 2-2", "inconsistent_ohlc 3-3"},
+		},
+		{
+			name: "zero volume run",
+			list: func() []candlestick.Candlestick {
+				list := series(0, 1, 2, 3, 4)
+				for i := 1; i <= 3; i++ {
+					list[i].Volume = 0
+				}
+				return list
+			}(),
+			expected: 5,
+			issues:   []string{"zero_volume 1-3"},
+		},
+		{
+			name: "zero volume run shorter than the minimum",
+			list: func() []candlestick.Candlestick {
+				list := series(0, 1, 2, 3)
+				list[1].Volume, list[2].Volume = 0, 0
+				return list
+			}(),
+			expected: 4,
+			issues:   []string{},
+		},
+		{
+			name:     "outlier",
+			list:     levelShift(),
+			expected: 20,
+			issues:   []string{"outlier 10-10"},
+		},
+		{
+			name:     "outlier detection disabled",
+			list:     levelShift(),
+			opts:     []Options{WithOutlierThreshold(0)},
+			expected: 20,
+			issues:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Check(tt.list, period.M1, tt.opts...)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, r.Expected)
+			require.Equal(t, len(tt.list), r.Received)
+			require.Equal(t, tt.issues, summary(r.Issues))
+			require.Equal(t, len(tt.issues) == 0, r.OK())
+		})
+	}
+}
+
+func TestCheckInvalidPeriod(t *testing.T) {
+	_, err := Check(series(0, 1), period.Symbol("invalid"))
+	require.Error(t, err)
+}
+
+func TestCheckResults(t *testing.T) {
+	start, end := fixture.Minute(0), fixture.Minute(3)
+	tests := []struct {
+		name   string
+		params candlesticksapi.ListCandlesticksWorkflowParams
+		issues []string
+	}{
+		{
+			name:   "requested range is expected",
+			params: candlesticksapi.ListCandlesticksWorkflowParams{Period: period.M1, Start: &start, End: &end},
+			issues: []string{"gap 0-0", "gap 3-3"},
+		},
+		{
+			name: "limited list has no expected range",
+			params: candlesticksapi.ListCandlesticksWorkflowParams{
+				Period: period.M1, Start: &start, End: &end, Limit: 2,
+			},
+			issues: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := CheckResults(tt.params, candlesticksapi.ListCandlesticksWorkflowResults{List: series(1, 2)})
+			require.NoError(t, err)
+			require.Equal(t, tt.issues, summary(r.Issues))
+		})
+	}
+}
+
+func TestReportGaps(t *testing.T) {
+	r, err := Check(series(0, 2, 3, 6), period.M1)
+	require.NoError(t, err)
+
+	gaps := r.Gaps()
+	require.Len(t, gaps, 2)
+	require.True(t, gaps[0].Start.Equal(fixture.Minute(1)) && gaps[0].End.Equal(fixture.Minute(1)))
+	require.True(t, gaps[1].Start.Equal(fixture.Minute(4)) && gaps[1].End.Equal(fixture.Minute(5)))
+}
+
+func TestIssueString(t *testing.T) {
+	tests := []struct {
+		name     string
+		issue    Issue
+		expected string
+	}{
+		{
+			name:     "without time",
+			issue:    Issue{Kind: IssueKindNoData, Details: "details"},
+			expected: "[no_data] details",
+		},
+		{
+			name:     "single time",
+			issue:    newIssue(IssueKindDuplicate, fixture.Start, "details"),
+			expected: "[duplicate] 2024-01-01T00:00:00Z: details",
+		},
+		{
+			name:     "time range",
+			issue:    Issue{Kind: IssueKindGap, Start: fixture.Start, End: fixture.Minute(2), Details: "details"},
+			expected: "[gap] 2024-01-01T00:00:00Z -> 2024-01-01T00:02:00Z: details",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.issue.String())
+		})
+	}
+}
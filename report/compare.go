@@ -0,0 +1,465 @@
+package report
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/cryptellation/runtime/order"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNothingToCompare is returned when comparing without backtests.
+	ErrNothingToCompare = errors.New("no backtest to compare")
+	// ErrNoCommonRange is returned when the compared backtests do not overlap in time.
+	ErrNoCommonRange = errors.New("backtests have no common time range")
+)
+
+// RankBy is the metric used to rank the compared backtests.
+type RankBy string
+
+const (
+	// RankByReturn ranks the backtests by decreasing return.
+	RankByReturn RankBy = "return"
+	// RankByMaxDrawdown ranks the backtests by increasing maximum drawdown.
+	RankByMaxDrawdown RankBy = "max_drawdown"
+	// RankBySharpe ranks the backtests by decreasing Sharpe ratio.
+	RankBySharpe RankBy = "sharpe"
+)
+
+const (
+	// TableRanking is the table of the ranked backtests and their metrics.
+	TableRanking Table = "ranking"
+	// TableEquityCurves is the table of the aligned equity curves, with a column per backtest.
+	TableEquityCurves Table = "equity_curves"
+	// TableCorrelations is the matrix of the correlations of the returns.
+	TableCorrelations Table = "correlations"
+)
+
+// WithRanking sets the metric used to rank the compared backtests.
+// Default is the return.
+func WithRanking(rank RankBy) Options {
+	return func(c *config) {
+		c.rankBy = rank
+	}
+}
+
+// ComparisonEntry is a compared backtest, with its metrics on the common time range.
+type ComparisonEntry struct {
+	Rank int       `json:"rank"`
+	ID   uuid.UUID `json:"id"`
+	// Summary are the statistics of the backtest on the common time range.
+	Summary Summary `json:"summary"`
+	// Sharpe is the mean of the returns divided by their standard deviation,
+	// per period and without risk free rate.
+	Sharpe float64 `json:"sharpe"`
+	// ExcessReturn is the return minus the average return of the compared backtests.
+	ExcessReturn float64 `json:"excess_return"`
+	// Balances are the balances aligned on the common times.
+	Balances []BalancePoint `json:"balances"`
+}
+
+// Comparison is the side-by-side comparison of several backtests.
+type Comparison struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	RankBy    RankBy    `json:"rank_by"`
+	// Entries are the compared backtests, by rank.
+	Entries []ComparisonEntry `json:"entries"`
+	// Correlations are the correlations of the returns between the entries,
+	// in the entries order.
+	Correlations [][]float64 `json:"correlations"`
+}
+
+// Compare gets the backtests from the source and compares them.
+func Compare(ctx context.Context, src Source, ids []uuid.UUID, opts ...Options) (Comparison, error) {
+	reports := make([]Report, len(ids))
+	for i, id := range ids {
+		r, err := New(ctx, src, id, opts...)
+		if err != nil {
+			return Comparison{}, fmt.Errorf("backtest %s: %w", id, err)
+		}
+		reports[i] = r
+	}
+
+	return CompareReports(reports, opts...)
+}
+
+// CompareReports compares the reports of several backtests. Their equity
+// curves are aligned on the times of the first report within the time range
+// common to all of them.
+func CompareReports(reports []Report, opts ...Options) (Comparison, error) {
+	cfg := config{
+		rankBy: RankByReturn,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if len(reports) == 0 {
+		return Comparison{}, ErrNothingToCompare
+	}
+
+	// Get the common time range
+	start, end, err := commonRange(reports)
+	if err != nil {
+		return Comparison{}, err
+	}
+
+	// Align the balances and compute the metrics on the common range
+	times := alignedTimes(reports[0].Balances, start, end)
+	entries := make([]ComparisonEntry, len(reports))
+	var averageReturn float64
+	for i, r := range reports {
+		balances := align(r.Balances, times)
+		returns := Returns(balances)
+		entries[i] = ComparisonEntry{
+			ID:       r.ID,
+			Summary:  summarize(balances, ordersBetween(r.Orders, start, end)),
+			Sharpe:   sharpe(returns),
+			Balances: balances,
+		}
+		averageReturn += entries[i].Summary.Return / float64(len(reports))
+	}
+	for i := range entries {
+		entries[i].ExcessReturn = entries[i].Summary.Return - averageReturn
+	}
+
+	// Rank the entries
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch cfg.rankBy {
+		case RankByMaxDrawdown:
+			return a.Summary.MaxDrawdown < b.Summary.MaxDrawdown
+		case RankBySharpe:
+			return a.Sharpe > b.Sharpe
+		default:
+			return a.Summary.Return > b.Summary.Return
+		}
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+
+	// Compute the correlations of the returns
+	correlations := make([][]float64, len(entries))
+	for i := range entries {
+		correlations[i] = make([]float64, len(entries))
+		for j := range entries {
+			correlations[i][j] = correlation(Returns(entries[i].Balances), Returns(entries[j].Balances))
+		}
+	}
+
+	return Comparison{
+		StartTime:    start,
+		EndTime:      end,
+		RankBy:       cfg.rankBy,
+		Entries:      entries,
+		Correlations: correlations,
+	}, nil
+}
+
+// commonRange returns the time range covered by the balances of every report.
+func commonRange(reports []Report) (start, end time.Time, err error) {
+	for i, r := range reports {
+		if len(r.Balances) == 0 {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: backtest %s has no balance", ErrNoCommonRange, r.ID)
+		}
+
+		first, last := r.Balances[0].Time, r.Balances[len(r.Balances)-1].Time
+		if i == 0 || first.After(start) {
+			start = first
+		}
+		if i == 0 || last.Before(end) {
+			end = last
+		}
+	}
+
+	if start.After(end) {
+		return time.Time{}, time.Time{}, ErrNoCommonRange
+	}
+	return start, end, nil
+}
+
+// alignedTimes returns the times of the balances within the range.
+func alignedTimes(balances []BalancePoint, start, end time.Time) []time.Time {
+	times := make([]time.Time, 0, len(balances))
+	for _, b := range balances {
+		if !b.Time.Before(start) && !b.Time.After(end) {
+			times = append(times, b.Time)
+		}
+	}
+	return times
+}
+
+// align returns the last balance known at each time, the times being increasing.
+// Drawdowns are computed again from the first time.
+func align(balances []BalancePoint, times []time.Time) []BalancePoint {
+	aligned := make([]BalancePoint, 0, len(times))
+
+	var peak float64
+	next := 0
+	for _, t := range times {
+		for next < len(balances) && !balances[next].Time.After(t) {
+			next++
+		}
+
+		point := balances[0]
+		if next > 0 {
+			point = balances[next-1]
+		}
+		point.Time = t
+		point.Drawdown = 0
+
+		if point.Equity > peak {
+			peak = point.Equity
+		} else if peak > 0 {
+			point.Drawdown = 1 - point.Equity/peak
+		}
+		aligned = append(aligned, point)
+	}
+
+	return aligned
+}
+
+// ordersBetween returns the orders executed between start and end.
+func ordersBetween(orders []order.Order, start, end time.Time) []order.Order {
+	between := make([]order.Order, 0, len(orders))
+	for _, o := range orders {
+		if o.ExecutionTime != nil && !o.ExecutionTime.Before(start) && !o.ExecutionTime.After(end) {
+			between = append(between, o)
+		}
+	}
+	return between
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func sharpe(returns []float64) float64 {
+	sd := stddev(returns)
+	if sd == 0 {
+		return 0
+	}
+	return mean(returns) / sd
+}
+
+// correlation computes the Pearson correlation of two series of the same length.
+func correlation(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	ma, mb := mean(a), mean(b)
+	var cov, va, vb float64
+	for i := range a {
+		cov += (a[i] - ma) * (b[i] - mb)
+		va += (a[i] - ma) * (a[i] - ma)
+		vb += (b[i] - mb) * (b[i] - mb)
+	}
+
+	if va == 0 || vb == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(va*vb)
+}
+
+// WriteJSON writes the whole comparison as JSON.
+func (c Comparison) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// WriteCSV writes a table of the comparison as CSV.
+func (c Comparison) WriteCSV(w io.Writer, table Table) error {
+	var rows [][]string
+	switch table {
+	case TableRanking:
+		rows = c.rankingRows()
+	case TableEquityCurves:
+		rows = c.equityCurvesRows()
+	case TableCorrelations:
+		rows = c.correlationsRows()
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownTable, table)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+func (c Comparison) rankingRows() [][]string {
+	rows := [][]string{{
+		"rank", "id", "initial_equity", "final_equity", "return", "excess_return",
+		"max_drawdown", "volatility", "sharpe", "orders",
+	}}
+
+	for _, e := range c.Entries {
+		rows = append(rows, []string{
+			strconv.Itoa(e.Rank), e.ID.String(),
+			formatFloat(e.Summary.InitialEquity), formatFloat(e.Summary.FinalEquity),
+			formatFloat(e.Summary.Return), formatFloat(e.ExcessReturn),
+			formatFloat(e.Summary.MaxDrawdown), formatFloat(e.Summary.Volatility),
+			formatFloat(e.Sharpe), strconv.Itoa(e.Summary.Orders),
+		})
+	}
+
+	return rows
+}
+
+func (c Comparison) equityCurvesRows() [][]string {
+	header := []string{"time"}
+	for _, e := range c.Entries {
+		header = append(header, e.ID.String())
+	}
+
+	rows := [][]string{header}
+	if len(c.Entries) == 0 {
+		return rows
+	}
+
+	for i, b := range c.Entries[0].Balances {
+		row := []string{formatTime(b.Time)}
+		for _, e := range c.Entries {
+			row = append(row, formatFloat(e.Balances[i].Equity))
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func (c Comparison) correlationsRows() [][]string {
+	header := []string{"id"}
+	for _, e := range c.Entries {
+		header = append(header, e.ID.String())
+	}
+
+	rows := [][]string{header}
+	for i, e := range c.Entries {
+		row := []string{e.ID.String()}
+		for _, corr := range c.Correlations[i] {
+			row = append(row, formatFloat(corr))
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// equityChart returns the chart of the equity curves, relative to their
+// initial equity so they can be compared.
+func (c Comparison) equityChart() chart {
+	ch := chart{Percent: true}
+	for _, e := range c.Entries {
+		s := equitySeries(e.ID.String(), e.Balances)
+		if initial := e.Summary.InitialEquity; initial != 0 {
+			for i := range s.Points {
+				s.Points[i].Value = s.Points[i].Value/initial - 1
+			}
+		}
+		ch.Series = append(ch.Series, s)
+	}
+	return ch
+}
+
+// drawdownChart returns the chart of the drawdowns.
+func (c Comparison) drawdownChart() chart {
+	ch := chart{Area: true, Percent: true}
+	for _, e := range c.Entries {
+		ch.Series = append(ch.Series, drawdownSeries(e.ID.String(), e.Balances))
+	}
+	return ch
+}
+
+// WriteSVG writes the combined chart of the returns of the backtests over time as SVG.
+func (c Comparison) WriteSVG(w io.Writer) error {
+	_, err := io.WriteString(w, string(c.equityChart().SVG()))
+	return err
+}
+
+// compareTemplate is the template of the self-contained HTML comparison.
+var compareTemplate = template.Must(template.New("compare").Funcs(template.FuncMap{
+	"time":    formatTime,
+	"float":   func(f float64) string { return strconv.FormatFloat(f, 'f', 2, 64) },
+	"percent": func(f float64) string { return chart{Percent: true}.format(f) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Backtests comparison</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 860px; color: #111827; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #e5e7eb; padding: 4px 8px; text-align: right; font-size: 13px; }
+th:first-child, td:first-child { text-align: left; }
+.chart { width: 100%; height: auto; margin-bottom: 2em; }
+</style>
+</head>
+<body>
+<h1>Backtests comparison</h1>
+{{with .Comparison -}}
+<p>From {{time .StartTime}} to {{time .EndTime}}, ranked by {{.RankBy}}.</p>
+<h2>Ranking</h2>
+<table>
+<tr><th>Rank</th><th>Backtest</th><th>Return</th><th>Excess return</th>
+<th>Max drawdown</th><th>Volatility</th><th>Sharpe</th><th>Orders</th></tr>
+{{- range .Entries}}
+<tr><td>{{.Rank}}</td><td>{{.ID}}</td><td>{{percent .Summary.Return}}</td><td>{{percent .ExcessReturn}}</td>
+<td>{{percent .Summary.MaxDrawdown}}</td><td>{{percent .Summary.Volatility}}</td>
+<td>{{float .Sharpe}}</td><td>{{.Summary.Orders}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+<h2>Returns</h2>
+{{.Equity.SVG}}
+<h2>Drawdowns</h2>
+{{.Drawdown.SVG}}
+{{with .Comparison -}}
+<h2>Correlations of returns</h2>
+<table>
+<tr><th>Rank</th>{{range .Entries}}<th>{{.Rank}}</th>{{end}}</tr>
+{{- range $i, $row := .Correlations}}
+<tr><td>{{(index $.Comparison.Entries $i).Rank}}</td>{{range $row}}<td>{{float .}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes a self-contained HTML comparison, with the ranking, the
+// combined returns and drawdowns charts as SVG and the correlations.
+func (c Comparison) WriteHTML(w io.Writer) error {
+	return compareTemplate.Execute(w, struct {
+		Comparison Comparison
+		Equity     chart
+		Drawdown   chart
+	}{
+		Comparison: c,
+		Equity:     c.equityChart(),
+		Drawdown:   c.drawdownChart(),
+	})
+}
@@ -0,0 +1,213 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/cryptellation/go-clients/internal/fixture"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// testReport returns a report whose balances start at the nth minute, with
+// the given equities and their drawdowns.
+func testReport(n int, equity ...float64) Report {
+	r := Report{ID: uuid.New(), Balances: make([]BalancePoint, len(equity))}
+
+	var peak float64
+	for i, e := range equity {
+		r.Balances[i] = BalancePoint{Time: fixture.Minute(n + i), Equity: e}
+		if e > peak {
+			peak = e
+		} else if peak > 0 {
+			r.Balances[i].Drawdown = 1 - e/peak
+		}
+	}
+	return r
+}
+
+func TestCompareReports(t *testing.T) {
+	steady := testReport(0, 100, 101, 102, 103, 104)
+	volatile := testReport(0, 100, 120, 90, 130, 110)
+	losing := testReport(0, 100, 95, 90, 85, 80)
+	average := (0.04 + 0.1 - 0.2) / 3
+
+	tests := []struct {
+		name     string
+		reports  []Report
+		opts     []Options
+		ranking  []uuid.UUID
+		excess   []float64
+		drawdown []float64
+	}{
+		{
+			name:     "ranked by return",
+			reports:  []Report{steady, volatile, losing},
+			ranking:  []uuid.UUID{volatile.ID, steady.ID, losing.ID},
+			excess:   []float64{0.1 - average, 0.04 - average, -0.2 - average},
+			drawdown: []float64{1 - 90.0/120, 0, 0.2},
+		},
+		{
+			name:     "ranked by maximum drawdown",
+			reports:  []Report{steady, volatile, losing},
+			opts:     []Options{WithRanking(RankByMaxDrawdown)},
+			ranking:  []uuid.UUID{steady.ID, losing.ID, volatile.ID},
+			excess:   []float64{0.04 - average, -0.2 - average, 0.1 - average},
+			drawdown: []float64{0, 0.2, 1 - 90.0/120},
+		},
+		{
+			name:     "ranked by Sharpe ratio",
+			reports:  []Report{volatile, losing, steady},
+			opts:     []Options{WithRanking(RankBySharpe)},
+			ranking:  []uuid.UUID{steady.ID, volatile.ID, losing.ID},
+			excess:   []float64{0.04 - average, 0.1 - average, -0.2 - average},
+			drawdown: []float64{0, 1 - 90.0/120, 0.2},
+		},
+		{
+			name:     "single report",
+			reports:  []Report{volatile},
+			ranking:  []uuid.UUID{volatile.ID},
+			excess:   []float64{0},
+			drawdown: []float64{1 - 90.0/120},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := CompareReports(tt.reports, tt.opts...)
+			require.NoError(t, err)
+			require.Len(t, c.Entries, len(tt.ranking))
+
+			for i, e := range c.Entries {
+				require.Equal(t, i+1, e.Rank)
+				require.Equal(t, tt.ranking[i], e.ID)
+				require.InDelta(t, tt.excess[i], e.ExcessReturn, 1e-9)
+				require.InDelta(t, tt.drawdown[i], e.Summary.MaxDrawdown, 1e-9)
+			}
+
+			// Returns are perfectly correlated with themselves
+			for i := range c.Correlations {
+				require.InDelta(t, 1, c.Correlations[i][i], 1e-9)
+			}
+		})
+	}
+}
+
+func TestCompareReportsAlignment(t *testing.T) {
+	tests := []struct {
+		name      string
+		reports   []Report
+		start     int
+		end       int
+		equities  [][]float64
+		drawdowns [][]float64
+	}{
+		{
+			name:      "same times",
+			reports:   []Report{testReport(0, 100, 110, 99), testReport(0, 50, 40, 60)},
+			start:     0,
+			end:       2,
+			equities:  [][]float64{{100, 110, 99}, {50, 40, 60}},
+			drawdowns: [][]float64{{0, 0, 0.1}, {0, 0.2, 0}},
+		},
+		{
+			name:    "overlapping times",
+			reports: []Report{testReport(0, 100, 110, 99, 120), testReport(2, 50, 40, 60, 30)},
+			start:   2,
+			end:     3,
+			// The drawdowns are computed again from the start of the common range
+			equities:  [][]float64{{99, 120}, {50, 40}},
+			drawdowns: [][]float64{{0, 0}, {0, 0.2}},
+		},
+		{
+			name: "sparse balances aligned on the first report",
+			reports: []Report{
+				testReport(0, 100, 90, 80, 70),
+				{ID: uuid.New(), Balances: []BalancePoint{
+					{Time: fixture.Minute(0), Equity: 50},
+					{Time: fixture.Minute(2), Equity: 40},
+					{Time: fixture.Minute(3), Equity: 60},
+				}},
+			},
+			start:     0,
+			end:       3,
+			equities:  [][]float64{{100, 90, 80, 70}, {50, 50, 40, 60}},
+			drawdowns: [][]float64{{0, 0.1, 0.2, 0.3}, {0, 0, 0.2, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := CompareReports(tt.reports)
+			require.NoError(t, err)
+			require.Equal(t, fixture.Minute(tt.start), c.StartTime)
+			require.Equal(t, fixture.Minute(tt.end), c.EndTime)
+
+			for i, r := range tt.reports {
+				var entry ComparisonEntry
+				for _, e := range c.Entries {
+					if e.ID == r.ID {
+						entry = e
+					}
+				}
+
+				require.InDeltaSlice(t, tt.equities[i], equities(entry.Balances), 1e-9)
+				require.InDeltaSlice(t, tt.drawdowns[i], drawdowns(entry.Balances), 1e-9)
+				for j, b := range entry.Balances {
+					require.Equal(t, fixture.Minute(tt.start+j), b.Time)
+				}
+			}
+		})
+	}
+}
+
+func TestCompareReportsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		reports []Report
+		err     error
+	}{
+		{
+			name:    "no report",
+			reports: nil,
+			err:     ErrNothingToCompare,
+		},
+		{
+			name:    "report without balance",
+			reports: []Report{testReport(0, 100, 110), {ID: uuid.New()}},
+			err:     ErrNoCommonRange,
+		},
+		{
+			name:    "disjoint reports",
+			reports: []Report{testReport(0, 100, 110), testReport(5, 100, 110)},
+			err:     ErrNoCommonRange,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompareReports(tt.reports)
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestCorrelation(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     []float64
+		expected float64
+	}{
+		{name: "identical", a: []float64{1, 2, 3}, b: []float64{1, 2, 3}, expected: 1},
+		{name: "scaled", a: []float64{1, 2, 3}, b: []float64{2, 4, 6}, expected: 1},
+		{name: "opposite", a: []float64{1, 2, 3}, b: []float64{3, 2, 1}, expected: -1},
+		{name: "constant", a: []float64{1, 2, 3}, b: []float64{2, 2, 2}, expected: 0},
+		{name: "different lengths", a: []float64{1, 2, 3}, b: []float64{1, 2}, expected: 0},
+		{name: "empty", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.InDelta(t, tt.expected, correlation(tt.a, tt.b), 1e-9)
+		})
+	}
+}
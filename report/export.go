@@ -0,0 +1,190 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/cryptellation/runtime/account"
+)
+
+var (
+	// ErrUnknownTable is returned when exporting a table that does not exist.
+	ErrUnknownTable = errors.New("unknown report table")
+)
+
+// Table is a table of the report that can be exported to CSV.
+type Table string
+
+const (
+	// TableParameters is the table of the backtest parameters, as name/value rows.
+	TableParameters Table = "parameters"
+	// TableOrders is the table of the executed orders.
+	TableOrders Table = "orders"
+	// TableBalances is the table of the balances over time, with a column per
+	// exchange and asset.
+	TableBalances Table = "balances"
+	// TableSummary is the table of the summary statistics, as name/value rows.
+	TableSummary Table = "summary"
+)
+
+// Tables are all the tables of the report.
+var Tables = []Table{TableParameters, TableOrders, TableBalances, TableSummary}
+
+// WriteJSON writes the whole report as JSON.
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV writes a table of the report as CSV.
+func (r Report) WriteCSV(w io.Writer, table Table) error {
+	var rows [][]string
+	switch table {
+	case TableParameters:
+		rows = r.parametersRows()
+	case TableOrders:
+		rows = r.ordersRows()
+	case TableBalances:
+		rows = r.balancesRows()
+	case TableSummary:
+		rows = r.summaryRows()
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownTable, table)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func (r Report) parametersRows() [][]string {
+	rows := [][]string{
+		{"name", "value"},
+		{"id", r.ID.String()},
+		{"start_time", formatTime(r.Parameters.StartTime)},
+		{"end_time", formatTime(r.Parameters.EndTime)},
+		{"mode", r.Parameters.Mode},
+		{"price_period", r.Parameters.PricePeriod},
+		{"quote_asset", r.Parameters.QuoteAsset},
+	}
+
+	for _, col := range accountsColumns(r.Parameters.InitialAccounts) {
+		qty := r.Parameters.InitialAccounts[col.Exchange].Balances[col.Symbol]
+		rows = append(rows, []string{"initial_" + col.String(), formatFloat(qty)})
+	}
+	for _, col := range accountsColumns(r.Parameters.FinalAccounts) {
+		qty := r.Parameters.FinalAccounts[col.Exchange].Balances[col.Symbol]
+		rows = append(rows, []string{"final_" + col.String(), formatFloat(qty)})
+	}
+
+	return rows
+}
+
+func (r Report) ordersRows() [][]string {
+	rows := [][]string{
+		{"id", "execution_time", "type", "exchange", "pair", "side", "quantity", "price"},
+	}
+
+	for _, o := range r.Orders {
+		var execution string
+		if o.ExecutionTime != nil {
+			execution = formatTime(*o.ExecutionTime)
+		}
+
+		rows = append(rows, []string{
+			o.ID.String(), execution, o.Type.String(), o.Exchange, o.Pair,
+			o.Side.String(), formatFloat(o.Quantity), formatFloat(o.Price),
+		})
+	}
+
+	return rows
+}
+
+func (r Report) balancesRows() [][]string {
+	// Get the columns of every asset held during the backtest
+	columns := accountsColumns(r.Parameters.InitialAccounts)
+	for _, b := range r.Balances {
+		columns = append(columns, accountsColumns(b.Accounts)...)
+	}
+	columns = uniqueColumns(columns)
+
+	header := []string{"time", "equity", "drawdown"}
+	for _, col := range columns {
+		header = append(header, col.String())
+	}
+
+	rows := [][]string{header}
+	for _, b := range r.Balances {
+		row := []string{formatTime(b.Time), formatFloat(b.Equity), formatFloat(b.Drawdown)}
+		for _, col := range columns {
+			row = append(row, formatFloat(b.Accounts[col.Exchange].Balances[col.Symbol]))
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func (r Report) summaryRows() [][]string {
+	s := r.Summary
+	return [][]string{
+		{"name", "value"},
+		{"initial_equity", formatFloat(s.InitialEquity)},
+		{"final_equity", formatFloat(s.FinalEquity)},
+		{"profit", formatFloat(s.Profit)},
+		{"return", formatFloat(s.Return)},
+		{"max_drawdown", formatFloat(s.MaxDrawdown)},
+		{"volatility", formatFloat(s.Volatility)},
+		{"orders", strconv.Itoa(s.Orders)},
+		{"buy_orders", strconv.Itoa(s.BuyOrders)},
+		{"sell_orders", strconv.Itoa(s.SellOrders)},
+	}
+}
+
+// String returns the name of the asset column.
+func (a asset) String() string {
+	return a.Exchange + ":" + a.Symbol
+}
+
+// accountsColumns returns the assets of the accounts, sorted.
+func accountsColumns(accounts map[string]account.Account) []asset {
+	columns := make([]asset, 0, len(accounts))
+	for exch, a := range accounts {
+		for symbol := range a.Balances {
+			columns = append(columns, asset{Exchange: exch, Symbol: symbol})
+		}
+	}
+	return uniqueColumns(columns)
+}
+
+// uniqueColumns sorts the columns and removes the duplicates.
+func uniqueColumns(columns []asset) []asset {
+	sort.Slice(columns, func(i, j int) bool {
+		return columns[i].String() < columns[j].String()
+	})
+
+	unique := columns[:0]
+	for i, col := range columns {
+		if i == 0 || col != columns[i-1] {
+			unique = append(unique, col)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,83 @@
+package report
+
+import (
+	"html/template"
+	"io"
+)
+
+// htmlTemplate is the template of the self-contained HTML report.
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"time":    formatTime,
+	"float":   formatFloat,
+	"percent": func(f float64) string { return chart{Percent: true}.format(f) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 860px; color: #111827; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #e5e7eb; padding: 4px 8px; text-align: right; font-size: 13px; }
+th:first-child, td:first-child { text-align: left; }
+.chart { width: 100%; height: auto; margin-bottom: 2em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{with .Report -}}
+<h2>Summary</h2>
+<table>
+<tr><td>Period</td><td>{{time .Parameters.StartTime}} - {{time .Parameters.EndTime}}</td></tr>
+<tr><td>Initial equity</td><td>{{float .Summary.InitialEquity}} {{.Parameters.QuoteAsset}}</td></tr>
+<tr><td>Final equity</td><td>{{float .Summary.FinalEquity}} {{.Parameters.QuoteAsset}}</td></tr>
+<tr><td>Profit</td><td>{{float .Summary.Profit}} {{.Parameters.QuoteAsset}}</td></tr>
+<tr><td>Return</td><td>{{percent .Summary.Return}}</td></tr>
+<tr><td>Max drawdown</td><td>{{percent .Summary.MaxDrawdown}}</td></tr>
+<tr><td>Volatility</td><td>{{percent .Summary.Volatility}}</td></tr>
+<tr><td>Orders (buy/sell)</td><td>{{.Summary.Orders}} ({{.Summary.BuyOrders}}/{{.Summary.SellOrders}})</td></tr>
+</table>
+{{- end}}
+<h2>Equity</h2>
+{{.Equity.SVG}}
+<h2>Drawdown</h2>
+{{.Drawdown.SVG}}
+{{with .Report -}}
+<h2>Parameters</h2>
+<table>
+<tr><td>Mode</td><td>{{.Parameters.Mode}}</td></tr>
+<tr><td>Price period</td><td>{{.Parameters.PricePeriod}}</td></tr>
+{{- range $exchange, $account := .Parameters.InitialAccounts}}
+{{- range $asset, $qty := $account.Balances}}
+<tr><td>Initial {{$asset}} on {{$exchange}}</td><td>{{float $qty}}</td></tr>
+{{- end}}
+{{- end}}
+</table>
+<h2>Orders</h2>
+<table>
+<tr><th>Time</th><th>Exchange</th><th>Pair</th><th>Type</th><th>Side</th><th>Quantity</th><th>Price</th></tr>
+{{- range .Orders}}
+<tr><td>{{with .ExecutionTime}}{{time .}}{{end}}</td><td>{{.Exchange}}</td><td>{{.Pair}}</td>
+<td>{{.Type}}</td><td>{{.Side}}</td><td>{{float .Quantity}}</td><td>{{float .Price}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes a self-contained HTML report, with the summary, the equity
+// curve and drawdown charts as SVG, the parameters and the orders.
+func (r Report) WriteHTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, struct {
+		Title    string
+		Report   Report
+		Equity   chart
+		Drawdown chart
+	}{
+		Title:    "Backtest " + r.ID.String(),
+		Report:   r,
+		Equity:   chart{Series: []chartSeries{equitySeries("Equity", r.Balances)}},
+		Drawdown: chart{Series: []chartSeries{drawdownSeries("Drawdown", r.Balances)}, Area: true, Percent: true},
+	})
+}
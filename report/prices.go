@@ -0,0 +1,219 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	candlesticksapi "github.com/cryptellation/candlesticks/api"
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
+	"github.com/cryptellation/candlesticks/pkg/pair"
+	"github.com/cryptellation/candlesticks/pkg/period"
+	"github.com/cryptellation/runtime/account"
+	"github.com/cryptellation/runtime/order"
+)
+
+// PricesSource is the source of the candlesticks used to value the accounts.
+// The Cryptellation client and the offline candlesticks sources implement
+// this interface.
+type PricesSource interface {
+	// ListCandlesticks lists candlesticks with the same semantics as the
+	// candlesticks service.
+	ListCandlesticks(
+		ctx context.Context,
+		params candlesticksapi.ListCandlesticksWorkflowParams,
+	) (candlesticksapi.ListCandlesticksWorkflowResults, error)
+}
+
+// asset is an asset held on an exchange.
+type asset struct {
+	Exchange string
+	Symbol   string
+}
+
+// series are the prices of an asset in the quote asset, in chronological order.
+type series struct {
+	candlesticks []candlestick.Candlestick
+	// next is the index of the first candlestick after the last valued time.
+	next int
+}
+
+// valuator values the accounts in the quote asset.
+type valuator struct {
+	source PricesSource
+	config config
+	period period.Symbol
+	prices map[asset]*series
+}
+
+func newValuator(src PricesSource, cfg config, per period.Symbol) *valuator {
+	return &valuator{
+		source: src,
+		config: cfg,
+		period: per,
+		prices: make(map[asset]*series),
+	}
+}
+
+// load loads the prices of the assets held in the accounts or traded by the orders.
+func (v *valuator) load(
+	ctx context.Context,
+	accounts map[string]account.Account,
+	orders []order.Order,
+	start, end time.Time,
+) error {
+	// Get the assets
+	assets := make(map[asset]bool)
+	for exch, a := range accounts {
+		for symbol, qty := range a.Balances {
+			if qty != 0 {
+				assets[asset{Exchange: exch, Symbol: symbol}] = true
+			}
+		}
+	}
+	for _, o := range orders {
+		base, quote, err := pair.ParsePair(o.Pair)
+		if err != nil {
+			return err
+		}
+		assets[asset{Exchange: o.Exchange, Symbol: base}] = true
+		assets[asset{Exchange: o.Exchange, Symbol: quote}] = true
+	}
+
+	// Get the prices of each asset
+	for a := range assets {
+		if a.Symbol == v.config.quoteAsset {
+			continue
+		}
+
+		list, err := v.list(ctx, a, start, end)
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return fmt.Errorf("%w: %s on %s in %s", ErrNoPrice, a.Symbol, a.Exchange, v.config.quoteAsset)
+		}
+		v.prices[a] = &series{candlesticks: list}
+	}
+
+	return nil
+}
+
+// list lists all the candlesticks of the asset between start and end, as the
+// candlesticks service can return them in several pages.
+func (v *valuator) list(ctx context.Context, a asset, start, end time.Time) ([]candlestick.Candlestick, error) {
+	var list []candlestick.Candlestick
+	for from := start; !from.After(end); {
+		res, err := v.source.ListCandlesticks(ctx, candlesticksapi.ListCandlesticksWorkflowParams{
+			Exchange: a.Exchange,
+			Pair:     pair.FormatPair(a.Symbol, v.config.quoteAsset),
+			Period:   v.period,
+			Start:    &from,
+			End:      &end,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(res.List) == 0 {
+			break
+		}
+		list = append(list, res.List...)
+
+		// Continue after the last candlestick
+		next := res.List[len(res.List)-1].Time.Add(v.period.Duration())
+		if !next.After(from) {
+			break
+		}
+		from = next
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Time.Before(list[j].Time)
+	})
+	return list, nil
+}
+
+// price returns the last known price of the asset at the given time. Before
+// the first candlestick, its price is used. Times must be increasing.
+func (v *valuator) price(a asset, t time.Time) (float64, error) {
+	if a.Symbol == v.config.quoteAsset {
+		return 1, nil
+	}
+
+	s, ok := v.prices[a]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s on %s in %s", ErrNoPrice, a.Symbol, a.Exchange, v.config.quoteAsset)
+	}
+
+	for s.next < len(s.candlesticks) && !s.candlesticks[s.next].Time.After(t) {
+		s.next++
+	}
+
+	cs := s.candlesticks[0]
+	if s.next > 0 {
+		cs = s.candlesticks[s.next-1]
+	}
+	return cs.Price(v.config.priceType), nil
+}
+
+// equity values the accounts at the given time.
+func (v *valuator) equity(accounts map[string]account.Account, t time.Time) (float64, error) {
+	var equity float64
+	for exch, a := range accounts {
+		for symbol, qty := range a.Balances {
+			if qty == 0 {
+				continue
+			}
+
+			p, err := v.price(asset{Exchange: exch, Symbol: symbol}, t)
+			if err != nil {
+				return 0, err
+			}
+			equity += qty * p
+		}
+	}
+	return equity, nil
+}
+
+// balances replays the orders on the accounts and values them at each period.
+func (v *valuator) balances(
+	initial map[string]account.Account,
+	orders []order.Order,
+	start, end time.Time,
+) ([]BalancePoint, error) {
+	accounts := copyAccounts(initial)
+	points := make([]BalancePoint, 0, v.period.CountBetweenTimes(start, end)+1)
+
+	var peak float64
+	for t, next := v.period.RoundTime(start), 0; !t.After(end); t = t.Add(v.period.Duration()) {
+		// Apply the orders executed until now
+		for ; next < len(orders) && !orders[next].ExecutionTime.After(t); next++ {
+			o := orders[next]
+			a := accounts[o.Exchange]
+			if err := a.ApplyOrder(o.Price, o); err != nil {
+				return nil, fmt.Errorf("applying order %s: %w", o.ID, err)
+			}
+		}
+
+		// Value the accounts
+		equity, err := v.equity(accounts, t)
+		if err != nil {
+			return nil, err
+		}
+
+		point := BalancePoint{
+			Time:     t,
+			Accounts: copyAccounts(accounts),
+			Equity:   equity,
+		}
+		if equity > peak {
+			peak = equity
+		} else if peak > 0 {
+			point.Drawdown = 1 - equity/peak
+		}
+		points = append(points, point)
+	}
+
+	return points, nil
+}
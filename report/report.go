@@ -0,0 +1,298 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	backtestsapi "github.com/cryptellation/backtests/api"
+	"github.com/cryptellation/backtests/pkg/backtest"
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
+	"github.com/cryptellation/runtime/account"
+	"github.com/cryptellation/runtime/order"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNoPrice is returned when an asset held by the backtest can not be
+	// valued in the quote asset.
+	ErrNoPrice = errors.New("no price to value asset")
+)
+
+// Source is the source of the backtests and of the prices used to value
+// their accounts. The Cryptellation client implements this interface.
+type Source interface {
+	PricesSource
+	// GetBacktestDetails gets the whole state of a backtest.
+	GetBacktestDetails(
+		ctx context.Context,
+		params backtestsapi.GetBacktestWorkflowParams,
+	) (backtest.Backtest, error)
+}
+
+type config struct {
+	quoteAsset string
+	priceType  candlestick.PriceType
+	rankBy     RankBy
+}
+
+// Options is a function that modifies the report configuration.
+type Options func(*config)
+
+// WithQuoteAsset sets the asset in which the balances are valued.
+// Default is USDT.
+func WithQuoteAsset(asset string) Options {
+	return func(c *config) {
+		c.quoteAsset = asset
+	}
+}
+
+// WithPriceType sets the candlestick price used to value the balances.
+// Default is the close price.
+func WithPriceType(pt candlestick.PriceType) Options {
+	return func(c *config) {
+		c.priceType = pt
+	}
+}
+
+// Parameters are the parameters of the backtest.
+type Parameters struct {
+	StartTime       time.Time                  `json:"start_time"`
+	EndTime         time.Time                  `json:"end_time"`
+	Mode            string                     `json:"mode"`
+	PricePeriod     string                     `json:"price_period"`
+	QuoteAsset      string                     `json:"quote_asset"`
+	InitialAccounts map[string]account.Account `json:"initial_accounts"`
+	FinalAccounts   map[string]account.Account `json:"final_accounts"`
+}
+
+// BalancePoint is the state of the accounts at a given time.
+type BalancePoint struct {
+	Time time.Time `json:"time"`
+	// Accounts are the balances of each exchange account.
+	Accounts map[string]account.Account `json:"accounts"`
+	// Equity is the value of all the accounts in the quote asset.
+	Equity float64 `json:"equity"`
+	// Drawdown is the relative loss of the equity from its previous peak, between 0 and 1.
+	Drawdown float64 `json:"drawdown"`
+}
+
+// Summary are the statistics of the backtest.
+type Summary struct {
+	InitialEquity float64 `json:"initial_equity"`
+	FinalEquity   float64 `json:"final_equity"`
+	Profit        float64 `json:"profit"`
+	// Return is the relative profit, where 0.1 is +10%.
+	Return float64 `json:"return"`
+	// MaxDrawdown is the largest relative loss from a peak, between 0 and 1.
+	MaxDrawdown float64 `json:"max_drawdown"`
+	// Volatility is the standard deviation of the returns between two points.
+	Volatility float64 `json:"volatility"`
+	Orders     int     `json:"orders"`
+	BuyOrders  int     `json:"buy_orders"`
+	SellOrders int     `json:"sell_orders"`
+}
+
+// Report is the outcome of a backtest, ready to be exported.
+type Report struct {
+	ID         uuid.UUID      `json:"id"`
+	Parameters Parameters     `json:"parameters"`
+	Orders     []order.Order  `json:"orders"`
+	Balances   []BalancePoint `json:"balances"`
+	Summary    Summary        `json:"summary"`
+}
+
+// New gets the backtest from the source and builds its report.
+func New(ctx context.Context, src Source, id uuid.UUID, opts ...Options) (Report, error) {
+	bt, err := src.GetBacktestDetails(ctx, backtestsapi.GetBacktestWorkflowParams{
+		BacktestID: id,
+	})
+	if err != nil {
+		return Report{}, err
+	}
+
+	return FromBacktest(ctx, bt, src, opts...)
+}
+
+// FromBacktest builds the report of a backtest, like the ones returned by
+// the client or by the offline engine. The balances over time are rebuilt
+// from the executed orders and valued with the prices of the source.
+func FromBacktest(
+	ctx context.Context,
+	bt backtest.Backtest,
+	prices PricesSource,
+	opts ...Options,
+) (Report, error) {
+	cfg := config{
+		quoteAsset: "USDT",
+		priceType:  candlestick.PriceTypeIsClose,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	// Get executed orders in chronological order
+	orders := executedOrders(bt.Orders)
+
+	// Rebuild initial accounts from the final ones
+	initial, err := initialAccounts(bt.Accounts, orders)
+	if err != nil {
+		return Report{}, err
+	}
+
+	// Get prices of every asset held during the backtest
+	end := bt.EndTime
+	if cur := bt.CurrentCandlestick.Time; !cur.IsZero() && cur.Before(end) {
+		end = cur
+	}
+	v := newValuator(prices, cfg, bt.PricePeriod)
+	if err := v.load(ctx, initial, orders, bt.StartTime, end); err != nil {
+		return Report{}, err
+	}
+
+	// Compute the balances over time
+	balances, err := v.balances(initial, orders, bt.StartTime, end)
+	if err != nil {
+		return Report{}, err
+	}
+
+	return Report{
+		ID: bt.ID,
+		Parameters: Parameters{
+			StartTime:       bt.StartTime,
+			EndTime:         bt.EndTime,
+			Mode:            bt.Mode.String(),
+			PricePeriod:     bt.PricePeriod.String(),
+			QuoteAsset:      cfg.quoteAsset,
+			InitialAccounts: initial,
+			FinalAccounts:   copyAccounts(bt.Accounts),
+		},
+		Orders:   orders,
+		Balances: balances,
+		Summary:  summarize(balances, orders),
+	}, nil
+}
+
+// executedOrders returns the orders that have been executed, in chronological order.
+func executedOrders(orders []order.Order) []order.Order {
+	executed := make([]order.Order, 0, len(orders))
+	for _, o := range orders {
+		if o.ExecutionTime != nil {
+			executed = append(executed, o)
+		}
+	}
+
+	sort.SliceStable(executed, func(i, j int) bool {
+		return executed[i].ExecutionTime.Before(*executed[j].ExecutionTime)
+	})
+	return executed
+}
+
+// initialAccounts reverts the orders on the final accounts.
+func initialAccounts(final map[string]account.Account, orders []order.Order) (map[string]account.Account, error) {
+	accounts := copyAccounts(final)
+	for i := len(orders) - 1; i >= 0; i-- {
+		o := orders[i]
+		a, ok := accounts[o.Exchange]
+		if !ok {
+			return nil, fmt.Errorf("order %s: no account on %q", o.ID, o.Exchange)
+		}
+
+		// Revert the order with the opposite side
+		reverted := o
+		switch o.Side {
+		case order.SideIsBuy:
+			reverted.Side = order.SideIsSell
+		case order.SideIsSell:
+			reverted.Side = order.SideIsBuy
+		}
+		if err := a.ApplyOrder(o.Price, reverted); err != nil {
+			return nil, fmt.Errorf("reverting order %s: %w", o.ID, err)
+		}
+	}
+
+	return accounts, nil
+}
+
+func copyAccounts(accounts map[string]account.Account) map[string]account.Account {
+	cp := make(map[string]account.Account, len(accounts))
+	for exch, a := range accounts {
+		balances := make(map[string]float64, len(a.Balances))
+		for asset, qty := range a.Balances {
+			balances[asset] = qty
+		}
+		cp[exch] = account.Account{Balances: balances}
+	}
+	return cp
+}
+
+// summarize computes the statistics of the balances over time.
+func summarize(balances []BalancePoint, orders []order.Order) Summary {
+	var s Summary
+	for _, o := range orders {
+		switch o.Side {
+		case order.SideIsBuy:
+			s.BuyOrders++
+		case order.SideIsSell:
+			s.SellOrders++
+		}
+	}
+	s.Orders = len(orders)
+
+	if len(balances) == 0 {
+		return s
+	}
+
+	s.InitialEquity = balances[0].Equity
+	s.FinalEquity = balances[len(balances)-1].Equity
+	s.Profit = s.FinalEquity - s.InitialEquity
+	if s.InitialEquity != 0 {
+		s.Return = s.Profit / s.InitialEquity
+	}
+
+	for _, b := range balances {
+		s.MaxDrawdown = math.Max(s.MaxDrawdown, b.Drawdown)
+	}
+	s.Volatility = stddev(Returns(balances))
+
+	return s
+}
+
+// Returns computes the relative returns of the equity between two consecutive points.
+func Returns(balances []BalancePoint) []float64 {
+	if len(balances) < 2 {
+		return nil
+	}
+
+	returns := make([]float64, 0, len(balances)-1)
+	for i := 1; i < len(balances); i++ {
+		prev := balances[i-1].Equity
+		if prev == 0 {
+			returns = append(returns, 0)
+			continue
+		}
+		returns = append(returns, balances[i].Equity/prev-1)
+	}
+	return returns
+}
+
+func stddev(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	var mean float64
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(variance / float64(len(values)))
+}
@@ -0,0 +1,322 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	backtestsapi "github.com/cryptellation/backtests/api"
+	"github.com/cryptellation/backtests/pkg/backtest"
+	candlesticksapi "github.com/cryptellation/candlesticks/api"
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
+	"github.com/cryptellation/candlesticks/pkg/period"
+	"github.com/cryptellation/go-clients/internal/fixture"
+	"github.com/cryptellation/runtime/account"
+	"github.com/cryptellation/runtime/order"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// source is a source of one minute candlesticks by pair, and of a backtest.
+type source struct {
+	prices   map[string][]candlestick.Candlestick
+	backtest backtest.Backtest
+}
+
+func (s source) ListCandlesticks(
+	_ context.Context,
+	params candlesticksapi.ListCandlesticksWorkflowParams,
+) (candlesticksapi.ListCandlesticksWorkflowResults, error) {
+	var list []candlestick.Candlestick
+	for _, cs := range s.prices[params.Pair] {
+		if !cs.Time.Before(*params.Start) && !cs.Time.After(*params.End) {
+			list = append(list, cs)
+		}
+	}
+	return candlesticksapi.ListCandlesticksWorkflowResults{List: list}, nil
+}
+
+func (s source) GetBacktestDetails(
+	_ context.Context,
+	params backtestsapi.GetBacktestWorkflowParams,
+) (backtest.Backtest, error) {
+	if params.BacktestID != s.backtest.ID {
+		return backtest.Backtest{}, errors.New("backtest not found")
+	}
+	return s.backtest, nil
+}
+
+// executed returns an order on BTC-USDT executed at the nth minute.
+func executed(n int, side order.Side, qty, price float64) order.Order {
+	t := fixture.Minute(n)
+	return order.Order{
+		ID:            uuid.New(),
+		ExecutionTime: &t,
+		Type:          order.TypeIsMarket,
+		Exchange:      "binance",
+		Pair:          "BTC-USDT",
+		Side:          side,
+		Quantity:      qty,
+		Price:         price,
+	}
+}
+
+// testBacktest returns a one minute backtest from the start of the fixtures
+// to the nth minute, ending with the given balances on binance.
+func testBacktest(n int, balances map[string]float64, orders ...order.Order) backtest.Backtest {
+	return backtest.Backtest{
+		ID:          uuid.New(),
+		StartTime:   fixture.Minute(0),
+		EndTime:     fixture.Minute(n),
+		Mode:        backtest.ModeIsCloseOHLC,
+		PricePeriod: period.M1,
+		Accounts:    map[string]account.Account{"binance": {Balances: balances}},
+		Orders:      orders,
+	}
+}
+
+func equities(balances []BalancePoint) []float64 {
+	res := make([]float64, len(balances))
+	for i, b := range balances {
+		res[i] = b.Equity
+	}
+	return res
+}
+
+func drawdowns(balances []BalancePoint) []float64 {
+	res := make([]float64, len(balances))
+	for i, b := range balances {
+		res[i] = b.Drawdown
+	}
+	return res
+}
+
+// requireSummary checks the summary, except the volatility that is checked by TestSummarize.
+func requireSummary(t *testing.T, expected, actual Summary) {
+	t.Helper()
+	require.InDelta(t, expected.InitialEquity, actual.InitialEquity, 1e-9)
+	require.InDelta(t, expected.FinalEquity, actual.FinalEquity, 1e-9)
+	require.InDelta(t, expected.Profit, actual.Profit, 1e-9)
+	require.InDelta(t, expected.Return, actual.Return, 1e-9)
+	require.InDelta(t, expected.MaxDrawdown, actual.MaxDrawdown, 1e-9)
+	require.Equal(t, expected.Orders, actual.Orders)
+	require.Equal(t, expected.BuyOrders, actual.BuyOrders)
+	require.Equal(t, expected.SellOrders, actual.SellOrders)
+}
+
+func TestFromBacktest(t *testing.T) {
+	prices := map[string][]candlestick.Candlestick{
+		"BTC-USDT": fixture.Closes(100, 110, 90, 120, 60),
+	}
+
+	tests := []struct {
+		name      string
+		backtest  backtest.Backtest
+		opts      []Options
+		equities  []float64
+		drawdowns []float64
+		initial   map[string]float64
+		summary   Summary
+	}{
+		{
+			name:      "quote asset only",
+			backtest:  testBacktest(4, map[string]float64{"USDT": 1000}),
+			equities:  []float64{1000, 1000, 1000, 1000, 1000},
+			drawdowns: []float64{0, 0, 0, 0, 0},
+			initial:   map[string]float64{"USDT": 1000},
+			summary:   Summary{InitialEquity: 1000, FinalEquity: 1000},
+		},
+		{
+			name: "bought then price moving",
+			backtest: testBacktest(4, map[string]float64{"USDT": 890, "BTC": 1},
+				executed(1, order.SideIsBuy, 1, 110)),
+			equities:  []float64{1000, 1000, 980, 1010, 950},
+			drawdowns: []float64{0, 0, 0.02, 0, 1 - 950.0/1010},
+			initial:   map[string]float64{"USDT": 1000, "BTC": 0},
+			summary: Summary{
+				InitialEquity: 1000, FinalEquity: 950, Profit: -50, Return: -0.05,
+				MaxDrawdown: 1 - 950.0/1010, Orders: 1, BuyOrders: 1,
+			},
+		},
+		{
+			name: "bought then sold",
+			backtest: testBacktest(4, map[string]float64{"USDT": 1010, "BTC": 0},
+				executed(1, order.SideIsBuy, 1, 110),
+				executed(3, order.SideIsSell, 1, 120)),
+			equities:  []float64{1000, 1000, 980, 1010, 1010},
+			drawdowns: []float64{0, 0, 0.02, 0, 0},
+			initial:   map[string]float64{"USDT": 1000, "BTC": 0},
+			summary: Summary{
+				InitialEquity: 1000, FinalEquity: 1010, Profit: 10, Return: 0.01,
+				MaxDrawdown: 0.02, Orders: 2, BuyOrders: 1, SellOrders: 1,
+			},
+		},
+		{
+			name:      "valued with open prices",
+			backtest:  testBacktest(2, map[string]float64{"BTC": 1}),
+			opts:      []Options{WithPriceType(candlestick.PriceTypeIsOpen)},
+			equities:  []float64{100, 100, 110},
+			drawdowns: []float64{0, 0, 0},
+			initial:   map[string]float64{"BTC": 1},
+			summary:   Summary{InitialEquity: 100, FinalEquity: 110, Profit: 10, Return: 0.1},
+		},
+		{
+			name: "stopped before its end",
+			backtest: func() backtest.Backtest {
+				bt := testBacktest(4, map[string]float64{"BTC": 1})
+				bt.CurrentCandlestick.Time = fixture.Minute(2)
+				return bt
+			}(),
+			equities:  []float64{100, 110, 90},
+			drawdowns: []float64{0, 0, 1 - 90.0/110},
+			initial:   map[string]float64{"BTC": 1},
+			summary: Summary{
+				InitialEquity: 100, FinalEquity: 90, Profit: -10, Return: -0.1,
+				MaxDrawdown: 1 - 90.0/110,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := FromBacktest(context.Background(), tt.backtest, source{prices: prices}, tt.opts...)
+			require.NoError(t, err)
+
+			require.InDeltaSlice(t, tt.equities, equities(r.Balances), 1e-9)
+			require.InDeltaSlice(t, tt.drawdowns, drawdowns(r.Balances), 1e-9)
+			require.Equal(t, tt.initial, r.Parameters.InitialAccounts["binance"].Balances)
+
+			requireSummary(t, tt.summary, r.Summary)
+		})
+	}
+}
+
+func TestFromBacktestErrors(t *testing.T) {
+	prices := map[string][]candlestick.Candlestick{
+		"BTC-USDT": fixture.Closes(100, 110, 90),
+	}
+
+	tests := []struct {
+		name     string
+		backtest backtest.Backtest
+		err      error
+	}{
+		{
+			name:     "asset without price",
+			backtest: testBacktest(2, map[string]float64{"USDT": 1000, "ETH": 1}),
+			err:      ErrNoPrice,
+		},
+		{
+			name: "order on an exchange without account",
+			backtest: func() backtest.Backtest {
+				o := executed(1, order.SideIsBuy, 1, 110)
+				o.Exchange = "kraken"
+				return testBacktest(2, map[string]float64{"USDT": 890, "BTC": 1}, o)
+			}(),
+		},
+		{
+			name: "order that can not be reverted",
+			backtest: testBacktest(2, map[string]float64{"USDT": 1000},
+				executed(1, order.SideIsBuy, 1, 110)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FromBacktest(context.Background(), tt.backtest, source{prices: prices})
+			require.Error(t, err)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	bt := testBacktest(2, map[string]float64{"BTC": 1})
+	src := source{prices: map[string][]candlestick.Candlestick{"BTC-USDT": fixture.Closes(100, 110, 90)}, backtest: bt}
+
+	r, err := New(context.Background(), src, bt.ID, WithQuoteAsset("USDT"))
+	require.NoError(t, err)
+	require.Equal(t, bt.ID, r.ID)
+	require.Equal(t, []float64{100, 110, 90}, equities(r.Balances))
+
+	_, err = New(context.Background(), src, uuid.New())
+	require.Error(t, err)
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name       string
+		equities   []float64
+		drawdowns  []float64
+		ret        float64
+		volatility float64
+	}{
+		{name: "no balance"},
+		{name: "single balance", equities: []float64{100}},
+		{
+			name:       "constant returns",
+			equities:   []float64{100, 110, 121},
+			ret:        0.21,
+			volatility: 0,
+		},
+		{
+			name:       "alternating returns",
+			equities:   []float64{100, 110, 99, 108.9},
+			drawdowns:  []float64{0, 0, 0.1, 0.01},
+			ret:        0.089,
+			volatility: math.Sqrt(0.08) / 3,
+		},
+		{
+			name:     "zero initial equity",
+			equities: []float64{0, 100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balances := make([]BalancePoint, len(tt.equities))
+			var maxDrawdown float64
+			for i, e := range tt.equities {
+				balances[i] = BalancePoint{Time: fixture.Minute(i), Equity: e}
+				if i < len(tt.drawdowns) {
+					balances[i].Drawdown = tt.drawdowns[i]
+					maxDrawdown = max(maxDrawdown, tt.drawdowns[i])
+				}
+			}
+
+			s := summarize(balances, nil)
+			require.InDelta(t, tt.ret, s.Return, 1e-9)
+			require.InDelta(t, maxDrawdown, s.MaxDrawdown, 1e-9)
+			require.InDelta(t, tt.volatility, s.Volatility, 1e-9)
+		})
+	}
+}
+
+func TestReturns(t *testing.T) {
+	tests := []struct {
+		name     string
+		equities []float64
+		expected []float64
+	}{
+		{name: "no balance", expected: nil},
+		{name: "single balance", equities: []float64{100}, expected: nil},
+		{name: "gains and losses", equities: []float64{100, 110, 99}, expected: []float64{0.1, -0.1}},
+		{name: "zero equity", equities: []float64{0, 100, 50}, expected: []float64{0, -0.5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balances := make([]BalancePoint, len(tt.equities))
+			for i, e := range tt.equities {
+				balances[i] = BalancePoint{Time: fixture.Minute(i), Equity: e}
+			}
+
+			returns := Returns(balances)
+			require.Len(t, returns, len(tt.expected))
+			require.InDeltaSlice(t, tt.expected, returns, 1e-9)
+		})
+	}
+}
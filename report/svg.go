@@ -0,0 +1,170 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	chartWidth     = 800
+	chartHeight    = 300
+	chartPadding   = 50
+	chartMaxPoints = 1000
+)
+
+// chartColors are the colors of the chart series, in order.
+var chartColors = []string{"#2563eb", "#dc2626", "#16a34a", "#9333ea", "#ea580c", "#0891b2", "#ca8a04", "#db2777"}
+
+// chartPoint is a point of a chart series.
+type chartPoint struct {
+	Time  time.Time
+	Value float64
+}
+
+// chartSeries is a line of a chart.
+type chartSeries struct {
+	Name   string
+	Points []chartPoint
+}
+
+// chart is a line chart rendered as SVG.
+type chart struct {
+	Series []chartSeries
+	// Area fills the area between the lines and zero.
+	Area bool
+	// Percent formats the values as percentages.
+	Percent bool
+}
+
+// equitySeries returns the equity curve of the balances.
+func equitySeries(name string, balances []BalancePoint) chartSeries {
+	s := chartSeries{Name: name, Points: make([]chartPoint, len(balances))}
+	for i, b := range balances {
+		s.Points[i] = chartPoint{Time: b.Time, Value: b.Equity}
+	}
+	return s
+}
+
+// drawdownSeries returns the drawdown curve of the balances, as negative values.
+func drawdownSeries(name string, balances []BalancePoint) chartSeries {
+	s := chartSeries{Name: name, Points: make([]chartPoint, len(balances))}
+	for i, b := range balances {
+		s.Points[i] = chartPoint{Time: b.Time, Value: -b.Drawdown}
+	}
+	return s
+}
+
+// bounds returns the time and value ranges of the chart.
+func (c chart) bounds() (start, end time.Time, lowest, highest float64) {
+	lowest, highest = math.Inf(1), math.Inf(-1)
+	if c.Area {
+		lowest, highest = 0, 0
+	}
+
+	for _, s := range c.Series {
+		for _, p := range s.Points {
+			if start.IsZero() || p.Time.Before(start) {
+				start = p.Time
+			}
+			if p.Time.After(end) {
+				end = p.Time
+			}
+			lowest = math.Min(lowest, p.Value)
+			highest = math.Max(highest, p.Value)
+		}
+	}
+
+	if highest <= lowest {
+		highest = lowest + 1
+	}
+	return start, end, lowest, highest
+}
+
+func (c chart) format(v float64) string {
+	if c.Percent {
+		return fmt.Sprintf("%.2f%%", v*100)
+	}
+	return fmt.Sprintf("%.2f", v)
+}
+
+// SVG renders the chart as an inline SVG element.
+func (c chart) SVG() template.HTML {
+	start, end, lowest, highest := c.bounds()
+	span := end.Sub(start)
+	x := func(t time.Time) float64 {
+		if span <= 0 {
+			return chartPadding
+		}
+		return chartPadding + float64(t.Sub(start))/float64(span)*(chartWidth-2*chartPadding)
+	}
+	y := func(v float64) float64 {
+		return chartHeight - chartPadding - (v-lowest)/(highest-lowest)*(chartHeight-2*chartPadding)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" class="chart">`, chartWidth, chartHeight)
+
+	// Axes and labels
+	fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#9ca3af"/>`,
+		chartPadding, chartHeight-chartPadding, chartWidth-chartPadding, chartHeight-chartPadding)
+	fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#9ca3af"/>`,
+		chartPadding, chartPadding, chartPadding, chartHeight-chartPadding)
+	fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" font-size="10">%s</text>`,
+		chartPadding-4, y(highest)+4, c.format(highest))
+	fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" font-size="10">%s</text>`,
+		chartPadding-4, y(lowest)+4, c.format(lowest))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="10">%s</text>`,
+		chartPadding, chartHeight-chartPadding+16, start.UTC().Format(time.DateTime))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end" font-size="10">%s</text>`,
+		chartWidth-chartPadding, chartHeight-chartPadding+16, end.UTC().Format(time.DateTime))
+
+	for i, s := range c.Series {
+		color := chartColors[i%len(chartColors)]
+		points := downsample(s.Points)
+		if len(points) == 0 {
+			continue
+		}
+
+		// Line
+		coords := make([]string, len(points))
+		for j, p := range points {
+			coords[j] = fmt.Sprintf("%.1f,%.1f", x(p.Time), y(p.Value))
+		}
+		if c.Area {
+			fmt.Fprintf(&b, `<polygon points="%.1f,%.1f %s %.1f,%.1f" fill="%s" fill-opacity="0.2" stroke="none"/>`,
+				x(points[0].Time), y(0), strings.Join(coords, " "), x(points[len(points)-1].Time), y(0), color)
+		}
+		fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1.5"/>`,
+			strings.Join(coords, " "), color)
+
+		// Legend
+		if len(c.Series) > 1 {
+			fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end" font-size="11" fill="%s">%s</text>`,
+				chartWidth-chartPadding, chartPadding+14*i, color, html.EscapeString(s.Name))
+		}
+	}
+
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String()) //nolint:gosec // Built from escaped values
+}
+
+// downsample keeps at most chartMaxPoints points, including the last one.
+func downsample(points []chartPoint) []chartPoint {
+	if len(points) <= chartMaxPoints {
+		return points
+	}
+
+	step := int(math.Ceil(float64(len(points)) / chartMaxPoints))
+	sampled := make([]chartPoint, 0, chartMaxPoints+1)
+	for i := 0; i < len(points); i += step {
+		sampled = append(sampled, points[i])
+	}
+	if last := points[len(points)-1]; sampled[len(sampled)-1] != last {
+		sampled = append(sampled, last)
+	}
+	return sampled
+}
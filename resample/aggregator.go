@@ -0,0 +1,296 @@
+package resample
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	candlesticksapi "github.com/cryptellation/candlesticks/api"
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
+	"github.com/cryptellation/candlesticks/pkg/period"
+	ticksapi "github.com/cryptellation/ticks/api"
+	"github.com/cryptellation/ticks/pkg/tick"
+	"go.temporal.io/sdk/workflow"
+)
+
+var (
+	// ErrInvalidPeriod is returned when an aggregator period is not positive.
+	ErrInvalidPeriod = errors.New("invalid aggregation period")
+)
+
+// CandlesticksSource is the source of the candlesticks used by the
+// aggregator to back-fill its first candles. The Cryptellation client
+// implements this interface.
+type CandlesticksSource interface {
+	// ListCandlesticks lists candlesticks with the same semantics as the
+	// candlesticks service.
+	ListCandlesticks(
+		ctx context.Context,
+		params candlesticksapi.ListCandlesticksWorkflowParams,
+	) (candlesticksapi.ListCandlesticksWorkflowResults, error)
+}
+
+// WithBackfill sets the source used by the Aggregator to back-fill the first
+// candle of each pair, that is only partially covered by the ticks, with the
+// candlestick of the same period. Only periods matching a candlesticks
+// service period can be back-filled.
+func WithBackfill(src CandlesticksSource) Options {
+	return func(c *config) {
+		c.backfill = src
+	}
+}
+
+// OnCandle adds a handler called by the Aggregator each time a candle is
+// updated or closed. Handlers are called outside of the aggregator lock and
+// must not block.
+func OnCandle(handler func(Candle)) Options {
+	return func(c *config) {
+		c.handlers = append(c.handlers, handler)
+	}
+}
+
+// Candle is a live candlestick built from ticks.
+type Candle struct {
+	Exchange string
+	Pair     string
+	Period   time.Duration
+	candlestick.Candlestick
+	// Closed is true once the period of the candle is over. A closed candle
+	// is not updated anymore.
+	Closed bool
+}
+
+type candleKey struct {
+	Exchange string
+	Pair     string
+	Period   time.Duration
+}
+
+// Aggregator aggregates ticks into live candles of several periods. Candles
+// are uncomplete when their first ticks are missing, which is the case of
+// the first candle of each pair unless it is back-filled (see WithBackfill).
+// Candles built from ticks have no volume.
+type Aggregator struct {
+	periods []time.Duration
+	config  config
+	candles map[candleKey]*Candle
+	mu      sync.Mutex
+	dropped atomic.Int64
+}
+
+// NewAggregator creates an aggregator emitting candles of the given periods.
+// The WithLocation, WithOffset and WithoutUncomplete options apply as for Resample.
+func NewAggregator(periods []time.Duration, opts ...Options) (*Aggregator, error) {
+	for _, p := range periods {
+		if p <= 0 {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPeriod, p)
+		}
+	}
+
+	cfg := config{
+		location: time.UTC,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &Aggregator{
+		periods: append([]time.Duration(nil), periods...),
+		config:  cfg,
+		candles: make(map[candleKey]*Candle),
+	}, nil
+}
+
+// Add aggregates a tick into the candles of its pair. Ticks of candles
+// already closed are ignored.
+func (a *Aggregator) Add(ctx context.Context, t tick.Tick) error {
+	a.mu.Lock()
+	var emitted []Candle
+	var errs []error
+	for _, p := range a.periods {
+		updated, err := a.add(ctx, t, p)
+		emitted = append(emitted, updated...)
+		errs = append(errs, err)
+	}
+	a.mu.Unlock()
+
+	a.emit(emitted)
+	return errors.Join(errs...)
+}
+
+// add aggregates a tick into the candle of the period and returns the
+// updated candles, with the closed one, if any.
+func (a *Aggregator) add(ctx context.Context, t tick.Tick, p time.Duration) ([]Candle, error) {
+	key := candleKey{Exchange: t.Exchange, Pair: t.Pair, Period: p}
+	start := a.config.bucketStart(t.Time, p)
+
+	var emitted []Candle
+	var err error
+	c, ok := a.candles[key]
+	switch {
+	case ok && (start.Before(c.Time) || c.Closed && start.Equal(c.Time)):
+		// Ignore late ticks
+		return nil, nil
+	case ok && start.Equal(c.Time):
+		c.High = math.Max(c.High, t.Price)
+		c.Low = math.Min(c.Low, t.Price)
+		c.Close = t.Price
+	default:
+		// Close the previous candle, if not already done
+		if ok && !c.Closed {
+			c.Closed = true
+			emitted = append(emitted, *c)
+		}
+
+		// Open a new candle, complete if it follows the previous one or starts with the tick
+		c = &Candle{
+			Exchange: t.Exchange,
+			Pair:     t.Pair,
+			Period:   p,
+			Candlestick: candlestick.Candlestick{
+				Time:       start,
+				Open:       t.Price,
+				High:       t.Price,
+				Low:        t.Price,
+				Close:      t.Price,
+				Uncomplete: !ok && !t.Time.Equal(start),
+			},
+		}
+		if c.Uncomplete {
+			err = a.backfill(ctx, c)
+		}
+		a.candles[key] = c
+	}
+
+	return append(emitted, *c), err
+}
+
+// backfill merges the candlestick of the same period from the back-fill
+// source into the partial candle.
+func (a *Aggregator) backfill(ctx context.Context, c *Candle) error {
+	if a.config.backfill == nil {
+		return nil
+	}
+
+	per, err := period.FromDuration(c.Period)
+	if err != nil || !per.IsAligned(c.Time) {
+		return nil
+	}
+
+	res, err := a.config.backfill.ListCandlesticks(ctx, candlesticksapi.ListCandlesticksWorkflowParams{
+		Exchange: c.Exchange,
+		Pair:     c.Pair,
+		Period:   per,
+		Start:    &c.Time,
+		End:      &c.Time,
+		Limit:    1,
+	})
+	if err != nil {
+		return fmt.Errorf("back-filling %s candle of %s on %s: %w", per, c.Pair, c.Exchange, err)
+	}
+	if len(res.List) == 0 || !res.List[0].Time.Equal(c.Time) {
+		return nil
+	}
+
+	cs := res.List[0]
+	c.Open = cs.Open
+	c.High = math.Max(c.High, cs.High)
+	c.Low = math.Min(c.Low, cs.Low)
+	c.Volume = cs.Volume
+	c.Uncomplete = false
+	return nil
+}
+
+// Advance closes the candles whose period is over at the given time, for
+// the pairs that have not received a tick since. It is meant to be called
+// periodically (e.g. from a time.Ticker) to close candles without waiting
+// for the next tick.
+func (a *Aggregator) Advance(now time.Time) {
+	a.mu.Lock()
+	var emitted []Candle
+	for _, c := range a.candles {
+		if !c.Closed && !now.Before(c.Time.Add(c.Period)) {
+			c.Closed = true
+			emitted = append(emitted, *c)
+		}
+	}
+	a.mu.Unlock()
+
+	sort.Slice(emitted, func(i, j int) bool {
+		return emitted[i].Time.Before(emitted[j].Time)
+	})
+	a.emit(emitted)
+}
+
+// Candles returns the current candle of each pair and period.
+func (a *Aggregator) Candles() []Candle {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	candles := make([]Candle, 0, len(a.candles))
+	for _, c := range a.candles {
+		candles = append(candles, *c)
+	}
+	return candles
+}
+
+// Consume aggregates the ticks of the channel until it is closed or the
+// context is done.
+func (a *Aggregator) Consume(ctx context.Context, ticks <-chan tick.Tick) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case t, ok := <-ticks:
+			if !ok {
+				return nil
+			}
+			if err := a.Add(ctx, t); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Callback returns a callback to set in the ListenerParams of ListenToTicks,
+// that only pushes the received ticks to the channel. The ticks are aggregated
+// by Consume, that must read the channel from its own goroutine: back-filling
+// calls the candlesticks service, which must not be done from the callback
+// workflow. The callback never blocks, so ticks are dropped when the channel
+// is full (see Dropped).
+func (a *Aggregator) Callback(
+	ticks chan<- tick.Tick,
+) func(workflow.Context, ticksapi.ListenToTicksCallbackWorkflowParams) error {
+	return func(_ workflow.Context, params ticksapi.ListenToTicksCallbackWorkflowParams) error {
+		select {
+		case ticks <- params.Tick:
+		default:
+			a.dropped.Add(1)
+		}
+		return nil
+	}
+}
+
+// Dropped returns the number of ticks dropped by the callback because the
+// channel was full.
+func (a *Aggregator) Dropped() int64 {
+	return a.dropped.Load()
+}
+
+// emit calls the handlers with the candles, except the uncomplete ones when
+// they are dropped.
+func (a *Aggregator) emit(candles []Candle) {
+	for _, c := range candles {
+		if a.config.dropUncomplete && c.Uncomplete {
+			continue
+		}
+		for _, h := range a.config.handlers {
+			h(c)
+		}
+	}
+}
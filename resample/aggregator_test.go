@@ -0,0 +1,235 @@
+package resample
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	candlesticksapi "github.com/cryptellation/candlesticks/api"
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
+	"github.com/cryptellation/go-clients/internal/fixture"
+	ticksapi "github.com/cryptellation/ticks/api"
+	"github.com/cryptellation/ticks/pkg/tick"
+	"github.com/stretchr/testify/require"
+)
+
+// source is a back-fill source returning the same candlesticks for every request.
+type source struct {
+	list []candlestick.Candlestick
+	err  error
+}
+
+func (s source) ListCandlesticks(
+	_ context.Context,
+	_ candlesticksapi.ListCandlesticksWorkflowParams,
+) (candlesticksapi.ListCandlesticksWorkflowResults, error) {
+	return candlesticksapi.ListCandlesticksWorkflowResults{List: s.list}, s.err
+}
+
+// candle returns a five minutes candle starting at the nth minute.
+func candle(n int, open, high, low, close float64) Candle {
+	return Candle{
+		Exchange: fixture.Exchange,
+		Pair:     fixture.Pair,
+		Period:   5 * time.Minute,
+		Candlestick: candlestick.Candlestick{
+			Time:  fixture.Minute(n),
+			Open:  open,
+			High:  high,
+			Low:   low,
+			Close: close,
+		},
+	}
+}
+
+func closed(c Candle) Candle {
+	c.Closed = true
+	return c
+}
+
+func uncomplete(c Candle) Candle {
+	c.Uncomplete = true
+	return c
+}
+
+func TestAggregatorAdd(t *testing.T) {
+	tests := []struct {
+		name     string
+		ticks    []tick.Tick
+		opts     []Options
+		expected []Candle
+	}{
+		{
+			name:     "tick at the start of the period",
+			ticks:    []tick.Tick{fixture.Tick(0, 10)},
+			expected: []Candle{candle(0, 10, 10, 10, 10)},
+		},
+		{
+			name:     "first tick in the middle of the period",
+			ticks:    []tick.Tick{fixture.Tick(2, 10)},
+			expected: []Candle{uncomplete(candle(0, 10, 10, 10, 10))},
+		},
+		{
+			name:  "ticks of the same period",
+			ticks: []tick.Tick{fixture.Tick(0, 10), fixture.Tick(1, 12), fixture.Tick(2, 8)},
+			expected: []Candle{
+				candle(0, 10, 10, 10, 10),
+				candle(0, 10, 12, 10, 12),
+				candle(0, 10, 12, 8, 8),
+			},
+		},
+		{
+			name:  "tick of the next period",
+			ticks: []tick.Tick{fixture.Tick(2, 10), fixture.Tick(6, 12)},
+			expected: []Candle{
+				uncomplete(candle(0, 10, 10, 10, 10)),
+				closed(uncomplete(candle(0, 10, 10, 10, 10))),
+				candle(5, 12, 12, 12, 12),
+			},
+		},
+		{
+			name:     "late tick",
+			ticks:    []tick.Tick{fixture.Tick(5, 10), fixture.Tick(4, 12)},
+			expected: []Candle{candle(5, 10, 10, 10, 10)},
+		},
+		{
+			name:     "without uncomplete",
+			ticks:    []tick.Tick{fixture.Tick(2, 10), fixture.Tick(3, 11), fixture.Tick(5, 12)},
+			opts:     []Options{WithoutUncomplete()},
+			expected: []Candle{candle(5, 12, 12, 12, 12)},
+		},
+		{
+			name:  "back-filled",
+			ticks: []tick.Tick{fixture.Tick(2, 10)},
+			opts: []Options{WithBackfill(source{list: []candlestick.Candlestick{
+				{Time: fixture.Minute(0), Open: 9, High: 15, Low: 7, Close: 12, Volume: 100},
+			}})},
+			expected: []Candle{func() Candle {
+				c := candle(0, 9, 15, 7, 10)
+				c.Volume = 100
+				return c
+			}()},
+		},
+		{
+			name:  "back-fill without candlestick",
+			ticks: []tick.Tick{fixture.Tick(2, 10)},
+			opts: []Options{WithBackfill(source{list: []candlestick.Candlestick{
+				{Time: fixture.Minute(5), Open: 9, High: 15, Low: 7, Close: 12, Volume: 100},
+			}})},
+			expected: []Candle{uncomplete(candle(0, 10, 10, 10, 10))},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var emitted []Candle
+			opts := append([]Options{OnCandle(func(c Candle) {
+				emitted = append(emitted, c)
+			})}, tt.opts...)
+
+			a, err := NewAggregator([]time.Duration{5 * time.Minute}, opts...)
+			require.NoError(t, err)
+			for _, tk := range tt.ticks {
+				require.NoError(t, a.Add(context.Background(), tk))
+			}
+			require.Equal(t, tt.expected, emitted)
+		})
+	}
+}
+
+func TestAggregatorAddBackfillError(t *testing.T) {
+	errSource := errors.New("source error")
+	a, err := NewAggregator([]time.Duration{5 * time.Minute}, WithBackfill(source{err: errSource}))
+	require.NoError(t, err)
+
+	err = a.Add(context.Background(), fixture.Tick(2, 10))
+	require.ErrorIs(t, err, errSource)
+	require.Equal(t, []Candle{uncomplete(candle(0, 10, 10, 10, 10))}, a.Candles())
+}
+
+func TestAggregatorAdvance(t *testing.T) {
+	var emitted []Candle
+	a, err := NewAggregator([]time.Duration{time.Minute, 5 * time.Minute}, OnCandle(func(c Candle) {
+		emitted = append(emitted, c)
+	}))
+	require.NoError(t, err)
+	require.NoError(t, a.Add(context.Background(), fixture.Tick(0, 10)))
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		periods []time.Duration
+	}{
+		{name: "before the end of the periods", now: fixture.Minute(0).Add(30 * time.Second)},
+		{name: "end of the shortest period", now: fixture.Minute(1), periods: []time.Duration{time.Minute}},
+		{name: "already closed", now: fixture.Minute(2)},
+		{name: "end of the longest period", now: fixture.Minute(5), periods: []time.Duration{5 * time.Minute}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emitted = nil
+			a.Advance(tt.now)
+
+			periods := make([]time.Duration, 0, len(emitted))
+			for _, c := range emitted {
+				require.True(t, c.Closed)
+				periods = append(periods, c.Period)
+			}
+			require.ElementsMatch(t, tt.periods, periods)
+		})
+	}
+}
+
+func TestAggregatorCandles(t *testing.T) {
+	a, err := NewAggregator([]time.Duration{time.Minute, 5 * time.Minute})
+	require.NoError(t, err)
+	require.NoError(t, a.Add(context.Background(), fixture.Tick(0, 10)))
+	require.NoError(t, a.Add(context.Background(), fixture.Tick(1, 12)))
+
+	candles := a.Candles()
+	sort.Slice(candles, func(i, j int) bool {
+		return candles[i].Period < candles[j].Period
+	})
+	require.Len(t, candles, 2)
+	require.Equal(t, time.Minute, candles[0].Period)
+	require.Equal(t, fixture.Minute(1), candles[0].Time)
+	require.Equal(t, candle(0, 10, 12, 10, 12), candles[1])
+}
+
+func TestAggregatorCallback(t *testing.T) {
+	a, err := NewAggregator([]time.Duration{5 * time.Minute})
+	require.NoError(t, err)
+
+	ticks := make(chan tick.Tick, 1)
+	callback := a.Callback(ticks)
+	for i := 0; i < 3; i++ {
+		err := callback(nil, ticksapi.ListenToTicksCallbackWorkflowParams{Tick: fixture.Tick(i, float64(i))})
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, int64(2), a.Dropped())
+	require.Equal(t, fixture.Tick(0, 0), <-ticks)
+}
+
+func TestNewAggregator(t *testing.T) {
+	tests := []struct {
+		name    string
+		periods []time.Duration
+		err     error
+	}{
+		{name: "valid", periods: []time.Duration{time.Minute, time.Hour}},
+		{name: "no period", periods: nil},
+		{name: "zero", periods: []time.Duration{time.Minute, 0}, err: ErrInvalidPeriod},
+		{name: "negative", periods: []time.Duration{-time.Minute}, err: ErrInvalidPeriod},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAggregator(tt.periods)
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
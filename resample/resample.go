@@ -0,0 +1,149 @@
+package resample
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
+	"github.com/cryptellation/candlesticks/pkg/period"
+)
+
+var (
+	// ErrInvalidTarget is returned when the target duration is not a multiple
+	// of the source period.
+	ErrInvalidTarget = errors.New("invalid resampling target")
+)
+
+type config struct {
+	location       *time.Location
+	offset         time.Duration
+	dropUncomplete bool
+
+	// Aggregator only
+	backfill CandlesticksSource
+	handlers []func(Candle)
+}
+
+// Options is a function that modifies the resampling configuration.
+type Options func(*config)
+
+// WithLocation aligns the resampled candlesticks on the given location
+// instead of UTC (e.g. daily candlesticks starting at local midnight).
+func WithLocation(loc *time.Location) Options {
+	return func(c *config) {
+		c.location = loc
+	}
+}
+
+// WithOffset shifts the start of the resampled candlesticks by the given
+// offset, for example to align them on an exchange session opening.
+func WithOffset(offset time.Duration) Options {
+	return func(c *config) {
+		c.offset = offset
+	}
+}
+
+// WithoutUncomplete drops the resampled candlesticks that are built from an
+// incomplete set of source candlesticks, instead of flagging them as uncomplete.
+func WithoutUncomplete() Options {
+	return func(c *config) {
+		c.dropUncomplete = true
+	}
+}
+
+// Resample aggregates candlesticks of the given period, as returned by
+// ListCandlesticks, into candlesticks of the target duration, which must be
+// a multiple of the source period.
+//
+// Resampled candlesticks are uncomplete if a source candlestick is missing
+// or uncomplete. As resampling is deterministic, it can be used from within
+// workflows.
+func Resample(
+	list []candlestick.Candlestick,
+	from period.Symbol,
+	to time.Duration,
+	opts ...Options,
+) ([]candlestick.Candlestick, error) {
+	if err := from.Validate(); err != nil {
+		return nil, err
+	}
+	if to <= 0 || to%from.Duration() != 0 {
+		return nil, fmt.Errorf("%w: %s is not a multiple of %s", ErrInvalidTarget, to, from.Duration())
+	}
+
+	cfg := config{
+		location: time.UTC,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	// Order source candlesticks
+	sorted := make([]candlestick.Candlestick, len(list))
+	copy(sorted, list)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	// Aggregate candlesticks by bucket
+	expected := int(to / from.Duration())
+	res := make([]candlestick.Candlestick, 0, len(sorted)/expected+1)
+	for i := 0; i < len(sorted); {
+		start := cfg.bucketStart(sorted[i].Time, to)
+		cs, count := aggregate(sorted[i:], start, start.Add(to))
+		i += count
+
+		cs.Uncomplete = cs.Uncomplete || count != expected
+		if cfg.dropUncomplete && cs.Uncomplete {
+			continue
+		}
+		res = append(res, cs)
+	}
+
+	return res, nil
+}
+
+// bucketStart returns the start time of the resampled candlestick containing t.
+func (c config) bucketStart(t time.Time, d time.Duration) time.Time {
+	// Shift the time so the buckets are aligned on the location and offset
+	_, zoneOffset := t.In(c.location).Zone()
+	shift := time.Duration(zoneOffset)*time.Second - c.offset
+
+	shifted := t.Add(shift).UnixNano()
+	start := shifted - mod(shifted, d.Nanoseconds())
+	return time.Unix(0, start).Add(-shift).In(time.UTC)
+}
+
+func mod(a, b int64) int64 {
+	return ((a % b) + b) % b
+}
+
+// aggregate aggregates the first candlesticks of the list that are between
+// start (included) and end (excluded), and returns the quantity used.
+func aggregate(list []candlestick.Candlestick, start, end time.Time) (candlestick.Candlestick, int) {
+	cs := candlestick.Candlestick{
+		Time: start,
+		Open: list[0].Open,
+		High: -math.MaxFloat64,
+		Low:  math.MaxFloat64,
+	}
+
+	count := 0
+	for _, c := range list {
+		if !c.Time.Before(end) {
+			break
+		}
+
+		cs.High = math.Max(cs.High, c.High)
+		cs.Low = math.Min(cs.Low, c.Low)
+		cs.Close = c.Close
+		cs.Volume += c.Volume
+		cs.Uncomplete = cs.Uncomplete || c.Uncomplete
+		count++
+	}
+
+	return cs, count
+}
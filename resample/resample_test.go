@@ -0,0 +1,194 @@
+package resample
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
+	"github.com/cryptellation/candlesticks/pkg/period"
+	"github.com/cryptellation/go-clients/internal/fixture"
+	"github.com/stretchr/testify/require"
+)
+
+// minutes returns a one minute candlestick for each given minute, whose
+// prices are based on the minute to check their aggregation.
+func minutes(list ...int) []candlestick.Candlestick {
+	res := make([]candlestick.Candlestick, len(list))
+	for i, n := range list {
+		res[i] = candlestick.Candlestick{
+			Time:   fixture.Minute(n),
+			Open:   float64(n),
+			High:   float64(n) + 1,
+			Low:    float64(n) - 1,
+			Close:  float64(n) + 0.5,
+			Volume: 1,
+		}
+	}
+	return res
+}
+
+func TestResample(t *testing.T) {
+	utc2 := time.FixedZone("UTC+2", 2*60*60)
+
+	tests := []struct {
+		name     string
+		list     []candlestick.Candlestick
+		from     period.Symbol
+		to       time.Duration
+		opts     []Options
+		expected []candlestick.Candlestick
+	}{
+		{
+			name: "complete buckets",
+			list: minutes(0, 1, 2, 3, 4, 5, 6, 7, 8, 9),
+			from: period.M1,
+			to:   5 * time.Minute,
+			expected: []candlestick.Candlestick{
+				{Time: fixture.Minute(0), Open: 0, High: 5, Low: -1, Close: 4.5, Volume: 5},
+				{Time: fixture.Minute(5), Open: 5, High: 10, Low: 4, Close: 9.5, Volume: 5},
+			},
+		},
+		{
+			name: "unordered source",
+			list: minutes(4, 2, 0, 3, 1),
+			from: period.M1,
+			to:   5 * time.Minute,
+			expected: []candlestick.Candlestick{
+				{Time: fixture.Minute(0), Open: 0, High: 5, Low: -1, Close: 4.5, Volume: 5},
+			},
+		},
+		{
+			name: "missing source candlestick",
+			list: minutes(0, 1, 3, 4, 5, 6, 7, 8, 9),
+			from: period.M1,
+			to:   5 * time.Minute,
+			expected: []candlestick.Candlestick{
+				{Time: fixture.Minute(0), Open: 0, High: 5, Low: -1, Close: 4.5, Volume: 4, Uncomplete: true},
+				{Time: fixture.Minute(5), Open: 5, High: 10, Low: 4, Close: 9.5, Volume: 5},
+			},
+		},
+		{
+			name: "source starting in the middle of a bucket",
+			list: minutes(3, 4, 5, 6, 7, 8, 9),
+			from: period.M1,
+			to:   5 * time.Minute,
+			expected: []candlestick.Candlestick{
+				{Time: fixture.Minute(0), Open: 3, High: 5, Low: 2, Close: 4.5, Volume: 2, Uncomplete: true},
+				{Time: fixture.Minute(5), Open: 5, High: 10, Low: 4, Close: 9.5, Volume: 5},
+			},
+		},
+		{
+			name: "uncomplete source candlestick",
+			list: func() []candlestick.Candlestick {
+				list := minutes(0, 1, 2, 3, 4)
+				list[4].Uncomplete = true
+				return list
+			}(),
+			from: period.M1,
+			to:   5 * time.Minute,
+			expected: []candlestick.Candlestick{
+				{Time: fixture.Minute(0), Open: 0, High: 5, Low: -1, Close: 4.5, Volume: 5, Uncomplete: true},
+			},
+		},
+		{
+			name: "without uncomplete",
+			list: minutes(3, 4, 5, 6, 7, 8, 9),
+			from: period.M1,
+			to:   5 * time.Minute,
+			opts: []Options{WithoutUncomplete()},
+			expected: []candlestick.Candlestick{
+				{Time: fixture.Minute(5), Open: 5, High: 10, Low: 4, Close: 9.5, Volume: 5},
+			},
+		},
+		{
+			name: "with offset",
+			list: minutes(2, 3, 4, 5, 6, 7, 8, 9, 10, 11),
+			from: period.M1,
+			to:   5 * time.Minute,
+			opts: []Options{WithOffset(2 * time.Minute)},
+			expected: []candlestick.Candlestick{
+				{Time: fixture.Minute(2), Open: 2, High: 7, Low: 1, Close: 6.5, Volume: 5},
+				{Time: fixture.Minute(7), Open: 7, High: 12, Low: 6, Close: 11.5, Volume: 5},
+			},
+		},
+		{
+			name: "with location",
+			list: minutes(-2*60, -60, 0, 60),
+			from: period.H1,
+			to:   3 * time.Hour,
+			opts: []Options{WithLocation(utc2)},
+			expected: []candlestick.Candlestick{
+				{Time: fixture.Minute(-2 * 60), Open: -120, High: 1, Low: -121, Close: 0.5, Volume: 3},
+				{Time: fixture.Minute(60), Open: 60, High: 61, Low: 59, Close: 60.5, Volume: 1, Uncomplete: true},
+			},
+		},
+		{
+			name: "daily with location",
+			list: minutes(-2*60, 0, 21*60),
+			from: period.H1,
+			to:   24 * time.Hour,
+			opts: []Options{WithLocation(utc2)},
+			expected: []candlestick.Candlestick{
+				{Time: fixture.Minute(-2 * 60), Open: -120, High: 1261, Low: -121, Close: 1260.5, Volume: 3, Uncomplete: true},
+			},
+		},
+		{
+			name:     "empty",
+			list:     nil,
+			from:     period.M1,
+			to:       5 * time.Minute,
+			expected: []candlestick.Candlestick{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Resample(tt.list, tt.from, tt.to, tt.opts...)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, res)
+		})
+	}
+}
+
+func TestResampleInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		from period.Symbol
+		to   time.Duration
+		err  error
+	}{
+		{
+			name: "invalid period",
+			from: period.Symbol("unknown"),
+			to:   5 * time.Minute,
+		},
+		{
+			name: "not a multiple",
+			from: period.M1,
+			to:   90 * time.Second,
+			err:  ErrInvalidTarget,
+		},
+		{
+			name: "zero",
+			from: period.M1,
+			to:   0,
+			err:  ErrInvalidTarget,
+		},
+		{
+			name: "negative",
+			from: period.M1,
+			to:   -5 * time.Minute,
+			err:  ErrInvalidTarget,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Resample(minutes(0, 1), tt.from, tt.to)
+			require.Error(t, err)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
@@ -1,7 +1,12 @@
 package wfclient
 
 import (
+	"time"
+
 	"github.com/cryptellation/candlesticks/api"
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
+	"github.com/cryptellation/go-clients/client"
+	"github.com/cryptellation/go-clients/resample"
 	"go.temporal.io/sdk/workflow"
 )
 
@@ -11,16 +16,43 @@ func (c wfClient) ListCandlesticks(
 	params api.ListCandlesticksWorkflowParams,
 	childWorkflowOptions *workflow.ChildWorkflowOptions,
 ) (result api.ListCandlesticksWorkflowResults, err error) {
-	// Set default child workflow options if not provided
-	if childWorkflowOptions == nil {
-		childWorkflowOptions = &workflow.ChildWorkflowOptions{}
+	// Normalize the pair before calling the service
+	if params.Pair, err = client.NormalizePair(params.Pair); err != nil {
+		return result, err
+	}
+
+	// Copy the child workflow options, so the caller ones are left untouched
+	var opts workflow.ChildWorkflowOptions
+	if childWorkflowOptions != nil {
+		opts = *childWorkflowOptions
 	}
 
 	// Set task queue if not already set
-	if childWorkflowOptions.TaskQueue == "" {
-		childWorkflowOptions.TaskQueue = api.WorkerTaskQueueName
+	if opts.TaskQueue == "" {
+		opts.TaskQueue = api.WorkerTaskQueueName
+	}
+
+	// Set execution timeout if not already set
+	opts = c.timeouts.withTimeout(MethodListCandlesticks, opts)
+
+	// Execute the child workflow with the options
+	result, err = c.candlesticks.ListCandlesticks(ctx, params, &opts)
+	return result, mapError(MethodListCandlesticks, opts, err)
+}
+
+// ListResampledCandlesticks lists candlesticks with the period of the params
+// and resamples them into candlesticks of the target duration.
+func (c wfClient) ListResampledCandlesticks(
+	ctx workflow.Context,
+	params api.ListCandlesticksWorkflowParams,
+	to time.Duration,
+	childWorkflowOptions *workflow.ChildWorkflowOptions,
+	opts ...resample.Options,
+) ([]candlestick.Candlestick, error) {
+	res, err := c.ListCandlesticks(ctx, params, childWorkflowOptions)
+	if err != nil {
+		return nil, err
 	}
 
-	// Execute the child workflow with the provided options
-	return c.candlesticks.ListCandlesticks(ctx, params, childWorkflowOptions)
+	return resample.Resample(res.List, params.Period, to, opts...)
 }
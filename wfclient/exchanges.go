@@ -11,16 +11,21 @@ func (c wfClient) GetExchange(
 	params api.GetExchangeWorkflowParams,
 	childWorkflowOptions *workflow.ChildWorkflowOptions,
 ) (result api.GetExchangeWorkflowResults, err error) {
-	// Set default child workflow options if not provided
-	if childWorkflowOptions == nil {
-		childWorkflowOptions = &workflow.ChildWorkflowOptions{}
+	// Copy the child workflow options, so the caller ones are left untouched
+	var opts workflow.ChildWorkflowOptions
+	if childWorkflowOptions != nil {
+		opts = *childWorkflowOptions
 	}
 
 	// Set task queue if not already set
-	if childWorkflowOptions.TaskQueue == "" {
-		childWorkflowOptions.TaskQueue = api.WorkerTaskQueueName
+	if opts.TaskQueue == "" {
+		opts.TaskQueue = api.WorkerTaskQueueName
 	}
 
-	// Execute the child workflow with the provided options
-	return c.exchanges.GetExchange(ctx, params, childWorkflowOptions)
+	// Set execution timeout if not already set
+	opts = c.timeouts.withTimeout(MethodGetExchange, opts)
+
+	// Execute the child workflow with the options
+	result, err = c.exchanges.GetExchange(ctx, params, &opts)
+	return result, mapError(MethodGetExchange, opts, err)
 }
@@ -3,6 +3,7 @@ package wfclient
 import (
 	backtestsapi "github.com/cryptellation/backtests/api"
 	forwardtestsapi "github.com/cryptellation/forwardtests/api"
+	"github.com/cryptellation/go-clients/client"
 	"github.com/cryptellation/runtime"
 	"go.temporal.io/sdk/workflow"
 )
@@ -12,8 +13,16 @@ func (c wfClient) SubscribeToPrice(ctx workflow.Context, params SubscribeToPrice
 	childWorkflowOptions := workflow.ChildWorkflowOptions{
 		TaskQueue: params.Context.ParentTaskQueue,
 	}
+	childWorkflowOptions = c.timeouts.withTimeout(MethodSubscribeToPrice, childWorkflowOptions)
 	ctx = workflow.WithChildOptions(ctx, childWorkflowOptions)
 
+	// Normalize the pair before calling the service
+	pair, err := client.NormalizePair(params.Pair)
+	if err != nil {
+		return err
+	}
+	params.Pair = pair
+
 	switch params.Context.Mode {
 	case runtime.ModeBacktest:
 		_, err := c.backtests.SubscribeToPrice(ctx, backtestsapi.SubscribeToPriceWorkflowParams{
@@ -21,14 +30,51 @@ func (c wfClient) SubscribeToPrice(ctx workflow.Context, params SubscribeToPrice
 			Exchange:   params.Exchange,
 			Pair:       params.Pair,
 		})
-		return err
+		return mapError(MethodSubscribeToPrice, childWorkflowOptions, err)
 	case runtime.ModeForwardtest:
 		_, err := c.forwardtests.SubscribeToPrice(ctx, forwardtestsapi.SubscribeToPriceWorkflowParams{
 			ForwardtestID: params.Context.ID,
 			Exchange:      params.Exchange,
 			Pair:          params.Pair,
 		})
+		return mapError(MethodSubscribeToPrice, childWorkflowOptions, err)
+	case runtime.ModeLive:
+		return ErrNotImplemented
+	default:
+		return runtime.ErrInvalidMode
+	}
+}
+
+// CreateOrder creates an order on the run (backtest, forwardtest, etc.).
+func (c wfClient) CreateOrder(ctx workflow.Context, params CreateOrderParams) error {
+	childWorkflowOptions := workflow.ChildWorkflowOptions{
+		TaskQueue: params.Context.ParentTaskQueue,
+	}
+	childWorkflowOptions = c.timeouts.withTimeout(MethodCreateOrder, childWorkflowOptions)
+	ctx = workflow.WithChildOptions(ctx, childWorkflowOptions)
+
+	// Normalize the pair before calling the service
+	pair, err := client.NormalizePair(params.Order.Pair)
+	if err != nil {
 		return err
+	}
+	params.Order.Pair = pair
+
+	switch params.Context.Mode {
+	case runtime.ModeBacktest:
+		return mapError(MethodCreateOrder, childWorkflowOptions, workflow.ExecuteChildWorkflow(ctx,
+			backtestsapi.CreateBacktestOrderWorkflowName,
+			backtestsapi.CreateBacktestOrderWorkflowParams{
+				BacktestID: params.Context.ID,
+				Order:      params.Order,
+			}).Get(ctx, nil))
+	case runtime.ModeForwardtest:
+		return mapError(MethodCreateOrder, childWorkflowOptions, workflow.ExecuteChildWorkflow(ctx,
+			forwardtestsapi.CreateForwardtestOrderWorkflowName,
+			forwardtestsapi.CreateForwardtestOrderWorkflowParams{
+				ForwardtestID: params.Context.ID,
+				Order:         params.Order,
+			}).Get(ctx, nil))
 	case runtime.ModeLive:
 		return ErrNotImplemented
 	default:
@@ -0,0 +1,67 @@
+package wfclient
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cryptellation/go-clients/client"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Names of the workflow client methods, used to configure behaviors per method.
+const (
+	MethodSubscribeToPrice = "SubscribeToPrice"
+	MethodCreateOrder      = "CreateOrder"
+	MethodListCandlesticks = client.MethodListCandlesticks
+	MethodGetExchange      = client.MethodGetExchange
+)
+
+// WithTimeout sets the default execution timeout of the child workflows.
+func WithTimeout(timeout time.Duration) func(*wfClient) {
+	return func(c *wfClient) {
+		c.timeouts.defaultTimeout = timeout
+	}
+}
+
+// WithMethodTimeout sets the execution timeout of the child workflows of a
+// specific method (see Method* constants). A zero timeout disables the default
+// timeout for the method.
+func WithMethodTimeout(method string, timeout time.Duration) func(*wfClient) {
+	return func(c *wfClient) {
+		c.timeouts.methods[method] = timeout
+	}
+}
+
+type timeouts struct {
+	defaultTimeout time.Duration
+	methods        map[string]time.Duration
+}
+
+// withTimeout returns the child workflow options with the execution timeout
+// set, if it is not already set.
+func (t timeouts) withTimeout(method string, opts workflow.ChildWorkflowOptions) workflow.ChildWorkflowOptions {
+	if opts.WorkflowExecutionTimeout != 0 {
+		return opts
+	}
+
+	if d, ok := t.methods[method]; ok {
+		opts.WorkflowExecutionTimeout = d
+	} else {
+		opts.WorkflowExecutionTimeout = t.defaultTimeout
+	}
+	return opts
+}
+
+// mapError maps the error of a child workflow to the client errors, with the
+// effective timeout if the child workflow timed out.
+func mapError(method string, opts workflow.ChildWorkflowOptions, err error) error {
+	err = client.MapError(err)
+	if opts.WorkflowExecutionTimeout > 0 && errors.Is(err, client.ErrTimeout) {
+		return &client.TimeoutError{
+			Method:  method,
+			Timeout: opts.WorkflowExecutionTimeout,
+			Err:     err,
+		}
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+package wfclient
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cryptellation/go-clients/client"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/workflow"
+)
+
+func TestTimeoutsWithTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeouts timeouts
+		method   string
+		options  workflow.ChildWorkflowOptions
+		expected time.Duration
+	}{
+		{
+			name:     "no timeout",
+			method:   MethodCreateOrder,
+			expected: 0,
+		},
+		{
+			name:     "default timeout",
+			timeouts: timeouts{defaultTimeout: time.Minute},
+			method:   MethodCreateOrder,
+			expected: time.Minute,
+		},
+		{
+			name: "method timeout",
+			timeouts: timeouts{
+				defaultTimeout: time.Minute,
+				methods:        map[string]time.Duration{MethodCreateOrder: time.Hour},
+			},
+			method:   MethodCreateOrder,
+			expected: time.Hour,
+		},
+		{
+			name: "method timeout disabled",
+			timeouts: timeouts{
+				defaultTimeout: time.Minute,
+				methods:        map[string]time.Duration{MethodCreateOrder: 0},
+			},
+			method:   MethodCreateOrder,
+			expected: 0,
+		},
+		{
+			name:     "already set",
+			timeouts: timeouts{defaultTimeout: time.Minute},
+			method:   MethodCreateOrder,
+			options:  workflow.ChildWorkflowOptions{WorkflowExecutionTimeout: time.Second},
+			expected: time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			opts := tt.timeouts.withTimeout(tt.method, options)
+			require.Equal(t, tt.expected, opts.WorkflowExecutionTimeout)
+			require.Equal(t, tt.options, options, "the given options should be left untouched")
+		})
+	}
+}
+
+func TestMapError(t *testing.T) {
+	errTimeout := &client.Error{Kind: client.ErrTimeout, Message: "timeout"}
+	errOther := errors.New("other")
+
+	var te *client.TimeoutError
+	err := mapError(MethodCreateOrder, workflow.ChildWorkflowOptions{WorkflowExecutionTimeout: time.Minute}, errTimeout)
+	require.ErrorAs(t, err, &te)
+	require.Equal(t, MethodCreateOrder, te.Method)
+	require.Equal(t, time.Minute, te.Timeout)
+
+	require.Equal(t, errTimeout, mapError(MethodCreateOrder, workflow.ChildWorkflowOptions{}, errTimeout))
+	require.Equal(t, errOther, mapError(MethodCreateOrder, workflow.ChildWorkflowOptions{}, errOther))
+	require.NoError(t, mapError(MethodCreateOrder, workflow.ChildWorkflowOptions{}, nil))
+}
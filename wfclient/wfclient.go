@@ -1,24 +1,29 @@
 package wfclient
 
 import (
-	"errors"
+	"time"
 
 	backtestsclient "github.com/cryptellation/backtests/pkg/clients"
 	candlesticksapi "github.com/cryptellation/candlesticks/api"
+	"github.com/cryptellation/candlesticks/pkg/candlestick"
 	candlesticksclient "github.com/cryptellation/candlesticks/pkg/clients"
 	exchangesapi "github.com/cryptellation/exchanges/api"
 	exchangesclient "github.com/cryptellation/exchanges/pkg/clients"
 	forwardtestsclient "github.com/cryptellation/forwardtests/pkg/clients"
+	"github.com/cryptellation/go-clients/client"
+	"github.com/cryptellation/go-clients/resample"
 	"github.com/cryptellation/runtime"
+	"github.com/cryptellation/runtime/order"
 	"go.temporal.io/sdk/workflow"
 )
 
 var (
 	// ErrNotImplemented is returned when the function is not implemented.
-	ErrNotImplemented = errors.New("not implemented")
+	ErrNotImplemented = client.ErrNotImplemented
 )
 
 // WfClient is a client for the cryptellation exchanges service from a workflow perspective.
+// Returned errors are mapped to the client errors kinds (see client.MapError).
 type WfClient interface {
 	// SubscribeToPrice subscribes to specific price updates.
 	SubscribeToPrice(
@@ -41,6 +46,29 @@ type WfClient interface {
 	) (result exchangesapi.GetExchangeWorkflowResults, err error)
 }
 
+// ExtendedWfClient is a workflow client with the features added after
+// WfClient. It is a separate interface so the existing implementations and
+// mocks of WfClient do not have to implement them.
+type ExtendedWfClient interface {
+	WfClient
+
+	// CreateOrder creates an order on the run (backtest, forwardtest, etc.).
+	CreateOrder(
+		ctx workflow.Context,
+		params CreateOrderParams,
+	) error
+
+	// ListResampledCandlesticks lists candlesticks with the period of the params
+	// and resamples them into candlesticks of the target duration.
+	ListResampledCandlesticks(
+		ctx workflow.Context,
+		params candlesticksapi.ListCandlesticksWorkflowParams,
+		to time.Duration,
+		childWorkflowOptions *workflow.ChildWorkflowOptions,
+		opts ...resample.Options,
+	) ([]candlestick.Candlestick, error)
+}
+
 // SubscribeToPriceParams is the parameters to subscribe to price updates.
 type SubscribeToPriceParams struct {
 	Context  runtime.Context
@@ -48,19 +76,50 @@ type SubscribeToPriceParams struct {
 	Pair     string
 }
 
+// CreateOrderParams is the parameters to create an order.
+type CreateOrderParams struct {
+	Context runtime.Context
+	Order   order.Order
+}
+
 type wfClient struct {
 	backtests    backtestsclient.WfClient
 	exchanges    exchangesclient.WfClient
 	candlesticks candlesticksclient.WfClient
 	forwardtests forwardtestsclient.WfClient
+
+	timeouts timeouts
 }
 
+// Options is a function that modifies the workflow client configuration.
+type Options func(*wfClient)
+
 // NewWfClient creates a new workflow client.
 // This client is used to call workflows from within other workflows.
 // It is not used to call workflows from outside the workflow environment.
-func NewWfClient() WfClient {
-	return wfClient{
+// The payloads are encoded with the data converter of the worker executing
+// the workflows: to compress or encrypt them, set it on the worker client
+// (see the codec package), not on the workflow client.
+func NewWfClient(opts ...Options) WfClient {
+	return NewExtendedWfClient(opts...)
+}
+
+// NewExtendedWfClient creates a new workflow client with the extended features.
+func NewExtendedWfClient(opts ...Options) ExtendedWfClient {
+	c := wfClient{
+		backtests:    backtestsclient.NewWfClient(),
+		candlesticks: candlesticksclient.NewWfClient(),
 		exchanges:    exchangesclient.NewWfClient(),
 		forwardtests: forwardtestsclient.NewWfClient(),
+		timeouts: timeouts{
+			methods: make(map[string]time.Duration),
+		},
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(&c)
 	}
+
+	return c
 }